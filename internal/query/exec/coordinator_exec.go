@@ -31,11 +31,21 @@ import (
 	"meerkat/internal/query/physical"
 	"meerkat/internal/storage"
 	"sync"
+	"time"
 )
 
 type nodeManager interface {
 	sendCancel(err *execpb.ExecError)
-	sendQueryFragments(queryId uuid.UUID, fragments []*logical.Fragment)
+	sendQueryFragments(queryId uuid.UUID, fragments []*logical.Fragment, limits execbase.QueryLimits)
+	// stats waits for the stats sent by the nodes at the end of the query.
+	// The stats not received before timeout are not returned.
+	stats(timeout time.Duration) []*physical.NodeStats
+	// nodes returns the ids of the nodes executing the query.
+	nodes() []string
+	// finished returns the number of nodes that have finished the query.
+	finished() int
+	// progress returns the data read so far by the nodes.
+	progress() physical.Progress
 	Close()
 }
 
@@ -92,12 +102,12 @@ func (d *defaultNodeManager) buildCancelCmd(err *execpb.ExecError) *execpb.ExecC
 
 }
 
-func (d *defaultNodeManager) sendQueryFragments(queryId uuid.UUID, fragments []*logical.Fragment) {
+func (d *defaultNodeManager) sendQueryFragments(queryId uuid.UUID, fragments []*logical.Fragment, limits execbase.QueryLimits) {
 
 	defer d.mu.Unlock()
 	d.mu.Lock()
 
-	msg := d.buildQueryMsg(queryId, fragments)
+	msg := d.buildQueryMsg(queryId, fragments, limits)
 	fmt.Println("client len :", len(d.nodeClients))
 	for _, client := range d.nodeClients {
 		fmt.Println("sending query to :", client.nodeId)
@@ -106,7 +116,7 @@ func (d *defaultNodeManager) sendQueryFragments(queryId uuid.UUID, fragments []*
 
 }
 
-func (d *defaultNodeManager) buildQueryMsg(queryId uuid.UUID, fragments []*logical.Fragment) *execpb.ExecCmd {
+func (d *defaultNodeManager) buildQueryMsg(queryId uuid.UUID, fragments []*logical.Fragment, limits execbase.QueryLimits) *execpb.ExecCmd {
 
 	var buf bytes.Buffer
 	enc := gob.NewEncoder(&buf)
@@ -119,14 +129,88 @@ func (d *defaultNodeManager) buildQueryMsg(queryId uuid.UUID, fragments []*logic
 	return &execpb.ExecCmd{
 		Cmd: &execpb.ExecCmd_ExecQuery{
 			ExecQuery: &execpb.ExecQuery{
-				Id:   queryId[:],
-				Plan: buf.Bytes(),
+				Id:              queryId[:],
+				Plan:            buf.Bytes(),
+				MaxMemory:       limits.MaxMemory,
+				MaxRowsScanned:  limits.MaxRowsScanned,
+				MaxBytesScanned: limits.MaxBytesScanned,
 			},
 		},
 	}
 
 }
 
+func (d *defaultNodeManager) stats(timeout time.Duration) []*physical.NodeStats {
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	var stats []*physical.NodeStats
+
+	for _, client := range d.nodeClients {
+
+		select {
+		case s := <-client.stats:
+			stats = append(stats, s)
+		case <-client.done:
+			// the stats are sent before the stream is closed.
+			select {
+			case s := <-client.stats:
+				stats = append(stats, s)
+			default:
+			}
+		case <-timer.C:
+			return stats
+		}
+
+	}
+
+	return stats
+
+}
+
+func (d *defaultNodeManager) nodes() []string {
+
+	ids := make([]string, len(d.nodeClients))
+
+	for i, client := range d.nodeClients {
+		ids[i] = client.nodeId
+	}
+
+	return ids
+
+}
+
+func (d *defaultNodeManager) finished() int {
+
+	n := 0
+
+	for _, client := range d.nodeClients {
+		select {
+		case <-client.done:
+			n++
+		default:
+		}
+	}
+
+	return n
+
+}
+
+func (d *defaultNodeManager) progress() physical.Progress {
+
+	var p physical.Progress
+
+	for _, client := range d.nodeClients {
+		clientProgress := client.getProgress()
+		p.RowsScanned += clientProgress.RowsScanned
+		p.BytesScanned += clientProgress.BytesScanned
+	}
+
+	return p
+
+}
+
 func (d *defaultNodeManager) Close() {
 
 	defer d.mu.Unlock()
@@ -144,6 +228,11 @@ func newNodeClient(execCtx execbase.ExecutionContext, nodeId string, conn *grpc.
 		nodeId:     nodeId,
 		execClient: execpb.NewExecutorClient(conn),
 		execCtx:    execCtx,
+		stats:      make(chan *physical.NodeStats, 1),
+		done:       make(chan struct{}),
+		log: log.With().
+			Str("component", "nodeClient").
+			Str("nodeId", nodeId).Logger(),
 	}
 
 }
@@ -154,6 +243,14 @@ type nodeClient struct {
 	execClient    execpb.ExecutorClient
 	controlClient execpb.Executor_ControlClient
 	execCtx       execbase.ExecutionContext
+	// stats receives the execution stats of the node.
+	stats chan *physical.NodeStats
+	// done is closed when the control stream is closed.
+	done chan struct{}
+	// progressMu guards the last progress reported by the node.
+	progressMu sync.Mutex
+	progress   physical.Progress
+	log        zerolog.Logger
 }
 
 func (n *nodeClient) sendCancelCmd(cmd *execpb.ExecCmd) {
@@ -197,6 +294,8 @@ func (n *nodeClient) sendQueryCmd(cmd *execpb.ExecCmd) {
 
 		if err != nil {
 
+			close(n.done)
+
 			n.execCtx.CancelWithPropagation(err, execbase.NewExecError(
 				fmt.Sprintf("cannot open control stream to node %s [%s]", n.nodeId, err),
 				"",
@@ -227,6 +326,8 @@ func (n *nodeClient) sendQueryCmd(cmd *execpb.ExecCmd) {
 
 func (n *nodeClient) handleStream() {
 
+	defer close(n.done)
+
 	for {
 
 		execEvent, err := n.controlClient.Recv()
@@ -257,14 +358,49 @@ func (n *nodeClient) handleStream() {
 			_ = event
 			// do nothing for now, just log
 		case *execpb.ExecEvent_ExecStats:
-			_ = event
-			// do nothing for now, just log
+
+			stats, err := decodeStats(event.ExecStats)
+
+			if err != nil {
+				// the stats are informative, a broken event doesn't fail
+				// the query.
+				n.log.Error().Err(err).Msg("cannot decode stats")
+				continue
+			}
+
+			n.stats <- stats
+
+		case *execpb.ExecEvent_ExecProgress:
+
+			n.setProgress(physical.Progress{
+				RowsScanned:  event.ExecProgress.RowsScanned,
+				BytesScanned: event.ExecProgress.BytesScanned,
+			})
+
 		}
 
 	}
 
 }
 
+func (n *nodeClient) setProgress(progress physical.Progress) {
+
+	defer n.progressMu.Unlock()
+	n.progressMu.Lock()
+
+	n.progress = progress
+
+}
+
+func (n *nodeClient) getProgress() physical.Progress {
+
+	defer n.progressMu.Unlock()
+	n.progressMu.Lock()
+
+	return n.progress
+
+}
+
 func (n *nodeClient) close() {
 	go func() {
 		_ = n.controlClient.CloseSend()
@@ -272,9 +408,11 @@ func (n *nodeClient) close() {
 }
 
 func NewCoordinatorExecutor(
+	catalog cluster.Catalog,
 	segReg storage.SegmentRegistry,
 	streamReg physical.StreamRegistry,
 	nodeReg cluster.NodeRegistry,
+	spillDir string,
 ) *coordinatorExecutor {
 
 	id := uuid.New()
@@ -283,11 +421,13 @@ func NewCoordinatorExecutor(
 
 	exec := &coordinatorExecutor{
 		id:          id,
+		catalog:     catalog,
 		segReg:      segReg,
 		nodeManager: nodeManager,
 		streamReg:   streamReg,
 		nodeReg:     nodeReg,
 		execCtx:     execCtx,
+		spillDir:    spillDir,
 		log: log.With().
 			Str("component", "coordinatorExecutor").
 			Str("queryId", id.String()).Logger(),
@@ -300,30 +440,95 @@ func NewCoordinatorExecutor(
 type coordinatorExecutor struct {
 	id          uuid.UUID
 	log         zerolog.Logger
+	catalog     cluster.Catalog
 	segReg      storage.SegmentRegistry
 	nodeManager nodeManager
 	streamReg   physical.StreamRegistry
 	nodeReg     cluster.NodeRegistry
 	execCtx     execbase.ExecutionContext
 	outputOp    physical.RunnableOp
+	spillDir    string
+	// mu guards the dag, it's read while the query runs.
+	mu  sync.Mutex
+	dag physical.DAG
 }
 
-func (c *coordinatorExecutor) exec(query string, writer execbase.QueryOutputWriter) error {
+func (c *coordinatorExecutor) exec(
+	query string,
+	limits execbase.QueryLimits,
+	format execbase.OutputFormat,
+	writer execbase.QueryOutputWriter,
+) error {
 
 	defer c.execCtx.Cancel()
 
+	if limits.Timeout > 0 {
+		// the cancellation is propagated to the nodes by handleCtxCancel.
+		timer := time.AfterFunc(limits.Timeout, func() {
+			c.execCtx.CancelWithExecError(execbase.NewExecError(
+				fmt.Sprintf("query timeout exceeded: the query didn't finish in %v", limits.Timeout),
+				c.nodeReg.LocalNodeId(),
+			))
+		})
+		defer timer.Stop()
+	}
+
+	fragments, err := c.plan(query, nil)
+
+	if err != nil {
+		return err
+	}
+
+	go c.handleCtxCancel()
+
+	go c.sendFragmentsToNodes(fragments.NodeFragments(), limits)
+
+	dag, err := c.buildDAG(writer, format, fragments.AllFragments(), limits)
+
+	if err != nil {
+		c.Cancel(err)
+		return err
+	}
+
+	c.mu.Lock()
+	c.dag = dag
+	c.mu.Unlock()
+
+	dag.Run()
+
+	// the stats are reported only if the query succeeded.
+	if execErr := c.execCtx.Err(); execErr != nil {
+		return fmt.Errorf("query failed on node %v: %v", execErr.NodeName, execErr.Detail)
+	}
+
+	// the stats are only appended to the JSON column batches, the other
+	// formats can't tell them apart from the results.
+	if format != execbase.JSONOutput {
+		return nil
+	}
+
+	nodeStats := append([]*physical.NodeStats{dag.Stats()}, c.nodeManager.stats(statsTimeout)...)
+
+	return writeStats(writer, newQueryStats(nodeStats))
+
+}
+
+// plan transforms the query into the fragments executed by the cluster
+// nodes. If explain is not nil it's filled with the intermediate plans.
+func (c *coordinatorExecutor) plan(query string, explain *Explain) (*logical.Fragments, error) {
+
 	// Transform the string text into a abstract syntax tree
 	ast, err := parser.Parse(query)
 
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// perform the semantic validation
-	ast, err = parser.Analyze(ast)
+	ast, err = parser.Analyze(ast, newSegmentSchema(c.catalog, c.segReg))
 
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// transform the ast into a logical query plan
@@ -332,27 +537,25 @@ func (c *coordinatorExecutor) exec(query string, writer execbase.QueryOutputWrit
 	// optimize the plan
 	optPlan := logical.Optimize(logicalPlan)
 
-	// parallelize
-	fragments := logical.Parallelize(
-		optPlan,
-		c.nodeReg.LocalNodeId(),
-		nodeIds(c.nodeReg.Nodes([]string{cluster.Ready}, true)),
-	)
-
-	go c.handleCtxCancel()
-
-	go c.sendFragmentsToNodes(fragments.NodeFragments())
+	// the plans are described before parallelize rewrites them.
+	if explain != nil {
+		explain.AST = describeTree(ast)
+		explain.Logical = describeTree(optPlan)
+	}
 
-	dag, err := c.buildDAG(writer, fragments.AllFragments())
+	nodes := nodeIds(c.nodeReg.Nodes([]string{cluster.Ready}, true))
 
-	if err != nil {
-		c.Cancel(err)
-		return err
+	if explain != nil {
+		explain.Nodes = append([]string{c.nodeReg.LocalNodeId()}, nodes...)
 	}
 
-	dag.Run()
+	// parallelize
+	fragments := logical.Parallelize(optPlan, c.nodeReg.LocalNodeId(), nodes)
+
+	// the outputs writing a schema before the rows need all the columns.
+	fragments.Output().Columns = ast.Columns
 
-	return nil
+	return fragments, nil
 
 }
 
@@ -366,16 +569,19 @@ func nodeIds(nodes []cluster.Node) []string {
 
 func (c *coordinatorExecutor) buildDAG(
 	writer execbase.QueryOutputWriter,
+	format execbase.OutputFormat,
 	fragments []*logical.Fragment,
+	limits execbase.QueryLimits,
 ) (physical.DAG, error) {
 
 	dagBuilder := physical.NewDAGBuilder(
 		c.nodeReg,
 		c.segReg,
 		c.streamReg,
+		c.spillDir,
 	)
 
-	dag, err := dagBuilder.BuildDAG(fragments, c.id, writer, c.execCtx)
+	dag, err := dagBuilder.BuildDAG(fragments, c.id, writer, format, c.execCtx, limits)
 
 	if err != nil {
 		return nil, err
@@ -385,18 +591,46 @@ func (c *coordinatorExecutor) buildDAG(
 
 }
 
-func (c *coordinatorExecutor) sendFragmentsToNodes(fragments []*logical.Fragment) {
-	c.nodeManager.sendQueryFragments(c.id, fragments)
+func (c *coordinatorExecutor) sendFragmentsToNodes(fragments []*logical.Fragment, limits execbase.QueryLimits) {
+	c.nodeManager.sendQueryFragments(c.id, fragments, limits)
 }
 
 func (c *coordinatorExecutor) handleCtxCancel() {
 
 	<-c.execCtx.Done()
 
-	if c.execCtx.Err() != nil {
-		c.nodeManager.sendCancel(c.execCtx.Err())
+	execErr := c.execCtx.Err()
+
+	// the nodes are done once the query finished without error, they are
+	// only stopped if a limit was reached before they sent all their rows.
+	if execErr == nil && !c.execCtx.Stopped() {
+		return
+	}
+
+	c.nodeManager.sendCancel(execErr)
+
+}
+
+// progress returns the data read so far by the local DAG and the
+// nodes.
+func (c *coordinatorExecutor) progress() physical.Progress {
+
+	p := c.nodeManager.progress()
+
+	defer c.mu.Unlock()
+	c.mu.Lock()
+
+	if c.dag == nil {
+		return p
 	}
 
+	local := c.dag.Progress()
+
+	p.RowsScanned += local.RowsScanned
+	p.BytesScanned += local.BytesScanned
+
+	return p
+
 }
 
 func (c *coordinatorExecutor) Cancel(err error) {
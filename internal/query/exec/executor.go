@@ -25,41 +25,98 @@ import (
 )
 
 type Executor interface {
-	ExecuteQuery(query string, writer execbase.QueryOutputWriter) error
-	CancelQuery(queryId uuid.UUID)
+	// ExecuteQuery executes the query writing the results to writer in the
+	// given format. The query is canceled with an error if it exceeds the
+	// limits.
+	ExecuteQuery(
+		query string,
+		limits execbase.QueryLimits,
+		format execbase.OutputFormat,
+		writer execbase.QueryOutputWriter,
+	) error
+	// ExplainQuery returns the plans of the query without executing it.
+	ExplainQuery(query string) (*Explain, error)
+	// ListQueries returns the queries coordinated by this node.
+	ListQueries() []*QueryInfo
+	// CancelQuery cancels a query coordinated by this node on every node
+	// executing it. ErrQueryNotFound is returned if it isn't running.
+	CancelQuery(queryId uuid.UUID) error
 	Stop()
 }
 
+var ErrQueryNotFound = errors.New("query not found")
+
 func NewExecutor(
+	catalog cluster.Catalog,
 	segReg storage.SegmentRegistry,
 	streamReg physical.StreamRegistry,
 	nodeReg cluster.NodeRegistry,
+	spillDir string,
 ) Executor {
 
 	return &executor{
+		catalog:   catalog,
 		segReg:    segReg,
 		nodeReg:   nodeReg,
 		streamReg: streamReg,
+		spillDir:  spillDir,
+		queries:   newQueryRegistry(),
 	}
 
 }
 
 type executor struct {
+	catalog   cluster.Catalog
 	segReg    storage.SegmentRegistry
 	streamReg physical.StreamRegistry
 	nodeReg   cluster.NodeRegistry
+	// spillDir is where the queries write the data that doesn't fit in
+	// their memory budget.
+	spillDir string
+	queries  *queryRegistry
 }
 
-func (e executor) ExecuteQuery(query string, writer execbase.QueryOutputWriter) error {
+func (e executor) ExecuteQuery(
+	query string,
+	limits execbase.QueryLimits,
+	format execbase.OutputFormat,
+	writer execbase.QueryOutputWriter,
+) error {
+
+	coordinator := NewCoordinatorExecutor(e.catalog, e.segReg, e.streamReg, e.nodeReg, e.spillDir)
 
-	coordinator := NewCoordinatorExecutor(e.segReg, e.streamReg, e.nodeReg)
+	e.queries.add(coordinator, query)
+	defer e.queries.remove(coordinator.id)
 
-	return coordinator.exec(query, writer)
+	return coordinator.exec(query, limits, format, writer)
 
 }
 
-func (e *executor) CancelQuery(queryId uuid.UUID) {
-	panic("implement me")
+func (e executor) ExplainQuery(query string) (*Explain, error) {
+
+	coordinator := NewCoordinatorExecutor(e.catalog, e.segReg, e.streamReg, e.nodeReg, "")
+
+	return coordinator.explain(query)
+
+}
+
+func (e executor) ListQueries() []*QueryInfo {
+	return e.queries.list()
+}
+
+func (e executor) CancelQuery(queryId uuid.UUID) error {
+
+	coordinator := e.queries.get(queryId)
+
+	if coordinator == nil {
+		return ErrQueryNotFound
+	}
+
+	// the cancellation is sent to the nodes by handleCtxCancel.
+	coordinator.Cancel(errors.New("query canceled"))
+
+	return nil
+
 }
 
 func (e *executor) Stop() {
@@ -70,11 +127,13 @@ func NewServer(
 	nodeReg cluster.NodeRegistry,
 	segReg storage.SegmentRegistry,
 	streamReg physical.StreamRegistry,
+	spillDir string,
 ) *Server {
 	return &Server{
 		streamReg: streamReg,
 		nodeReg:   nodeReg,
 		segReg:    segReg,
+		spillDir:  spillDir,
 	}
 }
 
@@ -82,13 +141,14 @@ type Server struct {
 	streamReg physical.StreamRegistry
 	nodeReg   cluster.NodeRegistry
 	segReg    storage.SegmentRegistry
+	spillDir  string
 }
 
 func (s *Server) Control(controlSrv execpb.Executor_ControlServer) error {
 
 	fmt.Println("new control stream")
 
-	nodeExec := NewNodeExec(s.nodeReg, s.segReg, s.streamReg, controlSrv)
+	nodeExec := NewNodeExec(s.nodeReg, s.segReg, s.streamReg, controlSrv, s.spillDir)
 	nodeExec.Start()
 
 	<-nodeExec.ExecutionContext().Done()
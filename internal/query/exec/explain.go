@@ -0,0 +1,306 @@
+// Copyright 2020 The Meerkat Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exec
+
+import (
+	"fmt"
+	"meerkat/internal/cluster"
+	"meerkat/internal/query/execbase"
+	"meerkat/internal/query/logical"
+	"meerkat/internal/query/physical"
+	"meerkat/internal/storage"
+	"reflect"
+	"time"
+)
+
+// Explain describes how a query is planned and executed.
+type Explain struct {
+	// AST is the analyzed syntax tree.
+	AST interface{} `json:"ast"`
+	// Logical is the optimized logical plan.
+	Logical interface{} `json:"logical"`
+	// Nodes are the nodes executing the query, the first one is the
+	// coordinator.
+	Nodes     []string        `json:"nodes"`
+	Fragments []*FragmentInfo `json:"fragments"`
+	// Physical maps every node to the roots of its operator DAG. The DAGs
+	// are built without segments so they don't read any.
+	Physical map[string][]*physical.OperatorInfo `json:"physical"`
+	// Scans describes the segments of the coordinator read by the sources.
+	Scans []*ScanInfo `json:"scans"`
+}
+
+// ScanInfo describes the local segments of a table read by a source.
+type ScanInfo struct {
+	Table          string `json:"table"`
+	Segments       int    `json:"segments"`
+	SegmentsPruned int    `json:"segmentsPruned"`
+	Rows           int64  `json:"rows"`
+}
+
+// FragmentInfo describes a plan fragment and the nodes executing it.
+type FragmentInfo struct {
+	Parallel bool        `json:"parallel"`
+	Nodes    []string    `json:"nodes"`
+	Roots    interface{} `json:"roots"`
+}
+
+func (c *coordinatorExecutor) explain(query string) (*Explain, error) {
+
+	defer c.execCtx.Cancel()
+
+	explain := &Explain{
+		Physical: make(map[string][]*physical.OperatorInfo),
+	}
+
+	fragments, err := c.plan(query, explain)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, fragment := range fragments.AllFragments() {
+
+		info := &FragmentInfo{
+			Parallel: fragment.IsParallel,
+			Nodes:    explain.Nodes,
+			Roots:    describeTree(fragment.Roots),
+		}
+
+		if !fragment.IsParallel {
+			info.Nodes = explain.Nodes[:1]
+		}
+
+		explain.Fragments = append(explain.Fragments, info)
+
+	}
+
+	local := explain.Nodes[0]
+
+	explain.Physical[local], err = c.explainDAG(c.nodeReg, fragments.AllFragments())
+
+	if err != nil {
+		return nil, err
+	}
+
+	explain.Scans = c.explainScans(fragments.AllFragments())
+
+	for _, nodeId := range explain.Nodes[1:] {
+
+		nodeReg := &remoteNodeRegistry{NodeRegistry: c.nodeReg, nodeId: nodeId}
+
+		explain.Physical[nodeId], err = c.explainDAG(nodeReg, fragments.NodeFragments())
+
+		if err != nil {
+			return nil, err
+		}
+
+	}
+
+	return explain, nil
+
+}
+
+// explainDAG builds the DAG of the fragments as the local node of nodeReg
+// would do and returns its operators. The DAG is built without segments
+// nor streams so explaining a query doesn't acquire any resource.
+func (c *coordinatorExecutor) explainDAG(
+	nodeReg cluster.NodeRegistry,
+	fragments []*logical.Fragment,
+) ([]*physical.OperatorInfo, error) {
+
+	segReg := &noSegmentRegistry{SegmentRegistry: c.segReg}
+	dagBuilder := physical.NewDAGBuilder(nodeReg, segReg, nil, "")
+
+	dag, err := dagBuilder.BuildDAG(fragments, c.id, nil, execbase.JSONOutput, c.execCtx, execbase.QueryLimits{})
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer dag.Release()
+
+	return dag.Explain(), nil
+
+}
+
+// explainScans describes the local segments read by the sources of the
+// fragments using the segment infos, the segments are not opened.
+func (c *coordinatorExecutor) explainScans(fragments []*logical.Fragment) []*ScanInfo {
+
+	v := &sourceVisitor{}
+
+	for _, fragment := range fragments {
+		for _, root := range fragment.Roots {
+			logical.Walk(root, v)
+		}
+	}
+
+	var scans []*ScanInfo
+
+	infos := c.segReg.SegmentInfos()
+
+	for _, source := range v.sources {
+
+		scan := &ScanInfo{Table: source.TableName}
+
+		if source.Database != "" {
+			scan.Table = source.Database + "." + source.TableName
+		}
+
+		for _, info := range infos {
+
+			if !storage.MatchSegment(info, nil, source.Database, source.TableName, nil) {
+				continue
+			}
+
+			if storage.MatchSegment(info, source.Partitions, source.Database, source.TableName, source.Interval) {
+				scan.Segments++
+				scan.Rows += int64(info.Len)
+			} else {
+				scan.SegmentsPruned++
+			}
+
+		}
+
+		scans = append(scans, scan)
+
+	}
+
+	return scans
+
+}
+
+// sourceVisitor collects the sources of a plan.
+type sourceVisitor struct {
+	sources []*logical.SourceOp
+}
+
+func (v *sourceVisitor) VisitPre(n logical.Node) logical.Node {
+
+	if source, ok := n.(*logical.SourceOp); ok {
+		v.sources = append(v.sources, source)
+	}
+
+	return n
+
+}
+
+func (v *sourceVisitor) VisitPost(n logical.Node) logical.Node { return n }
+
+// remoteNodeRegistry is a NodeRegistry seen from a remote node.
+type remoteNodeRegistry struct {
+	cluster.NodeRegistry
+	nodeId string
+}
+
+func (r *remoteNodeRegistry) LocalNodeId() string { return r.nodeId }
+
+// noSegmentRegistry is a SegmentRegistry without segments.
+type noSegmentRegistry struct {
+	storage.SegmentRegistry
+}
+
+func (r *noSegmentRegistry) Segments([]uint64, string, string, *storage.Interval) []storage.Segment {
+	return nil
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// describeTree turns a tree of nodes into maps and slices that can be
+// encoded as JSON. Every node is described by its type name and its
+// exported fields.
+func describeTree(node interface{}) interface{} {
+	return describeValue(reflect.ValueOf(node))
+}
+
+func describeValue(v reflect.Value) interface{} {
+
+	if !v.IsValid() {
+		return nil
+	}
+
+	if v.Type() == timeType {
+		return v.Interface().(time.Time).Format(time.RFC3339Nano)
+	}
+
+	if v.Kind() != reflect.Ptr && v.Kind() != reflect.Interface && v.CanInterface() {
+		if s, ok := v.Interface().(fmt.Stringer); ok {
+			return s.String()
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+
+		if v.IsNil() {
+			return nil
+		}
+
+		return describeValue(v.Elem())
+
+	case reflect.Struct:
+
+		node := map[string]interface{}{"node": v.Type().Name()}
+
+		for i := 0; i < v.NumField(); i++ {
+
+			field := v.Type().Field(i)
+
+			if field.PkgPath != "" || isZero(v.Field(i)) {
+				continue
+			}
+
+			node[field.Name] = describeValue(v.Field(i))
+
+		}
+
+		return node
+
+	case reflect.Slice, reflect.Array:
+
+		values := make([]interface{}, v.Len())
+
+		for i := range values {
+			values[i] = describeValue(v.Index(i))
+		}
+
+		return values
+
+	case reflect.Map:
+
+		values := make(map[string]interface{}, v.Len())
+
+		for _, key := range v.MapKeys() {
+			values[fmt.Sprint(key.Interface())] = describeValue(v.MapIndex(key))
+		}
+
+		return values
+
+	default:
+		return v.Interface()
+	}
+
+}
+
+func isZero(v reflect.Value) bool {
+
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Slice, reflect.Map:
+		return v.IsNil()
+	default:
+		return false
+	}
+
+}
@@ -0,0 +1,91 @@
+// Copyright 2020 The Meerkat Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exec
+
+import (
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"meerkat/internal/cluster"
+	"meerkat/internal/query/execbase"
+	"meerkat/internal/query/physical"
+	"meerkat/internal/storage"
+	"testing"
+	"time"
+)
+
+// refCountSegmentRegistry keeps the reference count of its segments.
+type refCountSegmentRegistry struct {
+	testSegmentRegistry
+	refs int
+}
+
+func (r *refCountSegmentRegistry) Segments(partitions []uint64, dbName string, tableName string, interval *storage.Interval) []storage.Segment {
+
+	for _, info := range r.infos {
+		if storage.MatchSegment(info, partitions, dbName, tableName, interval) {
+			r.refs++
+		}
+	}
+
+	return nil
+
+}
+
+func (r *refCountSegmentRegistry) Release(uuid.UUID) {
+	r.refs--
+}
+
+// panicStreamRegistry fails the test if a stream is requested.
+type panicStreamRegistry struct {
+	physical.StreamRegistry
+}
+
+func TestExplainSideEffects(t *testing.T) {
+
+	columns := []*storage.ColumnInfo{
+		{Name: storage.TSColumnName, ColumnType: storage.ColumnType_TIMESTAMP},
+		{Name: "host", ColumnType: storage.ColumnType_STRING},
+	}
+
+	logs := segmentInfo("logs", columns...)
+	logs.Len = 10
+	logs.Interval = &storage.Interval{From: time.Unix(2*86400, 0), To: time.Unix(3*86400, 0)}
+
+	oldLogs := segmentInfo("logs", columns...)
+	oldLogs.Len = 5
+	oldLogs.Interval = &storage.Interval{From: time.Unix(0, 0), To: time.Unix(10, 0)}
+
+	segReg := &refCountSegmentRegistry{
+		testSegmentRegistry: testSegmentRegistry{infos: []*storage.SegmentInfo{logs, oldLogs}},
+	}
+
+	c := &coordinatorExecutor{
+		id:        uuid.New(),
+		catalog:   &testCatalog{entries: make(map[string]cluster.Entry)},
+		segReg:    segReg,
+		streamReg: &panicStreamRegistry{},
+		nodeReg:   &testNodeRegistry{id: "coordinator"},
+		execCtx:   execbase.NewExecutionContext(),
+	}
+
+	explain, err := c.explain(`logs | where _ts > datetime(1970-01-02) and host == "web-1"`)
+	assert.NoError(t, err)
+
+	assert.Equal(t, 0, segReg.refs)
+	assert.Equal(t, []*ScanInfo{
+		{Table: "logs", Segments: 1, SegmentsPruned: 1, Rows: 10},
+	}, explain.Scans)
+	assert.NotEmpty(t, explain.Physical["coordinator"])
+
+}
@@ -18,6 +18,8 @@ import (
 	"encoding/gob"
 	"fmt"
 	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
 	"io"
 	"meerkat/internal/cluster"
 	"meerkat/internal/query/execbase"
@@ -25,15 +27,25 @@ import (
 	"meerkat/internal/query/logical"
 	"meerkat/internal/query/physical"
 	"meerkat/internal/storage"
+	"sync"
+	"time"
 )
 
+// progressInterval is how often the nodes report the progress of a query
+// to the coordinator.
+const progressInterval = time.Second
+
 type nodeExec struct {
 	nodeReg    cluster.NodeRegistry
 	segReg     storage.SegmentRegistry
 	execCtx    execbase.ExecutionContext
 	controlSrv execpb.Executor_ControlServer
-	dag        physical.DAG
-	streamReg  physical.StreamRegistry
+	// sendMu serializes the events sent on the control stream.
+	sendMu    sync.Mutex
+	dag       physical.DAG
+	streamReg physical.StreamRegistry
+	spillDir  string
+	log       zerolog.Logger
 }
 
 func NewNodeExec(
@@ -41,13 +53,16 @@ func NewNodeExec(
 	segReg storage.SegmentRegistry,
 	streamReg physical.StreamRegistry,
 	controlSrv execpb.Executor_ControlServer,
+	spillDir string,
 ) *nodeExec {
 	return &nodeExec{
+		spillDir:   spillDir,
 		nodeReg:    nodeReg,
 		segReg:     segReg,
 		controlSrv: controlSrv,
 		execCtx:    execbase.NewExecutionContext(),
 		streamReg:  streamReg,
+		log:        log.With().Str("component", "nodeExec").Logger(),
 	}
 }
 
@@ -82,6 +97,7 @@ func (n *nodeExec) handleControlStream() {
 		case *execpb.ExecCmd_ExecQuery:
 			go n.execQuery(cmd.ExecQuery)
 		case *execpb.ExecCmd_ExecCancel:
+			// a cancel without error stops the query gracefully.
 			n.execCtx.CancelWithExecError(cmd.ExecCancel.Error)
 			return
 		default:
@@ -120,7 +136,13 @@ func (n *nodeExec) execQuery(query *execpb.ExecQuery) {
 		return
 	}
 
-	err = n.buildDAG(fragments, query.Id)
+	limits := execbase.QueryLimits{
+		MaxMemory:       query.MaxMemory,
+		MaxRowsScanned:  query.MaxRowsScanned,
+		MaxBytesScanned: query.MaxBytesScanned,
+	}
+
+	err = n.buildDAG(fragments, query.Id, limits)
 
 	if err != nil {
 		n.execCtx.CancelWithExecError(
@@ -128,12 +150,87 @@ func (n *nodeExec) execQuery(query *execpb.ExecQuery) {
 		return
 	}
 
+	done := make(chan struct{})
+
+	go n.reportProgress(done)
+
 	n.dag.Run()
 
+	close(done)
+
+	n.sendStats()
+
 	n.execCtx.Cancel()
 
 }
 
+// reportProgress sends the data read by the DAG to the coordinator until
+// done is closed.
+func (n *nodeExec) reportProgress(done chan struct{}) {
+
+	ticker := time.NewTicker(progressInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			n.sendProgress()
+		case <-done:
+			return
+		}
+	}
+
+}
+
+func (n *nodeExec) sendProgress() {
+
+	progress := n.dag.Progress()
+
+	event := &execpb.ExecEvent{
+		Event: &execpb.ExecEvent_ExecProgress{
+			ExecProgress: &execpb.ExecProgressEvent{
+				RowsScanned:  progress.RowsScanned,
+				BytesScanned: progress.BytesScanned,
+			},
+		},
+	}
+
+	err := n.send(event)
+
+	if err != nil {
+		n.log.Error().Err(err).Msg("cannot send query progress")
+	}
+
+}
+
+func (n *nodeExec) send(event *execpb.ExecEvent) error {
+
+	defer n.sendMu.Unlock()
+	n.sendMu.Lock()
+
+	return n.controlSrv.Send(event)
+
+}
+
+// sendStats sends the execution stats to the coordinator. The query is
+// already done so errors are not propagated.
+func (n *nodeExec) sendStats() {
+
+	event, err := buildStatsEvent(n.dag.Stats())
+
+	if err != nil {
+		n.log.Error().Err(err).Msg("cannot encode query stats")
+		return
+	}
+
+	err = n.send(event)
+
+	if err != nil {
+		n.log.Error().Err(err).Msg("cannot send query stats")
+	}
+
+}
+
 func decodePlan(query *execpb.ExecQuery) ([]*logical.Fragment, error) {
 
 	reader := bytes.NewReader(query.Plan)
@@ -148,7 +245,7 @@ func decodePlan(query *execpb.ExecQuery) ([]*logical.Fragment, error) {
 
 }
 
-func (n *nodeExec) buildDAG(fragments []*logical.Fragment, queryId []byte) error {
+func (n *nodeExec) buildDAG(fragments []*logical.Fragment, queryId []byte, limits execbase.QueryLimits) error {
 
 	id, err := uuid.FromBytes(queryId)
 
@@ -156,9 +253,9 @@ func (n *nodeExec) buildDAG(fragments []*logical.Fragment, queryId []byte) error
 		return err
 	}
 
-	builder := physical.NewDAGBuilder(n.nodeReg, n.segReg, n.streamReg)
+	builder := physical.NewDAGBuilder(n.nodeReg, n.segReg, n.streamReg, n.spillDir)
 
-	n.dag, err = builder.BuildDAG(fragments, id, nil, n.execCtx)
+	n.dag, err = builder.BuildDAG(fragments, id, nil, execbase.JSONOutput, n.execCtx, limits)
 
 	if err != nil {
 		return err
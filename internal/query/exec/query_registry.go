@@ -0,0 +1,122 @@
+// Copyright 2020 The Meerkat Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exec
+
+import (
+	"github.com/google/uuid"
+	"meerkat/internal/query/physical"
+	"sort"
+	"sync"
+	"time"
+)
+
+// QueryInfo describes a query running on this coordinator.
+type QueryInfo struct {
+	Id        string    `json:"id"`
+	Query     string    `json:"query"`
+	StartTime time.Time `json:"startTime"`
+	// Nodes are the nodes executing the query, the coordinator first.
+	Nodes []string `json:"nodes"`
+	// NodesFinished is the number of remote nodes that have finished
+	// their fragments.
+	NodesFinished int `json:"nodesFinished"`
+	// Progress is the data read so far by the coordinator and the nodes.
+	Progress physical.Progress `json:"progress"`
+}
+
+type runningQuery struct {
+	coordinator *coordinatorExecutor
+	query       string
+	start       time.Time
+}
+
+// queryRegistry keeps track of the queries coordinated by this node.
+type queryRegistry struct {
+	mu      sync.Mutex
+	queries map[uuid.UUID]*runningQuery
+}
+
+func newQueryRegistry() *queryRegistry {
+	return &queryRegistry{
+		queries: make(map[uuid.UUID]*runningQuery),
+	}
+}
+
+func (r *queryRegistry) add(coordinator *coordinatorExecutor, query string) {
+
+	defer r.mu.Unlock()
+	r.mu.Lock()
+
+	r.queries[coordinator.id] = &runningQuery{
+		coordinator: coordinator,
+		query:       query,
+		start:       time.Now(),
+	}
+
+}
+
+func (r *queryRegistry) remove(id uuid.UUID) {
+
+	defer r.mu.Unlock()
+	r.mu.Lock()
+
+	delete(r.queries, id)
+
+}
+
+func (r *queryRegistry) get(id uuid.UUID) *coordinatorExecutor {
+
+	defer r.mu.Unlock()
+	r.mu.Lock()
+
+	q, found := r.queries[id]
+
+	if !found {
+		return nil
+	}
+
+	return q.coordinator
+
+}
+
+// list returns the running queries, oldest first.
+func (r *queryRegistry) list() []*QueryInfo {
+
+	defer r.mu.Unlock()
+	r.mu.Lock()
+
+	infos := make([]*QueryInfo, 0, len(r.queries))
+
+	for id, q := range r.queries {
+
+		nodeManager := q.coordinator.nodeManager
+
+		infos = append(infos, &QueryInfo{
+			Id:            id.String(),
+			Query:         q.query,
+			StartTime:     q.start,
+			Nodes:         append([]string{q.coordinator.nodeReg.LocalNodeId()}, nodeManager.nodes()...),
+			NodesFinished: nodeManager.finished(),
+			Progress:      q.coordinator.progress(),
+		})
+
+	}
+
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].StartTime.Before(infos[j].StartTime)
+	})
+
+	return infos
+
+}
@@ -0,0 +1,137 @@
+// Copyright 2020 The Meerkat Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exec
+
+import (
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"meerkat/internal/cluster"
+	"meerkat/internal/query/execbase"
+	"meerkat/internal/query/physical"
+	"testing"
+	"time"
+)
+
+type testNodeRegistry struct {
+	cluster.NodeRegistry
+	id string
+}
+
+func (r *testNodeRegistry) LocalNodeId() string { return r.id }
+
+func (r *testNodeRegistry) Nodes([]string, bool) []cluster.Node { return nil }
+
+type testNodeManager struct {
+	nodeManager
+	nodeIds      []string
+	nodeProgress physical.Progress
+}
+
+func (m *testNodeManager) nodes() []string { return m.nodeIds }
+
+func (m *testNodeManager) finished() int { return 1 }
+
+func (m *testNodeManager) progress() physical.Progress { return m.nodeProgress }
+
+type testDAG struct {
+	physical.DAG
+	progress physical.Progress
+}
+
+func (d *testDAG) Progress() physical.Progress { return d.progress }
+
+func testCoordinator(dag physical.DAG) *coordinatorExecutor {
+	return &coordinatorExecutor{
+		id:      uuid.New(),
+		nodeReg: &testNodeRegistry{id: "coordinator"},
+		execCtx: execbase.NewExecutionContext(),
+		nodeManager: &testNodeManager{
+			nodeIds:      []string{"node1", "node2"},
+			nodeProgress: physical.Progress{RowsScanned: 10, BytesScanned: 100},
+		},
+		dag: dag,
+	}
+}
+
+func TestQueryRegistry(t *testing.T) {
+
+	registry := newQueryRegistry()
+
+	first := testCoordinator(&testDAG{progress: physical.Progress{RowsScanned: 5, BytesScanned: 50}})
+	second := testCoordinator(nil)
+
+	registry.add(first, "T1 | take 1")
+	time.Sleep(time.Millisecond)
+	registry.add(second, "T2 | take 1")
+
+	assert.Equal(t, first, registry.get(first.id))
+	assert.Nil(t, registry.get(uuid.New()))
+
+	infos := registry.list()
+
+	if assert.Len(t, infos, 2) {
+
+		assert.Equal(t, first.id.String(), infos[0].Id)
+		assert.Equal(t, "T1 | take 1", infos[0].Query)
+		assert.Equal(t, []string{"coordinator", "node1", "node2"}, infos[0].Nodes)
+		assert.Equal(t, 1, infos[0].NodesFinished)
+		assert.Equal(t, physical.Progress{RowsScanned: 15, BytesScanned: 150}, infos[0].Progress)
+
+		// the local dag is not built yet.
+		assert.Equal(t, second.id.String(), infos[1].Id)
+		assert.Equal(t, physical.Progress{RowsScanned: 10, BytesScanned: 100}, infos[1].Progress)
+
+	}
+
+	registry.remove(first.id)
+
+	assert.Nil(t, registry.get(first.id))
+	assert.Len(t, registry.list(), 1)
+
+}
+
+func TestNodeManagerProgress(t *testing.T) {
+
+	manager := &defaultNodeManager{}
+
+	for i := int64(1); i <= 3; i++ {
+		client := newNodeClient(nil, "node", nil)
+		client.setProgress(physical.Progress{RowsScanned: i, BytesScanned: i * 10})
+		manager.nodeClients = append(manager.nodeClients, client)
+	}
+
+	assert.Equal(t, physical.Progress{RowsScanned: 6, BytesScanned: 60}, manager.progress())
+
+}
+
+func TestCancelQuery(t *testing.T) {
+
+	coordinator := testCoordinator(nil)
+
+	e := executor{queries: newQueryRegistry()}
+	e.queries.add(coordinator, "T | take 1")
+
+	assert.Equal(t, ErrQueryNotFound, e.CancelQuery(uuid.New()))
+
+	err := e.CancelQuery(coordinator.id)
+
+	assert.NoError(t, err)
+
+	select {
+	case <-coordinator.execCtx.Done():
+	default:
+		t.Fatal("the query was not canceled")
+	}
+
+}
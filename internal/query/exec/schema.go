@@ -0,0 +1,313 @@
+// Copyright 2020 The Meerkat Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exec
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"meerkat/internal/cluster"
+	"meerkat/internal/query/parser"
+	"meerkat/internal/storage"
+	"sync"
+	"time"
+)
+
+const (
+	// columnsMapName is the catalog map holding the columns stored by the
+	// cluster nodes.
+	columnsMapName  = "columns"
+	publishInterval = 1 * time.Second
+)
+
+// columnEntry is a column of a table stored with a given type on some
+// node. It's published to the catalog which replicates it to every node.
+type columnEntry struct {
+	Node     string `json:",omitempty"`
+	Database string `json:",omitempty"`
+	Table    string
+	Column   string
+	Type     storage.ColumnType
+}
+
+// tableName returns the name of the table in the queries, qualified by
+// the database if it's not the default one.
+func (e columnEntry) tableName() string {
+
+	if e.Database == "" {
+		return e.Table
+	}
+
+	return e.Database + "." + e.Table
+
+}
+
+// segmentColumns returns the columns of the segments known to segReg.
+func segmentColumns(segReg storage.SegmentRegistry) []columnEntry {
+
+	var entries []columnEntry
+
+	for _, info := range segReg.SegmentInfos() {
+		for _, column := range info.Columns {
+			entries = append(entries, columnEntry{
+				Database: info.DatabaseName,
+				Table:    info.TableName,
+				Column:   column.Name,
+				Type:     column.ColumnType,
+			})
+		}
+	}
+
+	return entries
+
+}
+
+// catalogColumns returns the columns published to the catalog by the
+// cluster nodes, the malformed entries are logged and skipped.
+func catalogColumns(catalog cluster.Catalog, log zerolog.Logger) []columnEntry {
+
+	var entries []columnEntry
+
+	for _, e := range catalog.GetAll(columnsMapName) {
+
+		var entry columnEntry
+
+		err := json.Unmarshal(e.Value, &entry)
+
+		if err != nil {
+			log.Error().Err(err).Str("key", e.Key).Msg("cannot unmarshal column entry")
+			continue
+		}
+
+		entries = append(entries, entry)
+
+	}
+
+	return entries
+
+}
+
+// segmentSchema provides the schema of the tables stored in the cluster.
+// The columns are read from the catalog, where every node publishes the
+// columns of its segments, and from the local segments which could not be
+// published yet. The columns of a table are the union of the columns of
+// its segments, columns stored with different types in different segments
+// have an unknown type.
+type segmentSchema struct {
+	catalog cluster.Catalog
+	segReg  storage.SegmentRegistry
+	log     zerolog.Logger
+}
+
+func newSegmentSchema(catalog cluster.Catalog, segReg storage.SegmentRegistry) *segmentSchema {
+	return &segmentSchema{
+		catalog: catalog,
+		segReg:  segReg,
+		log:     log.With().Str("component", "segmentSchema").Logger(),
+	}
+}
+
+func (s *segmentSchema) columns() []columnEntry {
+	return append(segmentColumns(s.segReg), catalogColumns(s.catalog, s.log)...)
+}
+
+func (s *segmentSchema) Columns(table string) (map[string]parser.Type, bool) {
+
+	columns := make(map[string]parser.Type)
+	found := false
+
+	for _, entry := range s.columns() {
+
+		if entry.tableName() != table {
+			continue
+		}
+
+		found = true
+
+		t := parser.TypeOf(entry.Type)
+
+		if prev, ok := columns[entry.Column]; ok && prev != t {
+			t = parser.UnknownType
+		}
+
+		columns[entry.Column] = t
+
+	}
+
+	return columns, found
+
+}
+
+func (s *segmentSchema) Tables() []string {
+
+	var tables []string
+	found := make(map[string]bool)
+
+	for _, entry := range s.columns() {
+		if name := entry.tableName(); !found[name] {
+			found[name] = true
+			tables = append(tables, name)
+		}
+	}
+
+	return tables
+
+}
+
+// SchemaPublisher publishes the columns of the local segments to the
+// catalog so the coordinators can resolve the tables stored on every node.
+// Every node owns the entries it publishes and deletes them when the
+// columns are no longer stored locally.
+type SchemaPublisher struct {
+	catalog cluster.Catalog
+	segReg  storage.SegmentRegistry
+	nodeReg cluster.NodeRegistry
+	log     zerolog.Logger
+	mu      sync.Mutex
+	ticker  *time.Ticker
+	done    chan struct{}
+	wg      sync.WaitGroup
+	running bool
+}
+
+func NewSchemaPublisher(
+	catalog cluster.Catalog,
+	segReg storage.SegmentRegistry,
+	nodeReg cluster.NodeRegistry,
+) *SchemaPublisher {
+	return &SchemaPublisher{
+		catalog: catalog,
+		segReg:  segReg,
+		nodeReg: nodeReg,
+		log:     log.With().Str("component", "SchemaPublisher").Logger(),
+	}
+}
+
+// publish adds to the catalog the local columns that are not there yet
+// and deletes the entries of this node whose columns are no longer
+// stored locally. The catalog never sets a deleted key again so the keys
+// include the publication time, a column stored again after its entry was
+// deleted is published with a new key.
+func (p *SchemaPublisher) publish() {
+
+	nodeId := p.nodeReg.LocalNodeId()
+	now := time.Now()
+
+	local := make(map[columnEntry]bool)
+
+	for _, column := range segmentColumns(p.segReg) {
+		column.Node = nodeId
+		local[column] = true
+	}
+
+	var entries []cluster.Entry
+	published := make(map[columnEntry]bool)
+
+	for _, e := range p.catalog.GetAll(columnsMapName) {
+
+		var column columnEntry
+
+		err := json.Unmarshal(e.Value, &column)
+
+		if err != nil || column.Node != nodeId {
+			continue
+		}
+
+		if local[column] && !published[column] {
+			published[column] = true
+			continue
+		}
+
+		e.Deleted = true
+		e.Time = now
+		entries = append(entries, e)
+
+	}
+
+	for column := range local {
+
+		if published[column] {
+			continue
+		}
+
+		value, err := json.Marshal(column)
+
+		if err != nil {
+			p.log.Panic().Err(err).Msg("cannot marshal column entry")
+		}
+
+		entries = append(entries, cluster.Entry{
+			MapName: columnsMapName,
+			Key:     fmt.Sprintf("%v/%v", string(value), now.UnixNano()),
+			Value:   value,
+			Time:    now,
+		})
+
+	}
+
+	if len(entries) > 0 {
+		p.catalog.SetAll(entries)
+	}
+
+}
+
+func (p *SchemaPublisher) run() {
+
+	defer p.wg.Done()
+
+	for {
+		select {
+		case <-p.ticker.C:
+			p.publish()
+		case <-p.done:
+			return
+		}
+	}
+
+}
+
+func (p *SchemaPublisher) Start() {
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.running {
+		return
+	}
+
+	p.running = true
+	p.done = make(chan struct{})
+	p.ticker = time.NewTicker(publishInterval)
+
+	p.wg.Add(1)
+	go p.run()
+
+}
+
+func (p *SchemaPublisher) Stop() {
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.running {
+		return
+	}
+
+	p.running = false
+	p.ticker.Stop()
+	close(p.done)
+	p.wg.Wait()
+
+}
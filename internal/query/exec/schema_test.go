@@ -0,0 +1,209 @@
+// Copyright 2020 The Meerkat Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exec
+
+import (
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"meerkat/internal/cluster"
+	"meerkat/internal/query/parser"
+	"meerkat/internal/storage"
+	"testing"
+)
+
+type testCatalog struct {
+	cluster.Catalog
+	entries map[string]cluster.Entry
+}
+
+func (c *testCatalog) GetAll(mapName string) []cluster.Entry {
+	var entries []cluster.Entry
+	for _, e := range c.entries {
+		if e.MapName == mapName && !e.Deleted {
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
+
+// SetAll ignores the deleted keys like the catalog does.
+func (c *testCatalog) SetAll(entries []cluster.Entry) []cluster.Entry {
+	var delta []cluster.Entry
+	for _, e := range entries {
+		key := e.MapName + "/" + e.Key
+		if c.entries[key].Deleted {
+			continue
+		}
+		c.entries[key] = e
+		delta = append(delta, e)
+	}
+	return delta
+}
+
+// nodeColumns returns the table.column names published by a node.
+func (c *testCatalog) nodeColumns(node string) []string {
+
+	var columns []string
+
+	for _, entry := range catalogColumns(c, zerolog.Nop()) {
+		if entry.Node == node {
+			columns = append(columns, entry.tableName()+"."+entry.Column)
+		}
+	}
+
+	return columns
+
+}
+
+type testSegmentRegistry struct {
+	storage.SegmentRegistry
+	infos []*storage.SegmentInfo
+}
+
+func (r *testSegmentRegistry) SegmentInfos() []*storage.SegmentInfo { return r.infos }
+
+func segmentInfo(table string, columns ...*storage.ColumnInfo) *storage.SegmentInfo {
+	return &storage.SegmentInfo{TableName: table, Columns: columns}
+}
+
+func TestSegmentSchema(t *testing.T) {
+
+	catalog := &testCatalog{entries: make(map[string]cluster.Entry)}
+
+	// the segments stored on another node are published to the catalog
+	remote := &testSegmentRegistry{
+		infos: []*storage.SegmentInfo{
+			segmentInfo("logs",
+				&storage.ColumnInfo{Name: "host", ColumnType: storage.ColumnType_STRING},
+				&storage.ColumnInfo{Name: "code", ColumnType: storage.ColumnType_INT64},
+			),
+			segmentInfo("metrics",
+				&storage.ColumnInfo{Name: "value", ColumnType: storage.ColumnType_FLOAT64},
+			),
+		},
+	}
+
+	publisher := NewSchemaPublisher(catalog, remote, &testNodeRegistry{id: "node-2"})
+	publisher.publish()
+	publisher.publish()
+
+	assert.Len(t, catalog.entries, 3)
+
+	// logs in another database is a different table.
+	prodLogs := segmentInfo("logs",
+		&storage.ColumnInfo{Name: "trace", ColumnType: storage.ColumnType_STRING},
+	)
+	prodLogs.DatabaseName = "prod"
+
+	local := &testSegmentRegistry{
+		infos: []*storage.SegmentInfo{
+			segmentInfo("logs",
+				&storage.ColumnInfo{Name: "code", ColumnType: storage.ColumnType_STRING},
+				&storage.ColumnInfo{Name: "msg", ColumnType: storage.ColumnType_STRING},
+			),
+			prodLogs,
+		},
+	}
+
+	schema := newSegmentSchema(catalog, local)
+
+	columns, found := schema.Columns("logs")
+	assert.True(t, found)
+	assert.Equal(t, map[string]parser.Type{
+		"host": parser.StringType,
+		"code": parser.UnknownType,
+		"msg":  parser.StringType,
+	}, columns)
+
+	columns, found = schema.Columns("metrics")
+	assert.True(t, found)
+	assert.Equal(t, map[string]parser.Type{"value": parser.FloatType}, columns)
+
+	_, found = schema.Columns("traces")
+	assert.False(t, found)
+
+	columns, found = schema.Columns("prod.logs")
+	assert.True(t, found)
+	assert.Equal(t, map[string]parser.Type{"trace": parser.StringType}, columns)
+
+	// metrics is only stored on the other node.
+	assert.ElementsMatch(t, []string{"logs", "metrics", "prod.logs"}, schema.Tables())
+
+}
+
+func TestSchemaPublisher(t *testing.T) {
+
+	catalog := &testCatalog{entries: make(map[string]cluster.Entry)}
+
+	segReg := &testSegmentRegistry{
+		infos: []*storage.SegmentInfo{
+			segmentInfo("logs", &storage.ColumnInfo{Name: "host", ColumnType: storage.ColumnType_STRING}),
+			segmentInfo("metrics", &storage.ColumnInfo{Name: "value", ColumnType: storage.ColumnType_FLOAT64}),
+		},
+	}
+
+	otherSegReg := &testSegmentRegistry{
+		infos: []*storage.SegmentInfo{
+			segmentInfo("metrics", &storage.ColumnInfo{Name: "value", ColumnType: storage.ColumnType_FLOAT64}),
+		},
+	}
+
+	publisher := NewSchemaPublisher(catalog, segReg, &testNodeRegistry{id: "node-1"})
+	otherPublisher := NewSchemaPublisher(catalog, otherSegReg, &testNodeRegistry{id: "node-2"})
+
+	publisher.publish()
+	otherPublisher.publish()
+
+	assert.ElementsMatch(t, []string{"logs.host", "metrics.value"}, catalog.nodeColumns("node-1"))
+	assert.ElementsMatch(t, []string{"metrics.value"}, catalog.nodeColumns("node-2"))
+
+	// the metrics segments of node-1 were removed.
+	segReg.infos = segReg.infos[:1]
+	publisher.publish()
+
+	assert.ElementsMatch(t, []string{"logs.host"}, catalog.nodeColumns("node-1"))
+	assert.ElementsMatch(t, []string{"metrics.value"}, catalog.nodeColumns("node-2"))
+
+	// a column stored again is published again.
+	segReg.infos = append(segReg.infos, otherSegReg.infos...)
+	publisher.publish()
+	publisher.publish()
+
+	assert.ElementsMatch(t, []string{"logs.host", "metrics.value"}, catalog.nodeColumns("node-1"))
+
+}
+
+func TestSegmentSchemaMalformedEntry(t *testing.T) {
+
+	catalog := &testCatalog{entries: make(map[string]cluster.Entry)}
+
+	catalog.SetAll([]cluster.Entry{
+		{MapName: columnsMapName, Key: "bad", Value: []byte("{")},
+	})
+
+	publisher := NewSchemaPublisher(catalog, &testSegmentRegistry{
+		infos: []*storage.SegmentInfo{
+			segmentInfo("logs", &storage.ColumnInfo{Name: "host", ColumnType: storage.ColumnType_STRING}),
+		},
+	}, &testNodeRegistry{id: "node-1"})
+
+	publisher.publish()
+
+	schema := newSegmentSchema(catalog, &testSegmentRegistry{})
+
+	columns, found := schema.Columns("logs")
+	assert.True(t, found)
+	assert.Equal(t, map[string]parser.Type{"host": parser.StringType}, columns)
+
+}
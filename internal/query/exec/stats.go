@@ -0,0 +1,120 @@
+// Copyright 2020 The Meerkat Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exec
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"meerkat/internal/query/execbase"
+	"meerkat/internal/query/execpb"
+	"meerkat/internal/query/physical"
+	"time"
+)
+
+// statsTimeout bounds the time the coordinator waits for the stats of
+// the nodes once the query is done.
+const statsTimeout = time.Second
+
+// QueryStats are the execution stats of a query aggregated by node.
+type QueryStats struct {
+	SegmentsScanned int                   `json:"segmentsScanned"`
+	SegmentsPruned  int                   `json:"segmentsPruned"`
+	RowsScanned     int64                 `json:"rowsScanned"`
+	BytesDecoded    int64                 `json:"bytesDecoded"`
+	Nodes           []*physical.NodeStats `json:"nodes"`
+}
+
+func newQueryStats(nodes []*physical.NodeStats) *QueryStats {
+
+	stats := &QueryStats{Nodes: nodes}
+
+	for _, node := range nodes {
+
+		stats.SegmentsScanned += node.SegmentsScanned
+		stats.SegmentsPruned += node.SegmentsPruned
+
+		for _, op := range node.Operators {
+			stats.addScans(op)
+		}
+
+	}
+
+	return stats
+
+}
+
+// addScans adds the rows and bytes read by the scan operators.
+func (s *QueryStats) addScans(op *physical.OperatorStats) {
+
+	if op.Segment != "" {
+		s.RowsScanned += op.RowsOut
+		s.BytesDecoded += op.BytesDecoded
+	}
+
+	for _, input := range op.Inputs {
+		s.addScans(input)
+	}
+
+}
+
+// writeStats writes the stats as the last message of the query output.
+func writeStats(writer execbase.QueryOutputWriter, stats *QueryStats) error {
+
+	m := map[string]interface{}{
+		"type":  "stats",
+		"stats": stats,
+	}
+
+	err := json.NewEncoder(writer).Encode(m)
+
+	if err != nil {
+		return err
+	}
+
+	writer.Flush()
+
+	return nil
+
+}
+
+func buildStatsEvent(stats *physical.NodeStats) (*execpb.ExecEvent, error) {
+
+	var buf bytes.Buffer
+
+	err := gob.NewEncoder(&buf).Encode(stats)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &execpb.ExecEvent{
+		Event: &execpb.ExecEvent_ExecStats{
+			ExecStats: &execpb.ExecStatsEvent{
+				Stats: buf.Bytes(),
+			},
+		},
+	}, nil
+
+}
+
+func decodeStats(event *execpb.ExecStatsEvent) (*physical.NodeStats, error) {
+
+	stats := &physical.NodeStats{}
+
+	err := gob.NewDecoder(bytes.NewReader(event.Stats)).Decode(stats)
+
+	return stats, err
+
+}
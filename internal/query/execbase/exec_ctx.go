@@ -21,6 +21,11 @@ import (
 type ExecutionContext interface {
 	Done() <-chan struct{}
 	Cancel()
+	// Stop cancels the query without error because it doesn't need more
+	// rows, ie. a limit was reached.
+	Stop()
+	// Stopped reports whether the query was stopped by Stop.
+	Stopped() bool
 	CancelWithExecError(execError *execpb.ExecError)
 	CancelWithPropagation(err error, execError *execpb.ExecError)
 	Err() *execpb.ExecError
@@ -37,6 +42,7 @@ type executionContext struct {
 	execError *execpb.ExecError
 	done      chan struct{}
 	canceled  bool
+	stopped   bool
 }
 
 func (c *executionContext) CancelWithExecError(execError *execpb.ExecError) {
@@ -56,6 +62,29 @@ func (c *executionContext) CancelWithExecError(execError *execpb.ExecError) {
 
 }
 
+func (c *executionContext) Stop() {
+
+	defer c.mu.Unlock()
+
+	c.mu.Lock()
+
+	if c.canceled {
+		return
+	}
+
+	c.canceled = true
+	c.stopped = true
+
+	close(c.done)
+
+}
+
+func (c *executionContext) Stopped() bool {
+	defer c.mu.Unlock()
+	c.mu.Lock()
+	return c.stopped
+}
+
 func (c *executionContext) CancelWithPropagation(err error, execError *execpb.ExecError) {
 
 	e := ExtractExecError(err)
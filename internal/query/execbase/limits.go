@@ -0,0 +1,26 @@
+// Copyright 2020 The Meerkat Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package execbase
+
+import "time"
+
+// QueryLimits bound the resources used by a query, zero values mean no
+// limit. Timeout is enforced by the coordinator, the other limits are
+// enforced by every node on its own share of the query.
+type QueryLimits struct {
+	Timeout         time.Duration
+	MaxMemory       int64
+	MaxRowsScanned  int64
+	MaxBytesScanned int64
+}
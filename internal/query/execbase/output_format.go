@@ -0,0 +1,28 @@
+// Copyright 2020 The Meerkat Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package execbase
+
+// OutputFormat is the format used to write the query results.
+type OutputFormat int
+
+const (
+	// JSONOutput writes the results as JSON column batches.
+	JSONOutput OutputFormat = iota
+	// ArrowOutput writes the results as an Arrow IPC stream.
+	ArrowOutput
+	// CSVOutput writes the results as CSV rows with a header.
+	CSVOutput
+	// NDJSONOutput writes the results as one JSON object per row.
+	NDJSONOutput
+)
@@ -37,6 +37,12 @@ type ExecQuery struct {
 	// plan contains the fragments of the query execution plan
 	// serialized as gobs
 	Plan []byte `protobuf:"bytes,2,opt,name=plan,proto3" json:"plan,omitempty"`
+	// maxMemory is the memory budget of the query in bytes, 0 means no limit.
+	MaxMemory int64 `protobuf:"varint,3,opt,name=maxMemory,proto3" json:"maxMemory,omitempty"`
+	// maxRowsScanned limits the rows read from the segments, 0 means no limit.
+	MaxRowsScanned int64 `protobuf:"varint,4,opt,name=maxRowsScanned,proto3" json:"maxRowsScanned,omitempty"`
+	// maxBytesScanned limits the bytes read from the segments, 0 means no limit.
+	MaxBytesScanned int64 `protobuf:"varint,5,opt,name=maxBytesScanned,proto3" json:"maxBytesScanned,omitempty"`
 }
 
 func (m *ExecQuery) Reset()         { *m = ExecQuery{} }
@@ -86,9 +92,31 @@ func (m *ExecQuery) GetPlan() []byte {
 	return nil
 }
 
+func (m *ExecQuery) GetMaxMemory() int64 {
+	if m != nil {
+		return m.MaxMemory
+	}
+	return 0
+}
+
+func (m *ExecQuery) GetMaxRowsScanned() int64 {
+	if m != nil {
+		return m.MaxRowsScanned
+	}
+	return 0
+}
+
+func (m *ExecQuery) GetMaxBytesScanned() int64 {
+	if m != nil {
+		return m.MaxBytesScanned
+	}
+	return 0
+}
+
 // ExecCancel signal a execution cancellation.
 type ExecCancel struct {
-	// The error detail.
+	// The error detail. A cancellation without error stops the
+	// execution gracefully ( ie. a limit was reached ).
 	Error *ExecError `protobuf:"bytes,1,opt,name=error,proto3" json:"error,omitempty"`
 }
 
@@ -258,6 +286,9 @@ var xxx_messageInfo_ExecOKEvent proto.InternalMessageInfo
 
 // ExecStatsEvent send stats from the node to the coordinator.
 type ExecStatsEvent struct {
+	// stats contains the execution stats of the node serialized
+	// as gobs
+	Stats []byte `protobuf:"bytes,1,opt,name=stats,proto3" json:"stats,omitempty"`
 }
 
 func (m *ExecStatsEvent) Reset()         { *m = ExecStatsEvent{} }
@@ -293,11 +324,73 @@ func (m *ExecStatsEvent) XXX_DiscardUnknown() {
 
 var xxx_messageInfo_ExecStatsEvent proto.InternalMessageInfo
 
+func (m *ExecStatsEvent) GetStats() []byte {
+	if m != nil {
+		return m.Stats
+	}
+	return nil
+}
+
+// ExecProgressEvent sends the data read so far by the node to the
+// coordinator.
+type ExecProgressEvent struct {
+	RowsScanned  int64 `protobuf:"varint,1,opt,name=rowsScanned,proto3" json:"rowsScanned,omitempty"`
+	BytesScanned int64 `protobuf:"varint,2,opt,name=bytesScanned,proto3" json:"bytesScanned,omitempty"`
+}
+
+func (m *ExecProgressEvent) Reset()         { *m = ExecProgressEvent{} }
+func (m *ExecProgressEvent) String() string { return proto.CompactTextString(m) }
+func (*ExecProgressEvent) ProtoMessage()    {}
+func (*ExecProgressEvent) Descriptor() ([]byte, []int) {
+	return fileDescriptor_4d737c7315c25422, []int{5}
+}
+func (m *ExecProgressEvent) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *ExecProgressEvent) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_ExecProgressEvent.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *ExecProgressEvent) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ExecProgressEvent.Merge(m, src)
+}
+func (m *ExecProgressEvent) XXX_Size() int {
+	return m.Size()
+}
+func (m *ExecProgressEvent) XXX_DiscardUnknown() {
+	xxx_messageInfo_ExecProgressEvent.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ExecProgressEvent proto.InternalMessageInfo
+
+func (m *ExecProgressEvent) GetRowsScanned() int64 {
+	if m != nil {
+		return m.RowsScanned
+	}
+	return 0
+}
+
+func (m *ExecProgressEvent) GetBytesScanned() int64 {
+	if m != nil {
+		return m.BytesScanned
+	}
+	return 0
+}
+
 // ExecEvent are events that flow from the NodeExecutor to the coordinator.
 type ExecEvent struct {
 	// Types that are valid to be assigned to Event:
 	//	*ExecEvent_ExecOk
 	//	*ExecEvent_ExecStats
+	//	*ExecEvent_ExecProgress
 	Event isExecEvent_Event `protobuf_oneof:"event"`
 }
 
@@ -305,7 +398,7 @@ func (m *ExecEvent) Reset()         { *m = ExecEvent{} }
 func (m *ExecEvent) String() string { return proto.CompactTextString(m) }
 func (*ExecEvent) ProtoMessage()    {}
 func (*ExecEvent) Descriptor() ([]byte, []int) {
-	return fileDescriptor_4d737c7315c25422, []int{5}
+	return fileDescriptor_4d737c7315c25422, []int{6}
 }
 func (m *ExecEvent) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -346,9 +439,13 @@ type ExecEvent_ExecOk struct {
 type ExecEvent_ExecStats struct {
 	ExecStats *ExecStatsEvent `protobuf:"bytes,2,opt,name=execStats,proto3,oneof" json:"execStats,omitempty"`
 }
+type ExecEvent_ExecProgress struct {
+	ExecProgress *ExecProgressEvent `protobuf:"bytes,3,opt,name=execProgress,proto3,oneof" json:"execProgress,omitempty"`
+}
 
-func (*ExecEvent_ExecOk) isExecEvent_Event()    {}
-func (*ExecEvent_ExecStats) isExecEvent_Event() {}
+func (*ExecEvent_ExecOk) isExecEvent_Event()       {}
+func (*ExecEvent_ExecStats) isExecEvent_Event()    {}
+func (*ExecEvent_ExecProgress) isExecEvent_Event() {}
 
 func (m *ExecEvent) GetEvent() isExecEvent_Event {
 	if m != nil {
@@ -371,11 +468,19 @@ func (m *ExecEvent) GetExecStats() *ExecStatsEvent {
 	return nil
 }
 
+func (m *ExecEvent) GetExecProgress() *ExecProgressEvent {
+	if x, ok := m.GetEvent().(*ExecEvent_ExecProgress); ok {
+		return x.ExecProgress
+	}
+	return nil
+}
+
 // XXX_OneofWrappers is for the internal use of the proto package.
 func (*ExecEvent) XXX_OneofWrappers() []interface{} {
 	return []interface{}{
 		(*ExecEvent_ExecOk)(nil),
 		(*ExecEvent_ExecStats)(nil),
+		(*ExecEvent_ExecProgress)(nil),
 	}
 }
 
@@ -394,7 +499,7 @@ func (m *Column) Reset()         { *m = Column{} }
 func (m *Column) String() string { return proto.CompactTextString(m) }
 func (*Column) ProtoMessage()    {}
 func (*Column) Descriptor() ([]byte, []int) {
-	return fileDescriptor_4d737c7315c25422, []int{6}
+	return fileDescriptor_4d737c7315c25422, []int{7}
 }
 func (m *Column) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -482,7 +587,7 @@ func (m *VectorBatch) Reset()         { *m = VectorBatch{} }
 func (m *VectorBatch) String() string { return proto.CompactTextString(m) }
 func (*VectorBatch) ProtoMessage()    {}
 func (*VectorBatch) Descriptor() ([]byte, []int) {
-	return fileDescriptor_4d737c7315c25422, []int{7}
+	return fileDescriptor_4d737c7315c25422, []int{8}
 }
 func (m *VectorBatch) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -536,7 +641,7 @@ func (m *StreamHeader) Reset()         { *m = StreamHeader{} }
 func (m *StreamHeader) String() string { return proto.CompactTextString(m) }
 func (*StreamHeader) ProtoMessage()    {}
 func (*StreamHeader) Descriptor() ([]byte, []int) {
-	return fileDescriptor_4d737c7315c25422, []int{8}
+	return fileDescriptor_4d737c7315c25422, []int{9}
 }
 func (m *StreamHeader) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -593,7 +698,7 @@ func (m *ExecError) Reset()         { *m = ExecError{} }
 func (m *ExecError) String() string { return proto.CompactTextString(m) }
 func (*ExecError) ProtoMessage()    {}
 func (*ExecError) Descriptor() ([]byte, []int) {
-	return fileDescriptor_4d737c7315c25422, []int{9}
+	return fileDescriptor_4d737c7315c25422, []int{10}
 }
 func (m *ExecError) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -664,7 +769,7 @@ func (m *VectorExchangeMsg) Reset()         { *m = VectorExchangeMsg{} }
 func (m *VectorExchangeMsg) String() string { return proto.CompactTextString(m) }
 func (*VectorExchangeMsg) ProtoMessage()    {}
 func (*VectorExchangeMsg) Descriptor() ([]byte, []int) {
-	return fileDescriptor_4d737c7315c25422, []int{10}
+	return fileDescriptor_4d737c7315c25422, []int{11}
 }
 func (m *VectorExchangeMsg) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -757,7 +862,7 @@ func (m *VectorExchangeResponse) Reset()         { *m = VectorExchangeResponse{}
 func (m *VectorExchangeResponse) String() string { return proto.CompactTextString(m) }
 func (*VectorExchangeResponse) ProtoMessage()    {}
 func (*VectorExchangeResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_4d737c7315c25422, []int{11}
+	return fileDescriptor_4d737c7315c25422, []int{12}
 }
 func (m *VectorExchangeResponse) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -792,6 +897,7 @@ func init() {
 	proto.RegisterType((*ExecCmd)(nil), "meerkat.exec.ExecCmd")
 	proto.RegisterType((*ExecOKEvent)(nil), "meerkat.exec.ExecOKEvent")
 	proto.RegisterType((*ExecStatsEvent)(nil), "meerkat.exec.ExecStatsEvent")
+	proto.RegisterType((*ExecProgressEvent)(nil), "meerkat.exec.ExecProgressEvent")
 	proto.RegisterType((*ExecEvent)(nil), "meerkat.exec.ExecEvent")
 	proto.RegisterType((*Column)(nil), "meerkat.exec.Column")
 	proto.RegisterType((*VectorBatch)(nil), "meerkat.exec.VectorBatch")
@@ -804,53 +910,59 @@ func init() {
 func init() { proto.RegisterFile("exec.proto", fileDescriptor_4d737c7315c25422) }
 
 var fileDescriptor_4d737c7315c25422 = []byte{
-	// 721 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x7c, 0x54, 0x41, 0x4f, 0xdb, 0x4a,
-	0x10, 0xb6, 0x09, 0x49, 0xc8, 0x04, 0x22, 0xde, 0x8a, 0xc7, 0x33, 0x79, 0xef, 0x05, 0x64, 0xf5,
-	0x90, 0x4b, 0xe3, 0x2a, 0xb4, 0x42, 0x6a, 0xcb, 0x25, 0x34, 0x52, 0x50, 0xd5, 0xa2, 0x9a, 0xaa,
-	0x52, 0x7b, 0x5b, 0xec, 0xc1, 0x44, 0xd8, 0x5e, 0xd7, 0xde, 0x44, 0xe4, 0x07, 0x54, 0xbd, 0xf6,
-	0x47, 0xf4, 0x87, 0xf4, 0xd8, 0x4b, 0x25, 0x8e, 0x3d, 0x56, 0xf0, 0x47, 0xaa, 0x9d, 0xb5, 0x43,
-	0x42, 0x28, 0x37, 0x7f, 0x3b, 0xdf, 0xec, 0x7c, 0x33, 0xfb, 0x8d, 0x01, 0xf0, 0x02, 0xbd, 0x4e,
-	0x92, 0x0a, 0x29, 0xd8, 0x6a, 0x84, 0x98, 0x9e, 0x73, 0xd9, 0x51, 0x67, 0xcd, 0x8d, 0x40, 0x04,
-	0x82, 0x02, 0x8e, 0xfa, 0xd2, 0x9c, 0xe6, 0x76, 0x20, 0x44, 0x10, 0xa2, 0x43, 0xe8, 0x64, 0x74,
-	0xea, 0xc8, 0x61, 0x84, 0x99, 0xe4, 0x51, 0x92, 0x13, 0xb6, 0x6e, 0x13, 0x78, 0x3c, 0xc9, 0x43,
-	0x6b, 0x99, 0x14, 0x29, 0x0f, 0x50, 0x43, 0xdb, 0x81, 0x5a, 0xff, 0x02, 0xbd, 0x37, 0x23, 0x4c,
-	0x27, 0xac, 0x01, 0x4b, 0x43, 0xdf, 0x32, 0x77, 0xcc, 0xf6, 0xaa, 0xbb, 0x34, 0xf4, 0x19, 0x83,
-	0xe5, 0x24, 0xe4, 0xb1, 0xb5, 0x44, 0x27, 0xf4, 0x6d, 0x3f, 0x03, 0x50, 0x09, 0x07, 0x3c, 0xf6,
-	0x30, 0x64, 0x0f, 0xa1, 0x8c, 0x69, 0x2a, 0x52, 0x4a, 0xaa, 0x77, 0xff, 0xe9, 0xcc, 0xaa, 0xef,
-	0x28, 0x62, 0x5f, 0x85, 0x5d, 0xcd, 0xb2, 0x3f, 0x99, 0x50, 0xa5, 0xec, 0xc8, 0x67, 0x7b, 0x50,
-	0xc3, 0xa2, 0xf2, 0x9f, 0xd3, 0x29, 0x3c, 0x30, 0xdc, 0x1b, 0x2e, 0x7b, 0xaa, 0xe7, 0xa5, 0x15,
-	0x90, 0xb6, 0x7a, 0xd7, 0x5a, 0xcc, 0xd4, 0xf1, 0x81, 0xe1, 0xce, 0xb0, 0x7b, 0x65, 0x28, 0x79,
-	0x91, 0x6f, 0xaf, 0x41, 0x5d, 0x51, 0x8e, 0x5e, 0xf6, 0xc7, 0x18, 0x4b, 0x7b, 0x1d, 0x1a, 0x0a,
-	0x1e, 0x4b, 0x2e, 0x33, 0x7d, 0xf2, 0xd9, 0xd4, 0x73, 0x21, 0xc4, 0x76, 0xa1, 0xa2, 0xee, 0x38,
-	0x3a, 0xcf, 0x75, 0x6e, 0x2d, 0x56, 0xcb, 0xaf, 0x1a, 0x18, 0x6e, 0x4e, 0x65, 0xcf, 0x75, 0x7f,
-	0x74, 0x69, 0xae, 0xf2, 0xbf, 0xc5, 0xbc, 0x9b, 0x9a, 0x45, 0x93, 0x74, 0xd2, 0xab, 0x42, 0x19,
-	0x49, 0xc9, 0x0f, 0x13, 0x2a, 0x07, 0x22, 0x1c, 0x45, 0xb1, 0x7a, 0x8e, 0x98, 0x47, 0x48, 0x22,
-	0x6a, 0x2e, 0x7d, 0xb3, 0x0d, 0x28, 0x07, 0xa9, 0x18, 0x25, 0x54, 0xa1, 0xe4, 0x6a, 0xa0, 0x4e,
-	0x45, 0xea, 0x63, 0x6a, 0x95, 0xf4, 0x29, 0x01, 0xf6, 0x04, 0xaa, 0x9e, 0x08, 0xdf, 0x4e, 0x12,
-	0xb4, 0x96, 0x77, 0xcc, 0x76, 0xa3, 0xfb, 0xef, 0x54, 0x4f, 0x61, 0x0a, 0x5d, 0x49, 0x51, 0xdc,
-	0x82, 0xcb, 0x36, 0xa1, 0x32, 0x46, 0x4f, 0x8a, 0xd4, 0x2a, 0x93, 0x0f, 0x72, 0xc4, 0x9a, 0xb0,
-	0x32, 0xe6, 0xe1, 0xd0, 0x1f, 0xca, 0x89, 0x55, 0xa1, 0xc8, 0x14, 0x33, 0x0b, 0xaa, 0xe2, 0xf4,
-	0x34, 0x43, 0x99, 0x59, 0x55, 0x0a, 0x15, 0xd0, 0x3e, 0x82, 0xfa, 0x3b, 0xca, 0xef, 0x71, 0xe9,
-	0x9d, 0xb1, 0x75, 0x28, 0x85, 0x18, 0x53, 0x4b, 0x25, 0x57, 0x7d, 0xb2, 0x0e, 0xa9, 0x1c, 0x45,
-	0xb1, 0x9a, 0x5a, 0xa9, 0x5d, 0xef, 0x6e, 0xcc, 0x4f, 0x4d, 0x4b, 0x74, 0x0b, 0x92, 0xfd, 0x02,
-	0x56, 0x8f, 0x65, 0x8a, 0x3c, 0x1a, 0x20, 0x57, 0x5d, 0x5a, 0x50, 0xfd, 0xa8, 0x7c, 0x72, 0x58,
-	0x38, 0xb9, 0x80, 0x4a, 0x70, 0x46, 0xcc, 0x43, 0x3f, 0x1f, 0xd7, 0x14, 0xdb, 0x98, 0xbf, 0xb7,
-	0xb2, 0xe9, 0xc2, 0x1e, 0x6c, 0x42, 0xc5, 0x47, 0xc9, 0x87, 0x21, 0xcd, 0xb3, 0xe6, 0xe6, 0x48,
-	0x5d, 0x18, 0x0b, 0x1f, 0x5f, 0xab, 0x47, 0x59, 0xa6, 0xc8, 0x14, 0xab, 0x27, 0xc8, 0x24, 0xf7,
-	0xce, 0x69, 0x68, 0x35, 0x57, 0x03, 0xfb, 0x9b, 0x09, 0x7f, 0xe9, 0xf6, 0xfb, 0x17, 0xde, 0x19,
-	0x8f, 0x03, 0x7c, 0x95, 0x05, 0xec, 0x31, 0x54, 0xce, 0x48, 0x7c, 0xee, 0xaf, 0xe6, 0x7c, 0xc7,
-	0xb3, 0xed, 0x29, 0x83, 0x69, 0x2e, 0xdb, 0x87, 0xfa, 0xf8, 0x66, 0x92, 0xb9, 0xc5, 0x6e, 0x59,
-	0x73, 0x66, 0xd4, 0x03, 0xc3, 0x9d, 0xe5, 0x33, 0xa7, 0x58, 0xdd, 0xd2, 0xbd, 0xab, 0x3b, 0x30,
-	0xf2, 0xe5, 0x55, 0xbb, 0x13, 0x65, 0x81, 0x6d, 0xc1, 0xe6, 0x7c, 0x07, 0x2e, 0x66, 0x89, 0x88,
-	0x33, 0xec, 0x7e, 0x35, 0x61, 0x45, 0xe5, 0x8d, 0x94, 0x3b, 0xf6, 0xa1, 0x7a, 0x20, 0x62, 0x99,
-	0x8a, 0x90, 0xfd, 0x7d, 0xc7, 0x72, 0x46, 0x7e, 0xf3, 0xae, 0x8a, 0xca, 0xf2, 0x6d, 0xf3, 0x91,
-	0xc9, 0xde, 0x43, 0x63, 0xbe, 0x0a, 0xdb, 0xbe, 0xab, 0xb3, 0x99, 0x29, 0x36, 0x1f, 0xdc, 0x47,
-	0x28, 0x44, 0xb6, 0xcd, 0xde, 0xde, 0xf7, 0xab, 0x96, 0x79, 0x79, 0xd5, 0x32, 0x7f, 0x5d, 0xb5,
-	0xcc, 0x2f, 0xd7, 0x2d, 0xe3, 0xf2, 0xba, 0x65, 0xfc, 0xbc, 0x6e, 0x19, 0x1f, 0xfe, 0xcf, 0x2f,
-	0x70, 0x86, 0xb1, 0xc4, 0x34, 0xe6, 0xa1, 0x43, 0xd6, 0x71, 0xd4, 0x7d, 0xc9, 0xc9, 0x49, 0x85,
-	0x7e, 0x99, 0xbb, 0xbf, 0x03, 0x00, 0x00, 0xff, 0xff, 0x64, 0xdb, 0x85, 0xe0, 0xaf, 0x05, 0x00,
-	0x00,
+	// 825 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x7c, 0x55, 0xcd, 0x6e, 0x23, 0x45,
+	0x10, 0x9e, 0xb1, 0xd7, 0xf6, 0xba, 0xc6, 0x6b, 0xd8, 0x56, 0x08, 0xb3, 0x66, 0x71, 0xa2, 0x11,
+	0x5a, 0xf9, 0x82, 0x8d, 0xbc, 0xa0, 0x95, 0x80, 0xbd, 0x38, 0x58, 0xf2, 0x0a, 0x2d, 0x81, 0x0e,
+	0x42, 0x5a, 0x6e, 0x9d, 0x99, 0xca, 0xc4, 0xca, 0x4c, 0xb7, 0xe9, 0x69, 0x07, 0xfb, 0x01, 0xb8,
+	0xf3, 0x06, 0x5c, 0x78, 0x10, 0x8e, 0x5c, 0x40, 0x7b, 0xe4, 0x88, 0x92, 0x17, 0x41, 0xfd, 0x33,
+	0xce, 0x38, 0x0e, 0xb9, 0xf5, 0x57, 0xf5, 0x55, 0x4f, 0xd5, 0xd7, 0x5f, 0xf7, 0x00, 0xe0, 0x0a,
+	0xe3, 0xe1, 0x42, 0x0a, 0x25, 0x48, 0x27, 0x47, 0x94, 0x17, 0x4c, 0x0d, 0x75, 0xac, 0xb7, 0x97,
+	0x8a, 0x54, 0x98, 0xc4, 0x48, 0xaf, 0x2c, 0xa7, 0x77, 0x90, 0x0a, 0x91, 0x66, 0x38, 0x32, 0xe8,
+	0x74, 0x79, 0x36, 0x52, 0xf3, 0x1c, 0x0b, 0xc5, 0xf2, 0x85, 0x23, 0x3c, 0xb9, 0x4d, 0x60, 0x7c,
+	0xed, 0x52, 0x8f, 0x0a, 0x25, 0x24, 0x4b, 0xd1, 0xc2, 0xe8, 0x37, 0x1f, 0xda, 0xd3, 0x15, 0xc6,
+	0xdf, 0x2d, 0x51, 0xae, 0x49, 0x17, 0x6a, 0xf3, 0x24, 0xf4, 0x0f, 0xfd, 0x41, 0x87, 0xd6, 0xe6,
+	0x09, 0x21, 0xf0, 0x60, 0x91, 0x31, 0x1e, 0xd6, 0x4c, 0xc4, 0xac, 0xc9, 0x53, 0x68, 0xe7, 0x6c,
+	0xf5, 0x1a, 0x73, 0x21, 0xd7, 0x61, 0xfd, 0xd0, 0x1f, 0xd4, 0xe9, 0x4d, 0x80, 0x3c, 0x83, 0x6e,
+	0xce, 0x56, 0x54, 0xfc, 0x5c, 0x9c, 0xc4, 0x8c, 0x73, 0x4c, 0xc2, 0x07, 0x86, 0x72, 0x2b, 0x4a,
+	0x06, 0xf0, 0x4e, 0xce, 0x56, 0x93, 0xb5, 0xc2, 0x0d, 0xb1, 0x61, 0x88, 0xb7, 0xc3, 0xd1, 0x17,
+	0x00, 0xba, 0xc1, 0x23, 0xc6, 0x63, 0xcc, 0xc8, 0xc7, 0xd0, 0x40, 0x29, 0x85, 0x34, 0x4d, 0x06,
+	0xe3, 0xf7, 0x87, 0x55, 0xb9, 0x86, 0x9a, 0x38, 0xd5, 0x69, 0x6a, 0x59, 0xd1, 0x2f, 0x3e, 0xb4,
+	0x4c, 0x75, 0x9e, 0x90, 0x17, 0xd0, 0xc6, 0x72, 0xd2, 0xff, 0x2f, 0x37, 0xe9, 0x99, 0x47, 0x6f,
+	0xb8, 0xe4, 0x73, 0x7b, 0x40, 0xb6, 0x03, 0xa3, 0x45, 0x30, 0x0e, 0x77, 0x2b, 0x6d, 0x7e, 0xe6,
+	0xd1, 0x0a, 0x7b, 0xd2, 0x80, 0x7a, 0x9c, 0x27, 0xd1, 0x23, 0x08, 0x34, 0xe5, 0xf8, 0xeb, 0xe9,
+	0x25, 0x72, 0x15, 0x3d, 0x83, 0xae, 0x86, 0x27, 0x8a, 0xa9, 0xc2, 0x44, 0xc8, 0x1e, 0x34, 0x0a,
+	0x8d, 0x9c, 0xf8, 0x16, 0x44, 0x6f, 0xe0, 0xb1, 0xe6, 0x7d, 0x2b, 0x45, 0x2a, 0xb1, 0x70, 0xd4,
+	0x43, 0x08, 0x64, 0x45, 0x5f, 0xdf, 0xc8, 0x56, 0x0d, 0x91, 0x08, 0x3a, 0xa7, 0x55, 0x65, 0x6b,
+	0x86, 0xb2, 0x15, 0x8b, 0xfe, 0x76, 0x07, 0x6f, 0xf7, 0x7c, 0x0e, 0x4d, 0xdd, 0xf4, 0xf1, 0x85,
+	0x13, 0xe6, 0xc9, 0xee, 0x78, 0xae, 0xf7, 0x99, 0x47, 0x1d, 0x95, 0x7c, 0x69, 0x05, 0x35, 0x53,
+	0x38, 0x59, 0x9e, 0xee, 0xd6, 0xdd, 0x0c, 0x59, 0xaa, 0x6a, 0x22, 0x64, 0x0a, 0x1d, 0xac, 0xcc,
+	0x66, 0xac, 0x14, 0x8c, 0x0f, 0x76, 0x37, 0xd8, 0x9a, 0x7e, 0xe6, 0xd1, 0xad, 0xb2, 0x49, 0x0b,
+	0x1a, 0x68, 0x34, 0xfd, 0xcb, 0x87, 0xe6, 0x91, 0xc8, 0x96, 0x39, 0xd7, 0xb6, 0xe5, 0x2c, 0x47,
+	0x33, 0x4b, 0x9b, 0x9a, 0xb5, 0x16, 0x38, 0x95, 0x62, 0xb9, 0x70, 0x62, 0x58, 0xa0, 0xa3, 0x42,
+	0x26, 0x28, 0x9d, 0x91, 0x2d, 0x20, 0x9f, 0x41, 0x2b, 0x16, 0xd9, 0xf7, 0xeb, 0x05, 0x1a, 0xf7,
+	0x76, 0xc7, 0x1f, 0x6c, 0xba, 0x2a, 0x6f, 0x8f, 0xfd, 0x92, 0xa6, 0xd0, 0x92, 0x4b, 0xf6, 0xa1,
+	0x79, 0x89, 0xb1, 0x12, 0xd2, 0x58, 0xb9, 0x43, 0x1d, 0x22, 0x3d, 0x78, 0x78, 0xc9, 0xb2, 0x79,
+	0x32, 0x57, 0xeb, 0xb0, 0x69, 0x32, 0x1b, 0x4c, 0x42, 0x68, 0x89, 0xb3, 0xb3, 0x02, 0x55, 0x11,
+	0xb6, 0x4c, 0xaa, 0x84, 0xd1, 0x31, 0x04, 0x3f, 0x98, 0xfa, 0x09, 0x53, 0xf1, 0x39, 0x79, 0x17,
+	0xea, 0x19, 0x72, 0x77, 0xda, 0x7a, 0x49, 0x86, 0xa6, 0xcb, 0x65, 0xce, 0xb5, 0xf8, 0xf5, 0x41,
+	0x30, 0xde, 0xdb, 0xd6, 0xce, 0xb6, 0x48, 0x4b, 0x52, 0xf4, 0x15, 0x74, 0x4e, 0x94, 0x44, 0x96,
+	0xcf, 0x90, 0xe9, 0x29, 0x43, 0x68, 0xfd, 0xa4, 0xfd, 0xfd, 0xaa, 0xbc, 0xf1, 0x25, 0xd4, 0x0d,
+	0x17, 0x86, 0xf9, 0xaa, 0xf4, 0xce, 0x06, 0x47, 0xe8, 0x6c, 0xa3, 0xaf, 0xd7, 0xce, 0x7b, 0xb1,
+	0x0f, 0xcd, 0x04, 0x15, 0x9b, 0x67, 0x46, 0xcf, 0x36, 0x75, 0x48, 0x6f, 0xc8, 0x45, 0x82, 0xdf,
+	0xb0, 0xdc, 0x2a, 0xda, 0xa6, 0x1b, 0xec, 0x9c, 0x1f, 0x5f, 0x18, 0xd1, 0xda, 0xd4, 0x82, 0xe8,
+	0x0f, 0x1f, 0x1e, 0xdb, 0xf1, 0xa7, 0xab, 0xf8, 0x9c, 0xf1, 0x14, 0x5f, 0x17, 0x29, 0xf9, 0x14,
+	0x9a, 0xe7, 0xa6, 0x79, 0x67, 0xd3, 0xde, 0xf6, 0xc4, 0xd5, 0xf1, 0xb4, 0x4f, 0x2d, 0x97, 0xbc,
+	0x84, 0xe0, 0xf2, 0x46, 0xc9, 0xb0, 0x76, 0x97, 0xc3, 0x2b, 0x52, 0xcf, 0x3c, 0x5a, 0xe5, 0x93,
+	0x51, 0xf9, 0xe4, 0xd4, 0xef, 0x7d, 0x72, 0x66, 0x9e, 0x7b, 0x74, 0xf4, 0x9d, 0xcf, 0x8b, 0x34,
+	0x0a, 0x61, 0x7f, 0x7b, 0x02, 0x8a, 0xc5, 0x42, 0xf0, 0x02, 0xc7, 0xbf, 0xfb, 0xf0, 0x50, 0xd7,
+	0x2d, 0xb5, 0x3b, 0x5e, 0x42, 0xeb, 0x48, 0x70, 0x25, 0x45, 0x46, 0xde, 0xbb, 0xe3, 0x51, 0xc9,
+	0x93, 0xde, 0x5d, 0x5f, 0xd4, 0x96, 0x1f, 0xf8, 0x9f, 0xf8, 0xe4, 0x0d, 0x74, 0xb7, 0xbf, 0x42,
+	0x0e, 0xee, 0x9a, 0xac, 0xa2, 0x62, 0xef, 0xa3, 0xfb, 0x08, 0x65, 0x93, 0x03, 0x7f, 0xf2, 0xe2,
+	0xcf, 0xab, 0xbe, 0xff, 0xf6, 0xaa, 0xef, 0xff, 0x7b, 0xd5, 0xf7, 0x7f, 0xbd, 0xee, 0x7b, 0x6f,
+	0xaf, 0xfb, 0xde, 0x3f, 0xd7, 0x7d, 0xef, 0xc7, 0x0f, 0xdd, 0x06, 0xa3, 0x39, 0x57, 0x28, 0x39,
+	0xcb, 0x46, 0xc6, 0x3a, 0x23, 0xbd, 0xdf, 0xe2, 0xf4, 0xb4, 0x69, 0xfe, 0x2d, 0xcf, 0xff, 0x1b,
+	0x00, 0x36, 0xae, 0x5c, 0x5d, 0xd8, 0x06, 0x00, 0x00,
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -1061,6 +1173,21 @@ func (m *ExecQuery) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	_ = i
 	var l int
 	_ = l
+	if m.MaxBytesScanned != 0 {
+		i = encodeVarintExec(dAtA, i, uint64(m.MaxBytesScanned))
+		i--
+		dAtA[i] = 0x28
+	}
+	if m.MaxRowsScanned != 0 {
+		i = encodeVarintExec(dAtA, i, uint64(m.MaxRowsScanned))
+		i--
+		dAtA[i] = 0x20
+	}
+	if m.MaxMemory != 0 {
+		i = encodeVarintExec(dAtA, i, uint64(m.MaxMemory))
+		i--
+		dAtA[i] = 0x18
+	}
 	if len(m.Plan) > 0 {
 		i -= len(m.Plan)
 		copy(dAtA[i:], m.Plan)
@@ -1230,6 +1357,46 @@ func (m *ExecStatsEvent) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	_ = i
 	var l int
 	_ = l
+	if len(m.Stats) > 0 {
+		i -= len(m.Stats)
+		copy(dAtA[i:], m.Stats)
+		i = encodeVarintExec(dAtA, i, uint64(len(m.Stats)))
+		i--
+		dAtA[i] = 0xa
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *ExecProgressEvent) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *ExecProgressEvent) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *ExecProgressEvent) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.BytesScanned != 0 {
+		i = encodeVarintExec(dAtA, i, uint64(m.BytesScanned))
+		i--
+		dAtA[i] = 0x10
+	}
+	if m.RowsScanned != 0 {
+		i = encodeVarintExec(dAtA, i, uint64(m.RowsScanned))
+		i--
+		dAtA[i] = 0x8
+	}
 	return len(dAtA) - i, nil
 }
 
@@ -1307,6 +1474,27 @@ func (m *ExecEvent_ExecStats) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	}
 	return len(dAtA) - i, nil
 }
+func (m *ExecEvent_ExecProgress) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *ExecEvent_ExecProgress) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	if m.ExecProgress != nil {
+		{
+			size, err := m.ExecProgress.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintExec(dAtA, i, uint64(size))
+		}
+		i--
+		dAtA[i] = 0x1a
+	}
+	return len(dAtA) - i, nil
+}
 func (m *Column) Marshal() (dAtA []byte, err error) {
 	size := m.Size()
 	dAtA = make([]byte, size)
@@ -1644,6 +1832,15 @@ func (m *ExecQuery) Size() (n int) {
 	if l > 0 {
 		n += 1 + l + sovExec(uint64(l))
 	}
+	if m.MaxMemory != 0 {
+		n += 1 + sovExec(uint64(m.MaxMemory))
+	}
+	if m.MaxRowsScanned != 0 {
+		n += 1 + sovExec(uint64(m.MaxRowsScanned))
+	}
+	if m.MaxBytesScanned != 0 {
+		n += 1 + sovExec(uint64(m.MaxBytesScanned))
+	}
 	return n
 }
 
@@ -1711,6 +1908,25 @@ func (m *ExecStatsEvent) Size() (n int) {
 	}
 	var l int
 	_ = l
+	l = len(m.Stats)
+	if l > 0 {
+		n += 1 + l + sovExec(uint64(l))
+	}
+	return n
+}
+
+func (m *ExecProgressEvent) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.RowsScanned != 0 {
+		n += 1 + sovExec(uint64(m.RowsScanned))
+	}
+	if m.BytesScanned != 0 {
+		n += 1 + sovExec(uint64(m.BytesScanned))
+	}
 	return n
 }
 
@@ -1750,6 +1966,18 @@ func (m *ExecEvent_ExecStats) Size() (n int) {
 	}
 	return n
 }
+func (m *ExecEvent_ExecProgress) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.ExecProgress != nil {
+		l = m.ExecProgress.Size()
+		n += 1 + l + sovExec(uint64(l))
+	}
+	return n
+}
 func (m *Column) Size() (n int) {
 	if m == nil {
 		return 0
@@ -2003,6 +2231,63 @@ func (m *ExecQuery) Unmarshal(dAtA []byte) error {
 				m.Plan = []byte{}
 			}
 			iNdEx = postIndex
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field MaxMemory", wireType)
+			}
+			m.MaxMemory = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowExec
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.MaxMemory |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field MaxRowsScanned", wireType)
+			}
+			m.MaxRowsScanned = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowExec
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.MaxRowsScanned |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 5:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field MaxBytesScanned", wireType)
+			}
+			m.MaxBytesScanned = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowExec
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.MaxBytesScanned |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
 		default:
 			iNdEx = preIndex
 			skippy, err := skipExec(dAtA[iNdEx:])
@@ -2321,6 +2606,131 @@ func (m *ExecStatsEvent) Unmarshal(dAtA []byte) error {
 			return fmt.Errorf("proto: ExecStatsEvent: illegal tag %d (wire type %d)", fieldNum, wire)
 		}
 		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Stats", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowExec
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthExec
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthExec
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Stats = append(m.Stats[:0], dAtA[iNdEx:postIndex]...)
+			if m.Stats == nil {
+				m.Stats = []byte{}
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipExec(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthExec
+			}
+			if (iNdEx + skippy) < 0 {
+				return ErrInvalidLengthExec
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *ExecProgressEvent) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowExec
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: ExecProgressEvent: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: ExecProgressEvent: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field RowsScanned", wireType)
+			}
+			m.RowsScanned = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowExec
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.RowsScanned |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field BytesScanned", wireType)
+			}
+			m.BytesScanned = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowExec
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.BytesScanned |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
 		default:
 			iNdEx = preIndex
 			skippy, err := skipExec(dAtA[iNdEx:])
@@ -2444,6 +2854,41 @@ func (m *ExecEvent) Unmarshal(dAtA []byte) error {
 			}
 			m.Event = &ExecEvent_ExecStats{v}
 			iNdEx = postIndex
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ExecProgress", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowExec
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthExec
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthExec
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			v := &ExecProgressEvent{}
+			if err := v.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			m.Event = &ExecEvent_ExecProgress{v}
+			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipExec(dAtA[iNdEx:])
@@ -0,0 +1,348 @@
+// Copyright 2020 The Meerkat Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logical
+
+import (
+	"math"
+	"strings"
+	"time"
+)
+
+// constantFolder replaces the expressions with constant operands by its
+// value. Functions with constant arguments like ago(1h) are folded once
+// the timestamps are frozen. The values follow the semantic of the
+// physical evaluators: timespans are integer nanoseconds, integer division
+// by zero gives null and any null operand gives a null result.
+type constantFolder struct{}
+
+func (f *constantFolder) VisitPre(n Node) Node { return n }
+
+func (f *constantFolder) VisitPost(n Node) Node {
+
+	switch e := n.(type) {
+
+	case *BinaryExpr:
+
+		if e.Op == AND || e.Op == OR {
+			return foldLogic(e)
+		}
+
+		l, lok := e.LeftExpr.(*LiteralExpr)
+		r, rok := e.RightExpr.(*LiteralExpr)
+
+		if !lok || !rok {
+			return n
+		}
+
+		if value, ok := foldBinary(e.Op, l.Value, r.Value); ok {
+			return &LiteralExpr{Value: value}
+		}
+
+	case *UnaryExpr:
+
+		lit, ok := e.Expr.(*LiteralExpr)
+
+		if !ok {
+			return n
+		}
+
+		if e.Op == ADD {
+			return lit
+		}
+
+		switch v := lit.Value.(type) {
+		case int:
+			return &LiteralExpr{Value: -int64(v)}
+		case int64:
+			return &LiteralExpr{Value: -v}
+		case float64:
+			return &LiteralExpr{Value: -v}
+		case time.Duration:
+			return &LiteralExpr{Value: -v}
+		case nil:
+			return lit
+		}
+
+	}
+
+	return n
+
+}
+
+// foldLogic simplifies the and/or operators with a constant operand using
+// three-valued logic, ie. "x and false" is false even if x is null.
+func foldLogic(e *BinaryExpr) Node {
+
+	l, lok := boolLiteral(e.LeftExpr)
+	r, rok := boolLiteral(e.RightExpr)
+
+	switch {
+	case lok && rok:
+		return &LiteralExpr{Value: e.Op == AND && l && r || e.Op == OR && (l || r)}
+	case lok:
+		return foldLogicOperand(e.Op, l, e.RightExpr)
+	case rok:
+		return foldLogicOperand(e.Op, r, e.LeftExpr)
+	default:
+		return e
+	}
+
+}
+
+func foldLogicOperand(op Operator, value bool, other Node) Node {
+	if (op == AND) == value {
+		// true and x, false or x
+		return other
+	}
+	return &LiteralExpr{Value: value}
+}
+
+func boolLiteral(n Node) (bool, bool) {
+	if lit, ok := n.(*LiteralExpr); ok {
+		b, ok := lit.Value.(bool)
+		return b, ok
+	}
+	return false, false
+}
+
+// foldBinary computes the value of a binary operator with constant
+// operands. ok is false if the operator cannot be folded.
+func foldBinary(op Operator, l interface{}, r interface{}) (value interface{}, ok bool) {
+
+	if l == nil || r == nil {
+		return nil, isArithOp(op) || isCompareOp(op)
+	}
+
+	l, r = normalizeInt(l), normalizeInt(r)
+
+	switch {
+	case isArithOp(op):
+		return foldArith(op, l, r)
+	case isCompareOp(op):
+		c, ok := compareConst(l, r)
+		if !ok {
+			return nil, false
+		}
+		if _, isBool := l.(bool); isBool && op != EQL && op != NEQ {
+			return nil, false
+		}
+		return compareResult(op, c), true
+	default:
+		return nil, false
+	}
+
+}
+
+func isArithOp(op Operator) bool {
+	return op == ADD || op == SUB || op == MUL || op == QUO || op == REM
+}
+
+func isCompareOp(op Operator) bool {
+	return op == EQL || op == NEQ || op == LSS || op == GTR || op == LEQ || op == GEQ
+}
+
+func normalizeInt(v interface{}) interface{} {
+	if i, ok := v.(int); ok {
+		return int64(i)
+	}
+	return v
+}
+
+// integerValue returns the value of an integer, timespan or datetime
+// constant as int64.
+func integerValue(v interface{}) (int64, bool) {
+	switch x := v.(type) {
+	case int64:
+		return x, true
+	case time.Duration:
+		return int64(x), true
+	case time.Time:
+		return x.UnixNano(), true
+	default:
+		return 0, false
+	}
+}
+
+func floatValue(v interface{}) (float64, bool) {
+	if f, ok := v.(float64); ok {
+		return f, true
+	}
+	if _, isTime := v.(time.Time); isTime {
+		return 0, false
+	}
+	i, ok := integerValue(v)
+	return float64(i), ok
+}
+
+func foldArith(op Operator, l interface{}, r interface{}) (interface{}, bool) {
+
+	lt, lTime := l.(time.Time)
+	rt, rTime := r.(time.Time)
+	_, lDur := l.(time.Duration)
+	_, rDur := r.(time.Duration)
+
+	switch {
+
+	case lTime && rTime:
+		if op == SUB {
+			return time.Duration(lt.UnixNano() - rt.UnixNano()), true
+		}
+		return nil, false
+
+	case lTime || rTime:
+		d, ok := integerValue(r)
+		t := lt
+		if rTime {
+			d, ok = integerValue(l)
+			t = rt
+		}
+		if !ok {
+			return nil, false
+		}
+		switch {
+		case op == ADD:
+			return time.Unix(0, t.UnixNano()+d).UTC(), true
+		case op == SUB && lTime:
+			return time.Unix(0, t.UnixNano()-d).UTC(), true
+		default:
+			return nil, false
+		}
+
+	}
+
+	li, lInt := integerValue(l)
+	ri, rInt := integerValue(r)
+
+	if lInt && rInt {
+
+		var v int64
+
+		switch op {
+		case ADD:
+			v = li + ri
+		case SUB:
+			v = li - ri
+		case MUL:
+			v = li * ri
+		case QUO, REM:
+			if ri == 0 {
+				return nil, true
+			}
+			if op == QUO {
+				v = li / ri
+			} else {
+				v = li % ri
+			}
+		}
+
+		if lDur || rDur {
+			return time.Duration(v), true
+		}
+
+		return v, true
+
+	}
+
+	lf, lok := floatValue(l)
+	rf, rok := floatValue(r)
+
+	if !lok || !rok {
+		return nil, false
+	}
+
+	switch op {
+	case ADD:
+		return lf + rf, true
+	case SUB:
+		return lf - rf, true
+	case MUL:
+		return lf * rf, true
+	case QUO:
+		return lf / rf, true
+	default:
+		return math.Mod(lf, rf), true
+	}
+
+}
+
+// compareConst compares two constants. Integer values are compared as
+// int64, mixed numeric values are compared as float64.
+func compareConst(l interface{}, r interface{}) (int, bool) {
+
+	if li, ok := integerValue(l); ok {
+		if ri, ok := integerValue(r); ok {
+			return compareInt64(li, ri), true
+		}
+	}
+
+	if lf, ok := floatValue(l); ok {
+		if rf, ok := floatValue(r); ok {
+			switch {
+			case lf < rf:
+				return -1, true
+			case lf > rf:
+				return 1, true
+			case lf == rf:
+				return 0, true
+			}
+			// NaN values are not folded.
+			return 0, false
+		}
+	}
+
+	switch lv := l.(type) {
+	case string:
+		if rv, ok := r.(string); ok {
+			return strings.Compare(lv, rv), true
+		}
+	case bool:
+		if rv, ok := r.(bool); ok {
+			if lv == rv {
+				return 0, true
+			}
+			return 1, true
+		}
+	}
+
+	return 0, false
+
+}
+
+func compareInt64(x, y int64) int {
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func compareResult(op Operator, c int) bool {
+	switch op {
+	case EQL:
+		return c == 0
+	case NEQ:
+		return c != 0
+	case LSS:
+		return c < 0
+	case GTR:
+		return c > 0
+	case LEQ:
+		return c <= 0
+	default:
+		return c >= 0
+	}
+}
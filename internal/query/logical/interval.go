@@ -0,0 +1,162 @@
+// Copyright 2020 The Meerkat Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logical
+
+import (
+	"math"
+	"meerkat/internal/storage"
+	"time"
+)
+
+// flippedOps maps a comparison operator to the one used when the operands
+// are swapped.
+var flippedOps = map[Operator]Operator{
+	EQL: EQL,
+	LSS: GTR,
+	GTR: LSS,
+	LEQ: GEQ,
+	GEQ: LEQ,
+}
+
+// timeInterval returns the interval covering all the timestamps that can
+// satisfy a filter, computed from the comparisons between the _ts column
+// and a constant in the terms of the conjunction. Returns nil if the
+// filter doesn't restrict the timestamps.
+func timeInterval(filter Node) *storage.Interval {
+
+	if filter == nil {
+		return nil
+	}
+
+	from, to := int64(math.MinInt64), int64(math.MaxInt64)
+	found := false
+
+	for _, term := range conjunctionTerms(filter) {
+
+		op, nanos, ok := tsComparison(term)
+
+		if !ok {
+			continue
+		}
+
+		found = true
+
+		switch op {
+		case GTR:
+			if nanos < math.MaxInt64 {
+				from = max64(from, nanos+1)
+			} else {
+				from, to = 0, -1
+			}
+		case GEQ:
+			from = max64(from, nanos)
+		case LSS:
+			if nanos > math.MinInt64 {
+				to = min64(to, nanos-1)
+			} else {
+				from, to = 0, -1
+			}
+		case LEQ:
+			to = min64(to, nanos)
+		case EQL:
+			from, to = max64(from, nanos), min64(to, nanos)
+		}
+
+	}
+
+	if !found {
+		return nil
+	}
+
+	return &storage.Interval{
+		From: time.Unix(0, from).UTC(),
+		To:   time.Unix(0, to).UTC(),
+	}
+
+}
+
+// tsComparison matches the expressions comparing the _ts column with a
+// constant timestamp, ie. _ts > datetime(2020-01-01). The operator is
+// returned as if _ts was the left operand.
+func tsComparison(n Node) (op Operator, nanos int64, ok bool) {
+
+	e, isBinary := n.(*BinaryExpr)
+
+	if !isBinary {
+		return 0, 0, false
+	}
+
+	op, ok = flippedOps[e.Op]
+
+	if !ok {
+		return 0, 0, false
+	}
+
+	ref, lit := e.LeftExpr, e.RightExpr
+
+	if _, isRef := ref.(*ColRefExpr); !isRef {
+		ref, lit = lit, ref
+	} else {
+		op = e.Op
+	}
+
+	if r, isRef := ref.(*ColRefExpr); !isRef || r.Name != storage.TSColumnName {
+		return 0, 0, false
+	}
+
+	l, isLit := lit.(*LiteralExpr)
+
+	if !isLit {
+		return 0, 0, false
+	}
+
+	switch v := l.Value.(type) {
+	case time.Time:
+		return op, v.UnixNano(), true
+	case int64:
+		return op, v, true
+	case int:
+		return op, int64(v), true
+	default:
+		return 0, 0, false
+	}
+
+}
+
+func max64(x, y int64) int64 {
+	if x > y {
+		return x
+	}
+	return y
+}
+
+func min64(x, y int64) int64 {
+	if x < y {
+		return x
+	}
+	return y
+}
+
+// intervalSetter sets the time interval of the segments read by every
+// SourceOp from its pushed down filter.
+type intervalSetter struct{}
+
+func (s *intervalSetter) VisitPre(n Node) Node { return n }
+
+func (s *intervalSetter) VisitPost(n Node) Node {
+	if source, ok := n.(*SourceOp); ok {
+		source.Interval = timeInterval(source.Filter)
+	}
+	return n
+}
@@ -16,6 +16,7 @@ package logical
 import (
 	"encoding/gob"
 	"meerkat/internal/query/parser"
+	"time"
 )
 
 //go:generate protoc -I . -I ../../../build/proto/ --plugin ../../../build/protoc-gen-gogofaster --gogofaster_out=plugins=grpc,paths=source_relative:.  ./logical.proto
@@ -32,9 +33,26 @@ func init() {
 	gob.Register(&CallExpr{})
 	gob.Register(&ColRefExpr{})
 	gob.Register(&LiteralExpr{})
+	gob.Register(&StarExpr{})
 	gob.Register(&AggExpr{})
 	gob.Register(&ColumnExpr{})
 	gob.Register(&FilterOp{})
+	gob.Register(&LimitOp{})
+	gob.Register(&LocalSummaryOp{})
+	gob.Register(&DistSummaryOp{})
+	gob.Register(&SummaryCollector{})
+	gob.Register(&HashExchangeOutOp{})
+	gob.Register(&HashExchangeInOp{})
+	gob.Register(&BroadcastOutOp{})
+	gob.Register(&JoinOp{})
+	gob.Register(&UnionOp{})
+	gob.Register(&SortOp{})
+	gob.Register(&SortExpr{})
+	gob.Register(&TopOp{})
+	gob.Register(time.Time{})
+	gob.Register(time.Duration(0))
+	gob.Register(&ProjectOp{})
+	gob.Register(&ExtendOp{})
 }
 
 func ToLogical(query *parser.TabularStmt) []Node {
@@ -44,7 +62,6 @@ func ToLogical(query *parser.TabularStmt) []Node {
 }
 
 type transform struct {
-	child       Node
 	outputNodes []Node
 }
 
@@ -54,22 +71,50 @@ func (t *transform) transform(n parser.Node) {
 	case *parser.TabularStmt:
 		t.transform(node.TabularExpr)
 	case *parser.TabularExpr:
-		t.child = &SourceOp{TableName: node.Source.Value.(string)}
-		for _, tabOp := range node.TabularOp {
-			switch op := tabOp.(type) {
-			case *parser.WhereOp:
-				t.child = t.transformWhereOp(t.child, op)
-			case *parser.SummarizeOp:
-				t.child = t.transformSummarizeOp(t.child, op)
-			case *parser.LimitOp:
-				t.child = t.transformLimitOp(t.child, op)
-			default:
-				panic("unknown operator")
-			}
+		t.outputNodes = append(t.outputNodes, t.transformTabularExpr(node))
+	}
+
+}
+
+func (t *transform) transformTabularExpr(expr *parser.TabularExpr) Node {
+
+	var child Node
+
+	if expr.Union != nil {
+		child = t.transformUnionExpr(expr.Union)
+	} else {
+		child = newSourceOp(expr.Source.Value.(string))
+	}
+
+	for _, tabOp := range expr.TabularOp {
+		switch op := tabOp.(type) {
+		case *parser.WhereOp:
+			child = t.transformWhereOp(child, op)
+		case *parser.SummarizeOp:
+			child = t.transformSummarizeOp(child, op)
+		case *parser.LimitOp:
+			child = t.transformLimitOp(child, op)
+		case *parser.SortOp:
+			child = t.transformSortOp(child, op)
+		case *parser.TopOp:
+			child = t.transformTopOp(child, op)
+		case *parser.ProjectOp:
+			child = t.transformProjectOp(child, op)
+		case *parser.ExtendOp:
+			child = t.transformExtendOp(child, op)
+		case *parser.JoinOp:
+			child = t.transformJoinOp(child, op)
+		case *parser.CountOp:
+			child = t.transformCountOp(child)
+		case *parser.DistinctOp:
+			child = t.transformDistinctOp(child, op)
+		default:
+			panic("unknown operator")
 		}
-		t.outputNodes = append(t.outputNodes, t.child)
 	}
 
+	return child
+
 }
 
 func (t *transform) transformWhereOp(child Node, whereOp *parser.WhereOp) *FilterOp {
@@ -81,6 +126,8 @@ func (t *transform) transformWhereOp(child Node, whereOp *parser.WhereOp) *Filte
 
 func (t *transform) transformSummarizeOp(child Node, op *parser.SummarizeOp) Node {
 
+	parser.NameSummarizeColumns(op)
+
 	return &SummarizeOp{
 		Agg:   t.transformAggExprList(op.Agg),
 		By:    t.transformColumnExprList(op.By),
@@ -96,6 +143,147 @@ func (t *transform) transformLimitOp(child Node, op *parser.LimitOp) *LimitOp {
 	}
 }
 
+func (t *transform) transformSortOp(child Node, op *parser.SortOp) *SortOp {
+	return &SortOp{
+		SortExpr: t.transformSortExprList(op.SortExpr),
+		Child:    child,
+	}
+}
+
+func (t *transform) transformTopOp(child Node, op *parser.TopOp) *TopOp {
+	return &TopOp{
+		NumOfRows: op.NumberOfRows.Value.(int),
+		SortExpr:  t.transformSortExprList([]*parser.SortExpr{op.By}),
+		Child:     child,
+	}
+}
+
+func (t *transform) transformProjectOp(child Node, op *parser.ProjectOp) *ProjectOp {
+
+	parser.NameColumns(op.Columns)
+
+	return &ProjectOp{
+		Columns: t.transformColumnExprList(op.Columns),
+		Child:   child,
+	}
+
+}
+
+func (t *transform) transformExtendOp(child Node, op *parser.ExtendOp) *ExtendOp {
+
+	parser.NameColumns(op.Columns)
+
+	return &ExtendOp{
+		Columns: t.transformColumnExprList(op.Columns),
+		Child:   child,
+	}
+
+}
+
+// transformCountOp counts the rows using a summarize so the count is
+// computed by every node on its own segments.
+func (t *transform) transformCountOp(child Node) *SummarizeOp {
+	return &SummarizeOp{
+		Agg: []*AggExpr{
+			{ColName: parser.CountColumnName, Expr: &CallExpr{FuncName: "count"}},
+		},
+		Child: child,
+	}
+}
+
+// transformDistinctOp groups the rows by the distinct columns using a
+// summarize without aggregations.
+func (t *transform) transformDistinctOp(child Node, op *parser.DistinctOp) *SummarizeOp {
+
+	by := make([]*ColumnExpr, len(op.Columns))
+
+	for i, col := range op.Columns {
+		name := col.Value.(string)
+		by[i] = &ColumnExpr{ColName: name, Expr: &ColRefExpr{Name: name}}
+	}
+
+	return &SummarizeOp{
+		By:    by,
+		Child: child,
+	}
+
+}
+
+var joinKinds = map[string]JoinKind{
+	"inner":     InnerJoin,
+	"leftouter": LeftOuterJoin,
+	"leftanti":  LeftAntiJoin,
+	"leftsemi":  LeftSemiJoin,
+}
+
+func (t *transform) transformJoinOp(child Node, op *parser.JoinOp) *JoinOp {
+
+	kind, found := joinKinds[parser.JoinKind(op)]
+
+	if !found {
+		panic("unknown join kind")
+	}
+
+	keys := make([]string, len(op.On))
+
+	for i, key := range op.On {
+		keys[i] = key.Value.(string)
+	}
+
+	return &JoinOp{
+		Kind:  kind,
+		Keys:  keys,
+		Left:  child,
+		Right: t.transformTabularExpr(op.Right),
+	}
+
+}
+
+// transformUnionExpr reads every table of the union adding the table name
+// as a column.
+func (t *transform) transformUnionExpr(expr *parser.UnionExpr) *UnionOp {
+
+	union := &UnionOp{Columns: expr.Columns}
+
+	for _, table := range expr.Tables {
+
+		name := table.Value.(string)
+
+		union.Children = append(union.Children, &ExtendOp{
+			Columns: []*ColumnExpr{
+				{ColName: parser.TableColumnName, Expr: &LiteralExpr{Value: name}},
+			},
+			Child: newSourceOp(name),
+		})
+
+	}
+
+	return union
+
+}
+
+// newSourceOp reads the table named name, qualified by its database if
+// it's not the default one.
+func newSourceOp(name string) *SourceOp {
+
+	database, table := parser.SplitTableName(name)
+
+	return &SourceOp{Database: database, TableName: table}
+
+}
+
+func (t *transform) transformSortExprList(exprList []*parser.SortExpr) []*SortExpr {
+	sortExpr := make([]*SortExpr, len(exprList))
+	for i, expr := range exprList {
+		sortExpr[i] = &SortExpr{
+			Expr:      t.transformExpr(expr.Expr),
+			Asc:       expr.Asc,
+			NullFirst: expr.NullFirst,
+		}
+	}
+	return sortExpr
+}
+
 func (t *transform) transformExpr(expr parser.Node) Node {
 
 	switch e := expr.(type) {
@@ -107,6 +295,8 @@ func (t *transform) transformExpr(expr parser.Node) Node {
 		return t.transformCallExpr(e)
 	case *parser.LitExpr:
 		return t.transformLitExpr(e)
+	case *parser.StarExpr:
+		return &StarExpr{}
 	default:
 		panic("unknown expr node")
 	}
@@ -124,7 +314,7 @@ func (t *transform) transformBinaryExpr(expr *parser.BinaryExpr) Node {
 func (t *transform) transformUnaryExpr(expr *parser.UnaryExpr) Node {
 	return &UnaryExpr{
 		Op:   t.transformOp(expr.Op.Type),
-		Expr: t.transformExpr(expr),
+		Expr: t.transformExpr(expr.Expr),
 	}
 }
 
@@ -154,7 +344,11 @@ func (t *transform) transformLitExpr(expr *parser.LitExpr) Node {
 	switch expr.Token.Type {
 	case parser.IDENT:
 		return &ColRefExpr{Name: expr.Value.(string)}
-	case parser.INT, parser.FLOAT, parser.STRING, parser.TIME, parser.DATETIME, parser.BOOL:
+	case parser.TIME:
+		return &LiteralExpr{Value: time.Duration(expr.Value.(int))}
+	case parser.DATETIME:
+		return &LiteralExpr{Value: time.Unix(0, int64(expr.Value.(int))).UTC()}
+	case parser.INT, parser.FLOAT, parser.STRING, parser.BOOL:
 		return &LiteralExpr{Value: expr.Value}
 	default:
 		panic("invalid literal type")
@@ -163,18 +357,26 @@ func (t *transform) transformLitExpr(expr *parser.LitExpr) Node {
 
 func (t *transform) transformAggExpr(expr *parser.AggExpr) *AggExpr {
 	return &AggExpr{
-		ColName: expr.ColName.Value.(string),
+		ColName: litName(expr.ColName),
 		Expr:    t.transformCallExpr(expr.Expr),
 	}
 }
 
 func (t *transform) transformColumnExpr(expr *parser.ColumnExpr) *ColumnExpr {
 	return &ColumnExpr{
-		ColName: expr.ColName.Value.(string),
+		ColName: litName(expr.ColName),
 		Expr:    t.transformExpr(expr.Expr),
 	}
 }
 
+// litName returns the column name of an optional IDENT literal.
+func litName(lit *parser.LitExpr) string {
+	if lit == nil {
+		return ""
+	}
+	return lit.Value.(string)
+}
+
 func (t *transform) transformAggExprList(exprList []*parser.AggExpr) []*AggExpr {
 	agg := make([]*AggExpr, len(exprList))
 	for i, expr := range exprList {
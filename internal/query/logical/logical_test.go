@@ -53,3 +53,90 @@ func TestTransform(t *testing.T) {
 
 	assert.Equal(t, expected, actual)
 }
+
+func TestTransformProject(t *testing.T) {
+
+	ast, err := parser.Parse("T | extend d=a*2 | project a, b+1, d, Column2=c")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	actual := ToLogical(ast)[0]
+
+	expected := &ProjectOp{
+		Columns: []*ColumnExpr{
+			{ColName: "a", Expr: &ColRefExpr{Name: "a"}},
+			{
+				ColName: "Column21",
+				Expr: &BinaryExpr{
+					LeftExpr:  &ColRefExpr{Name: "b"},
+					Op:        ADD,
+					RightExpr: &LiteralExpr{Value: 1},
+				},
+			},
+			{ColName: "d", Expr: &ColRefExpr{Name: "d"}},
+			{ColName: "Column2", Expr: &ColRefExpr{Name: "c"}},
+		},
+		Child: &ExtendOp{
+			Columns: []*ColumnExpr{{
+				ColName: "d",
+				Expr: &BinaryExpr{
+					LeftExpr:  &ColRefExpr{Name: "a"},
+					Op:        MUL,
+					RightExpr: &LiteralExpr{Value: 2},
+				},
+			}},
+			Child: &SourceOp{TableName: "T"},
+		},
+	}
+
+	assert.Equal(t, expected, actual)
+
+}
+
+func TestTransformDatabase(t *testing.T) {
+
+	ast, err := parser.Parse("union prod.T, E")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	union := ToLogical(ast)[0].(*UnionOp)
+
+	prod := union.Children[0].(*ExtendOp)
+	assert.Equal(t, &SourceOp{Database: "prod", TableName: "T"}, prod.Child)
+	assert.Equal(t, &LiteralExpr{Value: "prod.T"}, prod.Columns[0].Expr)
+
+	assert.Equal(t, &SourceOp{TableName: "E"}, union.Children[1].(*ExtendOp).Child)
+
+}
+
+func TestTransformCountDistinct(t *testing.T) {
+
+	ast, err := parser.Parse("T | distinct a, b | count")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	actual := ToLogical(ast)[0]
+
+	// both are summarized so they are aggregated by every node.
+	expected := &SummarizeOp{
+		Agg: []*AggExpr{
+			{ColName: "Count", Expr: &CallExpr{FuncName: "count"}},
+		},
+		Child: &SummarizeOp{
+			By: []*ColumnExpr{
+				{ColName: "a", Expr: &ColRefExpr{Name: "a"}},
+				{ColName: "b", Expr: &ColRefExpr{Name: "b"}},
+			},
+			Child: &SourceOp{TableName: "T"},
+		},
+	}
+
+	assert.Equal(t, expected, actual)
+
+}
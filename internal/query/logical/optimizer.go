@@ -13,17 +13,69 @@
 
 package logical
 
+import "time"
+
 // Optimize rewrite and optimize the logical tree.
 // Currently a very small set of heuristic rules
 // is supported.
 //
+// - capture/freeze timestamps
 // - fold constant
 // - evaluate functions with constant arguments ie. ago(1h)
-// - capture/freeze timestamps
 // - pushdown predicates or filters.
-// = collapse filter
+// - collapse filter
+// - prune the columns read from the segments
+// - bound the time interval of the segments read
 func Optimize(logicalTree []Node) []Node {
+	return optimize(logicalTree, time.Now())
+}
+
+func optimize(logicalTree []Node, now time.Time) []Node {
+
+	freezer := &timestampFreezer{now: now.UTC()}
+	folder := &constantFolder{}
+
+	for i, node := range logicalTree {
+		node = Walk(node, freezer)
+		node = Walk(node, folder)
+		node = pushFilters(node)
+		node = Walk(node, &intervalSetter{})
+		pruneColumns(node, nil)
+		logicalTree[i] = node
+	}
 
-	// do nothing for now
 	return logicalTree
+
+}
+
+// timestampFreezer replaces the calls to now() and ago() with the time the
+// query started. The plan is sent to the nodes with the frozen value so all
+// of them see the same time.
+type timestampFreezer struct {
+	now time.Time
+}
+
+func (f *timestampFreezer) VisitPre(n Node) Node { return n }
+
+func (f *timestampFreezer) VisitPost(n Node) Node {
+
+	call, ok := n.(*CallExpr)
+
+	if !ok {
+		return n
+	}
+
+	now := &LiteralExpr{Value: f.now}
+
+	switch {
+	case call.FuncName == "now" && len(call.ArgList) == 0:
+		return now
+	case call.FuncName == "now" && len(call.ArgList) == 1:
+		return &BinaryExpr{LeftExpr: now, Op: ADD, RightExpr: call.ArgList[0]}
+	case call.FuncName == "ago" && len(call.ArgList) == 1:
+		return &BinaryExpr{LeftExpr: now, Op: SUB, RightExpr: call.ArgList[0]}
+	default:
+		return n
+	}
+
 }
@@ -0,0 +1,397 @@
+// Copyright 2020 The Meerkat Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logical
+
+import (
+	"github.com/stretchr/testify/assert"
+	"math"
+	"meerkat/internal/query/parser"
+	"meerkat/internal/storage"
+	"testing"
+	"time"
+)
+
+func TestFreezeTimestamps(t *testing.T) {
+
+	ast, err := parser.Parse("T | where _ts > ago(1h) and _ts < now() | extend t=now(-1m)")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2020, 5, 1, 10, 0, 0, 0, time.UTC)
+	extend := Walk(ToLogical(ast)[0], &timestampFreezer{now: now}).(*ExtendOp)
+	filter := extend.Child.(*FilterOp)
+
+	expected := &BinaryExpr{
+		LeftExpr: &BinaryExpr{
+			LeftExpr: &ColRefExpr{Name: "_ts"},
+			Op:       GTR,
+			RightExpr: &BinaryExpr{
+				LeftExpr:  &LiteralExpr{Value: now},
+				Op:        SUB,
+				RightExpr: &LiteralExpr{Value: time.Hour},
+			},
+		},
+		Op: AND,
+		RightExpr: &BinaryExpr{
+			LeftExpr:  &ColRefExpr{Name: "_ts"},
+			Op:        LSS,
+			RightExpr: &LiteralExpr{Value: now},
+		},
+	}
+
+	assert.Equal(t, expected, filter.Predicate)
+
+	assert.Equal(t, &BinaryExpr{
+		LeftExpr: &LiteralExpr{Value: now},
+		Op:       ADD,
+		RightExpr: &UnaryExpr{
+			Op:   SUB,
+			Expr: &LiteralExpr{Value: time.Minute},
+		},
+	}, extend.Columns[0].Expr)
+
+}
+
+func optimizeQuery(t *testing.T, query string, now time.Time) Node {
+
+	ast, err := parser.Parse(query)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return optimize(ToLogical(ast), now)[0]
+
+}
+
+func TestFoldConstants(t *testing.T) {
+
+	now := time.Date(2020, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		expr     string
+		expected Node
+	}{
+		{"1 + 2 * 3", &LiteralExpr{Value: int64(7)}},
+		{"7 / 2 + 0.5", &LiteralExpr{Value: 3.5}},
+		{"1 / 0", &LiteralExpr{Value: nil}},
+		{"-(2h) + 30m", &LiteralExpr{Value: -90 * time.Minute}},
+		{"now(-1d)", &LiteralExpr{Value: now.Add(-24 * time.Hour)}},
+		{"ago(1h) < now()", &LiteralExpr{Value: true}},
+		{"now() - datetime(2020-04-30)", &LiteralExpr{Value: 34 * time.Hour}},
+		{`"a" == "b" or x`, &ColRefExpr{Name: "x"}},
+		{"x and 1 > 2", &LiteralExpr{Value: false}},
+		{"strlen(1 + x)", &CallExpr{
+			FuncName: "strlen",
+			ArgList: []Node{
+				&BinaryExpr{LeftExpr: &LiteralExpr{Value: 1}, Op: ADD, RightExpr: &ColRefExpr{Name: "x"}},
+			},
+		}},
+	}
+
+	for _, test := range tests {
+		extend := optimizeQuery(t, "T | extend c = "+test.expr, now).(*ExtendOp)
+		assert.Equal(t, test.expected, extend.Columns[0].Expr, test.expr)
+	}
+
+}
+
+func TestPushDownFilters(t *testing.T) {
+
+	now := time.Date(2020, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	root := optimizeQuery(t, `T
+		| where _ts > ago(1h)
+		| extend e = size * 2
+		| where e > 10 and host == "a"
+		| project h = host, e
+		| where h != "b"
+		| sort by h`, now)
+
+	sortOp := root.(*SortOp)
+	project := sortOp.Child.(*ProjectOp)
+	filter := project.Child.(*FilterOp)
+	extend := filter.Child.(*ExtendOp)
+	source := extend.Child.(*SourceOp)
+
+	// the filter on the extended column stays above the extend.
+	assert.Equal(t, &BinaryExpr{
+		LeftExpr:  &ColRefExpr{Name: "e"},
+		Op:        GTR,
+		RightExpr: &LiteralExpr{Value: 10},
+	}, filter.Predicate)
+
+	assert.Equal(t, &BinaryExpr{
+		LeftExpr: &BinaryExpr{
+			LeftExpr: &BinaryExpr{
+				LeftExpr:  &ColRefExpr{Name: "_ts"},
+				Op:        GTR,
+				RightExpr: &LiteralExpr{Value: now.Add(-time.Hour)},
+			},
+			Op: AND,
+			RightExpr: &BinaryExpr{
+				LeftExpr:  &ColRefExpr{Name: "host"},
+				Op:        EQL,
+				RightExpr: &LiteralExpr{Value: "a"},
+			},
+		},
+		Op: AND,
+		RightExpr: &BinaryExpr{
+			LeftExpr:  &ColRefExpr{Name: "host"},
+			Op:        NEQ,
+			RightExpr: &LiteralExpr{Value: "b"},
+		},
+	}, source.Filter)
+
+	assert.Equal(t, []string{"_ts", "host", "size"}, source.Columns)
+
+}
+
+func TestPushDownSummarize(t *testing.T) {
+
+	now := time.Now()
+
+	// filters on the groups go below the summarize.
+	root := optimizeQuery(t, `T | summarize n = count() by h = host | where h == "a" and n > 1`, now)
+
+	filter := root.(*FilterOp)
+	summarize := filter.Child.(*SummarizeOp)
+	source := summarize.Child.(*SourceOp)
+
+	assert.Equal(t, &BinaryExpr{
+		LeftExpr:  &ColRefExpr{Name: "host"},
+		Op:        EQL,
+		RightExpr: &LiteralExpr{Value: "a"},
+	}, source.Filter)
+
+	// without groups a single row is returned even without input rows.
+	root = optimizeQuery(t, `T | summarize n = count() | where n > 1`, now)
+
+	filter = root.(*FilterOp)
+	source = filter.Child.(*SummarizeOp).Child.(*SourceOp)
+
+	// and the rows are counted from the segment infos.
+	assert.Nil(t, source.Filter)
+	assert.True(t, source.CountOnly)
+	assert.Nil(t, source.Columns)
+
+}
+
+func TestPruneColumns(t *testing.T) {
+
+	now := time.Now()
+
+	tests := []struct {
+		query   string
+		columns []string
+	}{
+		{"T | where a > 1", nil},
+		{"T | extend a = b + 1", nil},
+		{"T | project a, c = b + 1", []string{"a", "b"}},
+		{"T | extend a = b + 1, c = a * 2 | project c, d", []string{"b", "d"}},
+		{"T | extend a = a + 1 | project a", []string{"a"}},
+		{"T | where x > 1 | summarize max(a) by bin(_ts, 1h) | top 1 by max_a", []string{"_ts", "a", "x"}},
+		{"T | summarize arg_max(_ts, b) by a", []string{"_ts", "a", "b"}},
+		{"T | project a, b, c | summarize arg_max(_ts, *) by a", []string{"a", "b", "c"}},
+	}
+
+	for _, test := range tests {
+
+		var source *SourceOp
+
+		Walk(optimizeQuery(t, test.query, now), &sourceFinder{found: &source})
+
+		assert.Equal(t, test.columns, source.Columns, test.query)
+
+	}
+
+}
+
+type sourceFinder struct {
+	found **SourceOp
+}
+
+func (f *sourceFinder) VisitPre(n Node) Node { return n }
+
+func (f *sourceFinder) VisitPost(n Node) Node {
+	if s, ok := n.(*SourceOp); ok {
+		*f.found = s
+	}
+	return n
+}
+
+func TestTimeInterval(t *testing.T) {
+
+	now := time.Date(2020, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		query    string
+		expected *storage.Interval
+	}{
+		{"T | where x > 1", nil},
+		{"T | where _ts > ago(1h) or x > 1", nil},
+		{"T | where _ts >= ago(1h)", &storage.Interval{
+			From: now.Add(-time.Hour),
+			To:   time.Unix(0, math.MaxInt64).UTC(),
+		}},
+		{"T | where _ts > datetime(2020-04-01) and x > 1 | where now() > _ts", &storage.Interval{
+			From: time.Date(2020, 4, 1, 0, 0, 0, 1, time.UTC),
+			To:   now.Add(-1),
+		}},
+		{"T | extend y = x | where _ts <= datetime(2020-04-01) and _ts >= datetime(2020-03-01)", &storage.Interval{
+			From: time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC),
+			To:   time.Date(2020, 4, 1, 0, 0, 0, 0, time.UTC),
+		}},
+	}
+
+	for _, test := range tests {
+
+		var source *SourceOp
+
+		Walk(optimizeQuery(t, test.query, now), &sourceFinder{found: &source})
+
+		assert.Equal(t, test.expected, source.Interval, test.query)
+
+	}
+
+}
+
+func TestPushDownJoin(t *testing.T) {
+
+	now := time.Now()
+
+	// only the filters on the keys are known to apply to the left side.
+	root := optimizeQuery(t, `T | join (E) on trace | where trace == "x" and code > 1 | project a, code`, now)
+
+	filter := root.(*ProjectOp).Child.(*FilterOp)
+	join := filter.Child.(*JoinOp)
+
+	assert.Equal(t, &BinaryExpr{
+		LeftExpr:  &ColRefExpr{Name: "code"},
+		Op:        GTR,
+		RightExpr: &LiteralExpr{Value: 1},
+	}, filter.Predicate)
+	assert.Equal(t, &BinaryExpr{
+		LeftExpr:  &ColRefExpr{Name: "trace"},
+		Op:        EQL,
+		RightExpr: &LiteralExpr{Value: "x"},
+	}, join.Left.(*SourceOp).Filter)
+	assert.Equal(t, []string{"a", "code", "trace"}, join.Left.(*SourceOp).Columns)
+	assert.Equal(t, []string{"a", "code", "trace"}, join.Right.(*SourceOp).Columns)
+
+	// leftsemi joins return the left rows so every filter is pushed down
+	// and the right side only reads the keys.
+	root = optimizeQuery(t, `T | join kind=leftsemi (E | project trace, code) on trace | where a > 1 | project a`, now)
+
+	join = root.(*ProjectOp).Child.(*JoinOp)
+	left := join.Left.(*SourceOp)
+	right := join.Right.(*ProjectOp).Child.(*SourceOp)
+
+	assert.NotNil(t, left.Filter)
+	assert.Equal(t, []string{"a", "trace"}, left.Columns)
+	assert.Equal(t, []string{"trace"}, right.Columns)
+
+}
+
+func TestPushDownUnion(t *testing.T) {
+
+	now := time.Now()
+
+	// the filters are pushed to every table, the ones on $table are kept
+	// above the column they reference.
+	root := optimizeQuery(t, `union T, E | where a > 1 and $table == "T" | project a`, now)
+
+	union := root.(*ProjectOp).Child.(*UnionOp)
+
+	assert.Len(t, union.Children, 2)
+
+	for _, child := range union.Children {
+
+		filter := child.(*FilterOp)
+		extend := filter.Child.(*ExtendOp)
+		source := extend.Child.(*SourceOp)
+
+		assert.Equal(t, &BinaryExpr{
+			LeftExpr:  &ColRefExpr{Name: "$table"},
+			Op:        EQL,
+			RightExpr: &LiteralExpr{Value: "T"},
+		}, filter.Predicate)
+		assert.Equal(t, &BinaryExpr{
+			LeftExpr:  &ColRefExpr{Name: "a"},
+			Op:        GTR,
+			RightExpr: &LiteralExpr{Value: 1},
+		}, source.Filter)
+		assert.Equal(t, []string{"a"}, source.Columns)
+
+	}
+
+	assert.Equal(t, "E", union.Children[1].(*FilterOp).Child.(*ExtendOp).Child.(*SourceOp).TableName)
+
+}
+
+func TestPruneUnionColumns(t *testing.T) {
+
+	ast, err := parser.Parse(`union T, E | project a`)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the columns of the union are set by the analyzer.
+	ast.TabularExpr.Union.Columns = map[string]parser.Type{
+		"a":                    parser.IntType,
+		"b":                    parser.StringType,
+		parser.TableColumnName: parser.StringType,
+	}
+
+	root := optimize(ToLogical(ast), time.Now())[0]
+
+	union := root.(*ProjectOp).Child.(*UnionOp)
+
+	assert.Equal(t, map[string]parser.Type{"a": parser.IntType}, union.Columns)
+
+}
+
+func TestCountOnly(t *testing.T) {
+
+	now := time.Now()
+
+	tests := []struct {
+		query     string
+		countOnly bool
+	}{
+		{`T | count`, true},
+		{`T | summarize count(), s = sum(1)`, true},
+		{`T | where a > 1 | count`, false},
+		{`T | summarize count(a)`, false},
+		{`T | summarize count() by a`, false},
+		{`T | project a | count`, false},
+		{`T | extend b = a + 1 | count`, true},
+		{`union T, E | count`, true},
+		{`union T, E | where $table == "T" | count`, false},
+	}
+
+	for _, test := range tests {
+
+		var source *SourceOp
+
+		Walk(optimizeQuery(t, test.query, now), &sourceFinder{found: &source})
+
+		assert.Equal(t, test.countOnly, source.CountOnly, test.query)
+
+	}
+
+}
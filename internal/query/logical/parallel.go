@@ -36,6 +36,12 @@ func (f *Fragments) NodeFragments() []*Fragment {
 	return f.fragments[:len(f.fragments)-1]
 }
 
+// Output returns the output of the query, the first root of the
+// coordinator fragment.
+func (f *Fragments) Output() *OutputOp {
+	return f.fragments[len(f.fragments)-1].Roots[0].(*OutputOp)
+}
+
 func (f *Fragments) append(fragment *Fragment) {
 	f.fragments = append(f.fragments, fragment)
 }
@@ -50,7 +56,8 @@ func (f *Fragments) append(fragment *Fragment) {
 // fragment is created every time a distributed operator is found ( ie.
 // Summary or  Join ).
 // The last fragment in the slice  always executed in the query coordinator
-// node.
+// node. Besides the output it can have extra roots sending data computed in
+// the coordinator to the nodes ( ie. the broadcast side of a join ).
 func Parallelize(rootNodes []Node, localNodeId string, nodeNames []string) *Fragments {
 
 	parallelizer := NewNaiveParallelizer(localNodeId, nodeNames)
@@ -74,6 +81,8 @@ func NewNaiveParallelizer(localNodeName string, nodeNames []string) *NaiveParall
 		nodeNames:      nodeNames,
 		fragments:      &Fragments{},
 		inParallelFlow: true,
+		joins:          make(map[Node]*JoinOp),
+		leftFlows:      make(map[*JoinOp]bool),
 	}
 }
 
@@ -83,9 +92,29 @@ type NaiveParallelizer struct {
 	inParallelFlow bool
 	fragments      *Fragments
 	streamId       int64
+	// joins maps the right input of every join to the join.
+	joins map[Node]*JoinOp
+	// leftFlows holds if the left input of a join is a parallel flow.
+	leftFlows map[*JoinOp]bool
+	// localRoots are the roots added to the coordinator fragment.
+	localRoots []Node
 }
 
-func (p *NaiveParallelizer) VisitPre(n Node) Node { return n }
+func (p *NaiveParallelizer) VisitPre(n Node) Node {
+
+	if join, ok := n.(*JoinOp); ok {
+		p.joins[join.Right] = join
+	}
+
+	// the right input of a join starts a new flow.
+	if join, found := p.joins[n]; found {
+		p.leftFlows[join] = p.inParallelFlow
+		p.inParallelFlow = true
+	}
+
+	return n
+
+}
 
 func (p *NaiveParallelizer) VisitPost(n Node) Node {
 
@@ -96,6 +125,26 @@ func (p *NaiveParallelizer) VisitPost(n Node) Node {
 			return p.buildDistSummary(n)
 		}
 		return buildLocalSummary(n)
+	case *SortOp:
+		if p.inParallelFlow {
+			p.inParallelFlow = false
+			return p.buildDistSort(n)
+		}
+		return n
+	case *TopOp:
+		if p.inParallelFlow {
+			p.inParallelFlow = false
+			return p.buildDistTop(n)
+		}
+		return n
+	case *LimitOp:
+		if p.inParallelFlow {
+			p.inParallelFlow = false
+			return p.buildDistLimit(n)
+		}
+		return n
+	case *JoinOp:
+		return p.buildJoin(n, p.leftFlows[n], p.inParallelFlow)
 	case *OutputOp:
 		return p.buildOutput(n)
 	}
@@ -108,9 +157,16 @@ func (p *NaiveParallelizer) buildOutput(op *OutputOp) Node {
 
 	if !p.inParallelFlow {
 
+		// only a limit feeding the output can stop the query, a limit
+		// anywhere else ( ie. in a join subquery or a union branch ) would
+		// truncate the rows of the other inputs.
+		if limitOp, ok := op.Child.(*LimitOp); ok {
+			limitOp.Stop = true
+		}
+
 		fragment := &Fragment{
 			IsParallel: false,
-			Roots:      []Node{op},
+			Roots:      append([]Node{op}, p.localRoots...),
 		}
 
 		p.fragments.append(fragment)
@@ -140,7 +196,7 @@ func (p *NaiveParallelizer) buildOutput(op *OutputOp) Node {
 
 	outputFragment := &Fragment{
 		IsParallel: false,
-		Roots:      []Node{op},
+		Roots:      append([]Node{op}, p.localRoots...),
 	}
 
 	p.fragments.append(outputFragment)
@@ -149,17 +205,211 @@ func (p *NaiveParallelizer) buildOutput(op *OutputOp) Node {
 
 }
 
+// buildDistSummary splits the aggregation in three steps. Every node
+// aggregates its own segments into partial states which are repartitioned
+// by group key across the cluster. Then each node merges the partial
+// states of the groups it owns and finally the coordinator collects the
+// merged states and computes the final values.
 func (p *NaiveParallelizer) buildDistSummary(summaryOp *SummarizeOp) Node {
-	distSummary := &DistSummaryOp{Child: summaryOp.Child}
-	exchangeOut := &NodeOutOp{Child: distSummary}
-	fragment := &Fragment{
+
+	streams := p.buildStreamMatrix()
+
+	hashExchangeOut := &HashExchangeOutOp{
+		Keys:    columnNames(summaryOp.By),
+		Streams: streams,
+		Child: &LocalSummaryOp{
+			Agg:   summaryOp.Agg,
+			By:    summaryOp.By,
+			Child: summaryOp.Child,
+		},
+	}
+
+	p.fragments.append(&Fragment{
+		IsParallel: true,
+		Roots:      []Node{hashExchangeOut},
+	})
+
+	streamMap := p.buildStreamMap()
+
+	nodeOutput := &NodeOutOp{
+		Dst:       p.localNodeName,
+		StreamMap: streamMap,
+		Child: &DistSummaryOp{
+			Agg:   summaryOp.Agg,
+			By:    summaryOp.By,
+			Child: &HashExchangeInOp{Streams: streams},
+		},
+	}
+
+	p.fragments.append(&Fragment{
 		IsParallel: true,
-		Roots:      []Node{exchangeOut},
+		Roots:      []Node{nodeOutput},
+	})
+
+	return &SummaryCollector{
+		Agg:   summaryOp.Agg,
+		By:    summaryOp.By,
+		Child: &MergeSortOp{StreamMap: streamMap},
 	}
-	p.fragments.append(fragment)
-	exchangeIn := &MergeSortOp{} // TODO: refactor to summarycollector ?
-	summCollector := &SummaryCollector{Child: exchangeIn}
-	return summCollector
+
+}
+
+// buildDistSort sorts the rows on every node and merges the sorted
+// streams on the coordinator.
+func (p *NaiveParallelizer) buildDistSort(sortOp *SortOp) Node {
+
+	streamMap := p.buildStreamMap()
+
+	nodeOutput := &NodeOutOp{
+		Dst:       p.localNodeName,
+		StreamMap: streamMap,
+		Child:     sortOp,
+	}
+
+	p.fragments.append(&Fragment{
+		IsParallel: true,
+		Roots:      []Node{nodeOutput},
+	})
+
+	return &MergeSortOp{
+		StreamMap: streamMap,
+		SortExpr:  sortOp.SortExpr,
+	}
+
+}
+
+// buildDistTop pushes a local top down to every node so at most NumOfRows
+// rows per node are sent to the coordinator, which computes the global top
+// from the local ones.
+func (p *NaiveParallelizer) buildDistTop(topOp *TopOp) Node {
+
+	streamMap := p.buildStreamMap()
+
+	nodeOutput := &NodeOutOp{
+		Dst:       p.localNodeName,
+		StreamMap: streamMap,
+		Child:     topOp,
+	}
+
+	p.fragments.append(&Fragment{
+		IsParallel: true,
+		Roots:      []Node{nodeOutput},
+	})
+
+	return &TopOp{
+		NumOfRows: topOp.NumOfRows,
+		SortExpr:  topOp.SortExpr,
+		Child:     &MergeSortOp{StreamMap: streamMap},
+	}
+
+}
+
+// buildDistLimit pushes a local limit down to every node and applies the
+// global limit on the coordinator.
+func (p *NaiveParallelizer) buildDistLimit(limitOp *LimitOp) Node {
+
+	streamMap := p.buildStreamMap()
+
+	nodeOutput := &NodeOutOp{
+		Dst:       p.localNodeName,
+		StreamMap: streamMap,
+		Child:     limitOp,
+	}
+
+	p.fragments.append(&Fragment{
+		IsParallel: true,
+		Roots:      []Node{nodeOutput},
+	})
+
+	return &LimitOp{
+		NumOfRows: limitOp.NumOfRows,
+		Child:     &MergeSortOp{StreamMap: streamMap},
+	}
+
+}
+
+// buildJoin places a join according to where its inputs are computed. If
+// both are parallel flows they are partitioned by the keys across the
+// cluster so every node joins the keys it owns. A right input gathered in
+// the coordinator ( ie. summarized ) is expected to be small, it's
+// broadcast to every node and joined with the local left rows. If the left
+// input is gathered the join runs in the coordinator.
+func (p *NaiveParallelizer) buildJoin(join *JoinOp, leftParallel bool, rightParallel bool) Node {
+
+	switch {
+	case leftParallel && rightParallel:
+		p.inParallelFlow = true
+		return p.buildShuffleJoin(join)
+	case leftParallel:
+		p.inParallelFlow = true
+		return p.buildBroadcastJoin(join)
+	case rightParallel:
+		join.Right = p.gather(join.Right)
+	}
+
+	p.inParallelFlow = false
+
+	return join
+
+}
+
+// buildShuffleJoin partitions both inputs by the join keys. The rows with
+// the same keys are sent to the same node regardless of the side.
+func (p *NaiveParallelizer) buildShuffleJoin(join *JoinOp) Node {
+
+	left := p.buildStreamMatrix()
+	right := p.buildStreamMatrix()
+
+	p.fragments.append(&Fragment{
+		IsParallel: true,
+		Roots: []Node{
+			&HashExchangeOutOp{Keys: join.Keys, Streams: left, Child: join.Left},
+			&HashExchangeOutOp{Keys: join.Keys, Streams: right, Child: join.Right},
+		},
+	})
+
+	join.Left = &HashExchangeInOp{Streams: left}
+	join.Right = &HashExchangeInOp{Streams: right}
+
+	return join
+
+}
+
+// buildBroadcastJoin sends the right input computed in the coordinator to
+// every node.
+func (p *NaiveParallelizer) buildBroadcastJoin(join *JoinOp) Node {
+
+	streams := p.buildStreamMap()
+
+	p.localRoots = append(p.localRoots, &BroadcastOutOp{
+		Streams: streams,
+		Child:   join.Right,
+	})
+
+	join.Right = &HashExchangeInOp{
+		Streams: map[string]map[string]int64{p.localNodeName: streams},
+	}
+
+	return join
+
+}
+
+// gather sends the rows of a parallel flow to the coordinator.
+func (p *NaiveParallelizer) gather(child Node) Node {
+
+	streamMap := p.buildStreamMap()
+
+	p.fragments.append(&Fragment{
+		IsParallel: true,
+		Roots: []Node{&NodeOutOp{
+			Dst:       p.localNodeName,
+			StreamMap: streamMap,
+			Child:     child,
+		}},
+	})
+
+	return &MergeSortOp{StreamMap: streamMap}
+
 }
 
 func (p *NaiveParallelizer) newStreamId() int64 {
@@ -181,7 +431,43 @@ func (p *NaiveParallelizer) buildStreamMap() map[string]int64 {
 
 }
 
+// buildStreamMatrix assigns a stream to every pair of nodes. The first
+// key is the source node name and the second one the destination.
+func (p *NaiveParallelizer) buildStreamMatrix() map[string]map[string]int64 {
+
+	names := append([]string{p.localNodeName}, p.nodeNames...)
+
+	matrix := make(map[string]map[string]int64, len(names))
+
+	for _, src := range names {
+		matrix[src] = make(map[string]int64, len(names))
+		for _, dst := range names {
+			matrix[src][dst] = p.newStreamId()
+		}
+	}
+
+	return matrix
+
+}
+
+// buildLocalSummary aggregates a non parallel flow. Both the partial and the
+// final aggregation run on the same node.
 func buildLocalSummary(summaryOp *SummarizeOp) Node {
-	localSummary := &LocalSummaryOp{Child: summaryOp.Child}
-	return localSummary
+	return &SummaryCollector{
+		Agg: summaryOp.Agg,
+		By:  summaryOp.By,
+		Child: &LocalSummaryOp{
+			Agg:   summaryOp.Agg,
+			By:    summaryOp.By,
+			Child: summaryOp.Child,
+		},
+	}
+}
+
+func columnNames(columns []*ColumnExpr) []string {
+	names := make([]string, len(columns))
+	for i, column := range columns {
+		names[i] = column.ColName
+	}
+	return names
 }
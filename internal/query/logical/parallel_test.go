@@ -14,8 +14,11 @@
 package logical
 
 import (
+	"bytes"
+	"encoding/gob"
 	"fmt"
 	"github.com/davecgh/go-spew/spew"
+	"github.com/stretchr/testify/assert"
 	"meerkat/internal/query/parser"
 	"testing"
 )
@@ -46,3 +49,304 @@ func TestParallelize(t *testing.T) {
 	spew.Dump(fragments)
 
 }
+
+func TestParallelizeSummarize(t *testing.T) {
+
+	ast, err := parser.Parse("T | where a > 1 | summarize count() by host")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fragments := Parallelize(ToLogical(ast), "localNode", []string{"node1", "node2"})
+
+	all := fragments.AllFragments()
+
+	if !assert.Len(t, all, 3) {
+		return
+	}
+
+	// partial aggregation repartitioned by group key
+	hashOut := all[0].Roots[0].(*HashExchangeOutOp)
+	assert.True(t, all[0].IsParallel)
+	assert.Equal(t, []string{"host"}, hashOut.Keys)
+	assert.Len(t, hashOut.Streams, 3)
+	assert.IsType(t, &FilterOp{}, hashOut.Child.(*LocalSummaryOp).Child)
+
+	// merge of the partial states owned by each node
+	nodeOut := all[1].Roots[0].(*NodeOutOp)
+	assert.True(t, all[1].IsParallel)
+	assert.Equal(t, "localNode", nodeOut.Dst)
+	hashIn := nodeOut.Child.(*DistSummaryOp).Child.(*HashExchangeInOp)
+	assert.Equal(t, hashOut.Streams, hashIn.Streams)
+
+	// final aggregation on the coordinator
+	output := all[2].Roots[0].(*OutputOp)
+	assert.False(t, all[2].IsParallel)
+	assert.Equal(t, output, fragments.Output())
+	collector := output.Child.(*SummaryCollector)
+	assert.Equal(t, "count_", collector.Agg[0].ColName)
+	assert.Equal(t, nodeOut.StreamMap, collector.Child.(*MergeSortOp).StreamMap)
+
+	// the node fragments should be serializable
+	var buf bytes.Buffer
+	assert.NoError(t, gob.NewEncoder(&buf).Encode(fragments.NodeFragments()))
+
+}
+
+func TestParallelizeSort(t *testing.T) {
+
+	ast, err := parser.Parse("T | sort by a asc, b desc nulls first")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fragments := Parallelize(ToLogical(ast), "localNode", []string{"node1"})
+
+	all := fragments.AllFragments()
+
+	if !assert.Len(t, all, 2) {
+		return
+	}
+
+	// every node sorts its rows
+	nodeOut := all[0].Roots[0].(*NodeOutOp)
+	sortOp := nodeOut.Child.(*SortOp)
+	assert.IsType(t, &SourceOp{}, sortOp.Child)
+
+	// and the coordinator merges the sorted streams
+	merge := all[1].Roots[0].(*OutputOp).Child.(*MergeSortOp)
+	assert.Equal(t, nodeOut.StreamMap, merge.StreamMap)
+	assert.Equal(t, sortOp.SortExpr, merge.SortExpr)
+	assert.Equal(t, &SortExpr{Expr: &ColRefExpr{Name: "b"}, NullFirst: true}, merge.SortExpr[1])
+
+}
+
+func TestParallelizeTop(t *testing.T) {
+
+	ast, err := parser.Parse("T | where a > 1 | top 10 by latency")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fragments := Parallelize(ToLogical(ast), "localNode", []string{"node1"})
+
+	all := fragments.AllFragments()
+
+	if !assert.Len(t, all, 2) {
+		return
+	}
+
+	// the local top is pushed down to every node
+	nodeOut := all[0].Roots[0].(*NodeOutOp)
+	localTop := nodeOut.Child.(*TopOp)
+	assert.Equal(t, 10, localTop.NumOfRows)
+	assert.IsType(t, &FilterOp{}, localTop.Child)
+
+	// the coordinator computes the global top
+	globalTop := all[1].Roots[0].(*OutputOp).Child.(*TopOp)
+	assert.Equal(t, 10, globalTop.NumOfRows)
+	assert.Equal(t, localTop.SortExpr, globalTop.SortExpr)
+	assert.Equal(t, nodeOut.StreamMap, globalTop.Child.(*MergeSortOp).StreamMap)
+
+}
+
+func TestParallelizeLimit(t *testing.T) {
+
+	ast, err := parser.Parse("T | take 5 | sort by a")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fragments := Parallelize(ToLogical(ast), "localNode", []string{"node1"})
+
+	all := fragments.AllFragments()
+
+	if !assert.Len(t, all, 2) {
+		return
+	}
+
+	// a local limit is pushed below the node output
+	nodeOut := all[0].Roots[0].(*NodeOutOp)
+	localLimit := nodeOut.Child.(*LimitOp)
+	assert.Equal(t, 5, localLimit.NumOfRows)
+	assert.False(t, localLimit.Stop)
+
+	// the global limit and the sort run on the coordinator, the limit
+	// doesn't feed the output so it doesn't stop the query.
+	sortOp := all[1].Roots[0].(*OutputOp).Child.(*SortOp)
+	globalLimit := sortOp.Child.(*LimitOp)
+	assert.Equal(t, 5, globalLimit.NumOfRows)
+	assert.False(t, globalLimit.Stop)
+	assert.Equal(t, nodeOut.StreamMap, globalLimit.Child.(*MergeSortOp).StreamMap)
+
+}
+
+func TestParallelizeLimitStop(t *testing.T) {
+
+	parallelize := func(query string) []*Fragment {
+
+		ast, err := parser.Parse(query)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return Parallelize(ToLogical(ast), "localNode", []string{"node1"}).AllFragments()
+
+	}
+
+	// a limit feeding the output stops the query
+	all := parallelize("T | take 5")
+	outputLimit := all[len(all)-1].Roots[0].(*OutputOp).Child.(*LimitOp)
+	assert.True(t, outputLimit.Stop)
+
+	// a limit in a join subquery doesn't
+	all = parallelize("T | join (E | take 5) on trace")
+	checker := &limitChecker{t: t}
+
+	for _, fragment := range all {
+		for _, root := range fragment.Roots {
+			Walk(root, checker)
+		}
+	}
+
+	assert.NotZero(t, checker.found)
+
+}
+
+// limitChecker asserts that no limit stops the query.
+type limitChecker struct {
+	t     *testing.T
+	found int
+}
+
+func (v *limitChecker) VisitPre(n Node) Node {
+	if limitOp, ok := n.(*LimitOp); ok {
+		assert.False(v.t, limitOp.Stop)
+		v.found++
+	}
+	return n
+}
+
+func (v *limitChecker) VisitPost(n Node) Node { return n }
+
+func TestParallelizeShuffleJoin(t *testing.T) {
+
+	ast, err := parser.Parse("T | join kind=leftouter (E | where code > 500) on trace")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fragments := Parallelize(ToLogical(ast), "localNode", []string{"node1"})
+
+	all := fragments.AllFragments()
+
+	if !assert.Len(t, all, 3) {
+		return
+	}
+
+	// both sides are repartitioned by the keys
+	assert.True(t, all[0].IsParallel)
+	assert.Len(t, all[0].Roots, 2)
+	leftOut := all[0].Roots[0].(*HashExchangeOutOp)
+	rightOut := all[0].Roots[1].(*HashExchangeOutOp)
+	assert.Equal(t, []string{"trace"}, leftOut.Keys)
+	assert.Equal(t, []string{"trace"}, rightOut.Keys)
+	assert.Equal(t, "T", leftOut.Child.(*SourceOp).TableName)
+	assert.IsType(t, &FilterOp{}, rightOut.Child)
+
+	// and every node joins the keys it owns
+	join := all[1].Roots[0].(*NodeOutOp).Child.(*JoinOp)
+	assert.Equal(t, LeftOuterJoin, join.Kind)
+	assert.Equal(t, leftOut.Streams, join.Left.(*HashExchangeInOp).Streams)
+	assert.Equal(t, rightOut.Streams, join.Right.(*HashExchangeInOp).Streams)
+
+	var buf bytes.Buffer
+	assert.NoError(t, gob.NewEncoder(&buf).Encode(fragments.NodeFragments()))
+
+}
+
+func TestParallelizeBroadcastJoin(t *testing.T) {
+
+	ast, err := parser.Parse("T | join (E | summarize count() by trace) on trace")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fragments := Parallelize(ToLogical(ast), "localNode", []string{"node1"})
+
+	all := fragments.AllFragments()
+
+	if !assert.Len(t, all, 4) {
+		return
+	}
+
+	// the summarized right side is computed in the coordinator and sent
+	// to every node.
+	output := all[3]
+	assert.False(t, output.IsParallel)
+	assert.Len(t, output.Roots, 2)
+	broadcast := output.Roots[1].(*BroadcastOutOp)
+	assert.IsType(t, &SummaryCollector{}, broadcast.Child)
+	assert.Len(t, broadcast.Streams, 2)
+
+	// which join it with their rows.
+	nodeOut := all[2].Roots[0].(*NodeOutOp)
+	join := nodeOut.Child.(*JoinOp)
+	assert.Equal(t, InnerJoin, join.Kind)
+	assert.IsType(t, &SourceOp{}, join.Left)
+	assert.Equal(t, map[string]map[string]int64{"localNode": broadcast.Streams}, join.Right.(*HashExchangeInOp).Streams)
+	assert.Equal(t, nodeOut.StreamMap, output.Roots[0].(*OutputOp).Child.(*MergeSortOp).StreamMap)
+
+	// a join of a gathered left side runs in the coordinator.
+	ast, err = parser.Parse("T | top 5 by a | join (E) on trace")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	all = Parallelize(ToLogical(ast), "localNode", []string{"node1"}).AllFragments()
+
+	if !assert.Len(t, all, 3) {
+		return
+	}
+
+	join = all[2].Roots[0].(*OutputOp).Child.(*JoinOp)
+	assert.IsType(t, &TopOp{}, join.Left)
+	assert.Equal(t, all[1].Roots[0].(*NodeOutOp).StreamMap, join.Right.(*MergeSortOp).StreamMap)
+
+}
+
+func TestParallelizeUnion(t *testing.T) {
+
+	ast, err := parser.Parse("union T, E | summarize count() by $table")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	all := Parallelize(ToLogical(ast), "localNode", []string{"node1"}).AllFragments()
+
+	if !assert.Len(t, all, 3) {
+		return
+	}
+
+	// every node reads its segments of all the tables.
+	hashOut := all[0].Roots[0].(*HashExchangeOutOp)
+	union := hashOut.Child.(*LocalSummaryOp).Child.(*UnionOp)
+	assert.Len(t, union.Children, 2)
+
+	for i, table := range []string{"T", "E"} {
+		extend := union.Children[i].(*ExtendOp)
+		assert.Equal(t, parser.TableColumnName, extend.Columns[0].ColName)
+		assert.Equal(t, &LiteralExpr{Value: table}, extend.Columns[0].Expr)
+		assert.Equal(t, table, extend.Child.(*SourceOp).TableName)
+	}
+
+}
@@ -0,0 +1,454 @@
+// Copyright 2020 The Meerkat Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package logical
+
+import (
+	"meerkat/internal/storage"
+	"sort"
+)
+
+// pushFilters moves the filters down the tree as close as possible to the
+// SourceOp, merging them into its Filter. Consecutive filters are
+// collapsed into a single one.
+func pushFilters(n Node) Node {
+
+	switch op := n.(type) {
+	case *FilterOp:
+		return pushFilter(op.Predicate, pushFilters(op.Child))
+	case *ProjectOp:
+		op.Child = pushFilters(op.Child)
+	case *ExtendOp:
+		op.Child = pushFilters(op.Child)
+	case *SummarizeOp:
+		op.Child = pushFilters(op.Child)
+	case *SortOp:
+		op.Child = pushFilters(op.Child)
+	case *TopOp:
+		op.Child = pushFilters(op.Child)
+	case *LimitOp:
+		op.Child = pushFilters(op.Child)
+	case *JoinOp:
+		op.Left = pushFilters(op.Left)
+		op.Right = pushFilters(op.Right)
+	case *UnionOp:
+		for i, child := range op.Children {
+			op.Children[i] = pushFilters(child)
+		}
+	}
+
+	return n
+
+}
+
+// pushFilter filters the output of child using predicate. Every term of
+// a conjunction is pushed down on its own, the terms that cannot be pushed
+// are kept in a filter on top of child.
+func pushFilter(predicate Node, child Node) Node {
+
+	var kept []Node
+
+	for _, term := range conjunctionTerms(predicate) {
+
+		if value, ok := boolLiteral(term); ok && value {
+			continue
+		}
+
+		if pushed, ok := pushTerm(term, child); ok {
+			child = pushed
+			continue
+		}
+
+		kept = append(kept, term)
+
+	}
+
+	if len(kept) == 0 {
+		return child
+	}
+
+	return &FilterOp{Predicate: conjunction(kept...), Child: child}
+
+}
+
+func pushTerm(term Node, child Node) (Node, bool) {
+
+	switch op := child.(type) {
+
+	case *SourceOp:
+		op.Filter = conjunction(op.Filter, term)
+		return op, true
+
+	case *FilterOp:
+		// collapse the filters
+		op.Child = pushFilter(term, op.Child)
+		if f, ok := op.Child.(*FilterOp); ok {
+			op.Predicate = conjunction(op.Predicate, f.Predicate)
+			op.Child = f.Child
+		}
+		return op, true
+
+	case *SortOp:
+		op.Child = pushFilter(term, op.Child)
+		return op, true
+
+	case *ExtendOp:
+		for name := range columnRefs(term) {
+			for _, col := range op.Columns {
+				if col.ColName == name {
+					return child, false
+				}
+			}
+		}
+		op.Child = pushFilter(term, op.Child)
+		return op, true
+
+	case *ProjectOp:
+		if renamed, ok := renameColumns(term, op.Columns); ok {
+			op.Child = pushFilter(renamed, op.Child)
+			return op, true
+		}
+
+	case *SummarizeOp:
+		// a summarize without groups returns a row even if its input is
+		// empty, only the filters on the groups can be pushed.
+		if len(op.By) == 0 {
+			return child, false
+		}
+		if renamed, ok := renameColumns(term, op.By); ok {
+			op.Child = pushFilter(renamed, op.Child)
+			return op, true
+		}
+
+	case *JoinOp:
+		// leftanti and leftsemi joins return the left rows as they are,
+		// the other kinds only the keys are known to be left columns.
+		if op.Kind == LeftAntiJoin || op.Kind == LeftSemiJoin || onlyRefs(term, op.Keys) {
+			op.Left = pushFilter(term, op.Left)
+			return op, true
+		}
+
+	case *UnionOp:
+		// the rows of every branch are filtered on their own.
+		for i, child := range op.Children {
+			op.Children[i] = pushFilter(term, child)
+		}
+		return op, true
+
+	}
+
+	return child, false
+
+}
+
+// onlyRefs returns true if expr only references the given columns.
+func onlyRefs(expr Node, columns []string) bool {
+
+	allowed := make(map[string]bool, len(columns))
+
+	for _, name := range columns {
+		allowed[name] = true
+	}
+
+	for name := range columnRefs(expr) {
+		if !allowed[name] {
+			return false
+		}
+	}
+
+	return true
+
+}
+
+// renameColumns rewrites an expression over the output of columns as an
+// expression over its input. It is only possible if every column
+// referenced by expr is a plain reference to an input column.
+func renameColumns(expr Node, columns []*ColumnExpr) (Node, bool) {
+
+	names := make(map[string]string)
+
+	for _, col := range columns {
+		if ref, ok := col.Expr.(*ColRefExpr); ok {
+			names[col.ColName] = ref.Name
+		}
+	}
+
+	for name := range columnRefs(expr) {
+		if _, found := names[name]; !found {
+			return nil, false
+		}
+	}
+
+	return Walk(expr, &colRenamer{names: names}), true
+
+}
+
+type colRenamer struct {
+	names map[string]string
+}
+
+func (r *colRenamer) VisitPre(n Node) Node { return n }
+
+func (r *colRenamer) VisitPost(n Node) Node {
+	if ref, ok := n.(*ColRefExpr); ok {
+		return &ColRefExpr{Name: r.names[ref.Name]}
+	}
+	return n
+}
+
+// conjunctionTerms splits a predicate into the terms of a conjunction.
+func conjunctionTerms(predicate Node) []Node {
+	if e, ok := predicate.(*BinaryExpr); ok && e.Op == AND {
+		return append(conjunctionTerms(e.LeftExpr), conjunctionTerms(e.RightExpr)...)
+	}
+	return []Node{predicate}
+}
+
+// conjunction joins the terms using the and operator. nil terms are
+// ignored.
+func conjunction(terms ...Node) Node {
+
+	var result Node
+
+	for _, term := range terms {
+		switch {
+		case term == nil:
+		case result == nil:
+			result = term
+		default:
+			result = &BinaryExpr{LeftExpr: result, Op: AND, RightExpr: term}
+		}
+	}
+
+	return result
+
+}
+
+// columnRefs returns the names of the columns referenced by n.
+func columnRefs(n Node) map[string]bool {
+	c := &colRefCollector{refs: make(map[string]bool)}
+	Walk(n, c)
+	return c.refs
+}
+
+type colRefCollector struct {
+	refs map[string]bool
+}
+
+func (c *colRefCollector) VisitPre(n Node) Node { return n }
+
+func (c *colRefCollector) VisitPost(n Node) Node {
+	if ref, ok := n.(*ColRefExpr); ok {
+		c.refs[ref.Name] = true
+	}
+	return n
+}
+
+// hasStar returns true if * is one of the arguments of call.
+func hasStar(call *CallExpr) bool {
+	for _, arg := range call.ArgList {
+		if _, ok := arg.(*StarExpr); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// pruneColumns sets the columns read by every SourceOp to the columns
+// referenced by the operators above it. needed holds the columns required
+// from the output of n, nil means all of them.
+func pruneColumns(n Node, needed map[string]bool) {
+
+	addRefs := func(nodes ...Node) map[string]bool {
+		if needed == nil {
+			return nil
+		}
+		for _, node := range nodes {
+			for name := range columnRefs(node) {
+				needed[name] = true
+			}
+		}
+		return needed
+	}
+
+	switch op := n.(type) {
+
+	case *SourceOp:
+
+		if needed == nil {
+			op.Columns = nil
+			return
+		}
+
+		if op.Filter != nil {
+			addRefs(op.Filter)
+		}
+
+		// at least a column must be read to know the number of rows.
+		if len(needed) == 0 {
+			needed[storage.TSColumnName] = true
+		}
+
+		op.Columns = make([]string, 0, len(needed))
+
+		for name := range needed {
+			op.Columns = append(op.Columns, name)
+		}
+
+		sort.Strings(op.Columns)
+
+	case *FilterOp:
+		pruneColumns(op.Child, addRefs(op.Predicate))
+
+	case *ProjectOp:
+		// the columns not needed above are dropped.
+		var columns []*ColumnExpr
+		projected := make(map[string]bool)
+		for _, col := range op.Columns {
+			if needed == nil || needed[col.ColName] {
+				columns = append(columns, col)
+				for name := range columnRefs(col.Expr) {
+					projected[name] = true
+				}
+			}
+		}
+		op.Columns = columns
+		pruneColumns(op.Child, projected)
+
+	case *ExtendOp:
+		// the columns can reference the columns extended before them.
+		for i := len(op.Columns) - 1; i >= 0 && needed != nil; i-- {
+			delete(needed, op.Columns[i].ColName)
+			addRefs(op.Columns[i].Expr)
+		}
+		pruneColumns(op.Child, needed)
+
+	case *SummarizeOp:
+		needed = make(map[string]bool)
+		for _, agg := range op.Agg {
+			// arg_max(_ts, *) needs all the columns.
+			if hasStar(agg.Expr) {
+				pruneColumns(op.Child, nil)
+				return
+			}
+			addRefs(agg.Expr)
+		}
+		for _, by := range op.By {
+			addRefs(by.Expr)
+		}
+		// the unfiltered rows of the sources are counted without reading
+		// them, ie. count.
+		if sources, ok := rowSources(op.Child); ok && len(needed) == 0 {
+			for _, source := range sources {
+				source.CountOnly = true
+				source.Columns = nil
+			}
+			return
+		}
+		pruneColumns(op.Child, needed)
+
+	case *SortOp:
+		for _, expr := range op.SortExpr {
+			addRefs(expr.Expr)
+		}
+		pruneColumns(op.Child, needed)
+
+	case *TopOp:
+		for _, expr := range op.SortExpr {
+			addRefs(expr.Expr)
+		}
+		pruneColumns(op.Child, needed)
+
+	case *LimitOp:
+		pruneColumns(op.Child, needed)
+
+	case *JoinOp:
+		// the side providing every column is not known so both of them
+		// are asked for the needed columns, the columns missing in a table
+		// are skipped by the scans.
+		right := needed
+		if op.Kind == LeftAntiJoin || op.Kind == LeftSemiJoin {
+			right = make(map[string]bool)
+		}
+		pruneColumns(op.Left, withColumns(needed, op.Keys))
+		pruneColumns(op.Right, withColumns(right, op.Keys))
+
+	case *UnionOp:
+		// the columns not needed are not added to the branches missing
+		// them.
+		if needed != nil {
+			for name := range op.Columns {
+				if !needed[name] {
+					delete(op.Columns, name)
+				}
+			}
+		}
+		// every branch adds its own columns to needed.
+		for _, child := range op.Children {
+			pruneColumns(child, withColumns(needed, nil))
+		}
+
+	}
+
+}
+
+// rowSources returns the sources of n if n returns all their rows, ie. a
+// union of tables.
+func rowSources(n Node) ([]*SourceOp, bool) {
+
+	switch op := n.(type) {
+
+	case *SourceOp:
+		return []*SourceOp{op}, op.Filter == nil
+
+	case *ExtendOp:
+		return rowSources(op.Child)
+
+	case *UnionOp:
+		var sources []*SourceOp
+		for _, child := range op.Children {
+			s, ok := rowSources(child)
+			if !ok {
+				return nil, false
+			}
+			sources = append(sources, s...)
+		}
+		return sources, true
+
+	}
+
+	return nil, false
+
+}
+
+// withColumns returns a copy of needed including columns, nil if needed is
+// nil.
+func withColumns(needed map[string]bool, columns []string) map[string]bool {
+
+	if needed == nil {
+		return nil
+	}
+
+	result := make(map[string]bool, len(needed)+len(columns))
+
+	for name := range needed {
+		result[name] = true
+	}
+
+	for _, name := range columns {
+		result[name] = true
+	}
+
+	return result
+
+}
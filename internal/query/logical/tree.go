@@ -13,7 +13,11 @@
 
 package logical
 
-import "meerkat/internal/query/parser"
+import (
+	"fmt"
+	"meerkat/internal/query/parser"
+	"meerkat/internal/storage"
+)
 
 type Operator byte
 
@@ -120,6 +124,64 @@ var tokenToOp = map[parser.TokenType]Operator{
 	parser.RANGE:             RANGE,
 }
 
+var opNames = [...]string{
+	ADD:               "+",
+	SUB:               "-",
+	MUL:               "*",
+	QUO:               "/",
+	REM:               "%",
+	EQL:               "==",
+	EQL_CI:            "=~",
+	NEQ:               "!=",
+	NEQ_CI:            "!~",
+	LSS:               "<",
+	GTR:               ">",
+	LEQ:               "<=",
+	GEQ:               ">=",
+	AND:               "and",
+	OR:                "or",
+	IN:                "in",
+	NOT_IN:            "!in",
+	IN_CI:             "in~",
+	NOT_IN_CI:         "!in~",
+	HAS:               "has",
+	NOT_HAS:           "!has",
+	HAS_CS:            "has_cs",
+	NOT_HAS_CS:        "!has_cs",
+	HASPREFIX:         "hasprefix",
+	NOT_HASPREFIX:     "!hasprefix",
+	HASPREFIX_CS:      "hasprefix_cs",
+	NOT_HASPREFIX_CS:  "!hasprefix_cs",
+	HASSUFFIX:         "hassuffix",
+	NOT_HASSUFFIX:     "!hassuffix",
+	HASSUFFIX_CS:      "hassuffix_cs",
+	NOT_HASSUFFIX_CS:  "!hassuffix_cs",
+	CONTAINS:          "contains",
+	NOT_CONTAINS:      "!contains",
+	CONTAINS_CS:       "contains_cs",
+	NOT_CONTAINS_CS:   "!contains_cs",
+	STARTSWITH:        "startswith",
+	NOT_STARTSWITH:    "!startswith",
+	STARTSWITH_CS:     "startswith_cs",
+	NOT_STARTSWITH_CS: "!startswith_cs",
+	ENDSWITH:          "endswith",
+	NOT_ENDSWITH:      "!endswith",
+	ENDSWITH_CS:       "endswith_cs",
+	NOT_ENDSWITH_CS:   "!endswith_cs",
+	MATCHES:           "matches",
+	HAS_ANY:           "has_any",
+	BETWEEN:           "between",
+	NOT_BETWEEN:       "!between",
+	RANGE:             "..",
+}
+
+func (o Operator) String() string {
+	if int(o) < len(opNames) {
+		return opNames[o]
+	}
+	return fmt.Sprintf("Operator(%d)", o)
+}
+
 type Visitor interface {
 	VisitPre(n Node) Node
 	VisitPost(n Node) Node
@@ -179,12 +241,20 @@ type ColRefExpr struct {
 
 func (n *ColRefExpr) Accept(Visitor) {}
 
+// LiteralExpr is a constant value. Timespan literals are represented as
+// time.Duration and datetime literals as time.Time.
 type LiteralExpr struct {
 	Value interface{}
 }
 
 func (n *LiteralExpr) Accept(Visitor) {}
 
+// StarExpr stands for all the columns of the input rows, it's only used
+// as an argument of arg_min() and arg_max().
+type StarExpr struct{}
+
+func (n *StarExpr) Accept(Visitor) {}
+
 type AggExpr struct {
 	ColName string
 	Expr    *CallExpr
@@ -199,17 +269,43 @@ type ColumnExpr struct {
 
 func (n *ColumnExpr) Accept(v Visitor) { n.Expr = Walk(n.Expr, v) }
 
+type SortExpr struct {
+	Expr      Node
+	Asc       bool
+	NullFirst bool
+}
+
+func (n *SortExpr) Accept(v Visitor) { n.Expr = Walk(n.Expr, v) }
+
 // Operators
 
 type SourceOp struct {
+	// Database is the database of the table, "" for the default database.
+	Database  string
 	TableName string
+	// Partitions are the partitions read, nil means all the partitions.
+	// The queries can't name partitions yet so all of them are read.
+	Partitions []uint64
+	// Columns are the columns read from the segments, nil means all the
+	// columns.
+	Columns []string
+	// Filter is a predicate pushed down by the optimizer, only the rows
+	// matching it are returned. nil if there is no filter.
+	Filter Node
+	// Interval bounds the timestamps of the segments that can contain rows
+	// matching Filter, nil if the timestamps are not bounded.
+	Interval *storage.Interval
+	// CountOnly is true if only the number of rows is needed. No column
+	// is read, the rows are counted from the segment infos.
+	CountOnly bool
 	// partitionMap ( partition by node )
-	// interval
-	// columns
-	// filter exp
 }
 
-func (n *SourceOp) Accept(Visitor) {}
+func (n *SourceOp) Accept(v Visitor) {
+	if n.Filter != nil {
+		n.Filter = Walk(n.Filter, v)
+	}
+}
 
 type FilterOp struct {
 	Predicate Node
@@ -221,8 +317,38 @@ func (n *FilterOp) Accept(v Visitor) {
 	n.Child = Walk(n.Child, v)
 }
 
+// ProjectOp replaces the input columns with the Columns expressions.
+type ProjectOp struct {
+	Columns []*ColumnExpr
+	Child   Node
+}
+
+func (n *ProjectOp) Accept(v Visitor) {
+	for i, expr := range n.Columns {
+		n.Columns[i] = Walk(expr, v).(*ColumnExpr)
+	}
+	n.Child = Walk(n.Child, v)
+}
+
+// ExtendOp adds the Columns expressions to the input columns.
+type ExtendOp struct {
+	Columns []*ColumnExpr
+	Child   Node
+}
+
+func (n *ExtendOp) Accept(v Visitor) {
+	for i, expr := range n.Columns {
+		n.Columns[i] = Walk(expr, v).(*ColumnExpr)
+	}
+	n.Child = Walk(n.Child, v)
+}
+
+// LimitOp returns the first NumOfRows rows of its input. If Stop is set
+// the query is stopped once the limit is reached so the nodes don't keep
+// scanning segments that are not needed anymore.
 type LimitOp struct {
 	NumOfRows int
+	Stop      bool
 	Child     Node
 }
 
@@ -244,6 +370,95 @@ func (n *SummarizeOp) Accept(v Visitor) {
 	n.Child = Walk(n.Child, v)
 }
 
+type SortOp struct {
+	SortExpr []*SortExpr
+	Child    Node
+}
+
+func (n *SortOp) Accept(v Visitor) {
+	for i, expr := range n.SortExpr {
+		n.SortExpr[i] = Walk(expr, v).(*SortExpr)
+	}
+	n.Child = Walk(n.Child, v)
+}
+
+// TopOp returns the first NumOfRows rows according to SortExpr.
+type TopOp struct {
+	NumOfRows int
+	SortExpr  []*SortExpr
+	Child     Node
+}
+
+func (n *TopOp) Accept(v Visitor) {
+	for i, expr := range n.SortExpr {
+		n.SortExpr[i] = Walk(expr, v).(*SortExpr)
+	}
+	n.Child = Walk(n.Child, v)
+}
+
+// JoinKind is the kind of a join.
+type JoinKind byte
+
+const (
+	// InnerJoin returns a row for every pair of matching left and right
+	// rows.
+	InnerJoin JoinKind = iota
+	// LeftOuterJoin is an InnerJoin that also returns the left rows
+	// without matches, the right columns are null.
+	LeftOuterJoin
+	// LeftAntiJoin returns the left rows without matches.
+	LeftAntiJoin
+	// LeftSemiJoin returns the left rows with at least one match.
+	LeftSemiJoin
+)
+
+var joinKindNames = [...]string{
+	InnerJoin:     "inner",
+	LeftOuterJoin: "leftouter",
+	LeftAntiJoin:  "leftanti",
+	LeftSemiJoin:  "leftsemi",
+}
+
+func (k JoinKind) String() string {
+	if int(k) < len(joinKindNames) {
+		return joinKindNames[k]
+	}
+	return fmt.Sprintf("JoinKind(%d)", k)
+}
+
+// JoinOp joins the Left rows with the Right rows having the same values in
+// the Keys columns. The Right rows are held in memory while the Left rows
+// are streamed so Right should be the smaller input. The output has the
+// Left columns followed by the Right ones except the keys, leftanti and
+// leftsemi joins only return the Left columns.
+type JoinOp struct {
+	Kind  JoinKind
+	Keys  []string
+	Left  Node
+	Right Node
+}
+
+func (n *JoinOp) Accept(v Visitor) {
+	n.Left = Walk(n.Left, v)
+	n.Right = Walk(n.Right, v)
+}
+
+// UnionOp returns the rows of all its children, a branch for every table
+// read by a union. The children are read in parallel so the rows are
+// returned in no particular order.
+type UnionOp struct {
+	// Columns are the columns of the union, the columns missing in a
+	// branch are returned as nulls.
+	Columns  map[string]parser.Type
+	Children []Node
+}
+
+func (n *UnionOp) Accept(v Visitor) {
+	for i, child := range n.Children {
+		n.Children[i] = Walk(child, v)
+	}
+}
+
 type NodeOutOp struct {
 	Dst       string
 	StreamMap map[string]int64
@@ -252,37 +467,69 @@ type NodeOutOp struct {
 
 func (n *NodeOutOp) Accept(v Visitor) { n.Child = Walk(n.Child, v) }
 
+// MergeSortOp merges the streams received from every node. If SortExpr is
+// not empty the streams are expected to be sorted and the merge keeps
+// the global order, otherwise the batches are returned as they arrive.
 type MergeSortOp struct {
 	StreamMap map[string]int64
+	SortExpr  []*SortExpr
 }
 
 func (n *MergeSortOp) Accept(Visitor) {}
 
+// HashExchangeOutOp partitions its input by the hash of the Keys columns
+// and sends each partition to a different node. Streams maps a source node
+// to the stream used to reach every destination node.
 type HashExchangeOutOp struct {
-	Child Node
+	Keys    []string
+	Streams map[string]map[string]int64
+	Child   Node
 }
 
 func (n *HashExchangeOutOp) Accept(v Visitor) { n.Child = Walk(n.Child, v) }
 
+// HashExchangeInOp receives the partitions sent to the local node by every
+// HashExchangeOutOp.
 type HashExchangeInOp struct {
-	Child Node
+	Streams map[string]map[string]int64
 }
 
-func (n *HashExchangeInOp) Accept(v Visitor) { n.Child = Walk(n.Child, v) }
+func (n *HashExchangeInOp) Accept(Visitor) {}
+
+// BroadcastOutOp sends its whole input to every node. Streams maps every
+// destination node to the stream used to reach it, the rows are received
+// by a HashExchangeInOp.
+type BroadcastOutOp struct {
+	Streams map[string]int64
+	Child   Node
+}
+
+func (n *BroadcastOutOp) Accept(v Visitor) { n.Child = Walk(n.Child, v) }
 
 type OutputOp struct {
-	Child Node
+	// Columns are the columns of the query result, nil if they are not
+	// known.
+	Columns map[string]parser.Type
+	Child   Node
 }
 
 func (n *OutputOp) Accept(v Visitor) { n.Child = Walk(n.Child, v) }
 
+// DistSummaryOp merges the partial aggregation states of the groups owned
+// by a node.
 type DistSummaryOp struct {
+	Agg   []*AggExpr
+	By    []*ColumnExpr
 	Child Node
 }
 
 func (n *DistSummaryOp) Accept(v Visitor) { n.Child = Walk(n.Child, v) }
 
+// SummaryCollector merges the partial aggregation states and computes
+// the final aggregated values.
 type SummaryCollector struct {
+	Agg   []*AggExpr
+	By    []*ColumnExpr
 	Child Node
 }
 
@@ -294,11 +541,22 @@ type ShuffleSummaryOp struct {
 
 func (n *ShuffleSummaryOp) Accept(v Visitor) { n.Child = Walk(n.Child, v) }
 
+// LocalSummaryOp aggregates the input rows into partial aggregation states.
 type LocalSummaryOp struct {
+	Agg   []*AggExpr
+	By    []*ColumnExpr
 	Child Node
 }
 
-func (n *LocalSummaryOp) Accept(v Visitor) { n.Child = Walk(n.Child, v) }
+func (n *LocalSummaryOp) Accept(v Visitor) {
+	for i, expr := range n.Agg {
+		n.Agg[i] = Walk(expr, v).(*AggExpr)
+	}
+	for i, expr := range n.By {
+		n.By[i] = Walk(expr, v).(*ColumnExpr)
+	}
+	n.Child = Walk(n.Child, v)
+}
 
 type MergeOp struct {
 	Child Node
@@ -33,6 +33,8 @@ type Node interface {
 
 type TabularStmt struct {
 	TabularExpr *TabularExpr
+	// Columns are the columns of the query result, set by the analyzer.
+	Columns map[string]Type
 }
 
 func (s *TabularStmt) Accept(v Visitor) {
@@ -55,6 +57,19 @@ type CountOp struct {
 func (op *CountOp) Accept(Visitor) {
 }
 
+// DistinctOp returns the distinct combinations of the values of Columns.
+// distinct * is parsed as a single * column, the analyzer replaces it with
+// all the input columns.
+type DistinctOp struct {
+	Columns []*LitExpr // IDENT
+}
+
+func (op *DistinctOp) Accept(v Visitor) {
+	for i, col := range op.Columns {
+		op.Columns[i] = Walk(col, v).(*LitExpr)
+	}
+}
+
 type ExtendOp struct {
 	Columns []*ColumnExpr
 }
@@ -110,6 +125,30 @@ func (op *SummarizeOp) Accept(v Visitor) {
 
 }
 
+// JoinOp joins the input rows with the rows returned by the Right
+// subquery that have the same values in the On columns. Kind is nil for
+// the default inner join.
+type JoinOp struct {
+	Kind  *LitExpr // IDENT
+	Right *TabularExpr
+	On    []*LitExpr // IDENT
+}
+
+func (op *JoinOp) Accept(v Visitor) {
+	op.Right = Walk(op.Right, v).(*TabularExpr)
+	for i, col := range op.On {
+		op.On[i] = Walk(col, v).(*LitExpr)
+	}
+}
+
+// JoinKind returns the kind of the join, inner if it's not specified.
+func JoinKind(op *JoinOp) string {
+	if op.Kind == nil {
+		return "inner"
+	}
+	return op.Kind.Value.(string)
+}
+
 type TopOp struct {
 	NumberOfRows *LitExpr
 	By           *SortExpr
@@ -121,18 +160,38 @@ func (op *TopOp) Accept(v Visitor) {
 
 // Expressions
 
-// TabularExpr =  StringLiteral , { "|" TabularOperator }
+// TabularExpr =  ( StringLiteral | UnionExpr ) , { "|" TabularOperator }
 type TabularExpr struct {
-	Source    *LitExpr
-	TabularOp []Node // tabular operators
+	Source    *LitExpr   // nil if the rows are read by Union
+	Union     *UnionExpr // nil if the rows are read from Source
+	TabularOp []Node     // tabular operators
 }
 
 func (e *TabularExpr) Accept(v Visitor) {
+	if e.Union != nil {
+		e.Union = Walk(e.Union, v).(*UnionExpr)
+	}
 	for i, op := range e.TabularOp {
 		e.TabularOp[i] = Walk(op, v)
 	}
 }
 
+// UnionExpr reads the rows of several tables. A table name ending with *
+// matches all the tables starting with the given prefix, the analyzer
+// replaces the patterns with the names of the matching tables.
+type UnionExpr struct {
+	Token  Token      // union keyword
+	Tables []*LitExpr // IDENT
+	// Columns are the columns of all the tables, set by the analyzer.
+	Columns map[string]Type
+}
+
+func (e *UnionExpr) Accept(v Visitor) {
+	for i, table := range e.Tables {
+		e.Tables[i] = Walk(table, v).(*LitExpr)
+	}
+}
+
 type BinaryExpr struct {
 	LeftExpr  Node
 	Op        Token
@@ -173,6 +232,15 @@ func (e *CallExpr) Accept(v Visitor) {
 	}
 }
 
+// StarExpr is the * argument of arg_min() and arg_max(), it stands for
+// all the columns of the input rows.
+type StarExpr struct {
+	Token Token
+}
+
+func (e *StarExpr) Accept(Visitor) {
+}
+
 type ColumnExpr struct {
 	ColName *LitExpr // IDENT
 	Expr    Node
@@ -0,0 +1,148 @@
+// Copyright 2020 The Meerkat Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+// signature describes the arguments and the result of a function.
+type signature struct {
+	minArgs int
+	maxArgs int
+	// args holds the types accepted by every argument, the last entry is
+	// used for the remaining arguments of variadic functions. A nil entry
+	// accepts any type.
+	args   [][]Type
+	result func(args []Type) Type
+}
+
+var (
+	anyArg      []Type
+	stringArg   = []Type{StringType}
+	boolArg     = []Type{BoolType}
+	intArg      = []Type{IntType}
+	dateTimeArg = []Type{DateTimeType}
+	numericArg  = numericTypes
+)
+
+func returns(t Type) func([]Type) Type {
+	return func([]Type) Type { return t }
+}
+
+// sameAsArg returns the type of the first argument.
+func sameAsArg(args []Type) Type {
+	return args[0]
+}
+
+// scalarFuncs are the signatures of the functions that can be used in
+// expressions. They must be kept in sync with the physical functions.
+var scalarFuncs = map[string]signature{
+	"strlen":    {minArgs: 1, maxArgs: 1, args: [][]Type{stringArg}, result: returns(IntType)},
+	"substring": {minArgs: 2, maxArgs: 3, args: [][]Type{stringArg, intArg, intArg}, result: returns(StringType)},
+	"tolower":   {minArgs: 1, maxArgs: 1, args: [][]Type{stringArg}, result: returns(StringType)},
+	"toupper":   {minArgs: 1, maxArgs: 1, args: [][]Type{stringArg}, result: returns(StringType)},
+	"trim":      {minArgs: 2, maxArgs: 2, args: [][]Type{stringArg, stringArg}, result: returns(StringType)},
+	"strcat":    {minArgs: 1, maxArgs: 64, args: [][]Type{anyArg}, result: returns(StringType)},
+	"split":     {minArgs: 2, maxArgs: 3, args: [][]Type{stringArg, stringArg, intArg}, result: splitResult},
+	"replace":   {minArgs: 3, maxArgs: 3, args: [][]Type{stringArg, stringArg, stringArg}, result: returns(StringType)},
+	"extract":   {minArgs: 3, maxArgs: 3, args: [][]Type{stringArg, intArg, stringArg}, result: returns(StringType)},
+	"indexof":   {minArgs: 2, maxArgs: 5, args: [][]Type{stringArg, stringArg, intArg}, result: returns(IntType)},
+
+	"now":             {minArgs: 0, maxArgs: 1, args: [][]Type{intArg}, result: returns(DateTimeType)},
+	"ago":             {minArgs: 1, maxArgs: 1, args: [][]Type{intArg}, result: returns(DateTimeType)},
+	"startofday":      {minArgs: 1, maxArgs: 2, args: [][]Type{dateTimeArg, intArg}, result: returns(DateTimeType)},
+	"startofweek":     {minArgs: 1, maxArgs: 2, args: [][]Type{dateTimeArg, intArg}, result: returns(DateTimeType)},
+	"startofmonth":    {minArgs: 1, maxArgs: 2, args: [][]Type{dateTimeArg, intArg}, result: returns(DateTimeType)},
+	"startofyear":     {minArgs: 1, maxArgs: 2, args: [][]Type{dateTimeArg, intArg}, result: returns(DateTimeType)},
+	"bin":             {minArgs: 2, maxArgs: 2, args: [][]Type{numericArg, {IntType, FloatType}}, result: binResult},
+	"floor":           {minArgs: 2, maxArgs: 2, args: [][]Type{numericArg, {IntType, FloatType}}, result: binResult},
+	"datetime_part":   {minArgs: 2, maxArgs: 2, args: [][]Type{stringArg, dateTimeArg}, result: returns(IntType)},
+	"format_datetime": {minArgs: 2, maxArgs: 2, args: [][]Type{dateTimeArg, stringArg}, result: returns(StringType)},
+	"datetime_add":    {minArgs: 3, maxArgs: 3, args: [][]Type{stringArg, intArg, dateTimeArg}, result: returns(DateTimeType)},
+	"datetime_diff":   {minArgs: 3, maxArgs: 3, args: [][]Type{stringArg, dateTimeArg, dateTimeArg}, result: returns(IntType)},
+
+	"dcount_hll": {minArgs: 1, maxArgs: 1, args: [][]Type{stringArg}, result: returns(IntType)},
+}
+
+// aggFuncs are the signatures of the aggregation functions.
+var aggFuncs = map[string]signature{
+	"count": {minArgs: 0, maxArgs: 1, args: [][]Type{anyArg}, result: returns(IntType)},
+	"sum":   {minArgs: 1, maxArgs: 1, args: [][]Type{numericArg}, result: sameAsArg},
+	"min":   {minArgs: 1, maxArgs: 1, args: [][]Type{{IntType, FloatType, DateTimeType, StringType}}, result: sameAsArg},
+	"max":   {minArgs: 1, maxArgs: 1, args: [][]Type{{IntType, FloatType, DateTimeType, StringType}}, result: sameAsArg},
+	"avg":   {minArgs: 1, maxArgs: 1, args: [][]Type{numericArg}, result: returns(FloatType)},
+
+	"countif":   {minArgs: 1, maxArgs: 1, args: [][]Type{boolArg}, result: returns(IntType)},
+	"sumif":     {minArgs: 2, maxArgs: 2, args: [][]Type{numericArg, boolArg}, result: sameAsArg},
+	"stdev":     {minArgs: 1, maxArgs: 1, args: [][]Type{numericArg}, result: returns(FloatType)},
+	"variance":  {minArgs: 1, maxArgs: 1, args: [][]Type{numericArg}, result: returns(FloatType)},
+	"arg_min":   {minArgs: 1, maxArgs: 1, args: [][]Type{{IntType, FloatType, DateTimeType, StringType}}, result: sameAsArg},
+	"arg_max":   {minArgs: 1, maxArgs: 1, args: [][]Type{{IntType, FloatType, DateTimeType, StringType}}, result: sameAsArg},
+	"any":       {minArgs: 1, maxArgs: 1, args: [][]Type{anyArg}, result: sameAsArg},
+	"make_list": {minArgs: 1, maxArgs: 2, args: [][]Type{anyArg, intArg}, result: returns(DynamicType)},
+	"make_set":  {minArgs: 1, maxArgs: 2, args: [][]Type{anyArg, intArg}, result: returns(DynamicType)},
+
+	"dcount":      {minArgs: 1, maxArgs: 1, args: [][]Type{anyArg}, result: returns(IntType)},
+	"hll":         {minArgs: 1, maxArgs: 1, args: [][]Type{anyArg}, result: returns(StringType)},
+	"hll_merge":   {minArgs: 1, maxArgs: 1, args: [][]Type{stringArg}, result: returns(StringType)},
+	"percentile":  {minArgs: 2, maxArgs: 2, args: [][]Type{numericArg, {IntType, FloatType}}, result: sameAsArg},
+	"percentiles": {minArgs: 2, maxArgs: 64, args: [][]Type{numericArg, {IntType, FloatType}}, result: sameAsArg},
+}
+
+// rowAggFuncs are the aggregation functions that return the columns of
+// the selected row besides the aggregated value. Their signature only
+// covers the aggregated value, the arguments that follow are column
+// references or * for all the input columns.
+var rowAggFuncs = map[string]bool{
+	"arg_min": true,
+	"arg_max": true,
+}
+
+// percentileFuncs are the aggregation functions whose arguments after the
+// first one are constant percentiles. percentiles() returns a column for
+// every percentile named by PercentileColumn.
+var percentileFuncs = map[string]bool{
+	"percentile":  true,
+	"percentiles": true,
+}
+
+// sizedAggFuncs are the aggregation functions whose second argument is a
+// constant bounding the size of the result.
+var sizedAggFuncs = map[string]bool{
+	"make_list": true,
+	"make_set":  true,
+}
+
+// split() returns a single part if the index is given, otherwise an
+// array with all the parts.
+func splitResult(args []Type) Type {
+	if len(args) == 3 {
+		return StringType
+	}
+	return DynamicType
+}
+
+// bin() keeps the type of the value unless the values or the bin size
+// are floats.
+func binResult(args []Type) Type {
+	if args[0] == FloatType || args[1] == FloatType {
+		return FloatType
+	}
+	return args[0]
+}
+
+// argTypes returns the types accepted by the i-th argument.
+func (s signature) argTypes(i int) []Type {
+	if i < len(s.args) {
+		return s.args[i]
+	}
+	return s.args[len(s.args)-1]
+}
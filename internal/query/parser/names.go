@@ -0,0 +1,183 @@
+// Copyright 2020 The Meerkat Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// NameSummarizeColumns assigns a name to the unnamed columns of a
+// summarize operator. Group columns referencing a column ( directly or
+// through bin() ) take the name of that column, aggregations are named
+// after the function and its argument ( ie. count_, sum_price ).
+func NameSummarizeColumns(op *SummarizeOp) {
+
+	used := make(map[string]bool)
+
+	for _, by := range op.By {
+		if by.ColName != nil {
+			used[by.ColName.Value.(string)] = true
+		}
+	}
+
+	for _, agg := range op.Agg {
+		if agg.ColName != nil {
+			used[agg.ColName.Value.(string)] = true
+		}
+	}
+
+	for i, by := range op.By {
+		if by.ColName == nil {
+			name := fmt.Sprintf("Column%d", i+1)
+			if colName, ok := refName(by.Expr); ok {
+				name = colName
+			}
+			by.ColName = nameLit(uniqueName(used, name), by.Expr)
+		}
+	}
+
+	for _, agg := range op.Agg {
+		if agg.ColName == nil {
+			funcName := agg.Expr.FuncName.Value.(string)
+			name := funcName + "_"
+			if percentileFuncs[funcName] {
+				name = "percentile_"
+			}
+			if len(agg.Expr.ArgList) > 0 {
+				if colName, ok := refName(agg.Expr.ArgList[0]); ok {
+					name += colName
+					// arg_max(_ts, *) returns the rows as they are.
+					if rowAggFuncs[funcName] {
+						name = colName
+					}
+				}
+			}
+			// percentile(latency, 95) is named percentile_latency_95.
+			if funcName == "percentile" && len(agg.Expr.ArgList) == 2 {
+				if p, ok := percentileValue(agg.Expr.ArgList[1]); ok {
+					name = PercentileColumn(name, p)
+				}
+			}
+			agg.ColName = nameLit(uniqueName(used, name), agg.Expr)
+		}
+	}
+
+}
+
+// NameColumns assigns a name to the unnamed columns of a project or extend
+// operator. Columns referencing a column take the name of that column,
+// the rest are named ColumnN.
+func NameColumns(columns []*ColumnExpr) {
+
+	used := make(map[string]bool)
+
+	for _, col := range columns {
+		if col.ColName != nil {
+			used[col.ColName.Value.(string)] = true
+		}
+	}
+
+	for i, col := range columns {
+		if col.ColName == nil {
+			if name, ok := colRef(col.Expr); ok && !used[name] {
+				col.ColName = nameLit(name, col.Expr)
+				used[name] = true
+				continue
+			}
+			col.ColName = nameLit(uniqueName(used, fmt.Sprintf("Column%d", i+1)), col.Expr)
+		}
+	}
+
+}
+
+// PercentileColumn returns the name of the column holding the percentile
+// p of the aggregation name, ie. percentile_latency_99_9.
+func PercentileColumn(name string, p float64) string {
+	return name + "_" + strings.Replace(strconv.FormatFloat(p, 'f', -1, 64), ".", "_", 1)
+}
+
+// percentileValue returns the value of a constant percentile.
+func percentileValue(expr Node) (float64, bool) {
+
+	lit, ok := expr.(*LitExpr)
+
+	if !ok {
+		return 0, false
+	}
+
+	switch v := lit.Value.(type) {
+	case int:
+		if lit.Token.Type == INT {
+			return float64(v), true
+		}
+	case float64:
+		if lit.Token.Type == FLOAT {
+			return v, true
+		}
+	}
+
+	return 0, false
+
+}
+
+// colRef returns the name of the column if expr is a column reference.
+func colRef(expr Node) (string, bool) {
+	if lit, ok := expr.(*LitExpr); ok && lit.Token.Type == IDENT {
+		return lit.Value.(string), true
+	}
+	return "", false
+}
+
+// refName returns the name of the column referenced by expr.
+func refName(expr Node) (string, bool) {
+
+	if e, ok := expr.(*CallExpr); ok {
+		name := e.FuncName.Value.(string)
+		if (name == "bin" || name == "floor") && len(e.ArgList) > 0 {
+			return refName(e.ArgList[0])
+		}
+		return "", false
+	}
+
+	return colRef(expr)
+
+}
+
+func uniqueName(used map[string]bool, name string) string {
+
+	unique := name
+
+	for i := 1; used[unique]; i++ {
+		unique = fmt.Sprintf("%s%d", name, i)
+	}
+
+	used[unique] = true
+
+	return unique
+
+}
+
+// nameLit returns an IDENT literal holding a generated column name. The
+// literal takes the position of the expression it names.
+func nameLit(name string, expr Node) *LitExpr {
+
+	token := startToken(expr)
+	token.Type = IDENT
+	token.Literal = name
+
+	return &LitExpr{Token: token, Value: name}
+
+}
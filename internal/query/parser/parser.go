@@ -298,20 +298,130 @@ func (p *Parser) parseDateTimeLit() int {
 }
 
 func (p *Parser) parseTabularStmt() *TabularStmt {
-	return &TabularStmt{p.parseTabularExpr()}
+
+	stmt := &TabularStmt{TabularExpr: p.parseTabularExpr()}
+
+	p.expect(EOF)
+
+	return stmt
+
 }
 
 func (p *Parser) parseTabularExpr() *TabularExpr {
 
-	tExpr := &TabularExpr{
-		Source:    p.parseLit(IDENT),
-		TabularOp: p.parseTabularOperatorList(),
+	tExpr := &TabularExpr{}
+
+	if p.token.Type == IDENT && p.token.Literal == "union" {
+		tExpr.Union = p.parseUnionExpr()
+	} else {
+		tExpr.Source = p.parseTableRef()
 	}
 
+	tExpr.TabularOp = p.parseTabularOperatorList()
+
 	return tExpr
 
 }
 
+// union = "union" table { "," table }
+// table = [ IDENT "." ] IDENT [ "*" ] | "*"
+func (p *Parser) parseUnionExpr() *UnionExpr {
+
+	union := &UnionExpr{Token: p.token}
+
+	p.next()
+
+	for {
+
+		union.Tables = append(union.Tables, p.parseTableName())
+
+		if p.token.Type != COMMA {
+			break
+		}
+
+		p.next()
+
+	}
+
+	return union
+
+}
+
+// parseStar parses a * standing for all the tables or columns as an IDENT
+// literal.
+func (p *Parser) parseStar() *LitExpr {
+
+	lit := &LitExpr{Token: p.token, Value: "*"}
+	lit.Token.Type = IDENT
+
+	p.next()
+
+	return lit
+
+}
+
+// parseTableName parses a table name or a table name pattern. The *
+// must follow the prefix without spaces.
+func (p *Parser) parseTableName() *LitExpr {
+
+	if p.token.Type == MUL {
+		return p.parseStar()
+	}
+
+	lit := p.parseTableRef()
+
+	end := lit.Token.Offset + len(lit.Token.Literal)
+
+	if p.token.Type == MUL && p.token.Offset == end {
+		lit.Value = lit.Value.(string) + "*"
+		lit.Token.Literal = lit.Value.(string)
+		p.next()
+	}
+
+	return lit
+
+}
+
+// parseTableRef parses a table name, optionally qualified by its database
+// as in db.table, as a single IDENT literal. The names are separated by
+// the dot without spaces.
+func (p *Parser) parseTableRef() *LitExpr {
+
+	lit := p.parseLit(IDENT)
+
+	end := lit.Token.Offset + len(lit.Token.Literal)
+
+	if p.token.Type != PERIOD || p.token.Offset != end {
+		return lit
+	}
+
+	p.next()
+
+	if p.token.Offset != end+1 {
+		p.errorf("expect table name after database %v", lit.Value)
+	}
+
+	table := p.parseLit(IDENT)
+
+	lit.Value = lit.Value.(string) + "." + table.Value.(string)
+	lit.Token.Literal = lit.Value.(string)
+
+	return lit
+
+}
+
+// SplitTableName returns the database and the table of a table name, the
+// database is empty for the tables of the default database.
+func SplitTableName(name string) (database string, table string) {
+
+	if i := strings.IndexByte(name, '.'); i >= 0 {
+		return name[:i], name[i+1:]
+	}
+
+	return "", name
+
+}
+
 func (p *Parser) parseTabularOperatorList() []Node {
 
 	var tOps []Node
@@ -322,8 +432,6 @@ func (p *Parser) parseTabularOperatorList() []Node {
 		tOps = append(tOps, op)
 	}
 
-	p.expect(PIPE, EOF) // TODO(gvelo): PIPE ????
-
 	return tOps
 
 }
@@ -343,6 +451,8 @@ func (p *Parser) parseTabularOperator() Node {
 		return p.parseLimitOp()
 	case "count":
 		return p.parseCountOp()
+	case "distinct":
+		return p.parseDistinctOp()
 	case "summarize":
 		return p.parseSummarizeOp()
 	case "sort":
@@ -353,6 +463,10 @@ func (p *Parser) parseTabularOperator() Node {
 		return p.parseExtendOp()
 	case "project":
 		return p.parseProjectOp()
+	case "top":
+		return p.parseTopOp()
+	case "join":
+		return p.parseJoinOp()
 	default:
 		p.errorf("unknown tabular operator %q", t.Literal)
 	}
@@ -383,12 +497,38 @@ func (p *Parser) parseCountOp() *CountOp {
 	return &CountOp{}
 }
 
+// distinct = "distinct" ( "*" | IDENT { "," IDENT } )
+func (p *Parser) parseDistinctOp() *DistinctOp {
+
+	op := &DistinctOp{}
+
+	if p.token.Type == MUL {
+		op.Columns = []*LitExpr{p.parseStar()}
+		return op
+	}
+
+	for {
+
+		op.Columns = append(op.Columns, p.parseLit(IDENT))
+
+		if p.token.Type != COMMA {
+			break
+		}
+
+		p.next()
+
+	}
+
+	return op
+
+}
+
 // exp = unaryExp || binaryExp
 func (p *Parser) parseExpr() Node {
 	return p.parseBinaryExpr(LowestPrec + 1)
 }
 
-// call = literal(IDENT) "(" { [ expr [","] } ]  ")"
+// call = literal(IDENT) "(" { [ ( expr | "*" ) [","] } ]  ")"
 func (p *Parser) parseCallExpr(funcName *LitExpr) *CallExpr {
 
 	callExpr := &CallExpr{
@@ -400,7 +540,13 @@ func (p *Parser) parseCallExpr(funcName *LitExpr) *CallExpr {
 
 	// parse argument list
 	for p.token.Type != RPAREN {
-		expr := p.parseExpr()
+		var expr Node
+		if p.token.Type == MUL {
+			expr = &StarExpr{Token: p.token}
+			p.next()
+		} else {
+			expr = p.parseExpr()
+		}
 		callExpr.ArgList = append(callExpr.ArgList, expr)
 		if p.token.Type == COMMA {
 			p.next()
@@ -627,6 +773,67 @@ func (p *Parser) parseSortExpr() *SortExpr {
 
 }
 
+// top = "top" INT "by" sortExpr
+func (p *Parser) parseTopOp() *TopOp {
+
+	op := &TopOp{
+		NumberOfRows: p.parseLit(INT),
+	}
+
+	if p.token.Type != IDENT || p.token.Literal != "by" {
+		p.errorf("expect \"by\" keyword got %v", p.token)
+	}
+
+	p.next()
+
+	op.By = p.parseSortExpr()
+
+	return op
+
+}
+
+// join = "join" [ "kind" "=" IDENT ] "(" tabularExpr ")" "on" IDENT { "," IDENT }
+func (p *Parser) parseJoinOp() *JoinOp {
+
+	op := &JoinOp{}
+
+	if p.token.Type == IDENT && p.token.Literal == "kind" {
+		p.next()
+		p.expect(ASSIGN)
+		p.next()
+		op.Kind = p.parseLit(IDENT)
+	}
+
+	p.expect(LPAREN)
+	p.next()
+
+	op.Right = p.parseTabularExpr()
+
+	p.expect(RPAREN)
+	p.next()
+
+	if p.token.Type != IDENT || p.token.Literal != "on" {
+		p.errorf("expect \"on\" keyword got %v", p.token)
+	}
+
+	p.next()
+
+	for {
+
+		op.On = append(op.On, p.parseLit(IDENT))
+
+		if p.token.Type != COMMA {
+			break
+		}
+
+		p.next()
+
+	}
+
+	return op
+
+}
+
 func (p *Parser) parseSummarizeOp() *SummarizeOp {
 
 	op := &SummarizeOp{
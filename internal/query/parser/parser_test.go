@@ -62,6 +62,9 @@ func (v *PrintVisitor) VisitPost(n Node) Node {
 		v.pushf("( WhereOp Predicate %v )", v.pop())
 	case *CountOp:
 		v.push("( CountOp )")
+	case *DistinctOp:
+		col := v.printStack(len(node.Columns))
+		v.pushf("( DistinctOp Columns ( %v ) )", col)
 	case *LimitOp:
 		v.pushf("( LimitOp NumberOfRows %v )", node.NumberOfRows.Value)
 	case *SummarizeOp:
@@ -71,6 +74,23 @@ func (v *PrintVisitor) VisitPost(n Node) Node {
 	case *ExtendOp:
 		col := v.printStack(len(node.Columns))
 		v.pushf("( ExtendOp Columns %v )", col)
+	case *SortExpr:
+		v.pushf("( SortExpr Expr %v Asc %v NullFirst %v )", v.pop(), node.Asc, node.NullFirst)
+	case *TopOp:
+		v.pushf("( TopOp NumberOfRows %v By %v )", node.NumberOfRows.Value, v.pop())
+	case *TabularExpr:
+		ops := v.printStack(len(node.TabularOp))
+		if node.Union != nil {
+			v.pushf("( TabularExpr Source %v TabularOp ( %v ) )", v.pop(), ops)
+		} else {
+			v.pushf("( TabularExpr Source %v TabularOp ( %v ) )", node.Source.Value, ops)
+		}
+	case *UnionExpr:
+		tables := v.printStack(len(node.Tables))
+		v.pushf("( UnionExpr Tables ( %v ) )", tables)
+	case *JoinOp:
+		on := v.printStack(len(node.On))
+		v.pushf("( JoinOp Kind %v Right %v On ( %v ) )", JoinKind(node), v.pop(), on)
 	case *ColumnExpr:
 		colName := ""
 		if node.ColName != nil {
@@ -271,6 +291,99 @@ var cases = []TestCase{
 		isError:  false,
 		fun:      func(p *Parser) Node { return p.parseTabularOperator() },
 	},
+	{
+		name:     "TopOp",
+		input:    "top 10 by latency",
+		expected: "( TopOp NumberOfRows 10 By ( SortExpr Expr ( LitExpr IDENT [latency] string ) Asc false NullFirst false ) )",
+		isError:  false,
+		fun:      func(p *Parser) Node { return p.parseTabularOperator() },
+	},
+	{
+		name:     "TopOp asc nulls first",
+		input:    "top 5 by ColA * 2 asc nulls first",
+		expected: "( TopOp NumberOfRows 5 By ( SortExpr Expr ( BinaryExpr op MUL LeftExpr ( LitExpr IDENT [ColA] string ) RightExpr ( LitExpr INT [2] int ) ) Asc true NullFirst true ) )",
+		isError:  false,
+		fun:      func(p *Parser) Node { return p.parseTabularOperator() },
+	},
+	{
+		name:    "TopOp without by",
+		input:   "top 5 latency",
+		isError: true,
+		fun:     func(p *Parser) Node { return p.parseTabularOperator() },
+	},
+	{
+		name:     "JoinOp",
+		input:    "join kind=leftouter (errors | limit 5) on host, code",
+		expected: "( JoinOp Kind leftouter Right ( TabularExpr Source errors TabularOp ( ( LimitOp NumberOfRows 5 ) ) ) On ( ( LitExpr IDENT [host] string ) ( LitExpr IDENT [code] string ) ) )",
+		isError:  false,
+		fun:      func(p *Parser) Node { return p.parseTabularOperator() },
+	},
+	{
+		name:     "JoinOp default kind",
+		input:    "join (errors) on host",
+		expected: "( JoinOp Kind inner Right ( TabularExpr Source errors TabularOp (  ) ) On ( ( LitExpr IDENT [host] string ) ) )",
+		isError:  false,
+		fun:      func(p *Parser) Node { return p.parseTabularOperator() },
+	},
+	{
+		name:    "JoinOp without on",
+		input:   "join (errors) host",
+		isError: true,
+		fun:     func(p *Parser) Node { return p.parseTabularOperator() },
+	},
+	{
+		name:     "UnionExpr",
+		input:    "union nginx, app_*, * | limit 5",
+		expected: "( TabularExpr Source ( UnionExpr Tables ( ( LitExpr IDENT [nginx] string ) ( LitExpr IDENT [app_*] string ) ( LitExpr IDENT [*] string ) ) ) TabularOp ( ( LimitOp NumberOfRows 5 ) ) )",
+		isError:  false,
+		fun:      func(p *Parser) Node { return p.parseTabularExpr() },
+	},
+	{
+		name:     "UnionExpr database",
+		input:    "union prod.nginx, prod.app_*",
+		expected: "( TabularExpr Source ( UnionExpr Tables ( ( LitExpr IDENT [prod.nginx] string ) ( LitExpr IDENT [prod.app_*] string ) ) ) TabularOp (  ) )",
+		isError:  false,
+		fun:      func(p *Parser) Node { return p.parseTabularExpr() },
+	},
+	{
+		name:     "TabularExpr database",
+		input:    "prod.nginx | limit 5",
+		expected: "( TabularExpr Source prod.nginx TabularOp ( ( LimitOp NumberOfRows 5 ) ) )",
+		isError:  false,
+		fun:      func(p *Parser) Node { return p.parseTabularExpr() },
+	},
+	{
+		name:    "TabularExpr space after database",
+		input:   "prod. nginx",
+		isError: true,
+		fun:     func(p *Parser) Node { return p.parseTabularStmt() },
+	},
+	{
+		name:    "UnionExpr space before *",
+		input:   "union app_ *",
+		isError: true,
+		fun:     func(p *Parser) Node { return p.parseTabularStmt() },
+	},
+	{
+		name:     "DistinctOp",
+		input:    "distinct host, code",
+		expected: "( DistinctOp Columns ( ( LitExpr IDENT [host] string ) ( LitExpr IDENT [code] string ) ) )",
+		isError:  false,
+		fun:      func(p *Parser) Node { return p.parseTabularOperator() },
+	},
+	{
+		name:     "DistinctOp star",
+		input:    "distinct *",
+		expected: "( DistinctOp Columns ( ( LitExpr IDENT [*] string ) ) )",
+		isError:  false,
+		fun:      func(p *Parser) Node { return p.parseTabularOperator() },
+	},
+	{
+		name:    "DistinctOp without columns",
+		input:   "distinct",
+		isError: true,
+		fun:     func(p *Parser) Node { return p.parseTabularOperator() },
+	},
 	{
 		name:     "test delete",
 		input:    "( A==1 and b>10) or (a==2 and b < 10)",
@@ -343,6 +343,12 @@ func (s *Scanner) Scan() (tok Token) {
 		lit := s.scanIdentifier()
 		tok = s.resolveLiteral(lit)
 
+	case ch == '$' && isLetter(rune(s.peek())):
+		// pseudo columns, ie. $table
+		s.next()
+		lit := "$" + s.scanIdentifier()
+		tok = s.newToken(IDENT, lit)
+
 	case isDecimal(ch) || ch == '.' && isDecimal(rune(s.peek())):
 		lit, tt := s.scanNumber()
 		tok = s.newToken(tt, lit)
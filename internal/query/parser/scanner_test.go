@@ -78,6 +78,25 @@ var tests = []Test{
 		},
 		isError: false,
 	},
+	{
+		name:  "pseudo column",
+		input: "$table == a",
+		tokens: []Token{
+			{
+				Type:    IDENT,
+				Literal: "$table",
+			},
+			{
+				Type:    EQL,
+				Literal: "==",
+			},
+			{
+				Type:    IDENT,
+				Literal: "a",
+			},
+		},
+		isError: false,
+	},
 	{
 		name:    "string not terminated",
 		input:   `foo("not terminated)`,
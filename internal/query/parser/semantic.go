@@ -1,17 +1,809 @@
+// Copyright 2020 The Meerkat Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
 package parser
 
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// joinKinds are the kinds of join supported.
+var joinKinds = map[string]bool{
+	"inner":     true,
+	"leftouter": true,
+	"leftanti":  true,
+	"leftsemi":  true,
+}
+
+// TableColumnName is the pseudo column holding the table of every row
+// read by a union.
+const TableColumnName = "$table"
+
+// CountColumnName is the column returned by the count operator.
+const CountColumnName = "Count"
+
+// Schema provides the columns of the tables referenced by a query.
+type Schema interface {
+	// Columns returns the columns of table and their types. found is false
+	// if the table doesn't exist.
+	Columns(table string) (columns map[string]Type, found bool)
+	// Tables returns the names of the known tables.
+	Tables() []string
+}
+
+// SemanticError is returned when a query is syntactically valid but it
+// cannot be executed, ie. it references an unknown column or it applies
+// an operator to values of the wrong type.
+type SemanticError struct {
+	msg    string
+	offset int
+	line   int
+	column int
+}
+
+func (e *SemanticError) Error() string {
+	return fmt.Sprintf("SemanticError: %v line:%v column:%v", e.msg, e.line, e.column)
+}
+
 // Analyze runs all the semantic validations rules.
 //
-// - type checking
-// - type inference
-// - column name resolution ( when possible )
+// - column name resolution against the table schema
 // - function name resolution
+// - type inference
+// - type checking
+//
+// Unnamed columns created by extend, project and summarize are given a
+// name so they can be referenced by the operators that follow.
 //
 // TODO(gvelo) TabularStmt will be replaced with a new ast root
 // that include suport for other statements types.
-func Analyze(ast *TabularStmt) (*TabularStmt, error) {
+func Analyze(ast *TabularStmt, schema Schema) (stmt *TabularStmt, err error) {
+
+	defer func() {
+		if r := recover(); r != nil {
+			e, ok := r.(*SemanticError)
+			if ok {
+				err = e
+				return
+			} else {
+				panic(r)
+			}
+		}
+	}()
+
+	a := &analyzer{schema: schema}
+	ast.Columns = a.analyzeTabularExpr(ast.TabularExpr)
 
-	// do nothing for now.
 	return ast, nil
 
 }
+
+// scope holds the columns available to an operator and their types.
+type scope map[string]Type
+
+type analyzer struct {
+	schema Schema
+}
+
+func (a *analyzer) errorf(token Token, format string, args ...interface{}) {
+
+	err := &SemanticError{
+		msg:    fmt.Sprintf(format, args...),
+		offset: token.Offset,
+		line:   token.Line,
+		column: token.Column,
+	}
+
+	panic(err)
+
+}
+
+// analyzeTabularExpr validates a tabular expression and returns the
+// columns of its output.
+func (a *analyzer) analyzeTabularExpr(e *TabularExpr) scope {
+
+	var s scope
+
+	if e.Union != nil {
+		s = a.analyzeUnion(e.Union)
+	} else {
+		s = a.tableScope(e.Source)
+	}
+
+	for _, tabOp := range e.TabularOp {
+		s = a.analyzeTabularOp(s, tabOp)
+	}
+
+	return s
+
+}
+
+// tableScope returns the columns of the table named by lit.
+func (a *analyzer) tableScope(lit *LitExpr) scope {
+
+	table := lit.Value.(string)
+	columns, found := a.schema.Columns(table)
+
+	if !found {
+		a.errorf(lit.Token, "unknown table %q", table)
+	}
+
+	s := make(scope, len(columns))
+
+	for name, t := range columns {
+		s[name] = t
+	}
+
+	return s
+
+}
+
+// analyzeUnion replaces the table name patterns of a union with the
+// matching tables and returns the union of their columns. The columns
+// with different types in different tables have an unknown type.
+func (a *analyzer) analyzeUnion(u *UnionExpr) scope {
+
+	var tables []*LitExpr
+	read := make(map[string]bool)
+
+	for _, lit := range u.Tables {
+		for _, table := range a.matchTables(lit) {
+			// a table matched by several patterns is read once.
+			if !read[table.Value.(string)] {
+				read[table.Value.(string)] = true
+				tables = append(tables, table)
+			}
+		}
+	}
+
+	u.Tables = tables
+
+	s := make(scope)
+
+	for _, table := range tables {
+		for name, t := range a.tableScope(table) {
+			if prev, found := s[name]; found && prev != t {
+				t = UnknownType
+			}
+			s[name] = t
+		}
+	}
+
+	s[TableColumnName] = StringType
+
+	u.Columns = make(map[string]Type, len(s))
+
+	for name, t := range s {
+		u.Columns[name] = t
+	}
+
+	return s
+
+}
+
+// matchTables returns the tables named by lit, the tables are returned in
+// alphabetical order if lit is a pattern.
+func (a *analyzer) matchTables(lit *LitExpr) []*LitExpr {
+
+	pattern := lit.Value.(string)
+
+	if !strings.HasSuffix(pattern, "*") {
+		return []*LitExpr{lit}
+	}
+
+	prefix := strings.TrimSuffix(pattern, "*")
+
+	var names []string
+
+	for _, table := range a.schema.Tables() {
+		if strings.HasPrefix(table, prefix) {
+			names = append(names, table)
+		}
+	}
+
+	if len(names) == 0 {
+		a.errorf(lit.Token, "no table matches %q", pattern)
+	}
+
+	sort.Strings(names)
+
+	tables := make([]*LitExpr, len(names))
+
+	for i, name := range names {
+		tables[i] = nameLit(name, lit)
+	}
+
+	return tables
+
+}
+
+// analyzeTabularOp validates a tabular operator and returns the columns
+// of its output.
+func (a *analyzer) analyzeTabularOp(s scope, n Node) scope {
+
+	switch op := n.(type) {
+
+	case *WhereOp:
+		if t := a.analyzeExpr(s, op.Predicate); !t.is(BoolType) {
+			a.errorf(startToken(op.Predicate), "where predicate must be bool, found %v", t)
+		}
+		return s
+
+	case *ExtendOp:
+
+		NameColumns(op.Columns)
+
+		out := make(scope, len(s)+len(op.Columns))
+
+		for name, t := range s {
+			out[name] = t
+		}
+
+		// extended columns can be referenced by the columns that follow.
+		for _, col := range op.Columns {
+			out[col.ColName.Value.(string)] = a.analyzeExpr(out, col.Expr)
+		}
+
+		return out
+
+	case *ProjectOp:
+
+		NameColumns(op.Columns)
+
+		out := make(scope, len(op.Columns))
+
+		for _, col := range op.Columns {
+			a.addColumn(out, col.ColName, a.analyzeExpr(s, col.Expr))
+		}
+
+		return out
+
+	case *SummarizeOp:
+
+		NameSummarizeColumns(op)
+
+		out := make(scope, len(op.By)+len(op.Agg))
+
+		for _, by := range op.By {
+			a.addColumn(out, by.ColName, a.analyzeExpr(s, by.Expr))
+		}
+
+		for _, agg := range op.Agg {
+			a.addAggColumns(s, out, agg)
+		}
+
+		return out
+
+	case *SortOp:
+		for _, expr := range op.SortExpr {
+			a.analyzeExpr(s, expr.Expr)
+		}
+		return s
+
+	case *TopOp:
+		a.analyzeExpr(s, op.By.Expr)
+		return s
+
+	case *LimitOp:
+		return s
+
+	case *JoinOp:
+		return a.analyzeJoin(s, op)
+
+	case *CountOp:
+		return scope{CountColumnName: IntType}
+
+	case *DistinctOp:
+		return a.analyzeDistinct(s, op)
+
+	default:
+		panic(fmt.Sprintf("unknown operator %T", n))
+
+	}
+
+}
+
+// analyzeJoin validates a join and returns the columns of its output. The
+// right columns named like a left column are renamed adding a numeric
+// suffix ( ie. host1 ) by a project appended to the right subquery, the
+// project also keeps a single copy of the keys. leftanti and leftsemi
+// joins only return the left columns so the project keeps just the keys.
+func (a *analyzer) analyzeJoin(s scope, op *JoinOp) scope {
+
+	kind := JoinKind(op)
+
+	if !joinKinds[kind] {
+		a.errorf(op.Kind.Token, "unknown join kind %q, expected inner, leftouter, leftanti or leftsemi", kind)
+	}
+
+	right := a.analyzeTabularExpr(op.Right)
+
+	keys := make(map[string]bool, len(op.On))
+	project := &ProjectOp{}
+
+	for _, key := range op.On {
+
+		name := key.Value.(string)
+
+		l, found := s[name]
+
+		if !found {
+			a.errorf(key.Token, "join key %q is not a column of the left side", name)
+		}
+
+		r, found := right[name]
+
+		if !found {
+			a.errorf(key.Token, "join key %q is not a column of the right side", name)
+		}
+
+		if l != r && l != UnknownType && r != UnknownType {
+			a.errorf(key.Token, "join key %q is %v on the left side and %v on the right side", name, l, r)
+		}
+
+		if keys[name] {
+			a.errorf(key.Token, "duplicate join key %q", name)
+		}
+
+		keys[name] = true
+
+		project.Columns = append(project.Columns, &ColumnExpr{
+			ColName: nameLit(name, key),
+			Expr:    nameLit(name, key),
+		})
+
+	}
+
+	op.Right.TabularOp = append(op.Right.TabularOp, project)
+
+	if kind == "leftanti" || kind == "leftsemi" {
+		return s
+	}
+
+	out := make(scope, len(s)+len(right))
+	used := make(map[string]bool, len(s)+len(right))
+
+	for name, t := range s {
+		out[name] = t
+		used[name] = true
+	}
+
+	for name := range right {
+		used[name] = true
+	}
+
+	for _, name := range sortedNames(right) {
+
+		if keys[name] {
+			continue
+		}
+
+		colName := name
+
+		if _, found := s[name]; found {
+			colName = uniqueName(used, name)
+		}
+
+		out[colName] = right[name]
+
+		project.Columns = append(project.Columns, &ColumnExpr{
+			ColName: nameLit(colName, op.Right),
+			Expr:    nameLit(name, op.Right),
+		})
+
+	}
+
+	return out
+
+}
+
+// analyzeDistinct validates the columns of a distinct and returns them as
+// its output. distinct * is replaced with all the input columns.
+func (a *analyzer) analyzeDistinct(s scope, op *DistinctOp) scope {
+
+	if len(op.Columns) == 1 && op.Columns[0].Value == "*" {
+
+		star := op.Columns[0]
+		op.Columns = nil
+
+		for _, name := range sortedNames(s) {
+			op.Columns = append(op.Columns, nameLit(name, star))
+		}
+
+	}
+
+	out := make(scope, len(op.Columns))
+
+	for _, col := range op.Columns {
+		a.addColumn(out, col, a.analyzeLit(s, col))
+	}
+
+	return out
+
+}
+
+// sortedNames returns the column names of s in alphabetical order.
+func sortedNames(s scope) []string {
+
+	names := make([]string, 0, len(s))
+
+	for name := range s {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+
+}
+
+// addColumn adds an output column to s. Output columns must have unique
+// names.
+func (a *analyzer) addColumn(s scope, name *LitExpr, t Type) {
+
+	if _, found := s[name.Value.(string)]; found {
+		a.errorf(name.Token, "duplicate column name %q", name.Value)
+	}
+
+	s[name.Value.(string)] = t
+
+}
+
+// analyzeExpr resolves the columns and functions referenced by an
+// expression and returns its type.
+func (a *analyzer) analyzeExpr(s scope, n Node) Type {
+
+	switch e := n.(type) {
+
+	case *LitExpr:
+		return a.analyzeLit(s, e)
+
+	case *BinaryExpr:
+		return a.analyzeBinaryExpr(e, a.analyzeExpr(s, e.LeftExpr), a.analyzeExpr(s, e.RightExpr))
+
+	case *UnaryExpr:
+		t := a.analyzeExpr(s, e.Expr)
+		if !t.is(IntType, FloatType) {
+			a.errorf(e.Op, "operator %q cannot be applied to a %v operand", opName(e.Op), t)
+		}
+		return t
+
+	case *StarExpr:
+		a.errorf(e.Token, "* can only be used as an argument of arg_min() and arg_max()")
+
+	case *CallExpr:
+
+		name := e.FuncName.Value.(string)
+
+		if sig, found := scalarFuncs[name]; found {
+			return a.analyzeCall(s, e, sig)
+		}
+
+		if _, found := aggFuncs[name]; found {
+			a.errorf(e.FuncName.Token, "aggregation function %v() can only be used in summarize", name)
+		}
+
+		a.errorf(e.FuncName.Token, "unknown function %q", name)
+
+	default:
+		panic(fmt.Sprintf("unknown expression %T", n))
+
+	}
+
+	return UnknownType
+
+}
+
+func (a *analyzer) analyzeLit(s scope, lit *LitExpr) Type {
+
+	switch lit.Token.Type {
+	case IDENT:
+		t, found := s[lit.Value.(string)]
+		if !found {
+			a.errorf(lit.Token, "unknown column %q", lit.Value)
+		}
+		return t
+	case INT, TIME:
+		// timespans are integer nanoseconds.
+		return IntType
+	case FLOAT:
+		return FloatType
+	case STRING:
+		return StringType
+	case BOOL:
+		return BoolType
+	case DATETIME:
+		return DateTimeType
+	default:
+		panic(fmt.Sprintf("invalid literal %v", lit.Token))
+	}
+
+}
+
+func (a *analyzer) analyzeBinaryExpr(e *BinaryExpr, l, r Type) Type {
+
+	switch op := e.Op.Type; op {
+
+	case AND, OR:
+		if !l.is(BoolType) || !r.is(BoolType) {
+			a.errorf(e.Op, "operator %q expects bool operands, found %v and %v", opName(e.Op), l, r)
+		}
+		return BoolType
+
+	case EQL, NEQ, LSS, GTR, LEQ, GEQ:
+		if !isComparable(op, l, r) {
+			a.errorf(e.Op, "operator %q cannot compare %v with %v", opName(e.Op), l, r)
+		}
+		return BoolType
+
+	case ADD, SUB, MUL, QUO, REM:
+		t, ok := arithType(op, l, r)
+		if !ok {
+			a.errorf(e.Op, "operator %q cannot be applied to %v and %v operands", opName(e.Op), l, r)
+		}
+		return t
+
+	case EQL_CI, NEQ_CI, HAS, NOT_HAS, HAS_CS, NOT_HAS_CS, HASPREFIX, NOT_HASPREFIX,
+		HASPREFIX_CS, NOT_HASPREFIX_CS, HASSUFFIX, NOT_HASSUFFIX, HASSUFFIX_CS,
+		NOT_HASSUFFIX_CS, CONTAINS, NOT_CONTAINS, CONTAINS_CS, NOT_CONTAINS_CS,
+		STARTSWITH, NOT_STARTSWITH, STARTSWITH_CS, NOT_STARTSWITH_CS, ENDSWITH,
+		NOT_ENDSWITH, ENDSWITH_CS, NOT_ENDSWITH_CS, MATCHES:
+		if !l.is(StringType) || !r.is(StringType) {
+			a.errorf(e.Op, "operator %q expects string operands, found %v and %v", opName(e.Op), l, r)
+		}
+		return BoolType
+
+	default:
+		a.errorf(e.Op, "operator %q is not supported", opName(e.Op))
+		return UnknownType
+
+	}
+
+}
+
+// analyzeCall checks the arguments of a function call against the
+// function signature and returns the type of the result.
+func (a *analyzer) analyzeCall(s scope, e *CallExpr, sig signature) Type {
+
+	name := e.FuncName.Value.(string)
+
+	if len(e.ArgList) < sig.minArgs || len(e.ArgList) > sig.maxArgs {
+
+		expected := fmt.Sprint(sig.minArgs)
+
+		if sig.maxArgs != sig.minArgs {
+			expected = fmt.Sprintf("%v to %v", sig.minArgs, sig.maxArgs)
+		}
+
+		a.errorf(e.FuncName.Token, "%v() expects %v arguments, found %v", name, expected, len(e.ArgList))
+
+	}
+
+	args := make([]Type, len(e.ArgList))
+
+	for i, arg := range e.ArgList {
+
+		args[i] = a.analyzeExpr(s, arg)
+
+		if accepted := sig.argTypes(i); accepted != nil && !args[i].is(accepted...) {
+			a.errorf(startToken(arg), "%v() expects %v as argument %v, found %v", name, typeList(accepted), i+1, args[i])
+		}
+
+	}
+
+	return sig.result(args)
+
+}
+
+func (a *analyzer) analyzeAgg(s scope, e *CallExpr) Type {
+
+	name := e.FuncName.Value.(string)
+
+	sig, found := aggFuncs[name]
+
+	if !found {
+
+		if _, found := scalarFuncs[name]; found {
+			a.errorf(e.FuncName.Token, "%v() is not an aggregation function", name)
+		}
+
+		a.errorf(e.FuncName.Token, "unknown aggregation function %q", name)
+
+	}
+
+	// the row columns are added by addRowColumns.
+	if rowAggFuncs[name] && len(e.ArgList) > 1 {
+		return a.analyzeCall(s, &CallExpr{FuncName: e.FuncName, ArgList: e.ArgList[:1]}, sig)
+	}
+
+	if percentileFuncs[name] {
+		for i, arg := range e.ArgList[1:] {
+			if p, ok := percentileValue(arg); !ok || p < 0 || p > 100 {
+				a.errorf(startToken(arg), "%v() expects a constant percentile between 0 and 100 as argument %v", name, i+2)
+			}
+		}
+	}
+
+	if sizedAggFuncs[name] && len(e.ArgList) > 1 {
+		if lit, ok := e.ArgList[1].(*LitExpr); !ok || lit.Token.Type != INT || lit.Value.(int) <= 0 {
+			a.errorf(startToken(e.ArgList[1]), "%v() expects a positive int constant as argument 2", name)
+		}
+	}
+
+	return a.analyzeCall(s, e, sig)
+
+}
+
+// addAggColumns adds the columns returned by an aggregation to out. Most of
+// them return a single column, percentiles() returns a column for every
+// percentile and arg_min() and arg_max() add the columns of the selected
+// row.
+func (a *analyzer) addAggColumns(s scope, out scope, agg *AggExpr) {
+
+	t := a.analyzeAgg(s, agg.Expr)
+
+	if agg.Expr.FuncName.Value.(string) == "percentiles" {
+		for _, arg := range agg.Expr.ArgList[1:] {
+			p, _ := percentileValue(arg)
+			a.addColumn(out, nameLit(PercentileColumn(agg.ColName.Value.(string), p), arg), t)
+		}
+		return
+	}
+
+	a.addColumn(out, agg.ColName, t)
+	a.addRowColumns(s, out, agg.Expr)
+
+}
+
+// addRowColumns adds the row columns returned by arg_min() and arg_max()
+// to out. The columns of * that are already in out are not added again,
+// ie. the group columns.
+func (a *analyzer) addRowColumns(s scope, out scope, e *CallExpr) {
+
+	name := e.FuncName.Value.(string)
+
+	if !rowAggFuncs[name] {
+		return
+	}
+
+	for _, arg := range e.ArgList[1:] {
+
+		switch arg := arg.(type) {
+		case *StarExpr:
+			for col, t := range s {
+				if _, found := out[col]; !found {
+					out[col] = t
+				}
+			}
+		case *LitExpr:
+			if arg.Token.Type != IDENT {
+				a.errorf(arg.Token, "%v() expects column references or * after argument 1", name)
+			}
+			a.addColumn(out, arg, a.analyzeLit(s, arg))
+		default:
+			a.errorf(startToken(arg), "%v() expects column references or * after argument 1", name)
+		}
+
+	}
+
+}
+
+// isComparable returns true if values of type l and r can be compared with
+// op. Numeric values can be compared between them, bool values can only
+// be compared for equality.
+func isComparable(op TokenType, l, r Type) bool {
+	switch {
+	case l == UnknownType || r == UnknownType:
+		return true
+	case l.is(numericTypes...) && r.is(numericTypes...):
+		return true
+	case l == StringType && r == StringType:
+		return true
+	case l == BoolType && r == BoolType:
+		return op == EQL || op == NEQ
+	default:
+		return false
+	}
+}
+
+// arithType returns the type of the result of an arithmetic operator.
+// Integer operands give an integer result, if any of the operands is a
+// float the result is a float. Adding or subtracting an integer to a
+// datetime gives a datetime and the difference between two datetimes is
+// an integer.
+func arithType(op TokenType, l, r Type) (Type, bool) {
+
+	if !l.is(numericTypes...) || !r.is(numericTypes...) {
+		return UnknownType, false
+	}
+
+	if l == UnknownType || r == UnknownType {
+		return UnknownType, true
+	}
+
+	lTime, rTime := l == DateTimeType, r == DateTimeType
+
+	switch {
+	case !lTime && !rTime:
+		if l == FloatType || r == FloatType {
+			return FloatType, true
+		}
+		return IntType, true
+	case lTime && rTime && op == SUB:
+		return IntType, true
+	case lTime && r == IntType && (op == ADD || op == SUB):
+		return DateTimeType, true
+	case rTime && l == IntType && op == ADD:
+		return DateTimeType, true
+	default:
+		return UnknownType, false
+	}
+
+}
+
+// typeList formats a list of types as "a, b or c".
+func typeList(types []Type) string {
+
+	names := make([]string, len(types))
+
+	for i, t := range types {
+		names[i] = t.String()
+	}
+
+	if len(names) == 1 {
+		return names[0]
+	}
+
+	return strings.Join(names[:len(names)-1], ", ") + " or " + names[len(names)-1]
+
+}
+
+func opName(token Token) string {
+	if token.Literal != "" {
+		return token.Literal
+	}
+	return token.Type.String()
+}
+
+// startToken returns the first token of a node, it is used to report the
+// position of errors.
+func startToken(n Node) Token {
+	switch e := n.(type) {
+	case *LitExpr:
+		return e.Token
+	case *BinaryExpr:
+		return startToken(e.LeftExpr)
+	case *UnaryExpr:
+		return e.Op
+	case *CallExpr:
+		return e.FuncName.Token
+	case *StarExpr:
+		return e.Token
+	case *ColumnExpr:
+		if e.ColName != nil {
+			return e.ColName.Token
+		}
+		return startToken(e.Expr)
+	case *SortExpr:
+		return startToken(e.Expr)
+	case *TabularExpr:
+		if e.Union != nil {
+			return e.Union.Token
+		}
+		return e.Source.Token
+	default:
+		return Token{}
+	}
+}
@@ -0,0 +1,307 @@
+// Copyright 2020 The Meerkat Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type testSchema map[string]map[string]Type
+
+func (s testSchema) Columns(table string) (map[string]Type, bool) {
+	columns, found := s[table]
+	return columns, found
+}
+
+func (s testSchema) Tables() []string {
+	var tables []string
+	for table := range s {
+		tables = append(tables, table)
+	}
+	return tables
+}
+
+var schema = testSchema{
+	"logs": {
+		"_ts":     DateTimeType,
+		"host":    StringType,
+		"latency": IntType,
+		"size":    FloatType,
+		"ok":      BoolType,
+		"mixed":   UnknownType,
+	},
+	"errors": {
+		"_ts":   DateTimeType,
+		"host":  StringType,
+		"host1": StringType,
+		"code":  IntType,
+	},
+	"app_web": {
+		"_ts":    DateTimeType,
+		"host":   StringType,
+		"status": IntType,
+	},
+	"app_api": {
+		"_ts":    DateTimeType,
+		"host":   StringType,
+		"status": StringType,
+		"method": StringType,
+	},
+}
+
+func analyze(t *testing.T, query string) error {
+
+	ast, err := Parse(query)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = Analyze(ast, schema)
+
+	return err
+
+}
+
+func TestAnalyze(t *testing.T) {
+
+	queries := []string{
+		`logs | where host == "a" and latency > 10`,
+		`logs | where _ts > ago(1h) and size / latency < 2.5`,
+		`logs | where mixed > 1 and (mixed contains "x")`,
+		`logs | extend l = latency * 2, m = l + 1 | where m > 3`,
+		`logs | project host, latency + 1 | where Column2 > 1 | sort by host`,
+		`logs | summarize count(), max(latency) by bin(_ts, 5m) | where _ts > now(-1d) | top 5 by max_latency`,
+		`logs | summarize n = count() by h = tolower(host) | project h, n | limit 10`,
+		`logs | extend t = datetime_add("hour", 1, startofday(_ts)) | extend d = datetime_diff("minute", t, _ts)`,
+		`logs | where strlen(strcat(host, latency, ok)) > indexof(host, "a", 1)`,
+		`logs | summarize countif(ok), sumif(size, latency > 1), stdev(latency), variance(size) by host`,
+		`logs | summarize any(ok), make_list(host), s = make_set(latency, 10) | project s, any_ok`,
+		`logs | summarize arg_max(_ts, *) by host | where latency > 1 and _ts > ago(1h)`,
+		`logs | summarize arg_min(latency, host, ok) | project latency, host, ok`,
+		`logs | summarize dcount(host), h = hll(host) by bin(_ts, 1h) | summarize hll_merge(h) | extend n = dcount_hll(hll_merge_h)`,
+		`logs | summarize percentile(latency, 50), p = percentiles(size, 95, 99.9) | where percentile_latency_50 > p_99_9 + p_95`,
+		`logs | join (errors | where code > 500) on host | where latency > code and _ts1 > _ts`,
+		`logs | join kind=leftsemi (errors | summarize count() by host) on host | project host, latency`,
+		`logs | summarize n = count() by host | join kind=leftouter (errors) on host | where code > n`,
+		`union logs, errors | where $table == "logs" or code > 500`,
+		`union app_* | summarize count() by $table, status | where status > 1`,
+		`union * | project host, $table | sort by host`,
+		`logs | join kind=leftsemi (union app_* | where method == "GET") on host`,
+		`logs | count | where Count > 10`,
+		`logs | distinct host, ok | where ok and host != "a"`,
+		`union app_* | distinct * | where $table == "app_web"`,
+	}
+
+	for _, query := range queries {
+		assert.NoError(t, analyze(t, query), query)
+	}
+
+}
+
+func TestAnalyzeErrors(t *testing.T) {
+
+	tests := []struct {
+		query  string
+		msg    string
+		column int
+	}{
+		{`foo | limit 1`, `unknown table "foo"`, 0},
+		{`logs | where hots == "a"`, `unknown column "hots"`, 13},
+		{`logs | where host > 3`, `operator ">" cannot compare string with int`, 18},
+		{`logs | where latency`, `where predicate must be bool, found int`, 13},
+		{`logs | where ok and host`, `operator "and" expects bool operands, found bool and string`, 16},
+		{`logs | extend x = host + 1`, `operator "+" cannot be applied to string and int operands`, 23},
+		{`logs | extend x = _ts + _ts`, `operator "+" cannot be applied to datetime and datetime operands`, 22},
+		{`logs | where latency contains "1"`, `operator "contains" expects string operands, found int and string`, 21},
+		{`logs | extend x = -host`, `operator "-" cannot be applied to a string operand`, 18},
+		{`logs | extend x = strlen(host, 1)`, `strlen() expects 1 arguments, found 2`, 18},
+		{`logs | extend x = substring(host, "1")`, `substring() expects int as argument 2, found string`, 34},
+		{`logs | extend x = bin(host, 1)`, `bin() expects int, float or datetime as argument 1, found string`, 22},
+		{`logs | extend x = foo(host)`, `unknown function "foo"`, 18},
+		{`logs | extend x = count()`, `aggregation function count() can only be used in summarize`, 18},
+		{`logs | summarize strlen(host)`, `strlen() is not an aggregation function`, 17},
+		{`logs | summarize avg(host)`, `avg() expects int, float or datetime as argument 1, found string`, 21},
+		{`logs | summarize n = count(), n = max(latency)`, `duplicate column name "n"`, 30},
+		{`logs | summarize sumif(size, host)`, `sumif() expects bool as argument 2, found string`, 29},
+		{`logs | summarize make_list(host, latency)`, `make_list() expects a positive int constant as argument 2`, 33},
+		{`logs | summarize arg_max(ok, *)`, `arg_max() expects int, float, datetime or string as argument 1, found bool`, 25},
+		{`logs | summarize arg_max(_ts, strlen(host))`, `arg_max() expects column references or * after argument 1`, 30},
+		{`logs | summarize arg_max(_ts, *, host)`, `duplicate column name "host"`, 33},
+		{`logs | summarize percentiles(latency, 50, 101)`, `percentiles() expects a constant percentile between 0 and 100 as argument 3`, 42},
+		{`logs | summarize percentile(latency, size)`, `percentile() expects a constant percentile between 0 and 100 as argument 2`, 37},
+		{`logs | summarize percentiles(host, 50)`, `percentiles() expects int, float or datetime as argument 1, found string`, 29},
+		{`logs | extend x = strcat(*)`, `* can only be used as an argument of arg_min() and arg_max()`, 25},
+		{`logs | project host | where latency > 1`, `unknown column "latency"`, 28},
+		{`logs | summarize count() by host | sort by latency`, `unknown column "latency"`, 43},
+		{`logs | join kind=outer (errors) on host`, `unknown join kind "outer", expected inner, leftouter, leftanti or leftsemi`, 17},
+		{`logs | join (errors) on latency`, `join key "latency" is not a column of the right side`, 24},
+		{`logs | join (errors | project h = host) on h`, `join key "h" is not a column of the left side`, 43},
+		{`logs | join (errors | project latency = host) on latency`, `join key "latency" is int on the left side and string on the right side`, 49},
+		{`logs | join kind=leftanti (errors) on host | where code > 1`, `unknown column "code"`, 51},
+		{`union logs, foo`, `unknown table "foo"`, 12},
+		{`union logs, web*`, `no table matches "web*"`, 12},
+		{`union app_* | where $table > 1`, `operator ">" cannot compare string with int`, 27},
+		{`logs | where $table == "logs"`, `unknown column "$table"`, 13},
+		{`logs | count | where latency > 1`, `unknown column "latency"`, 21},
+		{`logs | distinct host, hots`, `unknown column "hots"`, 22},
+		{`logs | distinct host, host`, `duplicate column name "host"`, 22},
+		{`logs | distinct host | where latency > 1`, `unknown column "latency"`, 29},
+	}
+
+	for _, test := range tests {
+
+		err := analyze(t, test.query)
+
+		if assert.IsType(t, &SemanticError{}, err, test.query) {
+			e := err.(*SemanticError)
+			assert.Equal(t, test.msg, e.msg, test.query)
+			assert.Equal(t, test.column, e.column, test.query)
+			assert.Equal(t, test.column, e.offset, test.query)
+		}
+
+	}
+
+}
+
+func TestAnalyzeNamesColumns(t *testing.T) {
+
+	ast, err := Parse(`logs | summarize count(), sum(latency) by bin(_ts, 1h), tolower(host)`)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = Analyze(ast, schema)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	op := ast.TabularExpr.TabularOp[0].(*SummarizeOp)
+
+	assert.Equal(t, "_ts", op.By[0].ColName.Value)
+	assert.Equal(t, "Column2", op.By[1].ColName.Value)
+	assert.Equal(t, "count_", op.Agg[0].ColName.Value)
+	assert.Equal(t, "sum_latency", op.Agg[1].ColName.Value)
+
+}
+
+func TestAnalyzeJoinColumns(t *testing.T) {
+
+	ast, err := Parse(`logs | join (errors) on host`)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = Analyze(ast, schema)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	join := ast.TabularExpr.TabularOp[0].(*JoinOp)
+	project := join.Right.TabularOp[0].(*ProjectOp)
+
+	var columns []string
+
+	for _, col := range project.Columns {
+		columns = append(columns, col.ColName.Value.(string)+"="+col.Expr.(*LitExpr).Value.(string))
+	}
+
+	// the right columns clashing with the left ones are renamed.
+	assert.Equal(t, []string{"host=host", "_ts1=_ts", "code=code", "host1=host1"}, columns)
+
+}
+
+func TestAnalyzeUnionTables(t *testing.T) {
+
+	ast, err := Parse(`union app_*, logs, app_web`)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = Analyze(ast, schema)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tables []string
+
+	for _, table := range ast.TabularExpr.Union.Tables {
+		tables = append(tables, table.Value.(string))
+	}
+
+	// the patterns are expanded and every table is read once.
+	assert.Equal(t, []string{"app_api", "app_web", "logs"}, tables)
+
+	columns := ast.TabularExpr.Union.Columns
+
+	assert.Equal(t, StringType, columns["method"])
+	assert.Equal(t, IntType, columns["latency"])
+	assert.Equal(t, StringType, columns[TableColumnName])
+	// status is an int in app_web and a string in app_api.
+	assert.Equal(t, UnknownType, columns["status"])
+
+}
+
+func TestAnalyzeResultColumns(t *testing.T) {
+
+	ast, err := Parse(`logs | project host, latency | extend ok = latency > 10`)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = Analyze(ast, schema)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, map[string]Type{
+		"host":    StringType,
+		"latency": IntType,
+		"ok":      BoolType,
+	}, ast.Columns)
+
+}
+
+func TestAnalyzeDistinctStar(t *testing.T) {
+
+	ast, err := Parse(`errors | distinct *`)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = Analyze(ast, schema)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var columns []string
+
+	for _, col := range ast.TabularExpr.TabularOp[0].(*DistinctOp).Columns {
+		columns = append(columns, col.Value.(string))
+	}
+
+	assert.Equal(t, []string{"_ts", "code", "host", "host1"}, columns)
+
+}
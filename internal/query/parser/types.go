@@ -0,0 +1,87 @@
+// Copyright 2020 The Meerkat Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package parser
+
+import "meerkat/internal/storage"
+
+// Type is the type of an expression as inferred by the semantic analyzer.
+type Type int
+
+const (
+	// UnknownType is the type of the expressions whose type is only known
+	// at execution time, ie. null literals. It is compatible with any type.
+	UnknownType Type = iota
+	BoolType
+	IntType
+	FloatType
+	StringType
+	DateTimeType
+	DynamicType
+)
+
+var typeNames = [...]string{
+	UnknownType:  "unknown",
+	BoolType:     "bool",
+	IntType:      "int",
+	FloatType:    "float",
+	StringType:   "string",
+	DateTimeType: "datetime",
+	DynamicType:  "dynamic",
+}
+
+func (t Type) String() string {
+	return typeNames[t]
+}
+
+// TypeOf returns the type of the values stored in a column of type
+// colType. Timespans are stored as integer nanoseconds.
+func TypeOf(colType storage.ColumnType) Type {
+	switch colType {
+	case storage.ColumnType_BOOL:
+		return BoolType
+	case storage.ColumnType_INT32, storage.ColumnType_INT64:
+		return IntType
+	case storage.ColumnType_FLOAT64:
+		return FloatType
+	case storage.ColumnType_STRING, storage.ColumnType_GUID:
+		return StringType
+	case storage.ColumnType_TIMESTAMP, storage.ColumnType_DATETIME:
+		return DateTimeType
+	case storage.ColumnType_DYNAMIC:
+		return DynamicType
+	default:
+		return UnknownType
+	}
+}
+
+// numericTypes are the types supporting arithmetic operators.
+var numericTypes = []Type{IntType, FloatType, DateTimeType}
+
+// is returns true if t can be used where a value of any of the given types
+// is expected.
+func (t Type) is(types ...Type) bool {
+
+	if t == UnknownType {
+		return true
+	}
+
+	for _, expected := range types {
+		if t == expected {
+			return true
+		}
+	}
+
+	return false
+
+}
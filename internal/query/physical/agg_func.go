@@ -0,0 +1,512 @@
+// Copyright 2020 The Meerkat Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package physical
+
+import (
+	"bytes"
+	"fmt"
+	"meerkat/internal/query/logical"
+	"meerkat/internal/storage"
+	"meerkat/internal/storage/vector"
+)
+
+// aggFunc keeps the state of an aggregation function for every group.
+// The state of a group can be exported as a set of partial state columns
+// which can be merged later by another aggFunc ( usually on another node ).
+type aggFunc interface {
+	// resize makes room for n groups.
+	resize(n int)
+	// add accumulates the arguments of each input row into the group
+	// found at the same position in groups.
+	add(groups []int, args []Col)
+	// merge accumulates the partial states found in batch.
+	merge(groups []int, batch Batch)
+	// partial returns the columns holding the partial states.
+	partial() map[string]Col
+	// final returns the aggregated value of every group.
+	final() Col
+}
+
+// multiAggFunc is an aggFunc returning more than one column, ie. arg_max()
+// returns the columns of the selected rows and percentiles() a column for
+// every percentile.
+type multiAggFunc interface {
+	aggFunc
+	// outputs returns the names and the values of the output columns.
+	outputs() ([]string, []Col)
+}
+
+// rowAggFunc is an aggFunc that reads the columns of the input rows
+// besides its arguments ( arg_min and arg_max ). Only its first argument
+// is evaluated, the row columns are taken from the input batch.
+type rowAggFunc interface {
+	aggFunc
+	// addRows is like add but it also receives the input batch.
+	addRows(groups []int, args []Col, batch Batch)
+}
+
+// aggFuncFactory creates an aggregation function. args are the arguments
+// of the call, they are only used by the functions taking constants or
+// column names as arguments.
+type aggFuncFactory func(name string, args []logical.Node) aggFunc
+
+var aggFuncs = map[string]aggFuncFactory{
+	"count": func(name string, _ []logical.Node) aggFunc { return &countAgg{name: name} },
+	"sum":   func(name string, _ []logical.Node) aggFunc { return &scalarAgg{name: name, op: aggSum} },
+	"min":   func(name string, _ []logical.Node) aggFunc { return &scalarAgg{name: name, op: aggMin} },
+	"max":   func(name string, _ []logical.Node) aggFunc { return &scalarAgg{name: name, op: aggMax} },
+	"avg":   func(name string, _ []logical.Node) aggFunc { return &avgAgg{name: name} },
+
+	"countif": func(name string, _ []logical.Node) aggFunc {
+		return &condAgg{funcName: "countif", agg: &countAgg{name: name}}
+	},
+	"sumif": func(name string, _ []logical.Node) aggFunc {
+		return &condAgg{funcName: "sumif", agg: &scalarAgg{name: name, op: aggSum}}
+	},
+	"stdev":    func(name string, _ []logical.Node) aggFunc { return &varianceAgg{name: name, stdev: true} },
+	"variance": func(name string, _ []logical.Node) aggFunc { return &varianceAgg{name: name} },
+	"arg_min":  func(name string, args []logical.Node) aggFunc { return newArgAgg(name, false, args) },
+	"arg_max":  func(name string, args []logical.Node) aggFunc { return newArgAgg(name, true, args) },
+	"any":      func(name string, _ []logical.Node) aggFunc { return &anyAgg{name: name} },
+	"make_list": func(name string, args []logical.Node) aggFunc {
+		return &listAgg{name: name, maxSize: listSize("make_list", args)}
+	},
+	"make_set": func(name string, args []logical.Node) aggFunc {
+		return &listAgg{name: name, maxSize: listSize("make_set", args), distinct: true}
+	},
+
+	"dcount":    func(name string, _ []logical.Node) aggFunc { return &hllAgg{name: name, funcName: "dcount"} },
+	"hll":       func(name string, _ []logical.Node) aggFunc { return &hllAgg{name: name, funcName: "hll"} },
+	"hll_merge": func(name string, _ []logical.Node) aggFunc { return &hllAgg{name: name, funcName: "hll_merge"} },
+	"percentile": func(name string, args []logical.Node) aggFunc {
+		return &percentileAgg{name: name, percentiles: percentileArgs("percentile", args)}
+	},
+	"percentiles": func(name string, args []logical.Node) aggFunc {
+		return &percentileAgg{name: name, percentiles: percentileArgs("percentiles", args), multi: true}
+	},
+}
+
+// newAggFunc creates the aggregation function funcName. name is the name
+// of the output column, partial state columns are named after it.
+func newAggFunc(funcName string, name string, args []logical.Node) aggFunc {
+
+	factory, found := aggFuncs[funcName]
+
+	if !found {
+		panic(fmt.Sprintf("unknown aggregation function %q", funcName))
+	}
+
+	return factory(name, args)
+
+}
+
+// stateName returns the name of a partial state column for aggregations
+// that need more than one column to represent its state.
+func stateName(name string, state string) string {
+	return name + "$" + state
+}
+
+func stateColumn(batch Batch, name string) Col {
+
+	col, found := batch.Columns[name]
+
+	if !found {
+		panic(fmt.Sprintf("partial aggregation state %q not found", name))
+	}
+
+	return col
+
+}
+
+func singleArg(funcName string, args []Col) Col {
+	if len(args) != 1 {
+		panic(fmt.Sprintf("%s() expects one argument, found %v", funcName, len(args)))
+	}
+	return args[0]
+}
+
+func resizeInt64(s []int64, n int) []int64 {
+	if n > len(s) {
+		s = append(s, make([]int64, n-len(s))...)
+	}
+	return s
+}
+
+func resizeFloat64(s []float64, n int) []float64 {
+	if n > len(s) {
+		s = append(s, make([]float64, n-len(s))...)
+	}
+	return s
+}
+
+func resizeBool(s []bool, n int) []bool {
+	if n > len(s) {
+		s = append(s, make([]bool, n-len(s))...)
+	}
+	return s
+}
+
+func int64Result(colType storage.ColumnType, values []int64, valid []uint64) Col {
+	v := vector.NewInt64Vector(values, valid)
+	return Col{Vec: &v, ColumnType: colType}
+}
+
+func float64Result(values []float64, valid []uint64) Col {
+	v := vector.NewFloat64Vector(values, valid)
+	return Col{Vec: &v, ColumnType: storage.ColumnType_FLOAT64}
+}
+
+// setValidity builds a validity bitmap from a slice of flags. Returns nil
+// if all the values are valid.
+func setValidity(set []bool) []uint64 {
+
+	for i, ok := range set {
+		if !ok {
+			valid := newValidity(len(set))
+			for j := 0; j < i; j++ {
+				setValid(valid, j)
+			}
+			for j := i + 1; j < len(set); j++ {
+				if set[j] {
+					setValid(valid, j)
+				}
+			}
+			return valid
+		}
+	}
+
+	return nil
+
+}
+
+// countAgg counts the number of rows of each group. If an argument is
+// given only the rows where it is not null are counted.
+type countAgg struct {
+	name   string
+	counts []int64
+}
+
+func (a *countAgg) resize(n int) { a.counts = resizeInt64(a.counts, n) }
+
+func (a *countAgg) add(groups []int, args []Col) {
+
+	if len(args) == 0 {
+		for _, g := range groups {
+			a.counts[g]++
+		}
+		return
+	}
+
+	arg := singleArg("count", args)
+
+	for i, g := range groups {
+		if !isNull(arg.Vec, i) {
+			a.counts[g]++
+		}
+	}
+
+}
+
+func (a *countAgg) merge(groups []int, batch Batch) {
+	counts := stateColumn(batch, a.name).Vec.(*vector.Int64Vector).Values()
+	for i, g := range groups {
+		a.counts[g] += counts[i]
+	}
+}
+
+func (a *countAgg) partial() map[string]Col {
+	return map[string]Col{a.name: a.final()}
+}
+
+func (a *countAgg) final() Col {
+	return int64Result(storage.ColumnType_INT64, a.counts, nil)
+}
+
+type scalarOp int
+
+const (
+	aggSum scalarOp = iota
+	aggMin
+	aggMax
+)
+
+var scalarOpNames = [...]string{
+	aggSum: "sum",
+	aggMin: "min",
+	aggMax: "max",
+}
+
+// scalarAgg implements the aggregations whose state is a single value of
+// the same type as the input ( sum, min and max ). Merging partial states
+// is the same operation as adding values. Groups without non null values
+// are aggregated as null.
+type scalarAgg struct {
+	name    string
+	op      scalarOp
+	colType storage.ColumnType
+	typed   bool
+	n       int
+	set     []bool
+	ints    []int64
+	floats  []float64
+	strs    [][]byte
+}
+
+func (a *scalarAgg) resize(n int) {
+
+	a.n = n
+	a.set = resizeBool(a.set, n)
+
+	if a.typed {
+		a.resizeValues()
+	}
+
+}
+
+func (a *scalarAgg) resizeValues() {
+	switch vectorKind(a.colType) {
+	case storage.ColumnType_INT64:
+		a.ints = resizeInt64(a.ints, a.n)
+	case storage.ColumnType_FLOAT64:
+		a.floats = resizeFloat64(a.floats, a.n)
+	case storage.ColumnType_STRING:
+		if a.n > len(a.strs) {
+			a.strs = append(a.strs, make([][]byte, a.n-len(a.strs))...)
+		}
+	}
+}
+
+func (a *scalarAgg) setType(colType storage.ColumnType) {
+
+	if a.typed {
+		if vectorKind(a.colType) != vectorKind(colType) {
+			panic(fmt.Sprintf("%s() cannot aggregate %v and %v values", scalarOpNames[a.op], a.colType, colType))
+		}
+		return
+	}
+
+	switch {
+	case isNumericType(colType):
+	case colType == storage.ColumnType_STRING && a.op != aggSum:
+	default:
+		panic(fmt.Sprintf("%s() cannot aggregate %v values", scalarOpNames[a.op], colType))
+	}
+
+	a.typed = true
+	a.colType = colType
+	a.resizeValues()
+
+}
+
+func (a *scalarAgg) add(groups []int, args []Col) {
+	a.accumulate(groups, singleArg(scalarOpNames[a.op], args))
+}
+
+func (a *scalarAgg) merge(groups []int, batch Batch) {
+	a.accumulate(groups, stateColumn(batch, a.name))
+}
+
+func (a *scalarAgg) accumulate(groups []int, col Col) {
+
+	if isNullCol(col) {
+		return
+	}
+
+	a.setType(col.ColumnType)
+
+	switch v := col.Vec.(type) {
+	case *vector.Int64Vector:
+		nulls := v.HasNulls()
+		values := v.Values()
+		for i, g := range groups {
+			if !nulls || v.IsValid(i) {
+				a.updateInt64(g, values[i])
+			}
+		}
+	case *vector.Float64Vector:
+		nulls := v.HasNulls()
+		values := v.Values()
+		for i, g := range groups {
+			if !nulls || v.IsValid(i) {
+				a.updateFloat64(g, values[i])
+			}
+		}
+	case *vector.ByteSliceVector:
+		nulls := v.HasNulls()
+		for i, g := range groups {
+			if !nulls || v.IsValid(i) {
+				a.updateBytes(g, v.Get(i))
+			}
+		}
+	default:
+		panic(fmt.Sprintf("%s() cannot aggregate vector %T", scalarOpNames[a.op], col.Vec))
+	}
+
+}
+
+func (a *scalarAgg) updateInt64(g int, x int64) {
+
+	if !a.set[g] {
+		a.ints[g] = x
+		a.set[g] = true
+		return
+	}
+
+	switch a.op {
+	case aggSum:
+		a.ints[g] += x
+	case aggMin:
+		if x < a.ints[g] {
+			a.ints[g] = x
+		}
+	case aggMax:
+		if x > a.ints[g] {
+			a.ints[g] = x
+		}
+	}
+
+}
+
+func (a *scalarAgg) updateFloat64(g int, x float64) {
+
+	if !a.set[g] {
+		a.floats[g] = x
+		a.set[g] = true
+		return
+	}
+
+	switch a.op {
+	case aggSum:
+		a.floats[g] += x
+	case aggMin:
+		if x < a.floats[g] {
+			a.floats[g] = x
+		}
+	case aggMax:
+		if x > a.floats[g] {
+			a.floats[g] = x
+		}
+	}
+
+}
+
+func (a *scalarAgg) updateBytes(g int, x []byte) {
+
+	if a.set[g] {
+		c := bytes.Compare(x, a.strs[g])
+		if (a.op == aggMin && c >= 0) || (a.op == aggMax && c <= 0) {
+			return
+		}
+	}
+
+	// the input buffers may be reused so we keep a copy
+	a.strs[g] = append(a.strs[g][:0], x...)
+	a.set[g] = true
+
+}
+
+func (a *scalarAgg) partial() map[string]Col {
+	return map[string]Col{a.name: a.final()}
+}
+
+func (a *scalarAgg) final() Col {
+
+	if !a.typed {
+		return Col{Vec: &nullVector{l: a.n}}
+	}
+
+	valid := setValidity(a.set)
+
+	switch vectorKind(a.colType) {
+	case storage.ColumnType_INT64:
+		return int64Result(a.colType, a.ints, valid)
+	case storage.ColumnType_FLOAT64:
+		return float64Result(a.floats, valid)
+	default:
+		v := vector.NewByteSliceVectorFromByteArray(a.strs, valid)
+		return Col{Vec: &v, ColumnType: a.colType}
+	}
+
+}
+
+// avgAgg computes the average of the non null values of each group. The
+// partial state is made of the sum and the count of the values.
+type avgAgg struct {
+	name   string
+	sums   []float64
+	counts []int64
+}
+
+func (a *avgAgg) resize(n int) {
+	a.sums = resizeFloat64(a.sums, n)
+	a.counts = resizeInt64(a.counts, n)
+}
+
+func (a *avgAgg) add(groups []int, args []Col) {
+
+	arg := singleArg("avg", args)
+
+	if isNullCol(arg) {
+		return
+	}
+
+	if !isNumericType(arg.ColumnType) {
+		panic(fmt.Sprintf("avg() cannot aggregate %v values", arg.ColumnType))
+	}
+
+	values := asFloat64(arg)
+
+	for i, g := range groups {
+		if !isNull(arg.Vec, i) {
+			a.sums[g] += values[i]
+			a.counts[g]++
+		}
+	}
+
+}
+
+func (a *avgAgg) merge(groups []int, batch Batch) {
+
+	sums := stateColumn(batch, stateName(a.name, "sum")).Vec.(*vector.Float64Vector).Values()
+	counts := stateColumn(batch, stateName(a.name, "count")).Vec.(*vector.Int64Vector).Values()
+
+	for i, g := range groups {
+		a.sums[g] += sums[i]
+		a.counts[g] += counts[i]
+	}
+
+}
+
+func (a *avgAgg) partial() map[string]Col {
+	return map[string]Col{
+		stateName(a.name, "sum"):   float64Result(a.sums, nil),
+		stateName(a.name, "count"): int64Result(storage.ColumnType_INT64, a.counts, nil),
+	}
+}
+
+func (a *avgAgg) final() Col {
+
+	avg := make([]float64, len(a.sums))
+	set := make([]bool, len(a.sums))
+
+	for i, sum := range a.sums {
+		if a.counts[i] != 0 {
+			avg[i] = sum / float64(a.counts[i])
+			set[i] = true
+		}
+	}
+
+	return float64Result(avg, setValidity(set))
+
+}
@@ -0,0 +1,181 @@
+// Copyright 2020 The Meerkat Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package physical
+
+import (
+	"encoding/json"
+	"fmt"
+	"meerkat/internal/query/logical"
+	"meerkat/internal/storage"
+	"meerkat/internal/storage/vector"
+)
+
+// maxListSize is the default max size of make_list() and make_set().
+const maxListSize = 1 << 20
+
+// listSize returns the max size given as the second argument of
+// make_list() and make_set().
+func listSize(funcName string, args []logical.Node) int {
+
+	if len(args) < 2 {
+		return maxListSize
+	}
+
+	if lit, ok := args[1].(*logical.LiteralExpr); ok {
+		switch size := lit.Value.(type) {
+		case int:
+			return size
+		case int64:
+			return int(size)
+		}
+	}
+
+	panic(fmt.Sprintf("%s() expects a constant max size", funcName))
+
+}
+
+// listAgg collects the non null values of each group into a dynamic array
+// ( make_list ) or into an array of distinct values ( make_set ). At most
+// maxSize values are kept. The values are kept JSON encoded and the
+// partial state is the array itself.
+type listAgg struct {
+	name     string
+	distinct bool
+	maxSize  int
+	values   [][][]byte
+	seen     []map[string]bool
+}
+
+func (a *listAgg) funcName() string {
+	if a.distinct {
+		return "make_set"
+	}
+	return "make_list"
+}
+
+func (a *listAgg) resize(n int) {
+
+	if n > len(a.values) {
+		a.values = append(a.values, make([][][]byte, n-len(a.values))...)
+	}
+
+	if a.distinct && n > len(a.seen) {
+		a.seen = append(a.seen, make([]map[string]bool, n-len(a.seen))...)
+	}
+
+}
+
+func (a *listAgg) add(groups []int, args []Col) {
+
+	// the max size is taken from the call.
+	if len(args) == 0 || len(args) > 2 {
+		panic(fmt.Sprintf("%s() expects one or two arguments, found %v", a.funcName(), len(args)))
+	}
+
+	arg := args[0]
+
+	if isNullCol(arg) {
+		return
+	}
+
+	for i, g := range groups {
+		if !isNull(arg.Vec, i) {
+			a.append(g, appendJSONValue(nil, arg, i))
+		}
+	}
+
+}
+
+func (a *listAgg) merge(groups []int, batch Batch) {
+
+	col := stateColumn(batch, a.name)
+
+	if isNullCol(col) {
+		return
+	}
+
+	v := col.Vec.(*vector.ByteSliceVector)
+
+	for i, g := range groups {
+
+		if isNull(v, i) {
+			continue
+		}
+
+		var values []json.RawMessage
+
+		if err := json.Unmarshal(v.Get(i), &values); err != nil {
+			panic(fmt.Sprintf("invalid %s() partial state: %v", a.funcName(), err))
+		}
+
+		for _, value := range values {
+			a.append(g, value)
+		}
+
+	}
+
+}
+
+func (a *listAgg) append(g int, value []byte) {
+
+	if len(a.values[g]) >= a.maxSize {
+		return
+	}
+
+	if a.distinct {
+
+		if a.seen[g] == nil {
+			a.seen[g] = make(map[string]bool)
+		}
+
+		if a.seen[g][string(value)] {
+			return
+		}
+
+		a.seen[g][string(value)] = true
+
+	}
+
+	a.values[g] = append(a.values[g], value)
+
+}
+
+func (a *listAgg) partial() map[string]Col {
+	return map[string]Col{a.name: a.final()}
+}
+
+func (a *listAgg) final() Col {
+
+	arrays := make([][]byte, len(a.values))
+
+	for g, values := range a.values {
+
+		array := append(make([]byte, 0, 64), '[')
+
+		for i, value := range values {
+			if i > 0 {
+				array = append(array, ',')
+			}
+			array = append(array, value...)
+		}
+
+		arrays[g] = append(array, ']')
+
+	}
+
+	v := vector.NewByteSliceVectorFromByteArray(arrays, nil)
+
+	return Col{Vec: &v, ColumnType: storage.ColumnType_DYNAMIC}
+
+}
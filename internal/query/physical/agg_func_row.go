@@ -0,0 +1,418 @@
+// Copyright 2020 The Meerkat Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package physical
+
+import (
+	"bytes"
+	"fmt"
+	"meerkat/internal/query/logical"
+	"meerkat/internal/storage"
+	"meerkat/internal/storage/vector"
+	"sort"
+	"strings"
+)
+
+// groupValues holds a value of any type for every group. The type is
+// taken from the first non null value, groups without a value are null.
+type groupValues struct {
+	colType storage.ColumnType
+	typed   bool
+	n       int
+	set     []bool
+	ints    []int64
+	floats  []float64
+	bools   []bool
+	strs    [][]byte
+}
+
+func (v *groupValues) resize(n int) {
+
+	v.n = n
+	v.set = resizeBool(v.set, n)
+
+	if v.typed {
+		v.resizeValues()
+	}
+
+}
+
+func (v *groupValues) resizeValues() {
+	switch vectorKind(v.colType) {
+	case storage.ColumnType_INT64:
+		v.ints = resizeInt64(v.ints, v.n)
+	case storage.ColumnType_FLOAT64:
+		v.floats = resizeFloat64(v.floats, v.n)
+	case storage.ColumnType_BOOL:
+		v.bools = resizeBool(v.bools, v.n)
+	case storage.ColumnType_STRING:
+		if v.n > len(v.strs) {
+			v.strs = append(v.strs, make([][]byte, v.n-len(v.strs))...)
+		}
+	default:
+		panic(fmt.Sprintf("unsupported column type %v", v.colType))
+	}
+}
+
+func (v *groupValues) setType(colType storage.ColumnType) {
+
+	if v.typed {
+		if vectorKind(v.colType) != vectorKind(colType) {
+			panic(fmt.Sprintf("cannot mix %v and %v values in the same column", v.colType, colType))
+		}
+		return
+	}
+
+	v.typed = true
+	v.colType = colType
+	v.resizeValues()
+
+}
+
+// put sets the value of the group g to the i-th value of col.
+func (v *groupValues) put(g int, col Col, i int) {
+
+	if isNull(col.Vec, i) {
+		v.set[g] = false
+		return
+	}
+
+	v.setType(col.ColumnType)
+
+	switch vec := col.Vec.(type) {
+	case *vector.Int64Vector:
+		v.ints[g] = vec.Get(i)
+	case *vector.Float64Vector:
+		v.floats[g] = vec.Get(i)
+	case *vector.BoolVector:
+		v.bools[g] = vec.Get(i)
+	case *vector.ByteSliceVector:
+		// the input buffers may be reused so we keep a copy
+		v.strs[g] = append(v.strs[g][:0], vec.Get(i)...)
+	default:
+		panic(fmt.Sprintf("cannot store values of vector %T", col.Vec))
+	}
+
+	v.set[g] = true
+
+}
+
+// compare compares the value of the group g with the i-th value of col.
+// Both values must be non null and of the same type.
+func (v *groupValues) compare(g int, col Col, i int) int {
+	switch vec := col.Vec.(type) {
+	case *vector.Int64Vector:
+		return compareInt(v.ints[g], vec.Get(i))
+	case *vector.Float64Vector:
+		return compareFloat(v.floats[g], vec.Get(i))
+	case *vector.ByteSliceVector:
+		return bytes.Compare(v.strs[g], vec.Get(i))
+	default:
+		panic(fmt.Sprintf("cannot compare values of vector %T", col.Vec))
+	}
+}
+
+func (v *groupValues) col() Col {
+
+	if !v.typed {
+		return Col{Vec: &nullVector{l: v.n}}
+	}
+
+	valid := setValidity(v.set)
+
+	switch vectorKind(v.colType) {
+	case storage.ColumnType_INT64:
+		return int64Result(v.colType, v.ints, valid)
+	case storage.ColumnType_FLOAT64:
+		return float64Result(v.floats, valid)
+	case storage.ColumnType_BOOL:
+		vec := vector.NewBoolVector(v.bools, valid)
+		return Col{Vec: &vec, ColumnType: v.colType}
+	default:
+		vec := vector.NewByteSliceVectorFromByteArray(v.strs, valid)
+		return Col{Vec: &vec, ColumnType: v.colType}
+	}
+
+}
+
+// anyAgg returns a value of each group, the first non null value found.
+type anyAgg struct {
+	name   string
+	values groupValues
+}
+
+func (a *anyAgg) resize(n int) { a.values.resize(n) }
+
+func (a *anyAgg) add(groups []int, args []Col) {
+	a.accumulate(groups, singleArg("any", args))
+}
+
+func (a *anyAgg) merge(groups []int, batch Batch) {
+	a.accumulate(groups, stateColumn(batch, a.name))
+}
+
+func (a *anyAgg) accumulate(groups []int, col Col) {
+
+	if isNullCol(col) {
+		return
+	}
+
+	for i, g := range groups {
+		if !a.values.set[g] && !isNull(col.Vec, i) {
+			a.values.put(g, col, i)
+		}
+	}
+
+}
+
+func (a *anyAgg) partial() map[string]Col {
+	return map[string]Col{a.name: a.final()}
+}
+
+func (a *anyAgg) final() Col { return a.values.col() }
+
+// argAgg selects the row with the min or the max value of its first
+// argument in each group. It returns that value and the row columns: the
+// columns given as arguments or all the input columns for *. Rows with a
+// null value are ignored and ties keep the first row found. The partial
+// state holds the value and the row columns.
+type argAgg struct {
+	name string
+	max  bool
+	star bool
+	// columns are the names of the row columns, the columns found by *
+	// are added in name order as they appear.
+	columns []string
+	values  groupValues
+	row     map[string]*groupValues
+	n       int
+	// selected is the row of the current batch selected for every group,
+	// -1 if none.
+	selected []int
+}
+
+func newArgAgg(name string, max bool, args []logical.Node) *argAgg {
+
+	a := &argAgg{
+		name: name,
+		max:  max,
+		row:  make(map[string]*groupValues),
+	}
+
+	if len(args) > 0 {
+		args = args[1:]
+	}
+
+	for _, arg := range args {
+		switch arg := arg.(type) {
+		case *logical.StarExpr:
+			a.star = true
+		case *logical.ColRefExpr:
+			a.addColumn(arg.Name)
+		default:
+			panic(fmt.Sprintf("%s() expects column references or * after argument 1", a.funcName()))
+		}
+	}
+
+	return a
+
+}
+
+func (a *argAgg) funcName() string {
+	if a.max {
+		return "arg_max"
+	}
+	return "arg_min"
+}
+
+// rowState returns the name of the partial state column of a row column.
+func (a *argAgg) rowState(column string) string {
+	return stateName(a.name, "row$"+column)
+}
+
+func (a *argAgg) addColumn(name string) {
+
+	if _, found := a.row[name]; found {
+		return
+	}
+
+	values := &groupValues{}
+	values.resize(a.n)
+
+	a.columns = append(a.columns, name)
+	a.row[name] = values
+
+}
+
+func (a *argAgg) resize(n int) {
+
+	for len(a.selected) < n {
+		a.selected = append(a.selected, -1)
+	}
+
+	a.n = n
+	a.values.resize(n)
+
+	for _, values := range a.row {
+		values.resize(n)
+	}
+
+}
+
+// add only keeps the aggregated values, the row columns are taken from
+// the input batch by addRows.
+func (a *argAgg) add(groups []int, args []Col) {
+	a.addRows(groups, args, Batch{})
+}
+
+func (a *argAgg) addRows(groups []int, args []Col, batch Batch) {
+
+	row := make(map[string]Col)
+
+	if a.star {
+		for name, col := range batch.Columns {
+			row[name] = col
+		}
+	} else {
+		for _, name := range a.columns {
+			if col, found := batch.Columns[name]; found {
+				row[name] = col
+			}
+		}
+	}
+
+	a.update(groups, singleArg(a.funcName(), args), row)
+
+}
+
+func (a *argAgg) merge(groups []int, batch Batch) {
+
+	prefix := a.rowState("")
+	row := make(map[string]Col)
+
+	for name, col := range batch.Columns {
+		if strings.HasPrefix(name, prefix) {
+			row[name[len(prefix):]] = col
+		}
+	}
+
+	a.update(groups, stateColumn(batch, a.name), row)
+
+}
+
+// update selects the rows of the batch holding a better value than the
+// current one of their group and copies their row columns.
+func (a *argAgg) update(groups []int, values Col, row map[string]Col) {
+
+	if isNullCol(values) {
+		return
+	}
+
+	a.values.setType(values.ColumnType)
+
+	var updated []int
+
+	for i, g := range groups {
+
+		if isNull(values.Vec, i) {
+			continue
+		}
+
+		if a.values.set[g] {
+			c := a.values.compare(g, values, i)
+			if (a.max && c >= 0) || (!a.max && c <= 0) {
+				continue
+			}
+		}
+
+		a.values.put(g, values, i)
+
+		if a.selected[g] < 0 {
+			updated = append(updated, g)
+		}
+
+		a.selected[g] = i
+
+	}
+
+	if len(updated) == 0 {
+		return
+	}
+
+	if a.star {
+
+		names := make([]string, 0, len(row))
+
+		for name := range row {
+			if _, found := a.row[name]; !found {
+				names = append(names, name)
+			}
+		}
+
+		sort.Strings(names)
+
+		for _, name := range names {
+			a.addColumn(name)
+		}
+
+	}
+
+	// the row columns missing in the batch are nulls.
+	for _, name := range a.columns {
+
+		values := a.row[name]
+		col, found := row[name]
+
+		for _, g := range updated {
+			if found {
+				values.put(g, col, a.selected[g])
+			} else {
+				values.set[g] = false
+			}
+		}
+
+	}
+
+	for _, g := range updated {
+		a.selected[g] = -1
+	}
+
+}
+
+func (a *argAgg) partial() map[string]Col {
+
+	partial := map[string]Col{a.name: a.final()}
+
+	for _, name := range a.columns {
+		partial[a.rowState(name)] = a.row[name].col()
+	}
+
+	return partial
+
+}
+
+func (a *argAgg) final() Col { return a.values.col() }
+
+// outputs returns the selected values followed by the row columns.
+func (a *argAgg) outputs() ([]string, []Col) {
+
+	names := append([]string{a.name}, a.columns...)
+	cols := []Col{a.final()}
+
+	for _, name := range a.columns {
+		cols = append(cols, a.row[name].col())
+	}
+
+	return names, cols
+
+}
@@ -0,0 +1,412 @@
+// Copyright 2020 The Meerkat Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package physical
+
+import (
+	"encoding/base64"
+	"fmt"
+	"math"
+	"meerkat/internal/query/logical"
+	"meerkat/internal/query/parser"
+	"meerkat/internal/query/sketch"
+	"meerkat/internal/storage"
+	"meerkat/internal/storage/vector"
+)
+
+// hllAgg estimates the number of distinct values of each group with a
+// HyperLogLog sketch. dcount() returns the estimate, hll() returns the
+// sketch encoded as a base64 string and hll_merge() merges the sketches
+// returned by hll(). The partial state is the binary encoding of the
+// sketch, groups without values have a null state.
+type hllAgg struct {
+	name     string
+	funcName string
+	sketches []*sketch.HLL
+	keyBuf   []byte
+}
+
+func (a *hllAgg) resize(n int) {
+	if n > len(a.sketches) {
+		a.sketches = append(a.sketches, make([]*sketch.HLL, n-len(a.sketches))...)
+	}
+}
+
+func (a *hllAgg) sketch(g int) *sketch.HLL {
+
+	if a.sketches[g] == nil {
+		a.sketches[g] = sketch.NewHLL()
+	}
+
+	return a.sketches[g]
+
+}
+
+func (a *hllAgg) add(groups []int, args []Col) {
+
+	arg := singleArg(a.funcName, args)
+
+	if isNullCol(arg) {
+		return
+	}
+
+	if a.funcName == "hll_merge" {
+
+		v, ok := arg.Vec.(*vector.ByteSliceVector)
+
+		if !ok || arg.ColumnType != storage.ColumnType_STRING {
+			panic(fmt.Sprintf("hll_merge() expects the strings returned by hll(), found %v", arg.ColumnType))
+		}
+
+		for i, g := range groups {
+			if !isNull(v, i) {
+				data, err := base64.StdEncoding.DecodeString(string(v.Get(i)))
+				if err != nil {
+					panic(fmt.Sprintf("hll_merge() expects the strings returned by hll(): %v", err))
+				}
+				a.mergeSketch(g, data)
+			}
+		}
+
+		return
+
+	}
+
+	// the values are hashed as group keys so values of different types
+	// are always distinct.
+	for i, g := range groups {
+		if !isNull(arg.Vec, i) {
+			a.keyBuf = appendKey(a.keyBuf[:0], arg, i)
+			a.sketch(g).Insert(a.keyBuf)
+		}
+	}
+
+}
+
+func (a *hllAgg) merge(groups []int, batch Batch) {
+
+	col := stateColumn(batch, a.name)
+
+	if isNullCol(col) {
+		return
+	}
+
+	v := col.Vec.(*vector.ByteSliceVector)
+
+	for i, g := range groups {
+		if !isNull(v, i) {
+			a.mergeSketch(g, v.Get(i))
+		}
+	}
+
+}
+
+func (a *hllAgg) mergeSketch(g int, data []byte) {
+
+	h, err := sketch.UnmarshalHLL(data)
+
+	if err != nil {
+		panic(fmt.Sprintf("%s(): %v", a.funcName, err))
+	}
+
+	a.sketch(g).Merge(h)
+
+}
+
+func (a *hllAgg) partial() map[string]Col {
+
+	data := make([][]byte, len(a.sketches))
+	set := make([]bool, len(a.sketches))
+
+	for g, h := range a.sketches {
+		if h != nil {
+			data[g] = marshalSketch(h.MarshalBinary())
+			set[g] = true
+		}
+	}
+
+	v := vector.NewByteSliceVectorFromByteArray(data, setValidity(set))
+
+	return map[string]Col{a.name: {Vec: &v, ColumnType: storage.ColumnType_STRING}}
+
+}
+
+// final returns the estimates for dcount() and the encoded sketches for
+// hll() and hll_merge(). Groups without values have an empty sketch.
+func (a *hllAgg) final() Col {
+
+	if a.funcName == "dcount" {
+
+		counts := make([]int64, len(a.sketches))
+
+		for g, h := range a.sketches {
+			if h != nil {
+				counts[g] = int64(h.Estimate())
+			}
+		}
+
+		return int64Result(storage.ColumnType_INT64, counts, nil)
+
+	}
+
+	data := make([][]byte, len(a.sketches))
+
+	for g, h := range a.sketches {
+
+		if h == nil {
+			h = sketch.NewHLL()
+		}
+
+		encoded := marshalSketch(h.MarshalBinary())
+		data[g] = make([]byte, base64.StdEncoding.EncodedLen(len(encoded)))
+		base64.StdEncoding.Encode(data[g], encoded)
+
+	}
+
+	v := vector.NewByteSliceVectorFromByteArray(data, nil)
+
+	return Col{Vec: &v, ColumnType: storage.ColumnType_STRING}
+
+}
+
+func marshalSketch(data []byte, err error) []byte {
+
+	if err != nil {
+		panic(fmt.Sprintf("cannot encode sketch: %v", err))
+	}
+
+	return data
+
+}
+
+// percentileArgs returns the constant percentiles given as arguments of
+// percentile() and percentiles().
+func percentileArgs(funcName string, args []logical.Node) []float64 {
+
+	var percentiles []float64
+
+	for i := 1; i < len(args); i++ {
+
+		lit, ok := args[i].(*logical.LiteralExpr)
+
+		if !ok {
+			panic(fmt.Sprintf("%s() expects constant percentiles", funcName))
+		}
+
+		switch p := lit.Value.(type) {
+		case int:
+			percentiles = append(percentiles, float64(p))
+		case int64:
+			percentiles = append(percentiles, float64(p))
+		case float64:
+			percentiles = append(percentiles, p)
+		default:
+			panic(fmt.Sprintf("%s() expects constant percentiles", funcName))
+		}
+
+	}
+
+	if len(percentiles) == 0 {
+		panic(fmt.Sprintf("%s() expects at least one percentile", funcName))
+	}
+
+	return percentiles
+
+}
+
+// percentileAgg estimates percentiles of the non null values of each group
+// with a t-digest. percentile() returns a single percentile and
+// percentiles() a column for every percentile. The results have the type
+// of the values, integers are rounded. The partial state is the binary
+// encoding of the digest preceded by the column type of the values.
+type percentileAgg struct {
+	name        string
+	percentiles []float64
+	multi       bool
+	colType     storage.ColumnType
+	typed       bool
+	digests     []*sketch.TDigest
+}
+
+func (a *percentileAgg) funcName() string {
+	if a.multi {
+		return "percentiles"
+	}
+	return "percentile"
+}
+
+func (a *percentileAgg) resize(n int) {
+	if n > len(a.digests) {
+		a.digests = append(a.digests, make([]*sketch.TDigest, n-len(a.digests))...)
+	}
+}
+
+func (a *percentileAgg) digest(g int) *sketch.TDigest {
+
+	if a.digests[g] == nil {
+		a.digests[g] = sketch.NewTDigest()
+	}
+
+	return a.digests[g]
+
+}
+
+func (a *percentileAgg) setType(colType storage.ColumnType) {
+
+	if !isNumericType(colType) {
+		panic(fmt.Sprintf("%s() cannot aggregate %v values", a.funcName(), colType))
+	}
+
+	if a.typed {
+		if vectorKind(a.colType) != vectorKind(colType) {
+			panic(fmt.Sprintf("%s() cannot aggregate %v and %v values", a.funcName(), a.colType, colType))
+		}
+		return
+	}
+
+	a.typed = true
+	a.colType = colType
+
+}
+
+// add receives the values and the percentiles, which are taken from the
+// call.
+func (a *percentileAgg) add(groups []int, args []Col) {
+
+	if len(args) == 0 {
+		panic(fmt.Sprintf("%s() expects the values to aggregate", a.funcName()))
+	}
+
+	arg := args[0]
+
+	if isNullCol(arg) {
+		return
+	}
+
+	a.setType(arg.ColumnType)
+
+	values := asFloat64(arg)
+
+	for i, g := range groups {
+		if !isNull(arg.Vec, i) {
+			a.digest(g).Add(values[i])
+		}
+	}
+
+}
+
+func (a *percentileAgg) merge(groups []int, batch Batch) {
+
+	col := stateColumn(batch, a.name)
+
+	if isNullCol(col) {
+		return
+	}
+
+	v := col.Vec.(*vector.ByteSliceVector)
+
+	for i, g := range groups {
+
+		if isNull(v, i) {
+			continue
+		}
+
+		data := v.Get(i)
+
+		if len(data) == 0 {
+			panic(fmt.Sprintf("invalid %s() partial state", a.funcName()))
+		}
+
+		a.setType(storage.ColumnType(data[0]))
+
+		d, err := sketch.UnmarshalTDigest(data[1:])
+
+		if err != nil {
+			panic(fmt.Sprintf("%s(): %v", a.funcName(), err))
+		}
+
+		a.digest(g).Merge(d)
+
+	}
+
+}
+
+func (a *percentileAgg) partial() map[string]Col {
+
+	data := make([][]byte, len(a.digests))
+	set := make([]bool, len(a.digests))
+
+	for g, d := range a.digests {
+		if d != nil {
+			data[g] = append([]byte{byte(a.colType)}, marshalSketch(d.MarshalBinary())...)
+			set[g] = true
+		}
+	}
+
+	v := vector.NewByteSliceVectorFromByteArray(data, setValidity(set))
+
+	return map[string]Col{a.name: {Vec: &v, ColumnType: storage.ColumnType_STRING}}
+
+}
+
+func (a *percentileAgg) final() Col { return a.percentile(a.percentiles[0]) }
+
+func (a *percentileAgg) outputs() ([]string, []Col) {
+
+	if !a.multi {
+		return []string{a.name}, []Col{a.final()}
+	}
+
+	names := make([]string, len(a.percentiles))
+	cols := make([]Col, len(a.percentiles))
+
+	for i, p := range a.percentiles {
+		names[i] = parser.PercentileColumn(a.name, p)
+		cols[i] = a.percentile(p)
+	}
+
+	return names, cols
+
+}
+
+// percentile returns the percentile p of every group, groups without
+// values are null.
+func (a *percentileAgg) percentile(p float64) Col {
+
+	if !a.typed {
+		return Col{Vec: &nullVector{l: len(a.digests)}}
+	}
+
+	values := make([]float64, len(a.digests))
+	set := make([]bool, len(a.digests))
+
+	for g, d := range a.digests {
+		if d != nil && d.Count() > 0 {
+			values[g] = d.Quantile(p / 100)
+			set[g] = true
+		}
+	}
+
+	if vectorKind(a.colType) == storage.ColumnType_FLOAT64 {
+		return float64Result(values, setValidity(set))
+	}
+
+	ints := make([]int64, len(values))
+
+	for i, x := range values {
+		ints[i] = int64(math.Round(x))
+	}
+
+	return int64Result(a.colType, ints, setValidity(set))
+
+}
@@ -0,0 +1,194 @@
+// Copyright 2020 The Meerkat Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package physical
+
+import (
+	"fmt"
+	"math"
+	"meerkat/internal/storage"
+	"meerkat/internal/storage/vector"
+)
+
+// condAgg aggregates only the rows where its last argument is true, the
+// other arguments are passed to agg ( ie. sumif(x, pred) is sum(x) over
+// the rows matching pred ). The partial state is the one of agg.
+type condAgg struct {
+	funcName string
+	agg      aggFunc
+}
+
+func (a *condAgg) resize(n int) { a.agg.resize(n) }
+
+func (a *condAgg) add(groups []int, args []Col) {
+
+	if len(args) == 0 {
+		panic(fmt.Sprintf("%s() expects a predicate", a.funcName))
+	}
+
+	pred := args[len(args)-1]
+	args = args[:len(args)-1]
+
+	var sel []int
+
+	switch v := pred.Vec.(type) {
+	case *vector.BoolVector:
+		sel = make([]int, 0, len(groups))
+		for i, selected := range v.Values() {
+			if selected && !isNull(v, i) {
+				sel = append(sel, i)
+			}
+		}
+	case *nullVector:
+		// null predicate, nothing selected.
+	default:
+		panic(fmt.Sprintf("%s() predicate must be a bool expression, found %v", a.funcName, pred.ColumnType))
+	}
+
+	if len(sel) == len(groups) {
+		a.agg.add(groups, args)
+		return
+	}
+
+	selGroups := make([]int, len(sel))
+
+	for i, row := range sel {
+		selGroups[i] = groups[row]
+	}
+
+	selArgs := make([]Col, len(args))
+
+	for i, arg := range args {
+		selArgs[i] = Col{Vec: selectVector(arg.Vec, sel), ColumnType: arg.ColumnType}
+	}
+
+	a.agg.add(selGroups, selArgs)
+
+}
+
+func (a *condAgg) merge(groups []int, batch Batch) { a.agg.merge(groups, batch) }
+func (a *condAgg) partial() map[string]Col         { return a.agg.partial() }
+func (a *condAgg) final() Col                      { return a.agg.final() }
+
+// varianceAgg computes the sample variance of the non null values of each
+// group, or its square root for stdev(). The partial state is made of the
+// count, the mean and the sum of the squared differences from the mean of
+// the values, the states are merged with the parallel algorithm of Chan
+// et al. which is also used to add the values one at a time.
+type varianceAgg struct {
+	name   string
+	stdev  bool
+	counts []int64
+	means  []float64
+	m2s    []float64
+}
+
+func (a *varianceAgg) funcName() string {
+	if a.stdev {
+		return "stdev"
+	}
+	return "variance"
+}
+
+func (a *varianceAgg) resize(n int) {
+	a.counts = resizeInt64(a.counts, n)
+	a.means = resizeFloat64(a.means, n)
+	a.m2s = resizeFloat64(a.m2s, n)
+}
+
+func (a *varianceAgg) add(groups []int, args []Col) {
+
+	arg := singleArg(a.funcName(), args)
+
+	if isNullCol(arg) {
+		return
+	}
+
+	if !isNumericType(arg.ColumnType) {
+		panic(fmt.Sprintf("%s() cannot aggregate %v values", a.funcName(), arg.ColumnType))
+	}
+
+	values := asFloat64(arg)
+
+	for i, g := range groups {
+		if !isNull(arg.Vec, i) {
+			a.combine(g, 1, values[i], 0)
+		}
+	}
+
+}
+
+func (a *varianceAgg) merge(groups []int, batch Batch) {
+
+	counts := stateColumn(batch, stateName(a.name, "count")).Vec.(*vector.Int64Vector).Values()
+	means := stateColumn(batch, stateName(a.name, "mean")).Vec.(*vector.Float64Vector).Values()
+	m2s := stateColumn(batch, stateName(a.name, "m2")).Vec.(*vector.Float64Vector).Values()
+
+	for i, g := range groups {
+		a.combine(g, counts[i], means[i], m2s[i])
+	}
+
+}
+
+// combine merges the state of a set of n values into the state of g.
+func (a *varianceAgg) combine(g int, n int64, mean float64, m2 float64) {
+
+	if n == 0 {
+		return
+	}
+
+	count := a.counts[g] + n
+	delta := mean - a.means[g]
+
+	a.m2s[g] += m2 + delta*delta*float64(a.counts[g])*float64(n)/float64(count)
+	a.means[g] += delta * float64(n) / float64(count)
+	a.counts[g] = count
+
+}
+
+func (a *varianceAgg) partial() map[string]Col {
+	return map[string]Col{
+		stateName(a.name, "count"): int64Result(storage.ColumnType_INT64, a.counts, nil),
+		stateName(a.name, "mean"):  float64Result(a.means, nil),
+		stateName(a.name, "m2"):    float64Result(a.m2s, nil),
+	}
+}
+
+// final returns null for the groups without values and 0 for the groups
+// with a single value.
+func (a *varianceAgg) final() Col {
+
+	result := make([]float64, len(a.counts))
+	set := make([]bool, len(a.counts))
+
+	for i, count := range a.counts {
+
+		if count == 0 {
+			continue
+		}
+
+		set[i] = true
+
+		if count > 1 {
+			result[i] = a.m2s[i] / float64(count-1)
+		}
+
+		if a.stdev {
+			result[i] = math.Sqrt(result[i])
+		}
+
+	}
+
+	return float64Result(result, setValidity(set))
+
+}
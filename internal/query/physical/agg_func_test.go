@@ -0,0 +1,191 @@
+// Copyright 2020 The Meerkat Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package physical
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func aggInput(hosts []string, latency []int64, msgs []string) BatchOperator {
+	return &batchSourceOp{batches: []Batch{
+		testBatch(map[string]Col{
+			"host":    stringCol(0, hosts...),
+			"latency": int64Col(1, latency...),
+			"msg":     stringCol(2, msgs...),
+		}),
+	}}
+}
+
+// aggregate runs a summarize as a partial aggregation on every input whose
+// partial states are merged by a final aggregation.
+func aggregate(t *testing.T, expr string, inputs ...BatchOperator) map[string][]interface{} {
+
+	op := summarize(t, expr)
+
+	var partials []BatchOperator
+
+	for _, input := range inputs {
+		partials = append(partials, NewHashAggOp(input, op.By, op.Agg, PartialAgg, NewAllocator(0, "", "")))
+	}
+
+	merge := NewHashAggOp(NewMergeOp(partials), op.By, op.Agg, MergeAgg, NewAllocator(0, "", ""))
+	final := NewHashAggOp(merge, op.By, op.Agg, FinalAgg, NewAllocator(0, "", ""))
+
+	return drain(final)
+
+}
+
+func jsonArray(t *testing.T, value interface{}) []interface{} {
+
+	var values []interface{}
+
+	if err := json.Unmarshal([]byte(value.(string)), &values); err != nil {
+		t.Fatal(err)
+	}
+
+	return values
+
+}
+
+func TestAggFuncs(t *testing.T) {
+
+	result := aggregate(t,
+		`countif(latency > 15), sumif(latency, msg != "m3"), variance(latency), stdev(latency), `+
+			`any(msg), make_list(latency), make_set(latency), l = make_list(msg, 1) by host`,
+		aggInput([]string{"a", "b", "a"}, []int64{10, 20, 30}, []string{"m1", "m2", "m3"}),
+		aggInput([]string{"b", "a", "b", "b"}, []int64{40, 10, 5, 40}, []string{"m4", "m5", "m6", "m7"}),
+	)
+
+	rows := rowsByKey(result, "host")
+
+	assert.Len(t, rows, 2)
+
+	assert.Equal(t, int64(1), rows["a"]["countif_"])
+	assert.Equal(t, int64(3), rows["b"]["countif_"])
+
+	assert.Equal(t, int64(20), rows["a"]["sumif_latency"])
+	assert.Equal(t, int64(105), rows["b"]["sumif_latency"])
+
+	assert.InDelta(t, 133.333, rows["a"]["variance_latency"], 0.001)
+	assert.InDelta(t, 289.583, rows["b"]["variance_latency"], 0.001)
+	assert.InDelta(t, 11.547, rows["a"]["stdev_latency"], 0.001)
+	assert.InDelta(t, 17.017, rows["b"]["stdev_latency"], 0.001)
+
+	assert.Contains(t, []interface{}{"m1", "m3", "m5"}, rows["a"]["any_msg"])
+
+	assert.ElementsMatch(t, []interface{}{10.0, 30.0, 10.0}, jsonArray(t, rows["a"]["make_list_latency"]))
+	assert.ElementsMatch(t, []interface{}{10.0, 30.0}, jsonArray(t, rows["a"]["make_set_latency"]))
+	assert.ElementsMatch(t, []interface{}{20.0, 40.0, 5.0}, jsonArray(t, rows["b"]["make_set_latency"]))
+	assert.Len(t, jsonArray(t, rows["b"]["l"]), 1)
+
+}
+
+func TestAggFuncsWithoutValues(t *testing.T) {
+
+	result := aggregate(t,
+		`countif(latency > 100), variance(latency), any(msg), make_set(msg), arg_max(latency, msg)`,
+		&batchSourceOp{},
+	)
+
+	assert.Equal(t, map[string][]interface{}{
+		"countif_":         {int64(0)},
+		"variance_latency": {nil},
+		"any_msg":          {nil},
+		"make_set_msg":     {"[]"},
+		"latency":          {nil},
+		"msg":              {nil},
+	}, result)
+
+}
+
+func TestArgMaxOp(t *testing.T) {
+
+	inputs := func() []BatchOperator {
+		return []BatchOperator{
+			aggInput([]string{"a", "b", "a"}, []int64{10, 20, 30}, []string{"m1", "m2", "m3"}),
+			aggInput([]string{"b", "a", "b", "b"}, []int64{40, 10, 5, 40}, []string{"m4", "m5", "m6", "m7"}),
+		}
+	}
+
+	rows := rowsByKey(aggregate(t, "arg_max(latency, *) by host", inputs()...), "host")
+
+	assert.Equal(t, map[interface{}]map[string]interface{}{
+		"a": {"host": "a", "latency": int64(30), "msg": "m3"},
+		// ties keep the first row found.
+		"b": {"host": "b", "latency": int64(40), "msg": "m4"},
+	}, rows)
+
+	rows = rowsByKey(aggregate(t, "m = arg_min(latency, msg) by host", inputs()...), "host")
+
+	assert.Equal(t, "m1", rows["a"]["msg"])
+	assert.Equal(t, int64(10), rows["a"]["m"])
+	assert.Equal(t, "m6", rows["b"]["msg"])
+	assert.Equal(t, int64(5), rows["b"]["m"])
+
+}
+
+// sketchInput returns the latencies from..to-1 of the hosts a and b, the
+// values of a are repeated.
+func sketchInput(from, to int) BatchOperator {
+
+	var hosts, msgs []string
+	var latency []int64
+
+	for i := from; i < to; i++ {
+		hosts = append(hosts, "a", "a", "b")
+		latency = append(latency, int64(i), int64(i), int64(i*2))
+		msgs = append(msgs, "", "", "")
+	}
+
+	return aggInput(hosts, latency, msgs)
+
+}
+
+func TestSketchAggFuncs(t *testing.T) {
+
+	result := aggregate(t,
+		"dcount(latency), percentile(latency, 50), percentiles(latency, 5, 99.5), h = hll(latency) by host",
+		sketchInput(0, 5000),
+		sketchInput(5000, 10000),
+	)
+
+	rows := rowsByKey(result, "host")
+
+	assert.InEpsilon(t, 10000, rows["a"]["dcount_latency"], 0.02)
+	assert.InEpsilon(t, 10000, rows["b"]["dcount_latency"], 0.02)
+
+	assert.InDelta(t, 5000, rows["a"]["percentile_latency_50"], 20)
+	assert.InDelta(t, 500, rows["a"]["percentile_latency_5"], 20)
+	assert.InDelta(t, 9950, rows["a"]["percentile_latency_99_5"], 20)
+	assert.InDelta(t, 10000, rows["b"]["percentile_latency_50"], 40)
+	assert.IsType(t, int64(0), rows["b"]["percentile_latency_50"])
+
+	// the sketches returned by hll() are merged with hll_merge() and
+	// counted with dcount_hll().
+	sketches := testBatch(map[string]Col{"h": stringCol(0, rows["a"]["h"].(string), rows["b"]["h"].(string))})
+
+	counts := evalValues(t, "dcount_hll(h)", sketches)
+
+	assert.Equal(t, rows["a"]["dcount_latency"], counts[0])
+	assert.Equal(t, rows["b"]["dcount_latency"], counts[1])
+
+	merged := aggregate(t, "m = hll_merge(h)", &batchSourceOp{batches: []Batch{sketches}})
+	total := evalValues(t, "dcount_hll(m)", testBatch(map[string]Col{"m": stringCol(0, merged["m"][0].(string))}))
+
+	// a holds 0..9999 and b the even numbers up to 19998.
+	assert.InEpsilon(t, 15000, total[0], 0.02)
+
+}
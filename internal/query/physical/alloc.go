@@ -1,4 +1,139 @@
+// Copyright 2020 The Meerkat Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
 package physical
 
+import (
+	"fmt"
+	"meerkat/internal/query/execbase"
+	"sync/atomic"
+)
+
+// Allocator accounts the memory used by the operators of a query ( ie.
+// the batches being scanned or projected, the sort buffers and the
+// aggregation hash tables ).
 type Allocator interface {
+	// Reserve accounts n bytes. It panics with an ExecError if the query
+	// memory budget is exceeded.
+	Reserve(n int64)
+	// TryReserve accounts n bytes only if they fit in the budget.
+	TryReserve(n int64) bool
+	// Release returns n bytes to the budget.
+	Release(n int64)
+	// Used returns the bytes currently reserved.
+	Used() int64
+	// SpillDir returns the directory where the operators write the data
+	// that doesn't fit in the budget, spilling is disabled if it's empty.
+	SpillDir() string
+}
+
+// NewAllocator returns an Allocator with a budget of maxMemory bytes
+// shared by all the operators of a query on a node. A zero maxMemory
+// means no limit.
+func NewAllocator(maxMemory int64, spillDir string, nodeName string) Allocator {
+	return &budgetAllocator{
+		maxMemory: maxMemory,
+		spillDir:  spillDir,
+		nodeName:  nodeName,
+	}
+}
+
+type budgetAllocator struct {
+	used      int64
+	maxMemory int64
+	spillDir  string
+	nodeName  string
+}
+
+func (a *budgetAllocator) Reserve(n int64) {
+
+	used := atomic.AddInt64(&a.used, n)
+
+	if a.maxMemory > 0 && used > a.maxMemory {
+		panic(execbase.NewExecError(
+			fmt.Sprintf("query memory limit exceeded: %v bytes required, limit is %v bytes", used, a.maxMemory),
+			a.nodeName,
+		))
+	}
+
+}
+
+func (a *budgetAllocator) TryReserve(n int64) bool {
+
+	used := atomic.AddInt64(&a.used, n)
+
+	if a.maxMemory > 0 && used > a.maxMemory {
+		atomic.AddInt64(&a.used, -n)
+		return false
+	}
+
+	return true
+
+}
+
+func (a *budgetAllocator) Release(n int64)  { atomic.AddInt64(&a.used, -n) }
+func (a *budgetAllocator) Used() int64      { return atomic.LoadInt64(&a.used) }
+func (a *budgetAllocator) SpillDir() string { return a.spillDir }
+
+// batchAllocOp reserves the memory of the batches produced by its input
+// ( ie. a scan or a projection ) from the query budget. The consumer is
+// done with a batch once it asks for the next one so the batch is
+// released then, the operators retaining batches reserve them on their
+// own.
+type batchAllocOp struct {
+	input    BatchOperator
+	alloc    Allocator
+	reserved int64
+}
+
+// newBatchAllocOp wraps input, a nil alloc doesn't account the batches.
+func newBatchAllocOp(input BatchOperator, alloc Allocator) BatchOperator {
+
+	if alloc == nil {
+		return input
+	}
+
+	return &batchAllocOp{input: input, alloc: alloc}
+
+}
+
+func (b *batchAllocOp) Init() { b.input.Init() }
+
+func (b *batchAllocOp) Close() {
+	b.release()
+	b.input.Close()
+}
+
+func (b *batchAllocOp) Next() Batch {
+
+	b.release()
+
+	batch := b.input.Next()
+
+	if batch.Len > 0 {
+		b.reserved = batchBytes(batch)
+		b.alloc.Reserve(b.reserved)
+	}
+
+	return batch
+
+}
+
+func (b *batchAllocOp) release() {
+	b.alloc.Release(b.reserved)
+	b.reserved = 0
+}
+
+func (b *batchAllocOp) Accept(v Visitor) {
+	b.input = Walk(b.input, v).(BatchOperator)
 }
@@ -0,0 +1,113 @@
+// Copyright 2020 The Meerkat Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package physical
+
+import (
+	"github.com/stretchr/testify/assert"
+	"meerkat/internal/query/execbase"
+	"meerkat/internal/query/execpb"
+	"testing"
+)
+
+func TestAllocator(t *testing.T) {
+
+	alloc := NewAllocator(100, "", "node1")
+
+	alloc.Reserve(60)
+	alloc.Release(20)
+	alloc.Reserve(60)
+
+	assert.Equal(t, int64(100), alloc.Used())
+
+	func() {
+		defer func() {
+			execErr, ok := recover().(*execpb.ExecError)
+			assert.True(t, ok)
+			assert.Equal(t, "query memory limit exceeded: 101 bytes required, limit is 100 bytes", execErr.Detail)
+			assert.Equal(t, "node1", execErr.NodeName)
+		}()
+		alloc.Reserve(1)
+	}()
+
+	// the sort buffers are reserved from the query budget.
+	op := NewSortOp(&batchSourceOp{batches: []Batch{
+		testBatch(map[string]Col{"a": int64Col(0, 3, 2, 1)}),
+	}}, sortExpr(t, "a asc"), NewAllocator(16, "", "node1"))
+
+	assert.Panics(t, func() { op.Next() })
+
+}
+
+func TestScanGuard(t *testing.T) {
+
+	execCtx := execbase.NewExecutionContext()
+
+	guard := newScanGuard(execCtx, execbase.QueryLimits{MaxRowsScanned: 10}, "node1")
+
+	guard.check(5, 40)
+	guard.check(5, 40)
+
+	assert.Equal(t, Progress{RowsScanned: 10, BytesScanned: 80}, guard.progress())
+
+	assert.Panics(t, func() { guard.check(1, 8) })
+
+	guard = newScanGuard(execCtx, execbase.QueryLimits{MaxBytesScanned: 100}, "node1")
+
+	scan := newScanStatsOp(&batchSourceOp{batches: []Batch{
+		testBatch(map[string]Col{"a": int64Col(0, 1, 2, 3, 4, 5, 6, 7, 8)}),
+		testBatch(map[string]Col{"a": int64Col(0, 1, 2, 3, 4, 5, 6, 7, 8)}),
+	}}, "segment", guard)
+
+	assert.Equal(t, 8, scan.Next().Len)
+	assert.Panics(t, func() { scan.Next() })
+
+	// the scans stop once the query is canceled.
+	execCtx.Cancel()
+
+	guard = newScanGuard(execCtx, execbase.QueryLimits{}, "node1")
+
+	assert.Panics(t, func() { guard.check(1, 8) })
+
+}
+
+func TestBatchAllocOp(t *testing.T) {
+
+	alloc := NewAllocator(100, "", "node1")
+
+	op := newBatchAllocOp(&batchSourceOp{batches: []Batch{
+		testBatch(map[string]Col{"a": int64Col(0, 1, 2, 3, 4, 5, 6, 7, 8)}),
+		testBatch(map[string]Col{"a": int64Col(0, 1, 2, 3, 4, 5, 6, 7, 8)}),
+		testBatch(map[string]Col{"a": int64Col(0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13)}),
+	}}, alloc)
+
+	// the batches are released once the next one is requested.
+	op.Next()
+	assert.Equal(t, int64(64), alloc.Used())
+
+	op.Next()
+	assert.Equal(t, int64(64), alloc.Used())
+
+	assert.Panics(t, func() { op.Next() })
+
+	op = newBatchAllocOp(&batchSourceOp{batches: []Batch{
+		testBatch(map[string]Col{"a": int64Col(0, 1, 2)}),
+	}}, NewAllocator(0, "", "node1"))
+
+	op.Next()
+	op.Next()
+	op.Close()
+
+	assert.Equal(t, int64(0), op.(*batchAllocOp).alloc.Used())
+
+}
@@ -0,0 +1,224 @@
+// Copyright 2020 The Meerkat Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package physical
+
+import (
+	"fmt"
+	"github.com/apache/arrow/go/arrow"
+	"github.com/apache/arrow/go/arrow/array"
+	"github.com/apache/arrow/go/arrow/ipc"
+	"github.com/apache/arrow/go/arrow/memory"
+	"meerkat/internal/query/execbase"
+	"meerkat/internal/query/parser"
+	"meerkat/internal/storage"
+	"meerkat/internal/storage/vector"
+)
+
+// ArrowOutputOp writes the query results as an Arrow IPC stream, a record
+// for every batch. The schema is written before the first record, it has
+// the columns of the first batch and the other columns of the plan. A
+// column missing in a batch is written as nulls.
+type ArrowOutputOp struct {
+	input   BatchOperator
+	plan    map[string]parser.Type
+	writer  execbase.QueryOutputWriter
+	mem     memory.Allocator
+	columns []outputColumn
+	schema  *arrow.Schema
+	stream  *ipc.Writer
+}
+
+func NewArrowOutputOp(input BatchOperator, plan map[string]parser.Type, writer execbase.QueryOutputWriter) *ArrowOutputOp {
+	return &ArrowOutputOp{
+		input:  input,
+		plan:   plan,
+		writer: writer,
+		mem:    memory.NewGoAllocator(),
+	}
+}
+
+func (o *ArrowOutputOp) Init()  { o.input.Init() }
+func (o *ArrowOutputOp) Close() { o.input.Close() }
+
+func (o *ArrowOutputOp) Run() {
+
+	for {
+
+		batch := o.input.Next()
+
+		if o.stream == nil {
+			// an empty result is written as a stream without records.
+			o.startStream(batch)
+		}
+
+		if batch.Len == 0 {
+
+			err := o.stream.Close()
+
+			if err != nil {
+				panic(fmt.Sprintf("cannot close arrow stream: %v", err))
+			}
+
+			o.writer.Flush()
+
+			return
+
+		}
+
+		o.writeBatch(batch)
+
+	}
+
+}
+
+func (o *ArrowOutputOp) Accept(v Visitor) {
+	o.input = Walk(o.input, v).(BatchOperator)
+}
+
+func (o *ArrowOutputOp) startStream(first Batch) {
+
+	o.columns = outputColumns(first, o.plan)
+
+	fields := make([]arrow.Field, len(o.columns))
+
+	for i, c := range o.columns {
+		fields[i] = arrow.Field{
+			Name:     c.name,
+			Type:     arrowType(c.colType),
+			Nullable: true,
+		}
+	}
+
+	o.schema = arrow.NewSchema(fields, nil)
+	o.stream = ipc.NewWriter(o.writer, ipc.WithSchema(o.schema), ipc.WithAllocator(o.mem))
+
+}
+
+func (o *ArrowOutputOp) writeBatch(batch Batch) {
+
+	cols := make([]array.Interface, len(o.columns))
+
+	for i, c := range o.columns {
+		cols[i] = buildArrowArray(o.mem, o.schema.Field(i).Type, c.col(batch))
+		defer cols[i].Release()
+	}
+
+	record := array.NewRecord(o.schema, cols, int64(batch.Len))
+	defer record.Release()
+
+	err := o.stream.Write(record)
+
+	if err != nil {
+		panic(fmt.Sprintf("cannot write arrow record: %v", err))
+	}
+
+}
+
+func arrowType(columnType storage.ColumnType) arrow.DataType {
+
+	switch columnType {
+	case storage.ColumnType_INT64:
+		return arrow.PrimitiveTypes.Int64
+	case storage.ColumnType_TIMESTAMP, storage.ColumnType_DATETIME:
+		return arrow.FixedWidthTypes.Timestamp_ns
+	case storage.ColumnType_FLOAT64:
+		return arrow.PrimitiveTypes.Float64
+	case storage.ColumnType_BOOL:
+		return arrow.FixedWidthTypes.Boolean
+	case storage.ColumnType_STRING, storage.ColumnType_DYNAMIC:
+		// dynamic values are written as JSON text.
+		return arrow.BinaryTypes.String
+	default:
+		panic(fmt.Sprintf("column type %v not supported by the arrow output", columnType))
+	}
+
+}
+
+// buildArrowArray converts col to an arrow array of type dtype, the nulls
+// are taken from the vector validity. The values of a vector that doesn't
+// hold dtype values are written as nulls.
+func buildArrowArray(mem memory.Allocator, dtype arrow.DataType, col Col) array.Interface {
+
+	n := col.Vec.Len()
+
+	switch dtype.ID() {
+
+	case arrow.INT64:
+		b := array.NewInt64Builder(mem)
+		defer b.Release()
+		v, ok := col.Vec.(*vector.Int64Vector)
+		for i := 0; i < n; i++ {
+			if !ok || isNull(col.Vec, i) {
+				b.AppendNull()
+			} else {
+				b.Append(v.Get(i))
+			}
+		}
+		return b.NewArray()
+
+	case arrow.TIMESTAMP:
+		b := array.NewTimestampBuilder(mem, dtype.(*arrow.TimestampType))
+		defer b.Release()
+		v, ok := col.Vec.(*vector.Int64Vector)
+		for i := 0; i < n; i++ {
+			if !ok || isNull(col.Vec, i) {
+				b.AppendNull()
+			} else {
+				b.Append(arrow.Timestamp(v.Get(i)))
+			}
+		}
+		return b.NewArray()
+
+	case arrow.FLOAT64:
+		b := array.NewFloat64Builder(mem)
+		defer b.Release()
+		v, ok := col.Vec.(*vector.Float64Vector)
+		for i := 0; i < n; i++ {
+			if !ok || isNull(col.Vec, i) {
+				b.AppendNull()
+			} else {
+				b.Append(v.Get(i))
+			}
+		}
+		return b.NewArray()
+
+	case arrow.BOOL:
+		b := array.NewBooleanBuilder(mem)
+		defer b.Release()
+		v, ok := col.Vec.(*vector.BoolVector)
+		for i := 0; i < n; i++ {
+			if !ok || isNull(col.Vec, i) {
+				b.AppendNull()
+			} else {
+				b.Append(v.Get(i))
+			}
+		}
+		return b.NewArray()
+
+	default:
+		// the values of a column with mixed types are written as text.
+		b := array.NewStringBuilder(mem)
+		defer b.Release()
+		for i := 0; i < n; i++ {
+			if isNull(col.Vec, i) {
+				b.AppendNull()
+			} else {
+				b.Append(csvValue(col, i))
+			}
+		}
+		return b.NewArray()
+
+	}
+
+}
@@ -0,0 +1,183 @@
+// Copyright 2020 The Meerkat Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package physical
+
+import (
+	"bytes"
+	"github.com/apache/arrow/go/arrow/array"
+	"github.com/apache/arrow/go/arrow/ipc"
+	"github.com/stretchr/testify/assert"
+	"meerkat/internal/query/parser"
+	"testing"
+)
+
+type bufferOutputWriter struct {
+	bytes.Buffer
+}
+
+func (w *bufferOutputWriter) Flush()                   {}
+func (w *bufferOutputWriter) CloseNotify() <-chan bool { return nil }
+
+// writtenSourceOp records the bytes written to the output every time a
+// batch is read.
+type writtenSourceOp struct {
+	batchSourceOp
+	writer  *bufferOutputWriter
+	written []int
+}
+
+func (s *writtenSourceOp) Next() Batch {
+	s.written = append(s.written, s.writer.Len())
+	return s.batchSourceOp.Next()
+}
+
+func TestArrowOutputOp(t *testing.T) {
+
+	writer := &bufferOutputWriter{}
+
+	input := &writtenSourceOp{
+		batchSourceOp: batchSourceOp{batches: []Batch{
+			testBatch(map[string]Col{
+				"a": nullableInt64Col(0, 1, nil, 3),
+				"b": stringCol(1, "x", "y", "z"),
+			}),
+			testBatch(map[string]Col{
+				"a": nullableInt64Col(0, nil, 5),
+				"b": stringCol(1, "v", "w"),
+			}),
+			// c is missing in the first batch.
+			testBatch(map[string]Col{
+				"c": int64Col(0, 7),
+			}),
+		}},
+		writer: writer,
+	}
+
+	plan := map[string]parser.Type{
+		"a": parser.IntType,
+		"b": parser.StringType,
+		"c": parser.IntType,
+	}
+
+	NewArrowOutputOp(input, plan, writer).Run()
+
+	// every batch is written before the next one is read.
+	if assert.Len(t, input.written, 4) {
+		assert.Equal(t, 0, input.written[0])
+		assert.True(t, input.written[1] > 0)
+		assert.True(t, input.written[2] > input.written[1])
+		assert.True(t, input.written[3] > input.written[2])
+	}
+
+	reader, err := ipc.NewReader(&writer.Buffer)
+
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	defer reader.Release()
+
+	if !assert.Equal(t, 3, len(reader.Schema().Fields())) {
+		return
+	}
+
+	assert.Equal(t, "a", reader.Schema().Field(0).Name)
+	assert.Equal(t, "b", reader.Schema().Field(1).Name)
+	assert.Equal(t, "c", reader.Schema().Field(2).Name)
+
+	var a, c []interface{}
+	var b []int
+
+	for reader.Next() {
+
+		record := reader.Record()
+
+		a = appendInt64Values(a, record.Column(0).(*array.Int64))
+		b = append(b, record.Column(1).NullN())
+		c = appendInt64Values(c, record.Column(2).(*array.Int64))
+
+	}
+
+	assert.Equal(t, []interface{}{int64(1), nil, int64(3), nil, int64(5), nil}, a)
+	assert.Equal(t, []int{0, 0, 1}, b)
+	assert.Equal(t, []interface{}{nil, nil, nil, nil, nil, int64(7)}, c)
+
+}
+
+func TestArrowOutputOpMixedTypes(t *testing.T) {
+
+	writer := &bufferOutputWriter{}
+
+	input := &batchSourceOp{batches: []Batch{
+		testBatch(map[string]Col{"a": int64Col(0, 1)}),
+		testBatch(map[string]Col{"a": stringCol(0, "x")}),
+	}}
+
+	NewArrowOutputOp(input, map[string]parser.Type{"a": parser.UnknownType}, writer).Run()
+
+	reader, err := ipc.NewReader(&writer.Buffer)
+
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	defer reader.Release()
+
+	var a []string
+
+	for reader.Next() {
+		col := reader.Record().Column(0).(*array.String)
+		a = append(a, col.Value(0))
+	}
+
+	// the column has different types so the values are written as text.
+	assert.Equal(t, []string{"1", "x"}, a)
+
+}
+
+func TestArrowOutputOpEmpty(t *testing.T) {
+
+	writer := &bufferOutputWriter{}
+
+	NewArrowOutputOp(&batchSourceOp{}, map[string]parser.Type{"a": parser.IntType}, writer).Run()
+
+	reader, err := ipc.NewReader(&writer.Buffer)
+
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	defer reader.Release()
+
+	// the schema has the columns of the plan.
+	if assert.Len(t, reader.Schema().Fields(), 1) {
+		assert.Equal(t, "a", reader.Schema().Field(0).Name)
+	}
+	assert.False(t, reader.Next())
+
+}
+
+func appendInt64Values(values []interface{}, col *array.Int64) []interface{} {
+
+	for i := 0; i < col.Len(); i++ {
+		if col.IsNull(i) {
+			values = append(values, nil)
+		} else {
+			values = append(values, col.Value(i))
+		}
+	}
+
+	return values
+
+}
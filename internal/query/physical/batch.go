@@ -5,6 +5,10 @@ import (
 	"meerkat/internal/storage/vector"
 )
 
+// batchSize is the maximum number of rows in the batches emitted by the
+// operators that build their own output ( ie. aggregations ).
+const batchSize = 1024 * 8
+
 // Col represent a column inside a Batch.
 // In order to keep the column batch ordered as it was speficied in the query
 // column are aranged into groups ( ie mv-expand create a new group ) and each
@@ -18,6 +22,9 @@ type Col struct {
 	Vec vector.Vector
 	// ColumnType represent the column type
 	ColumnType storage.ColumnType
+	// Sorted is true if the values are known to be in ascending order
+	// within the batch, ie. the _ts column of a segment.
+	Sorted bool
 }
 
 type Batch struct {
@@ -1,13 +1,20 @@
 package physical
 
+import (
+	"fmt"
+	"meerkat/internal/storage"
+)
+
 type BatchBuilderOp struct {
 	colNames []string
+	colTypes []storage.ColumnType
 	input    []ColumnOperator
 }
 
-func NewBatchBuilderOp(input []ColumnOperator, colNames []string) *BatchBuilderOp {
+func NewBatchBuilderOp(input []ColumnOperator, colNames []string, colTypes []storage.ColumnType) *BatchBuilderOp {
 	return &BatchBuilderOp{
 		colNames: colNames,
+		colTypes: colTypes,
 		input:    input,
 	}
 }
@@ -34,9 +41,12 @@ func (b *BatchBuilderOp) Next() Batch {
 		v := b.input[i].Next()
 
 		batch.Columns[name] = Col{
-			Group: 0,
-			Order: int64(i),
-			Vec:   v,
+			Group:      0,
+			Order:      int64(i),
+			Vec:        v,
+			ColumnType: b.colTypes[i],
+			// segments are sorted by timestamp at ingestion.
+			Sorted: name == storage.TSColumnName,
 		}
 
 		if i != 0 && lastVectorLen != v.Len() {
@@ -58,3 +68,7 @@ func (b *BatchBuilderOp) Accept(v Visitor) {
 		b.input[i] = Walk(operator, v).(ColumnOperator)
 	}
 }
+
+func (b *BatchBuilderOp) describe() string {
+	return fmt.Sprintf("columns: %v", b.colNames)
+}
@@ -0,0 +1,70 @@
+// Copyright 2020 The Meerkat Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package physical
+
+// BroadcastOutOp sends every batch of its input to all the streams, it's
+// used to send the small side of a join to every node.
+type BroadcastOutOp struct {
+	input         BatchOperator
+	streams       []batchStream
+	localNodeName string
+}
+
+func NewBroadcastOutOp(input BatchOperator, streams []batchStream, localNodeName string) *BroadcastOutOp {
+	return &BroadcastOutOp{
+		input:         input,
+		streams:       streams,
+		localNodeName: localNodeName,
+	}
+}
+
+func (b *BroadcastOutOp) Init()  { b.input.Init() }
+func (b *BroadcastOutOp) Close() { b.input.Close() }
+
+func (b *BroadcastOutOp) Run() {
+
+	for _, stream := range b.streams {
+		stream.open()
+	}
+
+	for {
+
+		batch, err := safeNext(b.input)
+
+		if err != nil {
+			execErr := buildExecError(err, b.localNodeName)
+			for _, stream := range b.streams {
+				stream.fail(execErr)
+			}
+			panic(execErr)
+		}
+
+		if batch.Len == 0 {
+			for _, stream := range b.streams {
+				stream.close()
+			}
+			return
+		}
+
+		for _, stream := range b.streams {
+			stream.send(batch)
+		}
+
+	}
+
+}
+
+func (b *BroadcastOutOp) Accept(v Visitor) {
+	b.input = Walk(b.input, v).(BatchOperator)
+}
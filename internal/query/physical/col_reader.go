@@ -28,6 +28,9 @@ func (c *ColumnReaderOp) Next() vector.Vector {
 		case storage.Int64Iterator:
 			v := i.Next()
 			return &v
+		case storage.Float64Iterator:
+			v := i.Next()
+			return &v
 		case storage.ByteSliceIterator:
 			v := i.Next()
 			return &v
@@ -0,0 +1,146 @@
+// Copyright 2020 The Meerkat Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package physical
+
+import (
+	"encoding/csv"
+	"fmt"
+	"meerkat/internal/query/execbase"
+	"meerkat/internal/query/parser"
+	"meerkat/internal/storage"
+	"meerkat/internal/storage/vector"
+	"strconv"
+	"time"
+)
+
+// CSVOutputOp writes the query results as CSV rows preceded by a header.
+// The header has the columns of the first batch and the other columns of
+// the plan. Nulls and the columns missing in a batch are written as empty
+// fields.
+type CSVOutputOp struct {
+	input   BatchOperator
+	plan    map[string]parser.Type
+	writer  execbase.QueryOutputWriter
+	csv     *csv.Writer
+	columns []outputColumn
+	record  []string
+}
+
+func NewCSVOutputOp(input BatchOperator, plan map[string]parser.Type, writer execbase.QueryOutputWriter) *CSVOutputOp {
+	return &CSVOutputOp{
+		input:  input,
+		plan:   plan,
+		writer: writer,
+		csv:    csv.NewWriter(writer),
+	}
+}
+
+func (o *CSVOutputOp) Init()  { o.input.Init() }
+func (o *CSVOutputOp) Close() { o.input.Close() }
+
+func (o *CSVOutputOp) Run() {
+
+	for {
+
+		batch := o.input.Next()
+
+		if batch.Len == 0 {
+			o.writer.Flush()
+			return
+		}
+
+		o.writeBatch(batch)
+
+	}
+
+}
+
+func (o *CSVOutputOp) Accept(v Visitor) {
+	o.input = Walk(o.input, v).(BatchOperator)
+}
+
+func (o *CSVOutputOp) writeBatch(batch Batch) {
+
+	if o.columns == nil {
+
+		o.columns = outputColumns(batch, o.plan)
+		o.record = make([]string, len(o.columns))
+
+		for i, c := range o.columns {
+			o.record[i] = c.name
+		}
+
+		o.write(o.record)
+
+	}
+
+	cols := make([]Col, len(o.columns))
+
+	for i, c := range o.columns {
+		cols[i] = c.col(batch)
+	}
+
+	for row := 0; row < batch.Len; row++ {
+
+		for i, col := range cols {
+			o.record[i] = csvValue(col, row)
+		}
+
+		o.write(o.record)
+
+	}
+
+	o.csv.Flush()
+
+	if err := o.csv.Error(); err != nil {
+		panic(fmt.Sprintf("cannot write csv output: %v", err))
+	}
+
+}
+
+func (o *CSVOutputOp) write(record []string) {
+
+	err := o.csv.Write(record)
+
+	if err != nil {
+		panic(fmt.Sprintf("cannot write csv output: %v", err))
+	}
+
+}
+
+// csvValue formats the i-th value of col, timestamps are written in
+// RFC 3339 format.
+func csvValue(col Col, i int) string {
+
+	if isNull(col.Vec, i) {
+		return ""
+	}
+
+	switch v := col.Vec.(type) {
+	case *vector.Int64Vector:
+		if col.ColumnType == storage.ColumnType_TIMESTAMP || col.ColumnType == storage.ColumnType_DATETIME {
+			return toTime(v.Get(i)).Format(time.RFC3339Nano)
+		}
+		return strconv.FormatInt(v.Get(i), 10)
+	case *vector.Float64Vector:
+		return strconv.FormatFloat(v.Get(i), 'g', -1, 64)
+	case *vector.BoolVector:
+		return strconv.FormatBool(v.Get(i))
+	case *vector.ByteSliceVector:
+		return string(v.Get(i))
+	default:
+		panic(fmt.Sprintf("cannot convert vector %T to csv", col.Vec))
+	}
+
+}
@@ -3,16 +3,29 @@ package physical
 import (
 	"fmt"
 	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
 	"meerkat/internal/query/execbase"
 	"meerkat/internal/query/execpb"
 	"meerkat/internal/storage"
+	"os"
+	"runtime"
 	"runtime/debug"
 	"strings"
 	"sync"
+	"time"
 )
 
 type DAG interface {
 	Run()
+	// Explain returns the operator trees of the DAG without running it.
+	Explain() []*OperatorInfo
+	// Release releases the resources acquired by a DAG that will not run.
+	Release()
+	// Stats returns the execution stats of a DAG that has run.
+	Stats() *NodeStats
+	// Progress returns the data read so far by the scans of the DAG.
+	Progress() Progress
 }
 
 var _ DAG = &executableDAG{}
@@ -27,6 +40,18 @@ type executableDAG struct {
 	segments      []storage.Segment
 	segReg        storage.SegmentRegistry
 	localNodeName string
+	// segmentsPruned is the number of segments skipped by the sources.
+	segmentsPruned int
+	// runtimes holds the time spent running every runnable.
+	runtimes []time.Duration
+	// cpuTimes holds the CPU time consumed running every runnable.
+	cpuTimes []time.Duration
+	// stats holds the stats tree of every runnable.
+	stats []*OperatorStats
+	// spillDir holds the data spilled by the operators of the query.
+	spillDir string
+	guard    *scanGuard
+	log      zerolog.Logger
 }
 
 func NewDAG(
@@ -35,19 +60,30 @@ func NewDAG(
 	roots []RunnableOp,
 	queryId uuid.UUID,
 	segments []storage.Segment,
+	segmentsPruned int,
 	segReg storage.SegmentRegistry,
 	localNodeName string,
+	spillDir string,
+	guard *scanGuard,
 ) *executableDAG {
 
 	return &executableDAG{
-		execCtx:       execCtx,
-		runnables:     runnables,
-		roots:         roots,
-		queryId:       queryId,
-		wg:            &sync.WaitGroup{},
-		segments:      segments,
-		segReg:        segReg,
-		localNodeName: localNodeName,
+		execCtx:        execCtx,
+		runnables:      runnables,
+		roots:          roots,
+		queryId:        queryId,
+		wg:             &sync.WaitGroup{},
+		segments:       segments,
+		segmentsPruned: segmentsPruned,
+		segReg:         segReg,
+		localNodeName:  localNodeName,
+		runtimes:       make([]time.Duration, len(runnables)),
+		cpuTimes:       make([]time.Duration, len(runnables)),
+		spillDir:       spillDir,
+		guard:          guard,
+		log: log.With().
+			Str("component", "DAG").
+			Str("queryId", queryId.String()).Logger(),
 	}
 
 }
@@ -59,9 +95,13 @@ func (ed *executableDAG) Run() {
 	ed.Dump()
 
 	for _, runnable := range ed.runnables {
+		ed.stats = append(ed.stats, statsTree(runnable))
+	}
+
+	for i, runnable := range ed.runnables {
 		ed.wg.Add(1)
 		fmt.Printf("running op %T \n", runnable)
-		go ed.runOp(runnable)
+		go ed.runOp(i, runnable)
 	}
 
 	ed.wg.Wait()
@@ -69,13 +109,43 @@ func (ed *executableDAG) Run() {
 	// TODO(gvelo) close operators
 
 	ed.releaseSegments()
+	ed.removeSpillDir()
+
+}
+
+// removeSpillDir removes the runs left behind by the operators that
+// didn't finish.
+func (ed *executableDAG) removeSpillDir() {
+
+	if ed.spillDir == "" {
+		return
+	}
+
+	err := os.RemoveAll(ed.spillDir)
+
+	if err != nil {
+		ed.log.Error().Err(err).Str("spillDir", ed.spillDir).Msg("cannot remove spill directory")
+	}
 
 }
 
-func (ed *executableDAG) runOp(op RunnableOp) {
+func (ed *executableDAG) runOp(i int, op RunnableOp) {
 
 	defer ed.wg.Done()
 
+	// the goroutine is locked to its thread so the CPU time of the thread
+	// is the CPU time of the operators.
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	start := time.Now()
+	cpuStart := threadCPUTime()
+
+	defer func() {
+		ed.runtimes[i] = time.Since(start)
+		ed.cpuTimes[i] = threadCPUTime() - cpuStart
+	}()
+
 	defer func() {
 		if r := recover(); r != nil {
 
@@ -118,6 +188,43 @@ func (ed *executableDAG) releaseSegments() {
 
 }
 
+func (ed *executableDAG) Explain() []*OperatorInfo {
+
+	infos := make([]*OperatorInfo, len(ed.roots))
+
+	for i, root := range ed.roots {
+		infos[i] = Explain(root)
+	}
+
+	return infos
+
+}
+
+func (ed *executableDAG) Stats() *NodeStats {
+
+	stats := &NodeStats{
+		NodeId:          ed.localNodeName,
+		SegmentsScanned: len(ed.segments),
+		SegmentsPruned:  ed.segmentsPruned,
+	}
+
+	for i, root := range ed.stats {
+		finishRootStats(root, ed.runtimes[i], ed.cpuTimes[i])
+		stats.Operators = append(stats.Operators, root)
+	}
+
+	return stats
+
+}
+
+func (ed *executableDAG) Progress() Progress {
+	return ed.guard.progress()
+}
+
+func (ed *executableDAG) Release() {
+	ed.releaseSegments()
+}
+
 func (ed *executableDAG) Dump() {
 	v := &dagDumpVisitor{}
 	Walk(ed.roots[0], v)
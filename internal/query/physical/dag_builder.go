@@ -14,7 +14,6 @@
 package physical
 
 import (
-	"errors"
 	"fmt"
 	"github.com/google/uuid"
 	"meerkat/internal/cluster"
@@ -22,6 +21,8 @@ import (
 	"meerkat/internal/query/execpb"
 	"meerkat/internal/query/logical"
 	"meerkat/internal/storage"
+	"path/filepath"
+	"sort"
 )
 
 type DAGBuilder interface {
@@ -29,7 +30,9 @@ type DAGBuilder interface {
 		fragments []*logical.Fragment,
 		queryId uuid.UUID,
 		writer execbase.QueryOutputWriter,
+		format execbase.OutputFormat,
 		execCtx execbase.ExecutionContext,
+		limits execbase.QueryLimits,
 	) (DAG, error)
 }
 
@@ -37,12 +40,14 @@ func NewDAGBuilder(
 	nodeReg cluster.NodeRegistry,
 	segReg storage.SegmentRegistry,
 	streamReg StreamRegistry,
+	spillDir string,
 ) DAGBuilder {
 
 	return &dagBuilder{
 		nodeReg:   nodeReg,
 		segReg:    segReg,
 		streamReg: streamReg,
+		spillDir:  spillDir,
 	}
 
 }
@@ -51,46 +56,74 @@ type dagBuilder struct {
 	nodeReg   cluster.NodeRegistry
 	segReg    storage.SegmentRegistry
 	streamReg StreamRegistry
+	// spillDir is the directory where the queries spill the data that
+	// doesn't fit in memory, spilling is disabled if it's empty.
+	spillDir string
 }
 
 func (e *dagBuilder) BuildDAG(
 	fragments []*logical.Fragment,
 	queryId uuid.UUID,
 	writer execbase.QueryOutputWriter,
+	format execbase.OutputFormat,
 	execCtx execbase.ExecutionContext,
+	limits execbase.QueryLimits,
 ) (DAG, error) {
 
 	var segments []storage.Segment
+	var segmentsPruned int
 	var roots []RunnableOp
 	var runnables []RunnableOp
 	localStreamMap := make(map[int64]BatchOperator)
+	localStreams := make(map[int64]*localStream)
+
+	var spillDir string
+
+	if e.spillDir != "" {
+		spillDir = filepath.Join(e.spillDir, queryId.String())
+	}
+
+	// the limits are shared by all the fragments executed in this node.
+	alloc := NewAllocator(limits.MaxMemory, spillDir, e.nodeReg.LocalNodeId())
+	guard := newScanGuard(execCtx, limits, e.nodeReg.LocalNodeId())
 
 	for _, fragment := range fragments {
 
-		builder := &dagBuilderVisitor{
-			outputWriter:   writer,
-			queryId:        queryId,
-			nodeReg:        e.nodeReg,
-			streamReg:      e.streamReg,
-			segReg:         e.segReg,
-			localStreamMap: make(map[int64]BatchOperator),
-			execCtx:        execCtx,
-		}
+		// every root of a fragment builds its own branch of the DAG, ie.
+		// both sides of a shuffle join.
+		for _, root := range fragment.Roots {
+
+			builder := &dagBuilderVisitor{
+				outputWriter:   writer,
+				outputFormat:   format,
+				queryId:        queryId,
+				nodeReg:        e.nodeReg,
+				streamReg:      e.streamReg,
+				segReg:         e.segReg,
+				localStreamMap: make(map[int64]BatchOperator),
+				localStreams:   localStreams,
+				execCtx:        execCtx,
+				alloc:          alloc,
+				guard:          guard,
+				joins:          make(map[logical.Node]*logical.JoinOp),
+				joinLeft:       make(map[*logical.JoinOp][]BatchOperator),
+				unions:         make(map[logical.Node]*logical.UnionOp),
+				unionInputs:    make(map[*logical.UnionOp][]BatchOperator),
+			}
 
-		if len(fragment.Roots) > 1 {
-			return nil, errors.New("fragments with multiple roots ( forkOp ) not supported yet")
-		}
+			// TODO(gvelo) catch panics, release adquired segments
+			//  and return error
+			logical.Walk(root, builder)
 
-		// TODO(gvelo) catch panics, release adquired segments
-		//  and return error
-		logical.Walk(fragment.Roots[0], builder)
+			segments = append(segments, builder.segments...)
+			segmentsPruned += builder.segmentsPruned
+			roots = append(roots, builder.roots...)
+			runnables = append(runnables, builder.runnableOps...)
 
-		segments = append(segments, builder.segments...)
-		roots = append(roots, builder.roots...)
-		runnables = append(runnables, builder.runnableOps...)
+			for streamId, outputOp := range builder.localStreamMap {
+				localStreamMap[streamId] = outputOp
+			}
 
-		for streamId, outputOp := range builder.localStreamMap {
-			localStreamMap[streamId] = outputOp
 		}
 
 	}
@@ -108,8 +141,11 @@ func (e *dagBuilder) BuildDAG(
 		roots,
 		queryId,
 		segments,
+		segmentsPruned,
 		e.segReg,
 		e.nodeReg.LocalNodeId(),
+		spillDir,
+		guard,
 	)
 
 	return dag, nil
@@ -120,6 +156,7 @@ type dagBuilderVisitor struct {
 	child        []BatchOperator
 	roots        []RunnableOp
 	outputWriter execbase.QueryOutputWriter
+	outputFormat execbase.OutputFormat
 	queryId      uuid.UUID
 	nodeReg      cluster.NodeRegistry
 	streamReg    StreamRegistry
@@ -127,49 +164,96 @@ type dagBuilderVisitor struct {
 	// localStreamMap map local streams to the output operator. This operator
 	// will be used as input operator for local streams instead of a ExchangeInOp
 	localStreamMap map[int64]BatchOperator
-	segments       []storage.Segment
+	// localStreams holds the channel based streams shared by the fragments
+	// executed in the local node.
+	localStreams map[int64]*localStream
+	segments     []storage.Segment
+	// segmentsPruned is the number of segments skipped because they can't
+	// contain rows matching the source filters.
+	segmentsPruned int
 	runnableOps    []RunnableOp
 	execCtx        execbase.ExecutionContext
+	alloc          Allocator
+	guard          *scanGuard
+	// joins maps the right input of every join to the join and joinLeft
+	// holds the operators built for the left input of a join.
+	joins    map[logical.Node]*logical.JoinOp
+	joinLeft map[*logical.JoinOp][]BatchOperator
+	// unions maps the branches of every union to the union and
+	// unionInputs holds the operators built for the previous branches.
+	unions      map[logical.Node]*logical.UnionOp
+	unionInputs map[*logical.UnionOp][]BatchOperator
 }
 
-func (g *dagBuilderVisitor) VisitPre(n logical.Node) logical.Node { return n }
+func (g *dagBuilderVisitor) VisitPre(n logical.Node) logical.Node {
+
+	if join, ok := n.(*logical.JoinOp); ok {
+		g.joins[join.Right] = join
+	}
+
+	// the left input is complete once the right one is reached.
+	if join, found := g.joins[n]; found {
+		g.joinLeft[join] = g.child
+		g.child = nil
+	}
+
+	if union, ok := n.(*logical.UnionOp); ok {
+		for _, child := range union.Children {
+			g.unions[child] = union
+		}
+	}
+
+	// every branch of a union starts with no inputs.
+	if union, found := g.unions[n]; found {
+		g.unionInputs[union] = append(g.unionInputs[union], g.child...)
+		g.child = nil
+	}
+
+	return n
+
+}
 
 func (g *dagBuilderVisitor) VisitPost(n logical.Node) logical.Node {
 
 	switch node := n.(type) {
 	case *logical.OutputOp:
 		g.assertSingleInput()
-		jsonOutputOp := NewJsonOutputOp(g.child[0], g.outputWriter)
-		g.roots = append(g.roots, jsonOutputOp)
-		g.runnableOps = append(g.runnableOps, jsonOutputOp)
+
+		var outputOp RunnableOp
+
+		switch g.outputFormat {
+		case execbase.ArrowOutput:
+			outputOp = NewArrowOutputOp(g.child[0], node.Columns, g.outputWriter)
+		case execbase.CSVOutput:
+			outputOp = NewCSVOutputOp(g.child[0], node.Columns, g.outputWriter)
+		case execbase.NDJSONOutput:
+			outputOp = NewNDJSONOutputOp(g.child[0], node.Columns, g.outputWriter)
+		default:
+			outputOp = NewJsonOutputOp(g.child[0], g.outputWriter)
+		}
+
+		g.roots = append(g.roots, outputOp)
+		g.runnableOps = append(g.runnableOps, outputOp)
 	case *logical.SourceOp:
 
-		// TODO(gvelo) call Segments() with actual params
-		g.segments = g.segReg.Segments(nil, "", "")
+		segments := g.segReg.Segments(node.Partitions, node.Database, node.TableName, node.Interval)
+		g.segments = append(g.segments, segments...)
+		g.segmentsPruned += tableSegments(g.segReg, node) - len(segments)
 
 		var child []BatchOperator
 
 		// TODO(gvelo) if there are no segments available, we should provide an
 		// virtual empty segment which always return a zero vector. Or fail with
 		// a table not found ?
-		for _, segment := range g.segments {
-			op := buildBatchOp(segment) // TODO(gvelo) add columns and filter exp
-			child = append(child, op)
+		for _, segment := range segments {
+			child = append(child, buildScanOp(segment, node, g.guard, g.alloc))
 		}
 
 		g.child = child
 
 	case *logical.NodeOutOp:
 
-		var input BatchOperator
-
-		if len(g.child) > 1 {
-			// TODO(gvelo) user merge sort op
-			input = NewMergeOp(g.child)
-
-		} else {
-			input = g.child[0]
-		}
+		input := g.mergeChild()
 
 		streamId, found := node.StreamMap[g.nodeReg.LocalNodeId()]
 
@@ -201,6 +285,7 @@ func (g *dagBuilderVisitor) VisitPost(n logical.Node) logical.Node {
 				g.queryId,
 				streamId,
 				g.nodeReg.LocalNodeId(),
+				g.execCtx,
 			)
 
 			g.roots = append(g.roots, exchangeOutOp)
@@ -208,6 +293,31 @@ func (g *dagBuilderVisitor) VisitPost(n logical.Node) logical.Node {
 
 		}
 
+	case *logical.FilterOp:
+
+		// the filter is applied to every input
+		for i, child := range g.child {
+			g.child[i] = NewFilterOp(child, NewEvaluator(node.Predicate))
+		}
+
+	case *logical.ProjectOp:
+
+		for i, child := range g.child {
+			g.child[i] = newBatchAllocOp(NewProjectOp(child, node.Columns), g.alloc)
+		}
+
+	case *logical.ExtendOp:
+
+		for i, child := range g.child {
+			g.child[i] = newBatchAllocOp(NewExtendOp(child, node.Columns), g.alloc)
+		}
+
+	case *logical.BinaryExpr, *logical.UnaryExpr, *logical.CallExpr,
+		*logical.ColRefExpr, *logical.LiteralExpr, *logical.StarExpr, *logical.AggExpr,
+		*logical.ColumnExpr, *logical.SortExpr:
+
+		// expressions are compiled by the operator that owns them.
+
 	case *logical.MergeSortOp:
 
 		var inputs []BatchOperator
@@ -226,45 +336,330 @@ func (g *dagBuilderVisitor) VisitPost(n logical.Node) logical.Node {
 
 		}
 
-		mergeOp := NewMergeOp(inputs)
+		if len(node.SortExpr) == 0 {
+			g.child = []BatchOperator{NewGatherOp(inputs, g.execCtx)}
+		} else {
+			g.child = []BatchOperator{NewMergeSortOp(inputs, node.SortExpr)}
+		}
+
+	case *logical.SortOp:
+
+		g.child = []BatchOperator{NewSortOp(g.mergeChild(), node.SortExpr, g.alloc)}
+
+	case *logical.LimitOp:
+
+		var onLimit func()
+
+		if node.Stop {
+			// a graceful cancellation stops the nodes still sending rows.
+			onLimit = g.execCtx.Stop
+		}
+
+		g.child = []BatchOperator{NewLimitOp(g.mergeChild(), node.NumOfRows, onLimit)}
+
+	case *logical.TopOp:
+
+		g.child = []BatchOperator{NewTopOp(g.mergeChild(), node.NumOfRows, node.SortExpr)}
+
+	case *logical.LocalSummaryOp:
+
+		g.child = []BatchOperator{
+			NewHashAggOp(g.mergeChild(), node.By, node.Agg, PartialAgg, g.alloc),
+		}
+
+	case *logical.DistSummaryOp:
+
+		g.child = []BatchOperator{
+			NewHashAggOp(g.mergeChild(), node.By, node.Agg, MergeAgg, g.alloc),
+		}
+
+	case *logical.SummaryCollector:
+
+		g.child = []BatchOperator{
+			NewHashAggOp(g.mergeChild(), node.By, node.Agg, FinalAgg, g.alloc),
+		}
+
+	case *logical.HashExchangeOutOp:
+
+		localNodeId := g.nodeReg.LocalNodeId()
+
+		streamMap, found := node.Streams[localNodeId]
+
+		if !found {
+			panic(fmt.Sprintf("cannot found streams for node %v", localNodeId))
+		}
+
+		var streams []batchStream
+
+		// every node must use the same partition order.
+		for _, dst := range sortedKeys(streamMap) {
+			streams = append(streams, g.outStream(dst, streamMap[dst]))
+		}
+
+		hashExchangeOutOp := NewHashExchangeOutOp(
+			g.mergeChild(),
+			node.Keys,
+			streams,
+			localNodeId,
+		)
+
+		g.roots = append(g.roots, hashExchangeOutOp)
+		g.runnableOps = append(g.runnableOps, hashExchangeOutOp)
+		g.child = nil
+
+	case *logical.BroadcastOutOp:
+
+		var streams []batchStream
+
+		for _, dst := range sortedKeys(node.Streams) {
+			streams = append(streams, g.outStream(dst, node.Streams[dst]))
+		}
+
+		broadcastOutOp := NewBroadcastOutOp(g.mergeChild(), streams, g.nodeReg.LocalNodeId())
+
+		g.roots = append(g.roots, broadcastOutOp)
+		g.runnableOps = append(g.runnableOps, broadcastOutOp)
+		g.child = nil
+
+	case *logical.JoinOp:
+
+		right := g.mergeChild()
+		g.child = g.joinLeft[node]
+
+		g.child = []BatchOperator{
+			NewHashJoinOp(g.mergeChild(), right, node.Kind, node.Keys, g.alloc),
+		}
+
+	case *logical.UnionOp:
+
+		// the segments of all the tables are read in parallel.
+		g.child = append(g.unionInputs[node], g.child...)
+
+		for i, child := range g.child {
+			g.child[i] = newBatchAllocOp(NewUnionColumnsOp(child, node.Columns), g.alloc)
+		}
+
+	case *logical.HashExchangeInOp:
+
+		localNodeId := g.nodeReg.LocalNodeId()
+
+		var inputs []BatchOperator
+
+		for src, streamMap := range node.Streams {
 
-		g.child = []BatchOperator{mergeOp}
+			streamId, found := streamMap[localNodeId]
+
+			if !found {
+				panic(fmt.Sprintf("cannot found stream from %v to %v", src, localNodeId))
+			}
+
+			if src == localNodeId {
+				inputs = append(inputs, NewLocalStreamInOp(g.localStream(streamId)))
+			} else {
+				inputs = append(inputs, NewExchangeInOp(g.streamReg, streamId, g.queryId, g.execCtx))
+			}
+
+		}
+
+		g.child = []BatchOperator{NewGatherOp(inputs, g.execCtx)}
 
 	default:
 		panic("unknown operator")
 
 	}
 
+	// every operator records its execution stats.
+	for i, child := range g.child {
+		if _, ok := child.(*statsOp); !ok {
+			g.child[i] = newStatsOp(child)
+		}
+	}
+
 	return n
 
 }
 
+// mergeChild returns a single operator reading from all the child inputs.
+func (g *dagBuilderVisitor) mergeChild() BatchOperator {
+
+	if len(g.child) == 1 {
+		return g.child[0]
+	}
+
+	return newStatsOp(NewMergeOp(g.child))
+
+}
+
+// outStream returns the stream sending batches to the dst node.
+func (g *dagBuilderVisitor) outStream(dst string, streamId int64) batchStream {
+
+	if dst == g.nodeReg.LocalNodeId() {
+		return g.localStream(streamId)
+	}
+
+	dstClusterNode := g.nodeReg.Node(dst)
+
+	if dstClusterNode == nil {
+		panic(fmt.Sprintf("cannot found node %v", dst))
+	}
+
+	client := execpb.NewExecutorClient(dstClusterNode.ClientConn())
+
+	return newRemoteStream(client, g.queryId, streamId)
+
+}
+
+// localStream returns the local stream identified by streamId creating it
+// if it doesn't exist yet.
+func (g *dagBuilderVisitor) localStream(streamId int64) *localStream {
+
+	stream, found := g.localStreams[streamId]
+
+	if !found {
+		stream = newLocalStream(g.execCtx)
+		g.localStreams[streamId] = stream
+	}
+
+	return stream
+
+}
+
+func sortedKeys(m map[string]int64) []string {
+
+	keys := make([]string, 0, len(m))
+
+	for key := range m {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+
+}
+
 func (g *dagBuilderVisitor) assertSingleInput() {
 	if len(g.child) != 1 {
 		panic("expected single input")
 	}
 }
 
-func buildBatchOp(segment storage.Segment) BatchOperator {
+// byteSliceIterable is implemented by the string columns.
+type byteSliceIterable interface {
+	Iterator() storage.ByteSliceIterator
+}
+
+// buildScanOp creates an operator returning the rows of segment matching
+// the source filter. The column indexes are used to select the matching
+// rows when possible, so only those rows are read. If only the number of rows
+// is needed the segment isn't read at all. The batches read are reserved
+// from the alloc budget.
+func buildScanOp(segment storage.Segment, node *logical.SourceOp, guard *scanGuard, alloc Allocator) BatchOperator {
+
+	if node.CountOnly {
+		return newScanStatsOp(NewSegmentLenOp(segment), segmentId(segment), guard)
+	}
+
+	var op BatchOperator
+
+	if rows := intervalRows(segment, node.Interval); rows != nil {
+		op = NewIndexScanOp(segment, node.Columns, rows)
+	} else {
+		op = buildBatchOp(segment, node.Columns)
+	}
+
+	op = newScanStatsOp(newBatchAllocOp(op, alloc), segmentId(segment), guard)
+
+	if node.Filter != nil {
+		op = NewFilterOp(op, NewEvaluator(node.Filter))
+	}
+
+	return op
+
+}
+
+// tableSegments returns the number of segments of the table.
+func tableSegments(segReg storage.SegmentRegistry, source *logical.SourceOp) int {
+
+	n := 0
+
+	for _, info := range segReg.SegmentInfos() {
+		if info.DatabaseName == source.Database && info.TableName == source.TableName {
+			n++
+		}
+	}
+
+	return n
+
+}
+
+func segmentId(segment storage.Segment) string {
+
+	id, err := uuid.FromBytes(segment.Info().Id)
+
+	if err != nil {
+		return ""
+	}
+
+	return id.String()
+
+}
+
+// segmentColumns returns the info of the given columns of segment, all
+// the columns if columns is nil. Columns missing in the segment are
+// skipped and evaluate to null.
+func segmentColumns(segment storage.Segment, columns []string) []*storage.ColumnInfo {
 
 	info := segment.Info()
 
+	if columns == nil {
+		return info.Columns
+	}
+
+	read := make(map[string]bool, len(columns)+1)
+
+	for _, name := range columns {
+		read[name] = true
+	}
+
+	// the timestamp is always read so the batches have the right
+	// length even if the segment has none of the columns.
+	read[storage.TSColumnName] = true
+
+	var result []*storage.ColumnInfo
+
+	for _, columnInfo := range info.Columns {
+		if read[columnInfo.Name] {
+			result = append(result, columnInfo)
+		}
+	}
+
+	return result
+
+}
+
+// buildBatchOp creates an operator reading the given columns of segment,
+// all the columns are read if columns is nil.
+func buildBatchOp(segment storage.Segment, columns []string) BatchOperator {
+
 	var input []ColumnOperator
 	var colNames []string
+	var colTypes []storage.ColumnType
 
-	for _, columnInfo := range info.Columns {
+	for _, columnInfo := range segmentColumns(segment, columns) {
 
 		colNames = append(colNames, columnInfo.Name)
+		colTypes = append(colTypes, columnInfo.ColumnType)
 		col := segment.Column(columnInfo.Name)
 		var iter storage.Iterator
 
 		switch c := col.(type) {
 		case storage.Int64Column:
 			iter = c.Iterator()
-		case storage.ByteSliceColumn:
-			iter = c.Iterator()
 		case storage.Float64Column:
 			iter = c.Iterator()
+		case byteSliceIterable:
+			iter = c.Iterator()
 		default:
 			panic(fmt.Sprintf("unknown column type : %T", col))
 
@@ -274,7 +669,7 @@ func buildBatchOp(segment storage.Segment) BatchOperator {
 		input = append(input, op)
 	}
 
-	batchBuilder := NewBatchBuilderOp(input, colNames)
+	batchBuilder := NewBatchBuilderOp(input, colNames, colTypes)
 
 	return batchBuilder
 
@@ -0,0 +1,127 @@
+// Copyright 2020 The Meerkat Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package physical
+
+import (
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"meerkat/internal/cluster"
+	"meerkat/internal/query/execbase"
+	"meerkat/internal/query/logical"
+	"meerkat/internal/storage"
+	"testing"
+)
+
+type testNodeRegistry struct {
+	cluster.NodeRegistry
+}
+
+func (r *testNodeRegistry) LocalNodeId() string { return "node-1" }
+
+// testSegmentRegistry is an in memory SegmentRegistry which keeps the
+// reference count of its segments.
+type testSegmentRegistry struct {
+	storage.SegmentRegistry
+	segments []storage.Segment
+	refs     map[uuid.UUID]int
+}
+
+func newTestSegmentRegistry(segments ...storage.Segment) *testSegmentRegistry {
+	return &testSegmentRegistry{segments: segments, refs: make(map[uuid.UUID]int)}
+}
+
+func (r *testSegmentRegistry) SegmentInfos() []*storage.SegmentInfo {
+
+	var infos []*storage.SegmentInfo
+
+	for _, segment := range r.segments {
+		infos = append(infos, segment.Info())
+	}
+
+	return infos
+
+}
+
+func (r *testSegmentRegistry) Segments(_ []uint64, dbName string, tableName string, _ *storage.Interval) []storage.Segment {
+
+	var segments []storage.Segment
+
+	for _, segment := range r.segments {
+
+		info := segment.Info()
+
+		if info.DatabaseName != dbName || info.TableName != tableName {
+			continue
+		}
+
+		r.refs[uuid.Must(uuid.FromBytes(info.Id))]++
+		segments = append(segments, segment)
+
+	}
+
+	return segments
+
+}
+
+func (r *testSegmentRegistry) Release(segmentId uuid.UUID) {
+	r.refs[segmentId]--
+}
+
+func newTableSegment(dbName string, tableName string) *testSegment {
+
+	id := uuid.New()
+
+	return &testSegment{
+		info: &storage.SegmentInfo{
+			Id:           id[:],
+			DatabaseName: dbName,
+			TableName:    tableName,
+			Len:          10,
+		},
+	}
+
+}
+
+func TestBuildDAGDatabase(t *testing.T) {
+
+	logs := newTableSegment("prod", "logs")
+	otherLogs := newTableSegment("", "logs")
+	segReg := newTestSegmentRegistry(logs, otherLogs)
+
+	fragments := []*logical.Fragment{
+		{
+			Roots: []logical.Node{
+				&logical.OutputOp{
+					Child: &logical.SourceOp{Database: "prod", TableName: "logs", CountOnly: true},
+				},
+			},
+		},
+	}
+
+	builder := NewDAGBuilder(&testNodeRegistry{}, segReg, nil, "")
+
+	dag, err := builder.BuildDAG(fragments, uuid.New(), nil, execbase.JSONOutput, nil, execbase.QueryLimits{})
+	assert.NoError(t, err)
+
+	logsId := uuid.Must(uuid.FromBytes(logs.info.Id))
+	otherLogsId := uuid.Must(uuid.FromBytes(otherLogs.info.Id))
+
+	assert.Equal(t, map[uuid.UUID]int{logsId: 1}, segReg.refs)
+
+	dag.Release()
+
+	assert.Equal(t, 0, segReg.refs[logsId])
+	assert.Equal(t, 0, segReg.refs[otherLogsId])
+
+}
@@ -136,7 +136,11 @@ func buildVector(column *execpb.Column) vector.Vector {
 		data := sliceutil.B2F(column.Vector)
 		vec := vector.NewFloat64Vector(data, validity)
 		return &vec
-	case storage.ColumnType_STRING:
+	case storage.ColumnType_BOOL:
+		data := sliceutil.B2Bool(column.Vector)
+		vec := vector.NewBoolVector(data, validity)
+		return &vec
+	case storage.ColumnType_STRING, storage.ColumnType_DYNAMIC:
 		offset := sliceutil.B2I(column.Offsets)
 		vec := vector.NewByteSliceVector(column.Vector, offset, validity)
 		return &vec
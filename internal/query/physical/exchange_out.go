@@ -16,6 +16,7 @@ type ExchangeOutOp struct {
 	queryId       uuid.UUID
 	streamId      int64
 	localNodeName string
+	execCtx       execbase.ExecutionContext
 }
 
 func NewExchangeOutOp(
@@ -24,6 +25,7 @@ func NewExchangeOutOp(
 	queryId uuid.UUID,
 	streamId int64,
 	localNodeName string,
+	execCtx execbase.ExecutionContext,
 ) *ExchangeOutOp {
 
 	return &ExchangeOutOp{
@@ -32,6 +34,7 @@ func NewExchangeOutOp(
 		queryId:       queryId,
 		streamId:      streamId,
 		localNodeName: localNodeName,
+		execCtx:       execCtx,
 	}
 
 }
@@ -41,102 +44,196 @@ func (e *ExchangeOutOp) Close() { e.input.Close() }
 
 func (e *ExchangeOutOp) Run() {
 
+	stream := newRemoteStream(e.execClient, e.queryId, e.streamId)
+
+	stream.open()
+
+	for {
+
+		if e.stopped() {
+			stream.abort()
+			return
+		}
+
+		v, err := safeNext(e.input)
+
+		if err != nil {
+			execErr := buildExecError(err, e.localNodeName)
+			stream.fail(execErr)
+			panic(execErr)
+		}
+
+		if v.Len == 0 {
+			stream.close()
+			return
+		}
+
+		stream.send(v)
+
+	}
+}
+
+// stopped reports whether the query was stopped without errors, ie. the
+// coordinator has received all the rows it needs. A query canceled due to
+// an error aborts the operator.
+func (e *ExchangeOutOp) stopped() bool {
+
+	select {
+	case <-e.execCtx.Done():
+		if err := e.execCtx.Err(); err != nil {
+			panic(err)
+		}
+		return true
+	default:
+		return false
+	}
+
+}
+
+// safeNext calls input.Next() returning the recovered panic, if any, as
+// an error.
+func safeNext(input BatchOperator) (batch Batch, err interface{}) {
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = r
+		}
+	}()
+
+	batch = input.Next()
+
+	return
+
+}
+
+// buildExecError extracts the ExecError carried by err in order to
+// propagate it over the stream or builds a new one.
+func buildExecError(err interface{}, localNodeName string) *execpb.ExecError {
+
+	if execErr, ok := err.(*execpb.ExecError); ok {
+		return execErr
+	}
+
+	execErr := execbase.ExtractExecError(err)
+
+	if execErr == nil {
+		execErr = execbase.NewExecError(
+			fmt.Sprintf("error executing query : %v", err),
+			localNodeName,
+		)
+	}
+
+	return execErr
+
+}
+
+// batchStream is the sending side of a stream of batches.
+type batchStream interface {
+	open()
+	send(batch Batch)
+	// fail propagates an error to the receiving side.
+	fail(execErr *execpb.ExecError)
+	close()
+}
+
+// remoteStream sends batches to another node using the VectorExchange rpc.
+type remoteStream struct {
+	execClient execpb.ExecutorClient
+	queryId    uuid.UUID
+	streamId   int64
+	client     execpb.Executor_VectorExchangeClient
+}
+
+func newRemoteStream(execClient execpb.ExecutorClient, queryId uuid.UUID, streamId int64) *remoteStream {
+	return &remoteStream{
+		execClient: execClient,
+		queryId:    queryId,
+		streamId:   streamId,
+	}
+}
+
+func (s *remoteStream) open() {
+
 	// TODO(gvelo) use a execCtx child here
-	vectorExchangeClient, err := e.execClient.VectorExchange(context.TODO())
+	client, err := s.execClient.VectorExchange(context.TODO())
 
 	if err != nil {
 		panic(err)
 	}
 
+	s.client = client
+
 	headerMsg := &execpb.VectorExchangeMsg{
 		Msg: &execpb.VectorExchangeMsg_Header{
 			Header: &execpb.StreamHeader{
-				QueryId:  e.queryId[:],
-				StreamId: e.streamId,
+				QueryId:  s.queryId[:],
+				StreamId: s.streamId,
 			},
 		},
 	}
 
-	err = vectorExchangeClient.Send(headerMsg)
+	err = s.client.Send(headerMsg)
 
 	if err != nil {
 		panic(err)
 	}
 
-	for {
-
-		v, err := e.safeNext()
-
-		if err != nil {
-
-			// extract the execError to propagate it over the stream.
-			execErr := execbase.ExtractExecError(err)
-
-			if execErr == nil {
-				execErr = execbase.NewExecError(
-					fmt.Sprintf("error executing query : %v", err),
-					e.localNodeName,
-				)
-			}
-
-			vectorMsg := &execpb.VectorExchangeMsg{
-				Msg: &execpb.VectorExchangeMsg_Error{
-					Error: execErr,
-				},
-			}
-
-			err := vectorExchangeClient.Send(vectorMsg)
-
-			if err != nil {
-				// TODO(gvelo): nothing we can do here , just log properly
-				fmt.Println(err)
-			}
+}
 
-			panic(execErr)
+func (s *remoteStream) send(batch Batch) {
 
-		}
+	vectorMsg := &execpb.VectorExchangeMsg{
+		Msg: &execpb.VectorExchangeMsg_VectorBatch{
+			VectorBatch: &execpb.VectorBatch{
+				Len:     int64(batch.Len),
+				Columns: buildColumns(batch),
+			},
+		},
+	}
 
-		if v.Len == 0 {
+	err := s.client.Send(vectorMsg)
 
-			_, err := vectorExchangeClient.CloseAndRecv() // EOF
+	if err != nil {
+		panic(err)
+	}
 
-			if err != nil && err != io.EOF {
-				panic(err) // TODO(gvelo) panic ?
-			}
+}
 
-			return
-		}
+func (s *remoteStream) fail(execErr *execpb.ExecError) {
 
-		vectorMsg := &execpb.VectorExchangeMsg{
-			Msg: &execpb.VectorExchangeMsg_VectorBatch{
-				VectorBatch: &execpb.VectorBatch{
-					Len:     int64(v.Len),
-					Columns: buildColumns(v),
-				},
-			},
-		}
+	if s.client == nil {
+		return
+	}
 
-		err = vectorExchangeClient.Send(vectorMsg)
+	vectorMsg := &execpb.VectorExchangeMsg{
+		Msg: &execpb.VectorExchangeMsg_Error{
+			Error: execErr,
+		},
+	}
 
-		if err != nil {
-			panic(err)
-		}
+	err := s.client.Send(vectorMsg)
 
+	if err != nil {
+		// TODO(gvelo): nothing we can do here , just log properly
+		fmt.Println(err)
 	}
+
 }
 
-func (e *ExchangeOutOp) safeNext() (batch Batch, err interface{}) {
+func (s *remoteStream) close() {
 
-	defer func() {
-		if r := recover(); r != nil {
-			err = r
-		}
-	}()
+	_, err := s.client.CloseAndRecv() // EOF
 
-	batch = e.input.Next()
+	if err != nil && err != io.EOF {
+		panic(err) // TODO(gvelo) panic ?
+	}
 
-	return
+}
 
+// abort closes the sending side of the stream without waiting for the
+// receiver, which may be already gone.
+func (s *remoteStream) abort() {
+	_ = s.client.CloseSend()
 }
 
 func (e *ExchangeOutOp) Accept(v Visitor) {
@@ -149,6 +246,11 @@ func buildColumns(batch Batch) []*execpb.Column {
 
 	for name, col := range batch.Columns {
 
+		// untyped nulls are sent as nulls of the column type.
+		if _, ok := col.Vec.(*nullVector); ok {
+			col.Vec = newNullVector(col.ColumnType, col.Vec.Len())
+		}
+
 		colProto := &execpb.Column{
 			Name:     name,
 			Group:    col.Group,
@@ -0,0 +1,89 @@
+// Copyright 2020 The Meerkat Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package physical
+
+// OperatorInfo describes a physical operator and its inputs.
+type OperatorInfo struct {
+	Operator string          `json:"operator"`
+	Details  string          `json:"details,omitempty"`
+	Inputs   []*OperatorInfo `json:"inputs,omitempty"`
+}
+
+// describer is implemented by the operators adding details to the
+// explained plan.
+type describer interface {
+	describe() string
+}
+
+// Explain returns the tree of operators rooted at op.
+func Explain(op Operator) *OperatorInfo {
+	v := &explainVisitor{}
+	Walk(op, v)
+	return v.root
+}
+
+type explainVisitor struct {
+	stack []*OperatorInfo
+	root  *OperatorInfo
+}
+
+func (e *explainVisitor) VisitPre(n Operator) Operator {
+
+	// the operators recording stats or accounting the batches are not
+	// part of the plan.
+	if isAccountingOp(n) {
+		return n
+	}
+
+	info := &OperatorInfo{
+		Operator: operatorName(n),
+	}
+
+	if d, ok := n.(describer); ok {
+		info.Details = d.describe()
+	}
+
+	if len(e.stack) > 0 {
+		parent := e.stack[len(e.stack)-1]
+		parent.Inputs = append(parent.Inputs, info)
+	} else {
+		e.root = info
+	}
+
+	e.stack = append(e.stack, info)
+
+	return n
+
+}
+
+func (e *explainVisitor) VisitPost(n Operator) Operator {
+
+	if isAccountingOp(n) {
+		return n
+	}
+
+	e.stack = e.stack[:len(e.stack)-1]
+
+	return n
+
+}
+
+func isAccountingOp(n Operator) bool {
+	switch n.(type) {
+	case *statsOp, *batchAllocOp:
+		return true
+	default:
+		return false
+	}
+}
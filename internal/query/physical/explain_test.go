@@ -0,0 +1,62 @@
+// Copyright 2020 The Meerkat Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package physical
+
+import (
+	"github.com/stretchr/testify/assert"
+	"meerkat/internal/query/logical"
+	"meerkat/internal/storage"
+	"testing"
+	"time"
+)
+
+func TestExplain(t *testing.T) {
+
+	source := &logical.SourceOp{
+		TableName: "T",
+		Columns:   []string{"n", "host"},
+		Filter:    predicate(t, `host == "web-1" and n > 1`),
+		Interval: &storage.Interval{
+			From: time.Unix(0, 0).UTC(),
+			To:   time.Unix(0, 30).UTC(),
+		},
+	}
+
+	scan := buildScanOp(newIndexedTestSegment(), source, nil, nil)
+	op := NewLimitOp(NewMergeOp([]BatchOperator{scan, &batchSourceOp{}}), 10, nil)
+
+	expected := &OperatorInfo{
+		Operator: "LimitOp",
+		Inputs: []*OperatorInfo{
+			{
+				Operator: "MergeOp",
+				Inputs: []*OperatorInfo{
+					{
+						Operator: "FilterOp",
+						Inputs: []*OperatorInfo{
+							{
+								Operator: "IndexScanOp",
+								Details:  "columns: [_ts n host] rows: 3",
+							},
+						},
+					},
+					{Operator: "batchSourceOp"},
+				},
+			},
+		},
+	}
+
+	assert.Equal(t, expected, Explain(op))
+
+}
@@ -0,0 +1,336 @@
+// Copyright 2020 The Meerkat Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package physical
+
+import (
+	"fmt"
+	"meerkat/internal/query/logical"
+	"meerkat/internal/storage"
+	"meerkat/internal/storage/vector"
+	"time"
+)
+
+// Evaluator evaluates a scalar expression over a Batch. The result is a
+// column with one value for each row in the batch.
+type Evaluator interface {
+	Eval(batch Batch) Col
+}
+
+// NewEvaluator builds the Evaluator tree for the given logical expression.
+func NewEvaluator(expr logical.Node) Evaluator {
+
+	switch e := expr.(type) {
+	case *logical.ColRefExpr:
+		return &colRefEvaluator{name: e.Name}
+	case *logical.LiteralExpr:
+		return newLiteralEvaluator(e.Value)
+	case *logical.BinaryExpr:
+		return &binaryEvaluator{
+			left:  NewEvaluator(e.LeftExpr),
+			op:    e.Op,
+			right: NewEvaluator(e.RightExpr),
+		}
+	case *logical.UnaryExpr:
+		return &unaryEvaluator{
+			op:   e.Op,
+			expr: NewEvaluator(e.Expr),
+		}
+	case *logical.CallExpr:
+		args := make([]Evaluator, len(e.ArgList))
+		for i, arg := range e.ArgList {
+			args[i] = NewEvaluator(arg)
+		}
+		return newCallEvaluator(e.FuncName, args)
+	default:
+		panic(fmt.Sprintf("unsupported expression %T", expr))
+	}
+
+}
+
+type colRefEvaluator struct {
+	name string
+}
+
+func (e *colRefEvaluator) Eval(batch Batch) Col {
+
+	col, found := batch.Columns[e.name]
+
+	if !found {
+		// columns are not required to be present in every segment
+		// so a missing column is evaluated as null.
+		return Col{Vec: &nullVector{l: batch.Len}}
+	}
+
+	return col
+
+}
+
+type literalEvaluator struct {
+	value   interface{}
+	colType storage.ColumnType
+}
+
+func newLiteralEvaluator(value interface{}) *literalEvaluator {
+
+	e := &literalEvaluator{value: value}
+
+	switch value.(type) {
+	case int, int64, time.Duration:
+		e.colType = storage.ColumnType_INT64
+	case time.Time:
+		e.colType = storage.ColumnType_TIMESTAMP
+	case float64:
+		e.colType = storage.ColumnType_FLOAT64
+	case string:
+		e.colType = storage.ColumnType_STRING
+	case bool:
+		e.colType = storage.ColumnType_BOOL
+	case nil:
+	default:
+		panic(fmt.Sprintf("unsupported literal type %T", value))
+	}
+
+	return e
+
+}
+
+func (e *literalEvaluator) Eval(batch Batch) Col {
+	return Col{
+		Vec:        constVector(e.value, batch.Len),
+		ColumnType: e.colType,
+	}
+}
+
+// constVector creates a vector of length n where all the values are equal
+// to value.
+func constVector(value interface{}, n int) vector.Vector {
+
+	switch v := value.(type) {
+	case int:
+		return constVector(int64(v), n)
+	case time.Duration:
+		return constVector(int64(v), n)
+	case time.Time:
+		return constVector(v.UnixNano(), n)
+	case int64:
+		buf := make([]int64, n)
+		for i := range buf {
+			buf[i] = v
+		}
+		vec := vector.NewInt64Vector(buf, nil)
+		return &vec
+	case float64:
+		buf := make([]float64, n)
+		for i := range buf {
+			buf[i] = v
+		}
+		vec := vector.NewFloat64Vector(buf, nil)
+		return &vec
+	case bool:
+		buf := make([]bool, n)
+		for i := range buf {
+			buf[i] = v
+		}
+		vec := vector.NewBoolVector(buf, nil)
+		return &vec
+	case string:
+		buf := make([]byte, 0, len(v)*n)
+		offsets := make([]int, n)
+		for i := range offsets {
+			buf = append(buf, v...)
+			offsets[i] = len(buf)
+		}
+		vec := vector.NewByteSliceVector(buf, offsets, nil)
+		return &vec
+	case nil:
+		return &nullVector{l: n}
+	default:
+		panic(fmt.Sprintf("unsupported literal type %T", value))
+	}
+
+}
+
+type binaryEvaluator struct {
+	left  Evaluator
+	op    logical.Operator
+	right Evaluator
+	// regex cache used by the matches operator.
+	regex regexCache
+}
+
+func (e *binaryEvaluator) Eval(batch Batch) Col {
+
+	l := e.left.Eval(batch)
+	r := e.right.Eval(batch)
+
+	switch e.op {
+	case logical.AND, logical.OR:
+		return evalLogic(e.op, l, r, batch.Len)
+	case logical.EQL, logical.NEQ, logical.LSS, logical.GTR, logical.LEQ, logical.GEQ:
+		return evalCompare(e.op, l, r, batch.Len)
+	case logical.ADD, logical.SUB, logical.MUL, logical.QUO, logical.REM:
+		return evalArith(e.op, l, r, batch.Len)
+	default:
+		if isStringOp(e.op) {
+			return evalStringOp(e.op, l, r, batch.Len, &e.regex)
+		}
+		panic(fmt.Sprintf("operator %q not supported", e.op))
+	}
+
+}
+
+type unaryEvaluator struct {
+	op   logical.Operator
+	expr Evaluator
+}
+
+func (e *unaryEvaluator) Eval(batch Batch) Col {
+
+	col := e.expr.Eval(batch)
+
+	switch e.op {
+	case logical.ADD:
+		return col
+	case logical.SUB:
+		return evalNegate(col)
+	default:
+		panic(fmt.Sprintf("unary operator %q not supported", e.op))
+	}
+
+}
+
+func evalNegate(col Col) Col {
+
+	switch v := col.Vec.(type) {
+	case *vector.Int64Vector:
+		src := v.Values()
+		buf := make([]int64, len(src))
+		for i, x := range src {
+			buf[i] = -x
+		}
+		r := vector.NewInt64Vector(buf, mergeValidity(len(src), v))
+		return Col{Vec: &r, ColumnType: col.ColumnType}
+	case *vector.Float64Vector:
+		src := v.Values()
+		buf := make([]float64, len(src))
+		for i, x := range src {
+			buf[i] = -x
+		}
+		r := vector.NewFloat64Vector(buf, mergeValidity(len(src), v))
+		return Col{Vec: &r, ColumnType: col.ColumnType}
+	case *nullVector:
+		return col
+	default:
+		panic(fmt.Sprintf("cannot negate a value of type %v", col.ColumnType))
+	}
+
+}
+
+// evalLogic evaluates the and/or operators using three-valued logic.
+func evalLogic(op logical.Operator, l, r Col, n int) Col {
+
+	lv := asBoolVector(l, op)
+	rv := asBoolVector(r, op)
+
+	buf := make([]bool, n)
+	var valid []uint64
+
+	if lv.HasNulls() || rv.HasNulls() {
+		valid = newValidity(n)
+	}
+
+	for i := 0; i < n; i++ {
+
+		lNull, rNull := isNull(lv, i), isNull(rv, i)
+		a, b := !lNull && lv.Get(i), !rNull && rv.Get(i)
+
+		var value, null bool
+
+		if op == logical.AND {
+			// false and null = false
+			lFalse, rFalse := !lNull && !a, !rNull && !b
+			value = a && b
+			null = (lNull || rNull) && !lFalse && !rFalse
+		} else {
+			// true or null = true
+			value = a || b
+			null = (lNull || rNull) && !value
+		}
+
+		buf[i] = value
+
+		if valid != nil && !null {
+			setValid(valid, i)
+		}
+
+	}
+
+	vec := vector.NewBoolVector(buf, valid)
+
+	return Col{Vec: &vec, ColumnType: storage.ColumnType_BOOL}
+
+}
+
+func asBoolVector(col Col, op logical.Operator) *vector.BoolVector {
+
+	switch v := col.Vec.(type) {
+	case *vector.BoolVector:
+		return v
+	case *nullVector:
+		r := vector.NewBoolVector(make([]bool, v.Len()), newValidity(v.Len()))
+		return &r
+	default:
+		panic(fmt.Sprintf("operator %q expects bool operands, found %v", op, col.ColumnType))
+	}
+
+}
+
+func isIntegerType(t storage.ColumnType) bool {
+	return t == storage.ColumnType_INT64 ||
+		t == storage.ColumnType_TIMESTAMP ||
+		t == storage.ColumnType_DATETIME
+}
+
+func isNumericType(t storage.ColumnType) bool {
+	return isIntegerType(t) || t == storage.ColumnType_FLOAT64
+}
+
+// asFloat64 returns the values of a numeric column as float64.
+func asFloat64(col Col) []float64 {
+
+	switch v := col.Vec.(type) {
+	case *vector.Float64Vector:
+		return v.Values()
+	case *vector.Int64Vector:
+		src := v.Values()
+		buf := make([]float64, len(src))
+		for i, x := range src {
+			buf[i] = float64(x)
+		}
+		return buf
+	default:
+		panic(fmt.Sprintf("cannot convert %v to float64", col.ColumnType))
+	}
+
+}
+
+func boolCol(buf []bool, valid []uint64) Col {
+	vec := vector.NewBoolVector(buf, valid)
+	return Col{Vec: &vec, ColumnType: storage.ColumnType_BOOL}
+}
+
+func isNullCol(col Col) bool {
+	_, ok := col.Vec.(*nullVector)
+	return ok
+}
@@ -0,0 +1,147 @@
+// Copyright 2020 The Meerkat Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package physical
+
+import (
+	"fmt"
+	"math"
+	"meerkat/internal/query/logical"
+	"meerkat/internal/storage"
+	"meerkat/internal/storage/vector"
+)
+
+func isTimeType(t storage.ColumnType) bool {
+	return t == storage.ColumnType_TIMESTAMP || t == storage.ColumnType_DATETIME
+}
+
+// arithResultType returns the type of the result of applying op to values
+// of type l and r. Integer operands give an integer result, if any of the
+// operands is a float the result is a float. Adding or subtracting an
+// integer to a timestamp gives a timestamp and the difference between two
+// timestamps is an integer.
+func arithResultType(op logical.Operator, l, r storage.ColumnType) storage.ColumnType {
+
+	if !isNumericType(l) || !isNumericType(r) {
+		panic(fmt.Sprintf("operator %q cannot be applied to %v and %v operands", op, l, r))
+	}
+
+	lTime, rTime := isTimeType(l), isTimeType(r)
+
+	switch {
+	case !lTime && !rTime:
+		if l == storage.ColumnType_FLOAT64 || r == storage.ColumnType_FLOAT64 {
+			return storage.ColumnType_FLOAT64
+		}
+		return storage.ColumnType_INT64
+	case lTime && rTime && op == logical.SUB:
+		return storage.ColumnType_INT64
+	case lTime && !rTime && isIntegerType(r) && (op == logical.ADD || op == logical.SUB):
+		return l
+	case rTime && !lTime && isIntegerType(l) && op == logical.ADD:
+		return r
+	default:
+		panic(fmt.Sprintf("operator %q cannot be applied to %v and %v operands", op, l, r))
+	}
+
+}
+
+// evalArith evaluates the arithmetic operators. Integer division or
+// modulo by zero gives a null value.
+func evalArith(op logical.Operator, l, r Col, n int) Col {
+
+	if isNullCol(l) || isNullCol(r) {
+		return Col{Vec: &nullVector{l: n}}
+	}
+
+	colType := arithResultType(op, l.ColumnType, r.ColumnType)
+	valid := mergeValidity(n, l.Vec, r.Vec)
+
+	if colType == storage.ColumnType_FLOAT64 {
+		buf := arithFloat64(op, asFloat64(l), asFloat64(r))
+		vec := vector.NewFloat64Vector(buf, valid)
+		return Col{Vec: &vec, ColumnType: colType}
+	}
+
+	lv, rv := l.Vec.(*vector.Int64Vector).Values(), r.Vec.(*vector.Int64Vector).Values()
+	buf := make([]int64, n)
+
+	switch op {
+	case logical.ADD:
+		for i := range buf {
+			buf[i] = lv[i] + rv[i]
+		}
+	case logical.SUB:
+		for i := range buf {
+			buf[i] = lv[i] - rv[i]
+		}
+	case logical.MUL:
+		for i := range buf {
+			buf[i] = lv[i] * rv[i]
+		}
+	case logical.QUO, logical.REM:
+		for i := range buf {
+			if rv[i] == 0 {
+				if valid == nil {
+					valid = newValidity(n)
+					for j := 0; j < n; j++ {
+						setValid(valid, j)
+					}
+				}
+				setInvalid(valid, i)
+				continue
+			}
+			if op == logical.QUO {
+				buf[i] = lv[i] / rv[i]
+			} else {
+				buf[i] = lv[i] % rv[i]
+			}
+		}
+	}
+
+	vec := vector.NewInt64Vector(buf, valid)
+
+	return Col{Vec: &vec, ColumnType: colType}
+
+}
+
+func arithFloat64(op logical.Operator, l, r []float64) []float64 {
+
+	buf := make([]float64, len(l))
+
+	switch op {
+	case logical.ADD:
+		for i := range buf {
+			buf[i] = l[i] + r[i]
+		}
+	case logical.SUB:
+		for i := range buf {
+			buf[i] = l[i] - r[i]
+		}
+	case logical.MUL:
+		for i := range buf {
+			buf[i] = l[i] * r[i]
+		}
+	case logical.QUO:
+		for i := range buf {
+			buf[i] = l[i] / r[i]
+		}
+	case logical.REM:
+		for i := range buf {
+			buf[i] = math.Mod(l[i], r[i])
+		}
+	}
+
+	return buf
+
+}
@@ -0,0 +1,148 @@
+// Copyright 2020 The Meerkat Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package physical
+
+import (
+	"bytes"
+	"fmt"
+	"meerkat/internal/query/logical"
+	"meerkat/internal/storage"
+	"meerkat/internal/storage/vector"
+)
+
+// evalCompare evaluates the comparison operators. Integer operands are
+// compared as int64, mixed numeric operands are compared as float64.
+func evalCompare(op logical.Operator, l, r Col, n int) Col {
+
+	if isNullCol(l) || isNullCol(r) {
+		return boolCol(make([]bool, n), newValidity(n))
+	}
+
+	buf := make([]bool, n)
+	valid := mergeValidity(n, l.Vec, r.Vec)
+
+	switch {
+
+	case isIntegerType(l.ColumnType) && isIntegerType(r.ColumnType):
+		compareInt64(op, l.Vec.(*vector.Int64Vector).Values(), r.Vec.(*vector.Int64Vector).Values(), buf)
+
+	case isNumericType(l.ColumnType) && isNumericType(r.ColumnType):
+		compareFloat64(op, asFloat64(l), asFloat64(r), buf)
+
+	case l.ColumnType == storage.ColumnType_STRING && r.ColumnType == storage.ColumnType_STRING:
+		lv, rv := l.Vec.(*vector.ByteSliceVector), r.Vec.(*vector.ByteSliceVector)
+		for i := range buf {
+			buf[i] = cmpResult(op, bytes.Compare(lv.Get(i), rv.Get(i)))
+		}
+
+	case l.ColumnType == storage.ColumnType_BOOL && r.ColumnType == storage.ColumnType_BOOL:
+		if op != logical.EQL && op != logical.NEQ {
+			panic(fmt.Sprintf("operator %q cannot be applied to bool operands", op))
+		}
+		lv, rv := l.Vec.(*vector.BoolVector).Values(), r.Vec.(*vector.BoolVector).Values()
+		for i := range buf {
+			buf[i] = (lv[i] == rv[i]) == (op == logical.EQL)
+		}
+
+	default:
+		panic(fmt.Sprintf("cannot compare %v with %v", l.ColumnType, r.ColumnType))
+
+	}
+
+	return boolCol(buf, valid)
+
+}
+
+func compareInt64(op logical.Operator, l, r []int64, out []bool) {
+	switch op {
+	case logical.EQL:
+		for i := range out {
+			out[i] = l[i] == r[i]
+		}
+	case logical.NEQ:
+		for i := range out {
+			out[i] = l[i] != r[i]
+		}
+	case logical.LSS:
+		for i := range out {
+			out[i] = l[i] < r[i]
+		}
+	case logical.GTR:
+		for i := range out {
+			out[i] = l[i] > r[i]
+		}
+	case logical.LEQ:
+		for i := range out {
+			out[i] = l[i] <= r[i]
+		}
+	case logical.GEQ:
+		for i := range out {
+			out[i] = l[i] >= r[i]
+		}
+	default:
+		panic(fmt.Sprintf("%q is not a comparison operator", op))
+	}
+}
+
+func compareFloat64(op logical.Operator, l, r []float64, out []bool) {
+	switch op {
+	case logical.EQL:
+		for i := range out {
+			out[i] = l[i] == r[i]
+		}
+	case logical.NEQ:
+		for i := range out {
+			out[i] = l[i] != r[i]
+		}
+	case logical.LSS:
+		for i := range out {
+			out[i] = l[i] < r[i]
+		}
+	case logical.GTR:
+		for i := range out {
+			out[i] = l[i] > r[i]
+		}
+	case logical.LEQ:
+		for i := range out {
+			out[i] = l[i] <= r[i]
+		}
+	case logical.GEQ:
+		for i := range out {
+			out[i] = l[i] >= r[i]
+		}
+	default:
+		panic(fmt.Sprintf("%q is not a comparison operator", op))
+	}
+}
+
+// cmpResult maps the result of a three-way comparison to the result of
+// the comparison operator.
+func cmpResult(op logical.Operator, c int) bool {
+	switch op {
+	case logical.EQL:
+		return c == 0
+	case logical.NEQ:
+		return c != 0
+	case logical.LSS:
+		return c < 0
+	case logical.GTR:
+		return c > 0
+	case logical.LEQ:
+		return c <= 0
+	case logical.GEQ:
+		return c >= 0
+	default:
+		panic(fmt.Sprintf("%q is not a comparison operator", op))
+	}
+}
@@ -0,0 +1,203 @@
+// Copyright 2020 The Meerkat Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package physical
+
+import (
+	"bytes"
+	"fmt"
+	"meerkat/internal/query/logical"
+	"meerkat/internal/storage"
+	"meerkat/internal/storage/vector"
+	"regexp"
+	"unicode"
+	"unicode/utf8"
+)
+
+type stringPredicate func(s []byte, t []byte) bool
+
+type stringOp struct {
+	fn     stringPredicate
+	negate bool
+}
+
+var stringOps = map[logical.Operator]stringOp{
+	logical.EQL_CI:            {fn: bytes.EqualFold},
+	logical.NEQ_CI:            {fn: bytes.EqualFold, negate: true},
+	logical.HAS:               {fn: ci(hasTerm)},
+	logical.NOT_HAS:           {fn: ci(hasTerm), negate: true},
+	logical.HAS_CS:            {fn: hasTerm},
+	logical.NOT_HAS_CS:        {fn: hasTerm, negate: true},
+	logical.HASPREFIX:         {fn: ci(hasTermPrefix)},
+	logical.NOT_HASPREFIX:     {fn: ci(hasTermPrefix), negate: true},
+	logical.HASPREFIX_CS:      {fn: hasTermPrefix},
+	logical.NOT_HASPREFIX_CS:  {fn: hasTermPrefix, negate: true},
+	logical.HASSUFFIX:         {fn: ci(hasTermSuffix)},
+	logical.NOT_HASSUFFIX:     {fn: ci(hasTermSuffix), negate: true},
+	logical.HASSUFFIX_CS:      {fn: hasTermSuffix},
+	logical.NOT_HASSUFFIX_CS:  {fn: hasTermSuffix, negate: true},
+	logical.CONTAINS:          {fn: ci(bytes.Contains)},
+	logical.NOT_CONTAINS:      {fn: ci(bytes.Contains), negate: true},
+	logical.CONTAINS_CS:       {fn: bytes.Contains},
+	logical.NOT_CONTAINS_CS:   {fn: bytes.Contains, negate: true},
+	logical.STARTSWITH:        {fn: ci(bytes.HasPrefix)},
+	logical.NOT_STARTSWITH:    {fn: ci(bytes.HasPrefix), negate: true},
+	logical.STARTSWITH_CS:     {fn: bytes.HasPrefix},
+	logical.NOT_STARTSWITH_CS: {fn: bytes.HasPrefix, negate: true},
+	logical.ENDSWITH:          {fn: ci(bytes.HasSuffix)},
+	logical.NOT_ENDSWITH:      {fn: ci(bytes.HasSuffix), negate: true},
+	logical.ENDSWITH_CS:       {fn: bytes.HasSuffix},
+	logical.NOT_ENDSWITH_CS:   {fn: bytes.HasSuffix, negate: true},
+}
+
+func isStringOp(op logical.Operator) bool {
+	_, found := stringOps[op]
+	return found || op == logical.MATCHES
+}
+
+// evalStringOp evaluates the string operators. Both operands must be
+// strings, the right operand is usually a literal.
+func evalStringOp(op logical.Operator, l, r Col, n int, cache *regexCache) Col {
+
+	if isNullCol(l) || isNullCol(r) {
+		return boolCol(make([]bool, n), newValidity(n))
+	}
+
+	if l.ColumnType != storage.ColumnType_STRING || r.ColumnType != storage.ColumnType_STRING {
+		panic(fmt.Sprintf("operator %q expects string operands, found %v and %v", op, l.ColumnType, r.ColumnType))
+	}
+
+	lv, rv := l.Vec.(*vector.ByteSliceVector), r.Vec.(*vector.ByteSliceVector)
+	buf := make([]bool, n)
+	valid := mergeValidity(n, lv, rv)
+
+	if op == logical.MATCHES {
+		for i := range buf {
+			buf[i] = cache.get(rv.Get(i)).Match(lv.Get(i))
+		}
+		return boolCol(buf, valid)
+	}
+
+	sop := stringOps[op]
+
+	for i := range buf {
+		buf[i] = sop.fn(lv.Get(i), rv.Get(i)) != sop.negate
+	}
+
+	return boolCol(buf, valid)
+
+}
+
+// ci turns a case sensitive predicate into a case insensitive one.
+func ci(fn stringPredicate) stringPredicate {
+	return func(s []byte, t []byte) bool {
+		return fn(bytes.ToLower(s), bytes.ToLower(t))
+	}
+}
+
+// isTermRune returns true if r is part of a term. Terms are maximal
+// sequences of letters and digits.
+func isTermRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// termBoundaries returns true if the match s[start:end] starts and/or ends
+// on a term boundary.
+func termBoundaries(s []byte, start int, end int) (left bool, right bool) {
+
+	left = true
+	right = true
+
+	if start > 0 {
+		r, _ := utf8.DecodeLastRune(s[:start])
+		left = !isTermRune(r)
+	}
+
+	if end < len(s) {
+		r, _ := utf8.DecodeRune(s[end:])
+		right = !isTermRune(r)
+	}
+
+	return
+
+}
+
+// findTerm looks for an occurrence of t in s satisfying the term boundary
+// requirements.
+func findTerm(s []byte, t []byte, needLeft bool, needRight bool) bool {
+
+	if len(t) == 0 {
+		return true
+	}
+
+	offset := 0
+
+	for offset <= len(s)-len(t) {
+
+		i := bytes.Index(s[offset:], t)
+
+		if i < 0 {
+			return false
+		}
+
+		start := offset + i
+		end := start + len(t)
+
+		left, right := termBoundaries(s, start, end)
+
+		if (left || !needLeft) && (right || !needRight) {
+			return true
+		}
+
+		offset = start + 1
+
+	}
+
+	return false
+
+}
+
+// hasTerm returns true if t is found in s as a whole term.
+func hasTerm(s []byte, t []byte) bool { return findTerm(s, t, true, true) }
+
+// hasTermPrefix returns true if a term in s starts with t.
+func hasTermPrefix(s []byte, t []byte) bool { return findTerm(s, t, true, false) }
+
+// hasTermSuffix returns true if a term in s ends with t.
+func hasTermSuffix(s []byte, t []byte) bool { return findTerm(s, t, false, true) }
+
+// regexCache keeps the compiled regular expressions used by the matches
+// operator. The pattern is usually a literal so it is compiled only once.
+type regexCache struct {
+	pattern []byte
+	regex   *regexp.Regexp
+}
+
+func (c *regexCache) get(pattern []byte) *regexp.Regexp {
+
+	if c.regex != nil && bytes.Equal(c.pattern, pattern) {
+		return c.regex
+	}
+
+	regex, err := regexp.Compile(string(pattern))
+
+	if err != nil {
+		panic(fmt.Sprintf("invalid regular expression %q: %v", pattern, err))
+	}
+
+	c.pattern = append(c.pattern[:0], pattern...)
+	c.regex = regex
+
+	return regex
+
+}
@@ -0,0 +1,95 @@
+// Copyright 2020 The Meerkat Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package physical
+
+import (
+	"fmt"
+	"meerkat/internal/storage/vector"
+)
+
+// FilterOp emits the rows of its input batches for which the predicate
+// evaluates to true. Rows where the predicate is null are discarded.
+type FilterOp struct {
+	input     BatchOperator
+	predicate Evaluator
+}
+
+func NewFilterOp(input BatchOperator, predicate Evaluator) *FilterOp {
+	return &FilterOp{
+		input:     input,
+		predicate: predicate,
+	}
+}
+
+func (f *FilterOp) Init()  { f.input.Init() }
+func (f *FilterOp) Close() { f.input.Close() }
+
+func (f *FilterOp) Next() Batch {
+
+	for {
+
+		batch := f.input.Next()
+
+		// EOF
+		if batch.Len == 0 {
+			return batch
+		}
+
+		sel := f.selection(batch)
+
+		// a zero length batch signals EOF so we need to keep
+		// going until some row is selected.
+		if len(sel) == 0 {
+			continue
+		}
+
+		if len(sel) == batch.Len {
+			return batch
+		}
+
+		return selectBatch(batch, sel)
+
+	}
+
+}
+
+// selection returns the position of the rows selected by the predicate.
+func (f *FilterOp) selection(batch Batch) []int {
+
+	result := f.predicate.Eval(batch)
+
+	var sel []int
+
+	switch v := result.Vec.(type) {
+	case *vector.BoolVector:
+		sel = make([]int, 0, batch.Len)
+		values := v.Values()
+		for i, selected := range values {
+			if selected && !isNull(v, i) {
+				sel = append(sel, i)
+			}
+		}
+	case *nullVector:
+		// null predicate, nothing selected.
+	default:
+		panic(fmt.Sprintf("filter predicate must be a bool expression, found %v", result.ColumnType))
+	}
+
+	return sel
+
+}
+
+func (f *FilterOp) Accept(v Visitor) {
+	f.input = Walk(f.input, v).(BatchOperator)
+}
@@ -0,0 +1,180 @@
+// Copyright 2020 The Meerkat Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package physical
+
+import (
+	"github.com/stretchr/testify/assert"
+	"meerkat/internal/query/logical"
+	"meerkat/internal/query/parser"
+	"meerkat/internal/storage"
+	"meerkat/internal/storage/vector"
+	"testing"
+)
+
+// batchSourceOp is a BatchOperator that emits a fixed list of batches.
+type batchSourceOp struct {
+	batches []Batch
+}
+
+func (s *batchSourceOp) Init()          {}
+func (s *batchSourceOp) Close()         {}
+func (s *batchSourceOp) Accept(Visitor) {}
+
+func (s *batchSourceOp) Next() Batch {
+	if len(s.batches) == 0 {
+		return Batch{}
+	}
+	b := s.batches[0]
+	s.batches = s.batches[1:]
+	return b
+}
+
+func int64Col(order int64, values ...int64) Col {
+	v := vector.NewInt64Vector(values, nil)
+	return Col{Order: order, Vec: &v, ColumnType: storage.ColumnType_INT64}
+}
+
+func stringCol(order int64, values ...string) Col {
+	data := make([][]byte, len(values))
+	for i, s := range values {
+		data[i] = []byte(s)
+	}
+	v := vector.NewByteSliceVectorFromByteArray(data, nil)
+	return Col{Order: order, Vec: &v, ColumnType: storage.ColumnType_STRING}
+}
+
+func testBatch(columns map[string]Col) Batch {
+	batch := NewBatch()
+	for name, col := range columns {
+		batch.Columns[name] = col
+		batch.Len = col.Vec.Len()
+	}
+	return batch
+}
+
+func stringValues(col Col) []string {
+	v := col.Vec.(*vector.ByteSliceVector)
+	s := make([]string, v.Len())
+	for i := range s {
+		s[i] = string(v.Get(i))
+	}
+	return s
+}
+
+// predicate parses a where expression and returns its logical form.
+func predicate(t *testing.T, expr string) logical.Node {
+
+	ast, err := parser.Parse("T | where " + expr)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return logical.ToLogical(ast)[0].(*logical.FilterOp).Predicate
+
+}
+
+func TestFilterOp(t *testing.T) {
+
+	newInput := func() BatchOperator {
+		return &batchSourceOp{batches: []Batch{
+			testBatch(map[string]Col{
+				"a":   int64Col(0, 1, 5, 10, 20),
+				"msg": stringCol(1, "GET /index.html", "POST /api", "get /api/v1", "error: timeout"),
+			}),
+			testBatch(map[string]Col{
+				"a":   int64Col(0, 30, 40),
+				"msg": stringCol(1, "ok", "fine"),
+			}),
+		}}
+	}
+
+	cases := []struct {
+		predicate string
+		expected  []string
+	}{
+		{predicate: "a > 5", expected: []string{"get /api/v1", "error: timeout", "ok", "fine"}},
+		{predicate: "a >= 5 and a < 30", expected: []string{"POST /api", "get /api/v1", "error: timeout"}},
+		{predicate: "a == 1 or a == 40", expected: []string{"GET /index.html", "fine"}},
+		{predicate: "a > 5.5", expected: []string{"get /api/v1", "error: timeout", "ok", "fine"}},
+		{predicate: `msg has "api"`, expected: []string{"POST /api", "get /api/v1"}},
+		{predicate: `msg has "ap"`, expected: nil},
+		{predicate: `msg hasprefix "ap"`, expected: []string{"POST /api", "get /api/v1"}},
+		{predicate: `msg has_cs "GET"`, expected: []string{"GET /index.html"}},
+		{predicate: `msg contains "TIME"`, expected: []string{"error: timeout"}},
+		{predicate: `msg !contains "/"`, expected: []string{"error: timeout", "ok", "fine"}},
+		{predicate: `msg startswith "get"`, expected: []string{"GET /index.html", "get /api/v1"}},
+		{predicate: `msg endswith_cs "v1"`, expected: []string{"get /api/v1"}},
+		{predicate: `msg matches "^[a-z]+$"`, expected: []string{"ok", "fine"}},
+		{predicate: `msg == "ok"`, expected: []string{"ok"}},
+		{predicate: `missing == "ok"`, expected: nil},
+	}
+
+	for _, c := range cases {
+
+		t.Run(c.predicate, func(t *testing.T) {
+
+			op := NewFilterOp(newInput(), NewEvaluator(predicate(t, c.predicate)))
+
+			var actual []string
+
+			for batch := op.Next(); batch.Len != 0; batch = op.Next() {
+				actual = append(actual, stringValues(batch.Columns["msg"])...)
+			}
+
+			assert.Equal(t, c.expected, actual)
+
+		})
+
+	}
+
+}
+
+func TestFilterOpNulls(t *testing.T) {
+
+	valid := newValidity(3)
+	setValid(valid, 0)
+	setValid(valid, 2)
+	a := vector.NewInt64Vector([]int64{1, 2, 3}, valid)
+
+	input := &batchSourceOp{batches: []Batch{
+		testBatch(map[string]Col{
+			"a": {Vec: &a, ColumnType: storage.ColumnType_INT64},
+			"b": stringCol(1, "x", "y", "z"),
+		}),
+	}}
+
+	op := NewFilterOp(input, NewEvaluator(predicate(t, `a > 0 or b == "y"`)))
+
+	batch := op.Next()
+
+	// null or true = true
+	assert.Equal(t, []string{"x", "y", "z"}, stringValues(batch.Columns["b"]))
+
+	input.batches = []Batch{
+		testBatch(map[string]Col{
+			"a": {Vec: &a, ColumnType: storage.ColumnType_INT64},
+			"b": stringCol(1, "x", "y", "z"),
+		}),
+	}
+
+	op = NewFilterOp(input, NewEvaluator(predicate(t, `a > 0 and b != "x"`)))
+
+	batch = op.Next()
+
+	// null and true = null
+	assert.Equal(t, []string{"z"}, stringValues(batch.Columns["b"]))
+	assert.False(t, isNull(batch.Columns["a"].Vec, 0))
+
+}
@@ -0,0 +1,167 @@
+// Copyright 2020 The Meerkat Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package physical
+
+import (
+	"fmt"
+	"meerkat/internal/storage"
+	"meerkat/internal/storage/vector"
+	"strconv"
+	"time"
+)
+
+// scalarFunc is a vectorized function that can be called from a CallExpr.
+// eval receives the evaluated arguments and returns a column with one
+// value for each one of the n rows.
+type scalarFunc struct {
+	minArgs int
+	// maxArgs is -1 for variadic functions.
+	maxArgs int
+	eval    func(call *callEvaluator, args []Col, n int) Col
+}
+
+// scalarFuncs holds the registered functions. Their signatures are also
+// declared in the parser for the semantic analysis.
+var scalarFuncs = make(map[string]scalarFunc)
+
+// registerFunc adds a function to the registry. It is meant to be called
+// from init functions.
+func registerFunc(name string, fn scalarFunc) {
+
+	if _, found := scalarFuncs[name]; found {
+		panic(fmt.Sprintf("function %q already registered", name))
+	}
+
+	scalarFuncs[name] = fn
+
+}
+
+// callEvaluator evaluates a function call. It keeps the state that can be
+// reused between batches, like compiled regular expressions.
+type callEvaluator struct {
+	name  string
+	fn    scalarFunc
+	args  []Evaluator
+	regex regexCache
+	// now is the time the evaluator was built. It is only used if now()
+	// was not frozen by the optimizer.
+	now time.Time
+}
+
+func newCallEvaluator(name string, args []Evaluator) *callEvaluator {
+
+	fn, found := scalarFuncs[name]
+
+	if !found {
+		panic(fmt.Sprintf("unknown function %q", name))
+	}
+
+	if len(args) < fn.minArgs || (fn.maxArgs >= 0 && len(args) > fn.maxArgs) {
+		panic(fmt.Sprintf("wrong number of arguments calling %v(), found %v", name, len(args)))
+	}
+
+	return &callEvaluator{name: name, fn: fn, args: args, now: time.Now()}
+
+}
+
+func (e *callEvaluator) Eval(batch Batch) Col {
+
+	args := make([]Col, len(e.args))
+
+	for i, arg := range e.args {
+		args[i] = arg.Eval(batch)
+	}
+
+	return e.fn.eval(e, args, batch.Len)
+
+}
+
+// stringArg returns the values of the i-th argument which must be a string.
+func (e *callEvaluator) stringArg(args []Col, i int) *vector.ByteSliceVector {
+
+	if v, ok := args[i].Vec.(*vector.ByteSliceVector); ok && args[i].ColumnType == storage.ColumnType_STRING {
+		return v
+	}
+
+	panic(fmt.Sprintf("%v() expects a string as argument %v, found %v", e.name, i+1, args[i].ColumnType))
+
+}
+
+// intArg returns the values of the i-th argument which must be an integer.
+func (e *callEvaluator) intArg(args []Col, i int) *vector.Int64Vector {
+
+	if v, ok := args[i].Vec.(*vector.Int64Vector); ok && args[i].ColumnType == storage.ColumnType_INT64 {
+		return v
+	}
+
+	panic(fmt.Sprintf("%v() expects an integer as argument %v, found %v", e.name, i+1, args[i].ColumnType))
+
+}
+
+// timeArg returns the values of the i-th argument which must be a
+// timestamp.
+func (e *callEvaluator) timeArg(args []Col, i int) *vector.Int64Vector {
+
+	if v, ok := args[i].Vec.(*vector.Int64Vector); ok && isTimeType(args[i].ColumnType) {
+		return v
+	}
+
+	panic(fmt.Sprintf("%v() expects a datetime as argument %v, found %v", e.name, i+1, args[i].ColumnType))
+
+}
+
+// hasNullArg returns true if the value of any argument is null at row i.
+// Functions return null when any of its arguments is null.
+func hasNullArg(args []Col, i int) bool {
+
+	for _, arg := range args {
+		if isNull(arg.Vec, i) {
+			return true
+		}
+	}
+
+	return false
+
+}
+
+// hasNullCol returns true if any argument is an untyped null column.
+func hasNullCol(args []Col) bool {
+
+	for _, arg := range args {
+		if isNullCol(arg) {
+			return true
+		}
+	}
+
+	return false
+
+}
+
+// formatValue returns the string representation of the i-th value of col.
+func formatValue(col Col, i int) []byte {
+
+	switch v := col.Vec.(type) {
+	case *vector.ByteSliceVector:
+		return v.Get(i)
+	case *vector.Int64Vector:
+		return strconv.AppendInt(nil, v.Get(i), 10)
+	case *vector.Float64Vector:
+		return strconv.AppendFloat(nil, v.Get(i), 'g', -1, 64)
+	case *vector.BoolVector:
+		return strconv.AppendBool(nil, v.Get(i))
+	default:
+		panic(fmt.Sprintf("cannot convert %v to string", col.ColumnType))
+	}
+
+}
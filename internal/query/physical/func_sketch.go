@@ -0,0 +1,64 @@
+// Copyright 2020 The Meerkat Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package physical
+
+import (
+	"encoding/base64"
+	"meerkat/internal/query/sketch"
+	"meerkat/internal/storage"
+)
+
+func init() {
+	registerFunc("dcount_hll", scalarFunc{minArgs: 1, maxArgs: 1, eval: evalDcountHll})
+}
+
+// dcount_hll(hll) returns the number of distinct values estimated by a
+// sketch returned by hll() or hll_merge(). Invalid sketches are null.
+func evalDcountHll(call *callEvaluator, args []Col, n int) Col {
+
+	if hasNullCol(args) {
+		return nullResult(storage.ColumnType_INT64, n)
+	}
+
+	src := call.stringArg(args, 0)
+	b := &vectorBuilder{}
+
+	for i := 0; i < n; i++ {
+
+		if hasNullArg(args, i) {
+			b.AppendNull()
+			continue
+		}
+
+		data, err := base64.StdEncoding.DecodeString(string(src.Get(i)))
+
+		if err != nil {
+			b.AppendNull()
+			continue
+		}
+
+		h, err := sketch.UnmarshalHLL(data)
+
+		if err != nil {
+			b.AppendNull()
+			continue
+		}
+
+		b.AppendInt64(storage.ColumnType_INT64, int64(h.Estimate()))
+
+	}
+
+	return b.Build()
+
+}
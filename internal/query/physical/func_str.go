@@ -0,0 +1,430 @@
+// Copyright 2020 The Meerkat Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package physical
+
+import (
+	"bytes"
+	"encoding/json"
+	"meerkat/internal/storage"
+	"regexp"
+	"unicode/utf8"
+)
+
+func init() {
+	registerFunc("strlen", scalarFunc{minArgs: 1, maxArgs: 1, eval: evalStrlen})
+	registerFunc("substring", scalarFunc{minArgs: 2, maxArgs: 3, eval: evalSubstring})
+	registerFunc("tolower", scalarFunc{minArgs: 1, maxArgs: 1, eval: evalToLower})
+	registerFunc("toupper", scalarFunc{minArgs: 1, maxArgs: 1, eval: evalToUpper})
+	registerFunc("trim", scalarFunc{minArgs: 2, maxArgs: 2, eval: evalTrim})
+	registerFunc("strcat", scalarFunc{minArgs: 1, maxArgs: 64, eval: evalStrcat})
+	registerFunc("split", scalarFunc{minArgs: 2, maxArgs: 3, eval: evalSplit})
+	registerFunc("replace", scalarFunc{minArgs: 3, maxArgs: 3, eval: evalReplace})
+	registerFunc("extract", scalarFunc{minArgs: 3, maxArgs: 3, eval: evalExtract})
+	registerFunc("indexof", scalarFunc{minArgs: 2, maxArgs: 5, eval: evalIndexOf})
+}
+
+// nullResult returns a column of n nulls of the given type.
+func nullResult(colType storage.ColumnType, n int) Col {
+	return Col{Vec: newNullVector(colType, n), ColumnType: colType}
+}
+
+// runeOffset returns the byte offset of the i-th rune of s or len(s) if s
+// has less than i runes.
+func runeOffset(s []byte, i int64) int {
+
+	offset := 0
+
+	for ; i > 0 && offset < len(s); i-- {
+		_, size := utf8.DecodeRune(s[offset:])
+		offset += size
+	}
+
+	return offset
+
+}
+
+// strlen(source) returns the number of characters in source.
+func evalStrlen(call *callEvaluator, args []Col, n int) Col {
+
+	if hasNullCol(args) {
+		return nullResult(storage.ColumnType_INT64, n)
+	}
+
+	src := call.stringArg(args, 0)
+	b := &vectorBuilder{}
+
+	for i := 0; i < n; i++ {
+		if hasNullArg(args, i) {
+			b.AppendNull()
+			continue
+		}
+		b.AppendInt64(storage.ColumnType_INT64, int64(utf8.RuneCount(src.Get(i))))
+	}
+
+	return b.Build()
+
+}
+
+// substring(source, start [, length]) extracts length characters from
+// source starting at the zero based start character.
+func evalSubstring(call *callEvaluator, args []Col, n int) Col {
+
+	if hasNullCol(args) {
+		return nullResult(storage.ColumnType_STRING, n)
+	}
+
+	src := call.stringArg(args, 0)
+	start := call.intArg(args, 1)
+	b := &vectorBuilder{}
+
+	for i := 0; i < n; i++ {
+
+		if hasNullArg(args, i) {
+			b.AppendNull()
+			continue
+		}
+
+		s := src.Get(i)
+		from := runeOffset(s, max64(start.Get(i), 0))
+		to := len(s)
+
+		if len(args) > 2 {
+			to = from + runeOffset(s[from:], max64(call.intArg(args, 2).Get(i), 0))
+		}
+
+		b.AppendBytes(s[from:to])
+
+	}
+
+	return b.Build()
+
+}
+
+func max64(a, b int64) int64 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// mapString applies fn to every value of the first argument.
+func mapString(call *callEvaluator, args []Col, n int, fn func([]byte) []byte) Col {
+
+	if hasNullCol(args) {
+		return nullResult(storage.ColumnType_STRING, n)
+	}
+
+	src := call.stringArg(args, 0)
+	b := &vectorBuilder{}
+
+	for i := 0; i < n; i++ {
+		if hasNullArg(args, i) {
+			b.AppendNull()
+			continue
+		}
+		b.AppendBytes(fn(src.Get(i)))
+	}
+
+	return b.Build()
+
+}
+
+func evalToLower(call *callEvaluator, args []Col, n int) Col {
+	return mapString(call, args, n, bytes.ToLower)
+}
+
+func evalToUpper(call *callEvaluator, args []Col, n int) Col {
+	return mapString(call, args, n, bytes.ToUpper)
+}
+
+// trim(regex, source) removes the leading and trailing matches of regex
+// from source.
+func evalTrim(call *callEvaluator, args []Col, n int) Col {
+
+	if hasNullCol(args) {
+		return nullResult(storage.ColumnType_STRING, n)
+	}
+
+	pattern := call.stringArg(args, 0)
+	src := call.stringArg(args, 1)
+	b := &vectorBuilder{}
+
+	for i := 0; i < n; i++ {
+
+		if hasNullArg(args, i) {
+			b.AppendNull()
+			continue
+		}
+
+		re := call.regex.get(trimPattern(pattern.Get(i)))
+		b.AppendBytes(re.ReplaceAllLiteral(src.Get(i), nil))
+
+	}
+
+	return b.Build()
+
+}
+
+func trimPattern(pattern []byte) []byte {
+	p := string(pattern)
+	return []byte("^(?:" + p + ")+|(?:" + p + ")+$")
+}
+
+// strcat(arg1, arg2, ...) concatenates its arguments converted to strings.
+// Null arguments are taken as empty strings.
+func evalStrcat(call *callEvaluator, args []Col, n int) Col {
+
+	b := &vectorBuilder{}
+	var buf []byte
+
+	for i := 0; i < n; i++ {
+
+		buf = buf[:0]
+
+		for _, arg := range args {
+			if !isNull(arg.Vec, i) {
+				buf = append(buf, formatValue(arg, i)...)
+			}
+		}
+
+		b.AppendBytes(buf)
+
+	}
+
+	return b.Build()
+
+}
+
+// split(source, delimiter [, index]) splits source by delimiter returning
+// a dynamic array with the substrings. If index is given only the
+// substring at that position is returned or null if there is none.
+func evalSplit(call *callEvaluator, args []Col, n int) Col {
+
+	colType := storage.ColumnType_DYNAMIC
+
+	if len(args) > 2 {
+		colType = storage.ColumnType_STRING
+	}
+
+	if hasNullCol(args) {
+		return nullResult(colType, n)
+	}
+
+	src := call.stringArg(args, 0)
+	delimiter := call.stringArg(args, 1)
+	b := &vectorBuilder{}
+
+	for i := 0; i < n; i++ {
+
+		if hasNullArg(args, i) {
+			b.AppendNull()
+			continue
+		}
+
+		parts := bytes.Split(src.Get(i), delimiter.Get(i))
+
+		if len(args) > 2 {
+			idx := call.intArg(args, 2).Get(i)
+			if idx < 0 || idx >= int64(len(parts)) {
+				b.AppendNull()
+				continue
+			}
+			b.AppendBytes(parts[idx])
+			continue
+		}
+
+		values := make([]string, len(parts))
+
+		for j, part := range parts {
+			values[j] = string(part)
+		}
+
+		encoded, err := json.Marshal(values)
+
+		if err != nil {
+			panic(err)
+		}
+
+		b.AppendDynamic(encoded)
+
+	}
+
+	return b.Build()
+
+}
+
+// replace(regex, rewrite, source) replaces all the matches of regex in
+// source by rewrite. Capture groups are referenced in rewrite as \1, \2...
+func evalReplace(call *callEvaluator, args []Col, n int) Col {
+
+	if hasNullCol(args) {
+		return nullResult(storage.ColumnType_STRING, n)
+	}
+
+	pattern := call.stringArg(args, 0)
+	rewrite := call.stringArg(args, 1)
+	src := call.stringArg(args, 2)
+	b := &vectorBuilder{}
+
+	for i := 0; i < n; i++ {
+
+		if hasNullArg(args, i) {
+			b.AppendNull()
+			continue
+		}
+
+		re := call.regex.get(pattern.Get(i))
+		b.AppendBytes(re.ReplaceAll(src.Get(i), expandTemplate(rewrite.Get(i))))
+
+	}
+
+	return b.Build()
+
+}
+
+var groupRef = regexp.MustCompile(`\\(\d+)`)
+
+// expandTemplate translates the \N group references of a rewrite string
+// to the ${N} form used by regexp.Expand escaping any $ sign.
+func expandTemplate(rewrite []byte) []byte {
+	escaped := bytes.ReplaceAll(rewrite, []byte("$"), []byte("$$"))
+	return groupRef.ReplaceAll(escaped, []byte("$${$1}"))
+}
+
+// extract(regex, group, source) returns the given capture group of the
+// first match of regex in source, the whole match for group 0. It returns
+// null if there is no match.
+func evalExtract(call *callEvaluator, args []Col, n int) Col {
+
+	if hasNullCol(args) {
+		return nullResult(storage.ColumnType_STRING, n)
+	}
+
+	pattern := call.stringArg(args, 0)
+	group := call.intArg(args, 1)
+	src := call.stringArg(args, 2)
+	b := &vectorBuilder{}
+
+	for i := 0; i < n; i++ {
+
+		if hasNullArg(args, i) {
+			b.AppendNull()
+			continue
+		}
+
+		re := call.regex.get(pattern.Get(i))
+		match := re.FindSubmatchIndex(src.Get(i))
+		g := group.Get(i)
+
+		if match == nil || g < 0 || int(g) > re.NumSubexp() || match[2*g] < 0 {
+			b.AppendNull()
+			continue
+		}
+
+		b.AppendBytes(src.Get(i)[match[2*g]:match[2*g+1]])
+
+	}
+
+	return b.Build()
+
+}
+
+// indexof(source, lookup [, start [, length [, occurrence]]]) returns the
+// zero based character index of the occurrence-th match of lookup in
+// source or -1 if it is not found. The search starts at the start
+// character and examines at most length characters ( -1 means to the end
+// of source ).
+func evalIndexOf(call *callEvaluator, args []Col, n int) Col {
+
+	if hasNullCol(args) {
+		return nullResult(storage.ColumnType_INT64, n)
+	}
+
+	src := call.stringArg(args, 0)
+	lookup := call.stringArg(args, 1)
+	b := &vectorBuilder{}
+
+	// optional arguments with its default value.
+	optional := func(pos int, i int, def int64) int64 {
+		if len(args) > pos {
+			return call.intArg(args, pos).Get(i)
+		}
+		return def
+	}
+
+	for i := 0; i < n; i++ {
+
+		if hasNullArg(args, i) {
+			b.AppendNull()
+			continue
+		}
+
+		s, t := src.Get(i), lookup.Get(i)
+		start := optional(2, i, 0)
+		length := optional(3, i, -1)
+		occurrence := optional(4, i, 1)
+
+		if start < 0 || occurrence < 1 || int64(utf8.RuneCount(s)) < start {
+			b.AppendInt64(storage.ColumnType_INT64, -1)
+			continue
+		}
+
+		from := runeOffset(s, start)
+		to := len(s)
+
+		if length >= 0 {
+			to = from + runeOffset(s[from:], length)
+		}
+
+		b.AppendInt64(storage.ColumnType_INT64, indexOf(s, t, from, to, occurrence))
+
+	}
+
+	return b.Build()
+
+}
+
+// indexOf finds the occurrence-th match of t inside s[from:to] returning
+// its character index in s.
+func indexOf(s []byte, t []byte, from int, to int, occurrence int64) int64 {
+
+	offset := from
+
+	for {
+
+		idx := bytes.Index(s[offset:to], t)
+
+		if idx < 0 {
+			return -1
+		}
+
+		occurrence--
+
+		if occurrence == 0 {
+			return int64(utf8.RuneCount(s[:offset+idx]))
+		}
+
+		// occurrences can overlap so the search continues on the next
+		// character.
+		_, size := utf8.DecodeRune(s[offset+idx:])
+
+		if size == 0 {
+			return -1
+		}
+
+		offset += idx + size
+
+	}
+
+}
@@ -0,0 +1,129 @@
+// Copyright 2020 The Meerkat Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package physical
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// evalValues evaluates expr over batch returning its values.
+func evalValues(t *testing.T, expr string, batch Batch) []interface{} {
+
+	columns := columnExpr(t, "project r="+expr)
+	col := NewEvaluator(columns[0].Expr).Eval(batch)
+
+	values := make([]interface{}, batch.Len)
+
+	for i := range values {
+		if !isNull(col.Vec, i) {
+			values[i] = jsonValue(col.Vec, i)
+		}
+	}
+
+	return values
+
+}
+
+func TestStringFunctions(t *testing.T) {
+
+	batch := testBatch(map[string]Col{
+		"s": stringCol(0, "Hello World", "  ñandú ", "a,b,,c"),
+		"n": nullableInt64Col(1, 1, nil, 3),
+	})
+
+	cases := []struct {
+		expr     string
+		expected []interface{}
+	}{
+		{expr: "strlen(s)", expected: []interface{}{int64(11), int64(8), int64(6)}},
+		{expr: "substring(s, 2, 3)", expected: []interface{}{"llo", "ñan", "b,,"}},
+		{expr: "substring(s, 6)", expected: []interface{}{"World", "ú ", ""}},
+		{expr: "substring(s, n, 2)", expected: []interface{}{"el", nil, ",,"}},
+		{expr: "tolower(s)", expected: []interface{}{"hello world", "  ñandú ", "a,b,,c"}},
+		{expr: "toupper(s)", expected: []interface{}{"HELLO WORLD", "  ÑANDÚ ", "A,B,,C"}},
+		{expr: `trim(" ", s)`, expected: []interface{}{"Hello World", "ñandú", "a,b,,c"}},
+		{expr: `trim("[a-zH]", s)`, expected: []interface{}{" W", "  ñandú ", ",b,,"}},
+		{expr: `strcat(s, "-", n)`, expected: []interface{}{"Hello World-1", "  ñandú -", "a,b,,c-3"}},
+		{expr: `split(s, ",", 1)`, expected: []interface{}{nil, nil, "b"}},
+		{expr: `replace("o", "0", s)`, expected: []interface{}{"Hell0 W0rld", "  ñandú ", "a,b,,c"}},
+		{expr: `replace("(\\w+) (\\w+)", "\\2 $ \\1", s)`, expected: []interface{}{"World $ Hello", "  ñandú ", "a,b,,c"}},
+		{expr: `extract("(\\w+) (\\w+)", 2, s)`, expected: []interface{}{"World", nil, nil}},
+		{expr: `extract("[a-z]+", 0, s)`, expected: []interface{}{"ello", "and", "a"}},
+		{expr: `indexof(s, "o")`, expected: []interface{}{int64(4), int64(-1), int64(-1)}},
+		{expr: `indexof(s, "d")`, expected: []interface{}{int64(10), int64(5), int64(-1)}},
+		{expr: `indexof(s, "o", 5)`, expected: []interface{}{int64(7), int64(-1), int64(-1)}},
+		{expr: `indexof(s, "o", 0, 5)`, expected: []interface{}{int64(4), int64(-1), int64(-1)}},
+		{expr: `indexof(s, ",", 0, -1, 3)`, expected: []interface{}{int64(-1), int64(-1), int64(4)}},
+		{expr: "strlen(missing)", expected: []interface{}{nil, nil, nil}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.expr, func(t *testing.T) {
+			assert.Equal(t, c.expected, evalValues(t, c.expr, batch))
+		})
+	}
+
+}
+
+func TestSplitDynamic(t *testing.T) {
+
+	batch := testBatch(map[string]Col{"s": stringCol(0, "a,b", "c")})
+
+	columns := columnExpr(t, `project r=split(s, ",")`)
+	col := NewEvaluator(columns[0].Expr).Eval(batch)
+
+	assert.Equal(t, []string{`["a","b"]`, `["c"]`}, stringValues(col))
+
+	op := NewFilterOp(&batchSourceOp{batches: []Batch{
+		testBatch(map[string]Col{"s": stringCol(0, "abc", "abcdef")}),
+	}}, NewEvaluator(predicate(t, "strlen(s) > 3")))
+
+	assert.Equal(t, []string{"abcdef"}, drainStrings(op, "s"))
+
+}
+
+func TestSummarizeByFunction(t *testing.T) {
+
+	op := summarize(t, "count(), sum(strlen(host)) by h=toupper(host)")
+
+	partial := NewHashAggOp(nodeInput(
+		[]string{"a", "ab", "A", "ab"},
+		[]int64{1, 2, 3, 4},
+	), op.By, op.Agg, PartialAgg, NewAllocator(0, "", ""))
+
+	final := NewHashAggOp(partial, op.By, op.Agg, FinalAgg, NewAllocator(0, "", ""))
+
+	rows := rowsByKey(drain(final), "h")
+
+	assert.Equal(t, map[string]interface{}{"h": "A", "count_": int64(2), "sum_": int64(2)}, rows["A"])
+	assert.Equal(t, map[string]interface{}{"h": "AB", "count_": int64(2), "sum_": int64(4)}, rows["AB"])
+
+}
+
+func TestUnknownFunction(t *testing.T) {
+
+	columns := columnExpr(t, "project r=nope(s)")
+
+	assert.PanicsWithValue(t, `unknown function "nope"`, func() {
+		NewEvaluator(columns[0].Expr)
+	})
+
+	columns = columnExpr(t, "project r=strlen(s, s)")
+
+	assert.Panics(t, func() {
+		NewEvaluator(columns[0].Expr)
+	})
+
+}
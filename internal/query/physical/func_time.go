@@ -0,0 +1,514 @@
+// Copyright 2020 The Meerkat Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package physical
+
+import (
+	"fmt"
+	"math"
+	"meerkat/internal/query/logical"
+	"meerkat/internal/storage"
+	"meerkat/internal/storage/vector"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Timestamps are represented as nanoseconds since the unix epoch and
+// timespans as nanoseconds. Dates are always computed in UTC.
+
+func init() {
+	registerFunc("now", scalarFunc{minArgs: 0, maxArgs: 1, eval: evalNow})
+	registerFunc("ago", scalarFunc{minArgs: 1, maxArgs: 1, eval: evalAgo})
+	registerFunc("startofday", scalarFunc{minArgs: 1, maxArgs: 2, eval: evalStartOf(startOfDay)})
+	registerFunc("startofweek", scalarFunc{minArgs: 1, maxArgs: 2, eval: evalStartOf(startOfWeek)})
+	registerFunc("startofmonth", scalarFunc{minArgs: 1, maxArgs: 2, eval: evalStartOf(startOfMonth)})
+	registerFunc("startofyear", scalarFunc{minArgs: 1, maxArgs: 2, eval: evalStartOf(startOfYear)})
+	registerFunc("bin", scalarFunc{minArgs: 2, maxArgs: 2, eval: evalBin})
+	registerFunc("floor", scalarFunc{minArgs: 2, maxArgs: 2, eval: evalBin})
+	registerFunc("datetime_part", scalarFunc{minArgs: 2, maxArgs: 2, eval: evalDatetimePart})
+	registerFunc("format_datetime", scalarFunc{minArgs: 2, maxArgs: 2, eval: evalFormatDatetime})
+	registerFunc("datetime_add", scalarFunc{minArgs: 3, maxArgs: 3, eval: evalDatetimeAdd})
+	registerFunc("datetime_diff", scalarFunc{minArgs: 3, maxArgs: 3, eval: evalDatetimeDiff})
+}
+
+func toTime(nanos int64) time.Time { return time.Unix(0, nanos).UTC() }
+
+// timeCol returns a TIMESTAMP column with all its values equal to t.
+func timeCol(t time.Time, n int) Col {
+	return Col{Vec: constVector(t.UnixNano(), n), ColumnType: storage.ColumnType_TIMESTAMP}
+}
+
+// now([offset]) returns the current time plus an optional timespan.
+func evalNow(call *callEvaluator, args []Col, n int) Col {
+
+	if len(args) == 0 {
+		return timeCol(call.now, n)
+	}
+
+	return evalArith(logical.ADD, timeCol(call.now, n), args[0], n)
+
+}
+
+// ago(timespan) returns the current time minus timespan.
+func evalAgo(call *callEvaluator, args []Col, n int) Col {
+	return evalArith(logical.SUB, timeCol(call.now, n), args[0], n)
+}
+
+// mapTime applies fn to every timestamp of the first argument. The second
+// argument, if present, is an integer passed to fn.
+func mapTime(call *callEvaluator, args []Col, n int, fn func(t time.Time, offset int64) time.Time) Col {
+
+	if hasNullCol(args) {
+		return nullResult(storage.ColumnType_TIMESTAMP, n)
+	}
+
+	src := call.timeArg(args, 0)
+	b := &vectorBuilder{}
+
+	for i := 0; i < n; i++ {
+
+		if hasNullArg(args, i) {
+			b.AppendNull()
+			continue
+		}
+
+		var offset int64
+
+		if len(args) > 1 {
+			offset = call.intArg(args, 1).Get(i)
+		}
+
+		b.AppendInt64(storage.ColumnType_TIMESTAMP, fn(toTime(src.Get(i)), offset).UnixNano())
+
+	}
+
+	return b.Build()
+
+}
+
+// evalStartOf builds the evaluation of the startofX(date [, offset])
+// functions where offset is a number of periods.
+func evalStartOf(fn func(t time.Time, offset int64) time.Time) func(*callEvaluator, []Col, int) Col {
+	return func(call *callEvaluator, args []Col, n int) Col {
+		return mapTime(call, args, n, fn)
+	}
+}
+
+func startOfDay(t time.Time, offset int64) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day()+int(offset), 0, 0, 0, 0, time.UTC)
+}
+
+// startOfWeek returns the start of the week, weeks start on sunday.
+func startOfWeek(t time.Time, offset int64) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day()-int(t.Weekday())+7*int(offset), 0, 0, 0, 0, time.UTC)
+}
+
+func startOfMonth(t time.Time, offset int64) time.Time {
+	return time.Date(t.Year(), t.Month()+time.Month(offset), 1, 0, 0, 0, 0, time.UTC)
+}
+
+func startOfYear(t time.Time, offset int64) time.Time {
+	return time.Date(t.Year()+int(offset), 1, 1, 0, 0, 0, 0, time.UTC)
+}
+
+// floorDiv returns the largest integer less than or equal to x / y.
+func floorDiv(x, y int64) int64 {
+	q := x / y
+	if (x%y != 0) && ((x < 0) != (y < 0)) {
+		q--
+	}
+	return q
+}
+
+// bin(value, roundTo) rounds value down to a multiple of roundTo. Integer,
+// float and timestamp ( rounded to a timespan ) values are supported.
+func evalBin(call *callEvaluator, args []Col, n int) Col {
+
+	value, roundTo := args[0], args[1]
+
+	if hasNullCol(args) {
+		return Col{Vec: &nullVector{l: n}}
+	}
+
+	if !isNumericType(value.ColumnType) || !isNumericType(roundTo.ColumnType) || isTimeType(roundTo.ColumnType) {
+		panic(fmt.Sprintf("%v() cannot be applied to %v and %v", call.name, value.ColumnType, roundTo.ColumnType))
+	}
+
+	valid := mergeValidity(n, value.Vec, roundTo.Vec)
+
+	if value.ColumnType == storage.ColumnType_FLOAT64 || roundTo.ColumnType == storage.ColumnType_FLOAT64 {
+
+		v, r := asFloat64(value), asFloat64(roundTo)
+		buf := make([]float64, n)
+
+		for i := range buf {
+			buf[i] = math.Floor(v[i]/r[i]) * r[i]
+		}
+
+		vec := vector.NewFloat64Vector(buf, valid)
+
+		return Col{Vec: &vec, ColumnType: storage.ColumnType_FLOAT64}
+
+	}
+
+	v := value.Vec.(*vector.Int64Vector).Values()
+	r := roundTo.Vec.(*vector.Int64Vector).Values()
+	buf := make([]int64, n)
+
+	// the values of a sorted column fill the buckets one after another so
+	// a bucket is only computed for the first value past the current one.
+	var lo, hi, size int64
+	uniform := true
+
+	for i := range buf {
+
+		if r[i] != r[0] {
+			uniform = false
+		}
+
+		if value.Sorted && r[i] == size && v[i] >= lo && v[i] < hi {
+			buf[i] = lo
+			continue
+		}
+
+		// non positive bin sizes give null.
+		if r[i] <= 0 {
+			if valid == nil {
+				valid = newValidity(n)
+				for j := 0; j < n; j++ {
+					setValid(valid, j)
+				}
+			}
+			setInvalid(valid, i)
+			continue
+		}
+
+		buf[i] = floorDiv(v[i], r[i]) * r[i]
+		lo, hi, size = buf[i], buf[i]+r[i], r[i]
+
+	}
+
+	vec := vector.NewInt64Vector(buf, valid)
+
+	// binning a sorted column by a constant size keeps it sorted.
+	return Col{Vec: &vec, ColumnType: value.ColumnType, Sorted: value.Sorted && uniform}
+
+}
+
+// datetime_part(part, date) returns the given part of date as an integer.
+func evalDatetimePart(call *callEvaluator, args []Col, n int) Col {
+
+	if hasNullCol(args) {
+		return nullResult(storage.ColumnType_INT64, n)
+	}
+
+	part := call.stringArg(args, 0)
+	src := call.timeArg(args, 1)
+	b := &vectorBuilder{}
+
+	for i := 0; i < n; i++ {
+
+		if hasNullArg(args, i) {
+			b.AppendNull()
+			continue
+		}
+
+		b.AppendInt64(storage.ColumnType_INT64, datetimePart(string(part.Get(i)), toTime(src.Get(i))))
+
+	}
+
+	return b.Build()
+
+}
+
+func datetimePart(part string, t time.Time) int64 {
+
+	switch strings.ToLower(part) {
+	case "year":
+		return int64(t.Year())
+	case "quarter":
+		return int64(t.Month()-1)/3 + 1
+	case "month":
+		return int64(t.Month())
+	case "week_of_year", "weekofyear":
+		_, week := t.ISOWeek()
+		return int64(week)
+	case "day":
+		return int64(t.Day())
+	case "dayofyear":
+		return int64(t.YearDay())
+	case "hour":
+		return int64(t.Hour())
+	case "minute":
+		return int64(t.Minute())
+	case "second":
+		return int64(t.Second())
+	case "millisecond":
+		return int64(t.Nanosecond() / int(time.Millisecond))
+	case "microsecond":
+		return int64(t.Nanosecond() / int(time.Microsecond))
+	case "nanosecond":
+		return int64(t.Nanosecond())
+	default:
+		panic(fmt.Sprintf("unknown datetime part %q", part))
+	}
+
+}
+
+// datetime_add(period, amount, date) adds amount periods to date.
+func evalDatetimeAdd(call *callEvaluator, args []Col, n int) Col {
+
+	if hasNullCol(args) {
+		return nullResult(storage.ColumnType_TIMESTAMP, n)
+	}
+
+	period := call.stringArg(args, 0)
+	amount := call.intArg(args, 1)
+	src := call.timeArg(args, 2)
+	b := &vectorBuilder{}
+
+	for i := 0; i < n; i++ {
+
+		if hasNullArg(args, i) {
+			b.AppendNull()
+			continue
+		}
+
+		t := datetimeAdd(string(period.Get(i)), amount.Get(i), toTime(src.Get(i)))
+		b.AppendInt64(storage.ColumnType_TIMESTAMP, t.UnixNano())
+
+	}
+
+	return b.Build()
+
+}
+
+// periodDuration returns the length of the fixed length periods.
+func periodDuration(period string) (time.Duration, bool) {
+
+	switch period {
+	case "week":
+		return 7 * 24 * time.Hour, true
+	case "day":
+		return 24 * time.Hour, true
+	case "hour":
+		return time.Hour, true
+	case "minute":
+		return time.Minute, true
+	case "second":
+		return time.Second, true
+	case "millisecond":
+		return time.Millisecond, true
+	case "microsecond":
+		return time.Microsecond, true
+	case "nanosecond":
+		return time.Nanosecond, true
+	default:
+		return 0, false
+	}
+
+}
+
+func datetimeAdd(period string, amount int64, t time.Time) time.Time {
+
+	period = strings.ToLower(period)
+
+	switch period {
+	case "year":
+		return t.AddDate(int(amount), 0, 0)
+	case "quarter":
+		return t.AddDate(0, 3*int(amount), 0)
+	case "month":
+		return t.AddDate(0, int(amount), 0)
+	}
+
+	d, ok := periodDuration(period)
+
+	if !ok {
+		panic(fmt.Sprintf("unknown datetime period %q", period))
+	}
+
+	return t.Add(time.Duration(amount) * d)
+
+}
+
+// datetime_diff(period, date1, date2) returns the number of period
+// boundaries crossed from date2 to date1.
+func evalDatetimeDiff(call *callEvaluator, args []Col, n int) Col {
+
+	if hasNullCol(args) {
+		return nullResult(storage.ColumnType_INT64, n)
+	}
+
+	period := call.stringArg(args, 0)
+	t1 := call.timeArg(args, 1)
+	t2 := call.timeArg(args, 2)
+	b := &vectorBuilder{}
+
+	for i := 0; i < n; i++ {
+
+		if hasNullArg(args, i) {
+			b.AppendNull()
+			continue
+		}
+
+		diff := datetimeDiff(string(period.Get(i)), toTime(t1.Get(i)), toTime(t2.Get(i)))
+		b.AppendInt64(storage.ColumnType_INT64, diff)
+
+	}
+
+	return b.Build()
+
+}
+
+func datetimeDiff(period string, t1 time.Time, t2 time.Time) int64 {
+
+	period = strings.ToLower(period)
+
+	months := func(t time.Time) int64 { return int64(t.Year())*12 + int64(t.Month()-1) }
+
+	switch period {
+	case "year":
+		return int64(t1.Year() - t2.Year())
+	case "quarter":
+		return floorDiv(months(t1), 3) - floorDiv(months(t2), 3)
+	case "month":
+		return months(t1) - months(t2)
+	case "week":
+		return (startOfWeek(t1, 0).UnixNano() - startOfWeek(t2, 0).UnixNano()) / int64(7*24*time.Hour)
+	}
+
+	d, ok := periodDuration(period)
+
+	if !ok {
+		panic(fmt.Sprintf("unknown datetime period %q", period))
+	}
+
+	return floorDiv(t1.UnixNano(), int64(d)) - floorDiv(t2.UnixNano(), int64(d))
+
+}
+
+// format_datetime(date, format) formats date using the format specifiers
+// d, dd, f..fffffffff, F..FFFFFFFFF, h, hh, H, HH, m, mm, M, MM, s, ss,
+// y, yy, yyyy and tt. Any other character is copied to the output.
+func evalFormatDatetime(call *callEvaluator, args []Col, n int) Col {
+
+	if hasNullCol(args) {
+		return nullResult(storage.ColumnType_STRING, n)
+	}
+
+	src := call.timeArg(args, 0)
+	format := call.stringArg(args, 1)
+	b := &vectorBuilder{}
+
+	for i := 0; i < n; i++ {
+
+		if hasNullArg(args, i) {
+			b.AppendNull()
+			continue
+		}
+
+		b.AppendBytes(formatDatetime(toTime(src.Get(i)), string(format.Get(i))))
+
+	}
+
+	return b.Build()
+
+}
+
+func formatDatetime(t time.Time, format string) []byte {
+
+	var buf []byte
+
+	for i := 0; i < len(format); {
+
+		c := format[i]
+		j := i
+
+		for j < len(format) && format[j] == c {
+			j++
+		}
+
+		count := j - i
+		i = j
+
+		switch c {
+		case 'd':
+			buf = appendPadded(buf, t.Day(), count)
+		case 'h':
+			hour := t.Hour() % 12
+			if hour == 0 {
+				hour = 12
+			}
+			buf = appendPadded(buf, hour, count)
+		case 'H':
+			buf = appendPadded(buf, t.Hour(), count)
+		case 'm':
+			buf = appendPadded(buf, t.Minute(), count)
+		case 'M':
+			buf = appendPadded(buf, int(t.Month()), count)
+		case 's':
+			buf = appendPadded(buf, t.Second(), count)
+		case 'y':
+			if count <= 2 {
+				buf = appendPadded(buf, t.Year()%100, count)
+			} else {
+				buf = appendPadded(buf, t.Year(), count)
+			}
+		case 'f', 'F':
+			if count > 9 {
+				count = 9
+			}
+			fraction := fmt.Sprintf("%09d", t.Nanosecond())[:count]
+			if c == 'F' {
+				fraction = strings.TrimRight(fraction, "0")
+			}
+			buf = append(buf, fraction...)
+		case 't':
+			if t.Hour() < 12 {
+				buf = append(buf, "AM"[:min(count, 2)]...)
+			} else {
+				buf = append(buf, "PM"[:min(count, 2)]...)
+			}
+		default:
+			for k := 0; k < count; k++ {
+				buf = append(buf, c)
+			}
+		}
+
+	}
+
+	return buf
+
+}
+
+// appendPadded appends value padded with zeros to width digits.
+func appendPadded(buf []byte, value int, width int) []byte {
+
+	s := strconv.Itoa(value)
+
+	for k := len(s); k < width; k++ {
+		buf = append(buf, '0')
+	}
+
+	return append(buf, s...)
+
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
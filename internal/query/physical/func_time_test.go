@@ -0,0 +1,212 @@
+// Copyright 2020 The Meerkat Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package physical
+
+import (
+	"github.com/stretchr/testify/assert"
+	"meerkat/internal/storage"
+	"meerkat/internal/storage/vector"
+	"testing"
+	"time"
+)
+
+func timestampCol(order int64, values ...time.Time) Col {
+
+	buf := make([]int64, len(values))
+
+	for i, t := range values {
+		buf[i] = t.UnixNano()
+	}
+
+	v := vector.NewInt64Vector(buf, nil)
+
+	return Col{Order: order, Vec: &v, ColumnType: storage.ColumnType_TIMESTAMP}
+
+}
+
+func TestDatetimeFunctions(t *testing.T) {
+
+	// wednesday
+	t1 := time.Date(2020, 5, 13, 14, 35, 12, 123456789, time.UTC)
+	t2 := time.Date(2019, 12, 31, 23, 59, 59, 0, time.UTC)
+
+	batch := testBatch(map[string]Col{
+		"_ts": timestampCol(0, t1, t2),
+		"n":   int64Col(1, 17, -17),
+	})
+
+	nanos := func(t time.Time) interface{} { return t.UnixNano() }
+
+	cases := []struct {
+		expr     string
+		expected []interface{}
+	}{
+		{expr: "startofday(_ts)", expected: []interface{}{
+			nanos(time.Date(2020, 5, 13, 0, 0, 0, 0, time.UTC)),
+			nanos(time.Date(2019, 12, 31, 0, 0, 0, 0, time.UTC)),
+		}},
+		{expr: "startofday(_ts, 1)", expected: []interface{}{
+			nanos(time.Date(2020, 5, 14, 0, 0, 0, 0, time.UTC)),
+			nanos(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)),
+		}},
+		{expr: "startofweek(_ts)", expected: []interface{}{
+			nanos(time.Date(2020, 5, 10, 0, 0, 0, 0, time.UTC)),
+			nanos(time.Date(2019, 12, 29, 0, 0, 0, 0, time.UTC)),
+		}},
+		{expr: "startofmonth(_ts, -1)", expected: []interface{}{
+			nanos(time.Date(2020, 4, 1, 0, 0, 0, 0, time.UTC)),
+			nanos(time.Date(2019, 11, 1, 0, 0, 0, 0, time.UTC)),
+		}},
+		{expr: "bin(_ts, 1h)", expected: []interface{}{
+			nanos(time.Date(2020, 5, 13, 14, 0, 0, 0, time.UTC)),
+			nanos(time.Date(2019, 12, 31, 23, 0, 0, 0, time.UTC)),
+		}},
+		{expr: "floor(_ts, 15m)", expected: []interface{}{
+			nanos(time.Date(2020, 5, 13, 14, 30, 0, 0, time.UTC)),
+			nanos(time.Date(2019, 12, 31, 23, 45, 0, 0, time.UTC)),
+		}},
+		{expr: "bin(n, 5)", expected: []interface{}{int64(15), int64(-20)}},
+		{expr: "bin(n, 0)", expected: []interface{}{nil, nil}},
+		{expr: "bin(n, 2.5)", expected: []interface{}{15.0, -17.5}},
+		{expr: `datetime_part("year", _ts)`, expected: []interface{}{int64(2020), int64(2019)}},
+		{expr: `datetime_part("quarter", _ts)`, expected: []interface{}{int64(2), int64(4)}},
+		{expr: `datetime_part("dayofyear", _ts)`, expected: []interface{}{int64(134), int64(365)}},
+		{expr: `datetime_part("millisecond", _ts)`, expected: []interface{}{int64(123), int64(0)}},
+		{expr: `format_datetime(_ts, "yyyy-MM-dd HH:mm:ss.fff")`, expected: []interface{}{
+			"2020-05-13 14:35:12.123", "2019-12-31 23:59:59.000",
+		}},
+		{expr: `format_datetime(_ts, "yy/M/d h:m tt FFFF")`, expected: []interface{}{
+			"20/5/13 2:35 PM 1234", "19/12/31 11:59 PM ",
+		}},
+		{expr: `datetime_add("month", 1, _ts)`, expected: []interface{}{
+			nanos(time.Date(2020, 6, 13, 14, 35, 12, 123456789, time.UTC)),
+			nanos(time.Date(2020, 1, 31, 23, 59, 59, 0, time.UTC)),
+		}},
+		{expr: `datetime_add("minute", -30, _ts)`, expected: []interface{}{
+			nanos(time.Date(2020, 5, 13, 14, 5, 12, 123456789, time.UTC)),
+			nanos(time.Date(2019, 12, 31, 23, 29, 59, 0, time.UTC)),
+		}},
+		{expr: `datetime_diff("year", _ts, datetime(2019-12-31))`, expected: []interface{}{int64(1), int64(0)}},
+		{expr: `datetime_diff("month", _ts, datetime(2019-12-31))`, expected: []interface{}{int64(5), int64(0)}},
+		{expr: `datetime_diff("day", _ts, datetime(2019-12-31))`, expected: []interface{}{int64(134), int64(0)}},
+		{expr: `datetime_diff("week", _ts, datetime(2019-12-31))`, expected: []interface{}{int64(19), int64(0)}},
+		{expr: "_ts - 1d", expected: []interface{}{
+			nanos(t1.Add(-24 * time.Hour)),
+			nanos(t2.Add(-24 * time.Hour)),
+		}},
+		{expr: "_ts > datetime(2020-01-01)", expected: []interface{}{true, false}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.expr, func(t *testing.T) {
+			assert.Equal(t, c.expected, evalValues(t, c.expr, batch))
+		})
+	}
+
+}
+
+func TestNowAndAgo(t *testing.T) {
+
+	batch := testBatch(map[string]Col{"n": int64Col(0, 1)})
+
+	before := time.Now().UnixNano()
+	now := evalValues(t, "now()", batch)[0].(int64)
+	ago := evalValues(t, "ago(1h)", batch)[0].(int64)
+	after := time.Now().UnixNano()
+
+	assert.True(t, before <= now && now <= after)
+	assert.True(t, before-int64(time.Hour) <= ago && ago <= after-int64(time.Hour))
+
+}
+
+func TestBinSortedTimestamps(t *testing.T) {
+
+	base := time.Date(2020, 5, 13, 14, 0, 0, 0, time.UTC)
+	var ts []time.Time
+
+	for i := 0; i < 40; i++ {
+		ts = append(ts, base.Add(time.Duration(i*37)*time.Second))
+	}
+
+	sorted := timestampCol(0, ts...)
+	sorted.Sorted = true
+
+	unsorted := timestampCol(0, ts...)
+	expr := columnExpr(t, "extend bin(_ts, 5m)")[0].Expr
+
+	fast := NewEvaluator(expr).Eval(testBatch(map[string]Col{"_ts": sorted}))
+	slow := NewEvaluator(expr).Eval(testBatch(map[string]Col{"_ts": unsorted}))
+
+	assert.Equal(t, slow.Vec, fast.Vec)
+	assert.True(t, fast.Sorted)
+	assert.False(t, slow.Sorted)
+
+}
+
+func TestSummarizeByTimeBucket(t *testing.T) {
+
+	op := summarize(t, "count(), sum(n) by bin(_ts, 5m)")
+	base := time.Date(2020, 5, 13, 14, 0, 0, 0, time.UTC)
+
+	segment := func(sorted bool, minutes ...int) Batch {
+
+		ts := make([]time.Time, len(minutes))
+		n := make([]int64, len(minutes))
+
+		for i, m := range minutes {
+			ts[i] = base.Add(time.Duration(m) * time.Minute)
+			n[i] = int64(m)
+		}
+
+		col := timestampCol(0, ts...)
+		col.Sorted = sorted
+
+		return testBatch(map[string]Col{"_ts": col, "n": int64Col(1, n...)})
+
+	}
+
+	// every node aggregates its segments, the segment timestamps are sorted
+	// while the rows of the last batch are not.
+	node1 := NewHashAggOp(&batchSourceOp{batches: []Batch{
+		segment(true, 0, 1, 4, 5, 9, 12),
+		segment(true, 3, 7, 8),
+	}}, op.By, op.Agg, PartialAgg, NewAllocator(0, "", ""))
+
+	node2 := NewHashAggOp(&batchSourceOp{batches: []Batch{
+		segment(true, 2, 6, 14),
+		segment(false, 11, 1, 6),
+	}}, op.By, op.Agg, PartialAgg, NewAllocator(0, "", ""))
+
+	merge := NewHashAggOp(NewMergeOp([]BatchOperator{node1, node2}), op.By, op.Agg, MergeAgg, NewAllocator(0, "", ""))
+	final := NewHashAggOp(merge, op.By, op.Agg, FinalAgg, NewAllocator(0, "", ""))
+
+	rows := rowsByKey(drain(final), "_ts")
+
+	assert.Len(t, rows, 3)
+
+	bucket := func(m int) int64 { return base.Add(time.Duration(m) * time.Minute).UnixNano() }
+
+	assert.Equal(t, map[string]interface{}{
+		"_ts": bucket(0), "count_": int64(6), "sum_n": int64(11),
+	}, rows[bucket(0)])
+
+	assert.Equal(t, map[string]interface{}{
+		"_ts": bucket(5), "count_": int64(6), "sum_n": int64(41),
+	}, rows[bucket(5)])
+
+	assert.Equal(t, map[string]interface{}{
+		"_ts": bucket(10), "count_": int64(3), "sum_n": int64(37),
+	}, rows[bucket(10)])
+
+}
@@ -0,0 +1,124 @@
+// Copyright 2020 The Meerkat Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package physical
+
+import "meerkat/internal/query/execbase"
+
+type gatherMsg struct {
+	batch Batch
+	err   interface{}
+}
+
+// GatherOp merges its inputs in no particular order. Every input is read
+// on its own goroutine so a slow input doesn't block the others. This is
+// required to read from exchange streams, a stream that is not read can
+// block the remote sender ( and so the whole query ).
+type GatherOp struct {
+	input   []BatchOperator
+	execCtx execbase.ExecutionContext
+	ch      chan gatherMsg
+	running int
+	started bool
+}
+
+func NewGatherOp(input []BatchOperator, execCtx execbase.ExecutionContext) *GatherOp {
+	return &GatherOp{
+		input:   input,
+		execCtx: execCtx,
+	}
+}
+
+func (g *GatherOp) Init() {
+	for _, operator := range g.input {
+		operator.Init()
+	}
+}
+
+func (g *GatherOp) Close() {
+	for _, operator := range g.input {
+		operator.Close()
+	}
+}
+
+func (g *GatherOp) start() {
+
+	g.ch = make(chan gatherMsg, len(g.input))
+	g.running = len(g.input)
+	g.started = true
+
+	for _, operator := range g.input {
+		go g.read(operator)
+	}
+
+}
+
+func (g *GatherOp) read(input BatchOperator) {
+
+	for {
+
+		batch, err := safeNext(input)
+
+		select {
+		case g.ch <- gatherMsg{batch: batch, err: err}:
+		case <-g.execCtx.Done():
+			return
+		}
+
+		if err != nil || batch.Len == 0 {
+			return
+		}
+
+	}
+
+}
+
+func (g *GatherOp) Next() Batch {
+
+	if !g.started {
+		g.start()
+	}
+
+	for g.running > 0 {
+
+		select {
+
+		case msg := <-g.ch:
+
+			if msg.err != nil {
+				panic(msg.err)
+			}
+
+			if msg.batch.Len == 0 {
+				g.running--
+				continue
+			}
+
+			return msg.batch
+
+		case <-g.execCtx.Done():
+			panic(canceledError(g.execCtx))
+
+		}
+
+	}
+
+	return Batch{}
+
+}
+
+func (g *GatherOp) Accept(v Visitor) {
+	for i, operator := range g.input {
+		g.input[i] = Walk(operator, v).(BatchOperator)
+	}
+}
@@ -0,0 +1,548 @@
+// Copyright 2020 The Meerkat Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package physical
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"meerkat/internal/query/logical"
+	"meerkat/internal/storage/vector"
+	"sort"
+)
+
+// AggMode defines the input and the output of a HashAggOp.
+type AggMode int
+
+const (
+	// PartialAgg aggregates the input rows into partial states.
+	PartialAgg AggMode = iota
+	// MergeAgg merges partial states into partial states.
+	MergeAgg
+	// FinalAgg merges partial states and emits the final values.
+	FinalAgg
+)
+
+type groupKey struct {
+	name string
+	expr Evaluator
+}
+
+type aggregation struct {
+	name string
+	args []Evaluator
+	fn   aggFunc
+}
+
+// HashAggOp groups the input rows using a hash table and computes the
+// aggregations of every group. The whole input is consumed on the first
+// call to Next(). The memory used by the groups is reserved from alloc,
+// when the budget runs out the partial states are spilled to disk in
+// partitions by group key and every partition is aggregated at the end.
+type HashAggOp struct {
+	input    BatchOperator
+	mode     AggMode
+	by       []*logical.ColumnExpr
+	agg      []*logical.AggExpr
+	keys     []groupKey
+	aggs     []aggregation
+	alloc    Allocator
+	reserved int64
+	// pending is the memory used by the groups added by the current
+	// batch, it's reserved once the batch has been aggregated.
+	pending int64
+	groups  map[string]int
+	keyCols []vectorBuilder
+	keyBuf  []byte
+	output  Batch
+	pos     int
+	done    bool
+	// level is the number of times the input has been partitioned.
+	level      int
+	partitions []*runWriter
+	merges     []*HashAggOp
+}
+
+const (
+	// groupOverhead is the estimated memory used by a hash table entry.
+	groupOverhead = 64
+	// aggStateSize is the estimated memory used by the state of an
+	// aggregation for a group.
+	aggStateSize = 16
+	// spillPartitions is the number of partitions the groups are spilled to.
+	spillPartitions = 16
+	// maxSpillLevel bounds the times a partition can be spilled again.
+	maxSpillLevel = 3
+)
+
+func NewHashAggOp(
+	input BatchOperator,
+	by []*logical.ColumnExpr,
+	agg []*logical.AggExpr,
+	mode AggMode,
+	alloc Allocator,
+) *HashAggOp {
+
+	op := &HashAggOp{
+		input:   input,
+		mode:    mode,
+		by:      by,
+		agg:     agg,
+		alloc:   alloc,
+		groups:  make(map[string]int),
+		keyCols: make([]vectorBuilder, len(by)),
+	}
+
+	for _, expr := range by {
+
+		key := groupKey{name: expr.ColName}
+
+		// partial states are keyed by the group columns of the
+		// partial aggregation.
+		if mode == PartialAgg {
+			key.expr = NewEvaluator(expr.Expr)
+		} else {
+			key.expr = &colRefEvaluator{name: expr.ColName}
+		}
+
+		op.keys = append(op.keys, key)
+
+	}
+
+	for _, expr := range agg {
+
+		aggregation := aggregation{
+			name: expr.ColName,
+			fn:   newAggFunc(expr.Expr.FuncName, expr.ColName, expr.Expr.ArgList),
+		}
+
+		if mode == PartialAgg {
+
+			args := expr.Expr.ArgList
+
+			// the row columns are read by the aggregation itself.
+			if _, ok := aggregation.fn.(rowAggFunc); ok && len(args) > 1 {
+				args = args[:1]
+			}
+
+			for _, arg := range args {
+				aggregation.args = append(aggregation.args, NewEvaluator(arg))
+			}
+
+		}
+
+		op.aggs = append(op.aggs, aggregation)
+
+	}
+
+	return op
+
+}
+
+func (h *HashAggOp) Init() { h.input.Init() }
+
+func (h *HashAggOp) Close() {
+
+	h.release()
+
+	for _, merge := range h.merges {
+		merge.Close()
+	}
+
+	h.input.Close()
+
+}
+
+func (h *HashAggOp) Next() Batch {
+
+	if !h.done {
+		h.consume()
+		if h.partitions != nil {
+			h.mergePartitions()
+		} else {
+			h.output = h.buildOutput(h.mode == FinalAgg)
+		}
+		h.done = true
+	}
+
+	if h.partitions != nil {
+		return h.nextMerged()
+	}
+
+	if h.pos >= h.output.Len {
+		h.release()
+		return Batch{}
+	}
+
+	if h.pos == 0 && h.output.Len <= batchSize {
+		h.pos = h.output.Len
+		return h.output
+	}
+
+	end := h.pos + batchSize
+
+	if end > h.output.Len {
+		end = h.output.Len
+	}
+
+	sel := make([]int, 0, end-h.pos)
+
+	for i := h.pos; i < end; i++ {
+		sel = append(sel, i)
+	}
+
+	h.pos = end
+
+	return selectBatch(h.output, sel)
+
+}
+
+func (h *HashAggOp) consume() {
+
+	for batch := h.input.Next(); batch.Len != 0; batch = h.input.Next() {
+
+		groups := h.groupIds(batch)
+
+		for _, agg := range h.aggs {
+
+			agg.fn.resize(len(h.groups))
+
+			if h.mode == PartialAgg {
+				args := make([]Col, len(agg.args))
+				for i, arg := range agg.args {
+					args[i] = arg.Eval(batch)
+				}
+				if fn, ok := agg.fn.(rowAggFunc); ok {
+					fn.addRows(groups, args, batch)
+				} else {
+					agg.fn.add(groups, args)
+				}
+			} else {
+				agg.fn.merge(groups, batch)
+			}
+
+		}
+
+		h.reserve()
+
+	}
+
+	// an aggregation without group columns always returns one row.
+	if h.mode == FinalAgg && len(h.keys) == 0 && len(h.groups) == 0 && h.partitions == nil {
+		h.groups[""] = 0
+		for _, agg := range h.aggs {
+			agg.fn.resize(1)
+		}
+	}
+
+}
+
+// groupIds returns the group of every row in batch. New groups are added
+// to the hash table.
+func (h *HashAggOp) groupIds(batch Batch) []int {
+
+	keyCols := make([]Col, len(h.keys))
+	sorted := false
+
+	for i, key := range h.keys {
+		keyCols[i] = key.expr.Eval(batch)
+		sorted = sorted || keyCols[i].Sorted
+	}
+
+	groups := make([]int, batch.Len)
+
+	for row := range groups {
+
+		// rows with a sorted key ( ie. bin(_ts, 1m) ) come in runs of the
+		// same group, skip the hash table lookup within a run.
+		if sorted && row > 0 && sameKey(keyCols, row-1, row) {
+			groups[row] = groups[row-1]
+			continue
+		}
+
+		h.keyBuf = h.keyBuf[:0]
+
+		for _, col := range keyCols {
+			h.keyBuf = appendKey(h.keyBuf, col, row)
+		}
+
+		id, found := h.groups[string(h.keyBuf)]
+
+		if !found {
+			// the key is held by the hash table and the key builders.
+			h.pending += int64(2*len(h.keyBuf) + groupOverhead + len(h.aggs)*aggStateSize)
+			id = len(h.groups)
+			h.groups[string(h.keyBuf)] = id
+			for i, col := range keyCols {
+				h.keyCols[i].Append(col, row)
+			}
+		}
+
+		groups[row] = id
+
+	}
+
+	return groups
+
+}
+
+// reserve accounts the memory of the groups added by the last batch. The
+// groups are spilled if they don't fit in the budget.
+func (h *HashAggOp) reserve() {
+
+	n := h.pending
+	h.pending = 0
+
+	if h.alloc.TryReserve(n) {
+		h.reserved += n
+		return
+	}
+
+	if h.alloc.SpillDir() != "" && h.level < maxSpillLevel {
+		h.spill()
+		return
+	}
+
+	// panics with the memory limit error.
+	h.alloc.Reserve(n)
+	h.reserved += n
+
+}
+
+// spill writes the partial states of the groups to the partition given by
+// the hash of the group key and empties the hash table.
+func (h *HashAggOp) spill() {
+
+	if h.partitions == nil {
+		h.partitions = make([]*runWriter, spillPartitions)
+	}
+
+	output := h.buildOutput(false)
+
+	sel := make([][]int, spillPartitions)
+
+	for key, id := range h.groups {
+		p := spillPartition(key, h.level)
+		sel[p] = append(sel[p], id)
+	}
+
+	for p, rows := range sel {
+
+		if len(rows) == 0 {
+			continue
+		}
+
+		// keep the order in which the groups were found.
+		sort.Ints(rows)
+
+		if h.partitions[p] == nil {
+			h.partitions[p] = newRunWriter(h.alloc.SpillDir())
+		}
+
+		h.partitions[p].write(selectBatch(output, rows))
+
+	}
+
+	h.groups = make(map[string]int)
+	h.keyCols = make([]vectorBuilder, len(h.keys))
+
+	for i, expr := range h.agg {
+		h.aggs[i].fn = newAggFunc(expr.Expr.FuncName, expr.ColName, expr.Expr.ArgList)
+	}
+
+	h.release()
+
+}
+
+// mergePartitions spills the groups left in memory and creates the
+// operators aggregating the partitions. The partitions hold disjoint sets
+// of groups so they are aggregated one at a time.
+func (h *HashAggOp) mergePartitions() {
+
+	if len(h.groups) > 0 {
+		h.spill()
+	}
+
+	mode := MergeAgg
+
+	if h.mode == FinalAgg {
+		mode = FinalAgg
+	}
+
+	for _, writer := range h.partitions {
+
+		if writer == nil {
+			continue
+		}
+
+		op := NewHashAggOp(writer.close(), h.by, h.agg, mode, h.alloc)
+		op.level = h.level + 1
+		op.Init()
+
+		h.merges = append(h.merges, op)
+
+	}
+
+}
+
+func (h *HashAggOp) nextMerged() Batch {
+
+	for len(h.merges) > 0 {
+
+		batch := h.merges[0].Next()
+
+		if batch.Len > 0 {
+			return batch
+		}
+
+		h.merges = h.merges[1:]
+
+	}
+
+	return Batch{}
+
+}
+
+func (h *HashAggOp) release() {
+	h.alloc.Release(h.reserved)
+	h.reserved = 0
+}
+
+// spillPartition returns the partition of a group key. The level is part
+// of the hash so a partition spilled again is split in new partitions.
+func spillPartition(key string, level int) int {
+
+	hash := fnv.New32a()
+
+	_, _ = hash.Write([]byte{byte(level)})
+	_, _ = hash.Write([]byte(key))
+
+	return int(hash.Sum32() % spillPartitions)
+
+}
+
+func (h *HashAggOp) buildOutput(final bool) Batch {
+
+	output := NewBatch()
+	output.Len = len(h.groups)
+
+	for i, key := range h.keys {
+		col := h.keyCols[i].Build()
+		col.Order = int64(i)
+		output.Columns[key.name] = col
+	}
+
+	order := int64(len(h.keys))
+
+	for _, agg := range h.aggs {
+
+		if !final {
+			for name, col := range agg.fn.partial() {
+				col.Order = order
+				output.Columns[name] = col
+			}
+			order++
+			continue
+		}
+
+		if fn, ok := agg.fn.(multiAggFunc); ok {
+
+			// the columns already in the output are skipped, ie. the
+			// group columns found by arg_max(_ts, *).
+			names, cols := fn.outputs()
+
+			for i, name := range names {
+				if _, found := output.Columns[name]; !found {
+					cols[i].Order = order
+					output.Columns[name] = cols[i]
+					order++
+				}
+			}
+
+			continue
+
+		}
+
+		col := agg.fn.final()
+		col.Order = order
+		output.Columns[agg.name] = col
+		order++
+
+	}
+
+	return output
+
+}
+
+func (h *HashAggOp) Accept(v Visitor) {
+	h.input = Walk(h.input, v).(BatchOperator)
+}
+
+// appendKey appends the binary representation of the i-th value of col to
+// key. Nulls are represented by a single zero byte and values are prefixed
+// with a one byte so the key of a null never matches the key of a value.
+func appendKey(key []byte, col Col, i int) []byte {
+
+	if isNull(col.Vec, i) {
+		return append(key, 0)
+	}
+
+	key = append(key, 1)
+
+	var buf [binary.MaxVarintLen64]byte
+
+	switch v := col.Vec.(type) {
+	case *vector.Int64Vector:
+		binary.LittleEndian.PutUint64(buf[:], uint64(v.Get(i)))
+		return append(key, buf[:8]...)
+	case *vector.Float64Vector:
+		binary.LittleEndian.PutUint64(buf[:], math.Float64bits(v.Get(i)))
+		return append(key, buf[:8]...)
+	case *vector.BoolVector:
+		if v.Get(i) {
+			return append(key, 1)
+		}
+		return append(key, 0)
+	case *vector.ByteSliceVector:
+		value := v.Get(i)
+		n := binary.PutUvarint(buf[:], uint64(len(value)))
+		key = append(key, buf[:n]...)
+		return append(key, value...)
+	default:
+		panic(fmt.Sprintf("cannot group by vector %T", col.Vec))
+	}
+
+}
+
+// sameKey returns true if the rows i and j have the same key values.
+func sameKey(keyCols []Col, i, j int) bool {
+
+	for _, col := range keyCols {
+
+		iNull, jNull := isNull(col.Vec, i), isNull(col.Vec, j)
+
+		if iNull != jNull {
+			return false
+		}
+
+		if !iNull && compareValues(col, i, col, j) != 0 {
+			return false
+		}
+
+	}
+
+	return true
+
+}
@@ -0,0 +1,223 @@
+// Copyright 2020 The Meerkat Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package physical
+
+import (
+	"github.com/stretchr/testify/assert"
+	"meerkat/internal/query/execpb"
+	"meerkat/internal/query/logical"
+	"meerkat/internal/query/parser"
+	"testing"
+)
+
+// summarize parses a summarize operator and returns its logical form.
+func summarize(t *testing.T, expr string) *logical.SummarizeOp {
+
+	ast, err := parser.Parse("T | summarize " + expr)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return logical.ToLogical(ast)[0].(*logical.SummarizeOp)
+
+}
+
+// drain reads all the batches from op and returns the values of every
+// column.
+func drain(op BatchOperator) map[string][]interface{} {
+
+	result := make(map[string][]interface{})
+
+	for batch := op.Next(); batch.Len != 0; batch = op.Next() {
+		for name, col := range batch.Columns {
+			for i := 0; i < batch.Len; i++ {
+				var value interface{}
+				if !isNull(col.Vec, i) {
+					value = jsonValue(col.Vec, i)
+				}
+				result[name] = append(result[name], value)
+			}
+		}
+	}
+
+	return result
+
+}
+
+func rowsByKey(result map[string][]interface{}, key string) map[interface{}]map[string]interface{} {
+
+	rows := make(map[interface{}]map[string]interface{})
+
+	for i, k := range result[key] {
+		row := make(map[string]interface{})
+		for name, values := range result {
+			row[name] = values[i]
+		}
+		rows[k] = row
+	}
+
+	return rows
+
+}
+
+func nodeInput(hosts []string, latency []int64) BatchOperator {
+	return &batchSourceOp{batches: []Batch{
+		testBatch(map[string]Col{
+			"host":    stringCol(0, hosts...),
+			"latency": int64Col(1, latency...),
+		}),
+	}}
+}
+
+func TestHashAggOp(t *testing.T) {
+
+	op := summarize(t, "count(), sum(latency), min(latency), max(latency), avg(latency) by host")
+
+	// two nodes compute partial aggregations of its own rows.
+	node1 := NewHashAggOp(nodeInput(
+		[]string{"a", "b", "a", "c"},
+		[]int64{10, 20, 30, 5},
+	), op.By, op.Agg, PartialAgg, NewAllocator(0, "", ""))
+
+	node2 := NewHashAggOp(nodeInput(
+		[]string{"b", "a", "b"},
+		[]int64{40, 2, 60},
+	), op.By, op.Agg, PartialAgg, NewAllocator(0, "", ""))
+
+	merge := NewHashAggOp(NewMergeOp([]BatchOperator{node1, node2}), op.By, op.Agg, MergeAgg, NewAllocator(0, "", ""))
+	final := NewHashAggOp(merge, op.By, op.Agg, FinalAgg, NewAllocator(0, "", ""))
+
+	rows := rowsByKey(drain(final), "host")
+
+	assert.Len(t, rows, 3)
+
+	assert.Equal(t, map[string]interface{}{
+		"host":        "a",
+		"count_":      int64(3),
+		"sum_latency": int64(42),
+		"min_latency": int64(2),
+		"max_latency": int64(30),
+		"avg_latency": float64(14),
+	}, rows["a"])
+
+	assert.Equal(t, map[string]interface{}{
+		"host":        "b",
+		"count_":      int64(3),
+		"sum_latency": int64(120),
+		"min_latency": int64(20),
+		"max_latency": int64(60),
+		"avg_latency": float64(40),
+	}, rows["b"])
+
+	assert.Equal(t, map[string]interface{}{
+		"host":        "c",
+		"count_":      int64(1),
+		"sum_latency": int64(5),
+		"min_latency": int64(5),
+		"max_latency": int64(5),
+		"avg_latency": float64(5),
+	}, rows["c"])
+
+}
+
+func TestHashAggOpWithoutGroups(t *testing.T) {
+
+	op := summarize(t, "n=count(), m=max(host)")
+
+	partial := NewHashAggOp(nodeInput(
+		[]string{"a", "c", "b"},
+		[]int64{1, 2, 3},
+	), op.By, op.Agg, PartialAgg, NewAllocator(0, "", ""))
+
+	final := NewHashAggOp(partial, op.By, op.Agg, FinalAgg, NewAllocator(0, "", ""))
+
+	assert.Equal(t, map[string][]interface{}{
+		"n": {int64(3)},
+		"m": {"c"},
+	}, drain(final))
+
+	// without input rows a single row is returned.
+	partial = NewHashAggOp(&batchSourceOp{}, op.By, op.Agg, PartialAgg, NewAllocator(0, "", ""))
+	final = NewHashAggOp(partial, op.By, op.Agg, FinalAgg, NewAllocator(0, "", ""))
+
+	assert.Equal(t, map[string][]interface{}{
+		"n": {int64(0)},
+		"m": {nil},
+	}, drain(final))
+
+}
+
+func TestHashAggOpNullKeys(t *testing.T) {
+
+	op := summarize(t, "count() by missing")
+
+	partial := NewHashAggOp(nodeInput(
+		[]string{"a", "c", "b"},
+		[]int64{1, 2, 3},
+	), op.By, op.Agg, PartialAgg, NewAllocator(0, "", ""))
+
+	final := NewHashAggOp(partial, op.By, op.Agg, FinalAgg, NewAllocator(0, "", ""))
+
+	assert.Equal(t, map[string][]interface{}{
+		"missing": {nil},
+		"count_":  {int64(3)},
+	}, drain(final))
+
+}
+
+type testStream struct {
+	batches []Batch
+}
+
+func (s *testStream) open()                  {}
+func (s *testStream) send(batch Batch)       { s.batches = append(s.batches, batch) }
+func (s *testStream) fail(*execpb.ExecError) {}
+func (s *testStream) close()                 {}
+
+func TestHashExchangeOutOp(t *testing.T) {
+
+	streams := []*testStream{{}, {}, {}}
+
+	out := NewHashExchangeOutOp(
+		nodeInput(
+			[]string{"a", "b", "c", "d", "a", "b", "c", "d"},
+			[]int64{1, 2, 3, 4, 5, 6, 7, 8},
+		),
+		[]string{"host"},
+		[]batchStream{streams[0], streams[1], streams[2]},
+		"local",
+	)
+
+	out.Run()
+
+	hosts := make(map[string]int)
+	rows := 0
+
+	for i, stream := range streams {
+		for _, batch := range stream.batches {
+			rows += batch.Len
+			for _, host := range stringValues(batch.Columns["host"]) {
+				if p, found := hosts[host]; found {
+					assert.Equal(t, p, i, "host %v found in more than one partition", host)
+				}
+				hosts[host] = i
+			}
+		}
+	}
+
+	assert.Equal(t, 8, rows)
+	assert.Len(t, hosts, 4)
+
+}
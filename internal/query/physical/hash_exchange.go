@@ -0,0 +1,133 @@
+// Copyright 2020 The Meerkat Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package physical
+
+import (
+	"hash/fnv"
+)
+
+// HashExchangeOutOp partitions the input rows by the hash of the key
+// columns and sends every partition to a different stream. All the rows
+// with the same key end up in the same stream regardless of the node that
+// produced them as long as every node uses the same stream order.
+type HashExchangeOutOp struct {
+	input         BatchOperator
+	keys          []string
+	streams       []batchStream
+	localNodeName string
+}
+
+func NewHashExchangeOutOp(
+	input BatchOperator,
+	keys []string,
+	streams []batchStream,
+	localNodeName string,
+) *HashExchangeOutOp {
+	return &HashExchangeOutOp{
+		input:         input,
+		keys:          keys,
+		streams:       streams,
+		localNodeName: localNodeName,
+	}
+}
+
+func (h *HashExchangeOutOp) Init()  { h.input.Init() }
+func (h *HashExchangeOutOp) Close() { h.input.Close() }
+
+func (h *HashExchangeOutOp) Run() {
+
+	for _, stream := range h.streams {
+		stream.open()
+	}
+
+	for {
+
+		batch, err := safeNext(h.input)
+
+		if err != nil {
+			execErr := buildExecError(err, h.localNodeName)
+			for _, stream := range h.streams {
+				stream.fail(execErr)
+			}
+			panic(execErr)
+		}
+
+		if batch.Len == 0 {
+			for _, stream := range h.streams {
+				stream.close()
+			}
+			return
+		}
+
+		for i, sel := range h.partition(batch) {
+
+			if len(sel) == 0 {
+				continue
+			}
+
+			if len(sel) == batch.Len {
+				h.streams[i].send(batch)
+				continue
+			}
+
+			h.streams[i].send(selectBatch(batch, sel))
+
+		}
+
+	}
+
+}
+
+// partition returns the rows of batch that should be sent to each stream.
+func (h *HashExchangeOutOp) partition(batch Batch) [][]int {
+
+	partitions := make([][]int, len(h.streams))
+
+	keyCols := make([]Col, len(h.keys))
+
+	for i, key := range h.keys {
+		col, found := batch.Columns[key]
+		if !found {
+			col = Col{Vec: &nullVector{l: batch.Len}}
+		}
+		keyCols[i] = col
+	}
+
+	var key []byte
+	hash := fnv.New64a()
+
+	for row := 0; row < batch.Len; row++ {
+
+		key = key[:0]
+
+		for _, col := range keyCols {
+			key = appendKey(key, col, row)
+		}
+
+		hash.Reset()
+		_, _ = hash.Write(key)
+
+		p := hash.Sum64() % uint64(len(h.streams))
+
+		partitions[p] = append(partitions[p], row)
+
+	}
+
+	return partitions
+
+}
+
+func (h *HashExchangeOutOp) Accept(v Visitor) {
+	h.input = Walk(h.input, v).(BatchOperator)
+}
@@ -0,0 +1,298 @@
+// Copyright 2020 The Meerkat Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package physical
+
+import "meerkat/internal/query/logical"
+
+// HashJoinOp joins the rows of the left input with the rows of the right
+// input having the same key values. The right input is read first into a
+// hash table held in memory, then the left rows are streamed probing it.
+// Rows with null keys never match. The right columns named like a left
+// column are not returned, the analyzer renames them.
+type HashJoinOp struct {
+	left     BatchOperator
+	right    BatchOperator
+	kind     logical.JoinKind
+	keys     []string
+	alloc    Allocator
+	reserved int64
+	// batches are the right batches and table maps every key to the
+	// right rows having it.
+	batches []Batch
+	table   map[string][]rowRef
+	// rightCols are the right columns returned in the output.
+	rightCols []string
+	built     bool
+	// probe is the left batch being joined, pos is the next row and match
+	// the next right row matching it.
+	probe Batch
+	pos   int
+	match int
+	key   []byte
+}
+
+func NewHashJoinOp(
+	left BatchOperator,
+	right BatchOperator,
+	kind logical.JoinKind,
+	keys []string,
+	alloc Allocator,
+) *HashJoinOp {
+	return &HashJoinOp{
+		left:  left,
+		right: right,
+		kind:  kind,
+		keys:  keys,
+		alloc: alloc,
+		table: make(map[string][]rowRef),
+	}
+}
+
+func (j *HashJoinOp) Init() {
+	j.left.Init()
+	j.right.Init()
+}
+
+func (j *HashJoinOp) Close() {
+	j.release()
+	j.left.Close()
+	j.right.Close()
+}
+
+func (j *HashJoinOp) Next() Batch {
+
+	if !j.built {
+		j.build()
+		j.built = true
+	}
+
+	for {
+
+		if j.pos == j.probe.Len {
+
+			j.probe = j.left.Next()
+			j.pos = 0
+
+			if j.probe.Len == 0 {
+				j.release()
+				return Batch{}
+			}
+
+		}
+
+		batch := j.join()
+
+		if batch.Len > 0 {
+			return batch
+		}
+
+	}
+
+}
+
+// build reads the right input into the hash table.
+func (j *HashJoinOp) build() {
+
+	columns := make(map[string]Col)
+
+	for batch := j.right.Next(); batch.Len != 0; batch = j.right.Next() {
+
+		n := batchBytes(batch) + int64(batch.Len)*rowRefSize
+
+		// panics if the right side doesn't fit in the query budget.
+		j.alloc.Reserve(n)
+		j.reserved += n
+
+		idx := len(j.batches)
+		j.batches = append(j.batches, batch)
+
+		keyCols := j.keyCols(batch)
+
+		for i := 0; i < batch.Len; i++ {
+			if key, ok := j.rowKey(keyCols, i); ok {
+				j.table[string(key)] = append(j.table[string(key)], rowRef{batch: idx, row: i})
+			}
+		}
+
+		for name, col := range batch.Columns {
+			if _, found := columns[name]; !found {
+				columns[name] = col
+			}
+		}
+
+	}
+
+	// the keys are already in the left columns.
+	for _, key := range j.keys {
+		delete(columns, key)
+	}
+
+	j.rightCols = columnOrder(columns)
+
+}
+
+func (j *HashJoinOp) keyCols(batch Batch) []Col {
+
+	cols := make([]Col, len(j.keys))
+
+	for i, key := range j.keys {
+		col, found := batch.Columns[key]
+		if !found {
+			col = Col{Vec: &nullVector{l: batch.Len}}
+		}
+		cols[i] = col
+	}
+
+	return cols
+
+}
+
+// rowKey returns the key of the i-th row, ok is false if any of the key
+// values is null.
+func (j *HashJoinOp) rowKey(keyCols []Col, i int) ([]byte, bool) {
+
+	j.key = j.key[:0]
+
+	for _, col := range keyCols {
+		if isNull(col.Vec, i) {
+			return nil, false
+		}
+		j.key = appendKey(j.key, col, i)
+	}
+
+	return j.key, true
+
+}
+
+// join joins the rows of the probe batch starting at pos until the output
+// batch is full or the probe batch is exhausted.
+func (j *HashJoinOp) join() Batch {
+
+	keyCols := j.keyCols(j.probe)
+
+	var left []int
+	var right []rowRef
+
+	for j.pos < j.probe.Len && len(left) < batchSize {
+
+		var matches []rowRef
+
+		if key, ok := j.rowKey(keyCols, j.pos); ok {
+			matches = j.table[string(key)]
+		}
+
+		switch j.kind {
+
+		case logical.LeftSemiJoin:
+			if len(matches) > 0 {
+				left = append(left, j.pos)
+			}
+
+		case logical.LeftAntiJoin:
+			if len(matches) == 0 {
+				left = append(left, j.pos)
+			}
+
+		default:
+
+			if len(matches) == 0 && j.kind == logical.LeftOuterJoin {
+				left = append(left, j.pos)
+				right = append(right, rowRef{batch: -1})
+			}
+
+			for j.match < len(matches) && len(left) < batchSize {
+				left = append(left, j.pos)
+				right = append(right, matches[j.match])
+				j.match++
+			}
+
+			// the output is full, the remaining matches are returned in
+			// the next batch.
+			if j.match < len(matches) {
+				return j.output(left, right)
+			}
+
+			j.match = 0
+
+		}
+
+		j.pos++
+
+	}
+
+	return j.output(left, right)
+
+}
+
+// output builds a batch with the left rows and their matching right rows.
+func (j *HashJoinOp) output(left []int, right []rowRef) Batch {
+
+	batch := selectBatch(j.probe, left)
+
+	if j.kind == logical.LeftSemiJoin || j.kind == logical.LeftAntiJoin {
+		return batch
+	}
+
+	group, order := lastPosition(j.probe)
+
+	for _, name := range j.rightCols {
+
+		if _, found := batch.Columns[name]; found {
+			continue
+		}
+
+		var b vectorBuilder
+
+		for _, ref := range right {
+
+			if ref.batch < 0 {
+				b.AppendNull()
+				continue
+			}
+
+			col, found := j.batches[ref.batch].Columns[name]
+
+			if !found {
+				b.AppendNull()
+				continue
+			}
+
+			b.Append(col, ref.row)
+
+		}
+
+		order++
+
+		col := b.Build()
+		col.Group, col.Order = group, order
+
+		batch.Columns[name] = col
+
+	}
+
+	return batch
+
+}
+
+func (j *HashJoinOp) release() {
+	j.alloc.Release(j.reserved)
+	j.reserved = 0
+	j.batches = nil
+	j.table = nil
+}
+
+func (j *HashJoinOp) Accept(v Visitor) {
+	j.left = Walk(j.left, v).(BatchOperator)
+	j.right = Walk(j.right, v).(BatchOperator)
+}
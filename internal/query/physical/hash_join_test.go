@@ -0,0 +1,138 @@
+// Copyright 2020 The Meerkat Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package physical
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"meerkat/internal/query/logical"
+	"sort"
+	"testing"
+)
+
+func joinInputs() (BatchOperator, BatchOperator) {
+
+	left := &batchSourceOp{batches: []Batch{
+		testBatch(map[string]Col{
+			"host":  stringCol(0, "a", "b", "c", "d", "e"),
+			"trace": nullableInt64Col(1, 1, 2, 3, nil, 2),
+		}),
+	}}
+
+	right := &batchSourceOp{batches: []Batch{
+		testBatch(map[string]Col{
+			"trace": nullableInt64Col(0, 2, 2, nil),
+			"code":  int64Col(1, 500, 501, 502),
+			"host":  stringCol(2, "x", "y", "z"),
+		}),
+		testBatch(map[string]Col{
+			"trace": nullableInt64Col(0, 3, 5),
+			"code":  int64Col(1, 503, 504),
+		}),
+	}}
+
+	return left, right
+
+}
+
+// joinedRows returns the output rows as host:code strings sorted.
+func joinedRows(result map[string][]interface{}) []string {
+
+	var rows []string
+
+	for i, host := range result["host"] {
+		row := fmt.Sprint(host)
+		if codes, found := result["code"]; found {
+			row += fmt.Sprint(":", codes[i])
+		}
+		rows = append(rows, row)
+	}
+
+	sort.Strings(rows)
+
+	return rows
+
+}
+
+func TestHashJoinOp(t *testing.T) {
+
+	tests := []struct {
+		kind     logical.JoinKind
+		expected []string
+		columns  []string
+	}{
+		{logical.InnerJoin, []string{"b:500", "b:501", "c:503", "e:500", "e:501"}, []string{"host", "trace", "code"}},
+		{logical.LeftOuterJoin, []string{"a:<nil>", "b:500", "b:501", "c:503", "d:<nil>", "e:500", "e:501"}, []string{"host", "trace", "code"}},
+		{logical.LeftSemiJoin, []string{"b", "c", "e"}, []string{"host", "trace"}},
+		{logical.LeftAntiJoin, []string{"a", "d"}, []string{"host", "trace"}},
+	}
+
+	for _, test := range tests {
+
+		left, right := joinInputs()
+		alloc := NewAllocator(0, "", "node1")
+
+		op := NewHashJoinOp(left, right, test.kind, []string{"trace"}, alloc)
+		op.Init()
+
+		batch := op.Next()
+		result := drain(&batchSourceOp{batches: []Batch{batch}})
+
+		// the right key and the right host clashing with the left one are
+		// not returned.
+		assert.Equal(t, test.columns, columnOrder(batch.Columns), test.kind.String())
+		assert.Equal(t, test.expected, joinedRows(result), test.kind.String())
+		assert.Equal(t, 0, op.Next().Len)
+		assert.Equal(t, int64(0), alloc.Used())
+
+		op.Close()
+
+	}
+
+}
+
+func TestHashJoinOpLargeOutput(t *testing.T) {
+
+	n := batchSize + 10
+
+	traces := make([]interface{}, n)
+	codes := make([]int64, n)
+
+	for i := range traces {
+		traces[i] = 1
+		codes[i] = int64(i)
+	}
+
+	left := &batchSourceOp{batches: []Batch{testBatch(map[string]Col{
+		"trace": nullableInt64Col(0, 1, 1),
+	})}}
+
+	right := &batchSourceOp{batches: []Batch{testBatch(map[string]Col{
+		"trace": nullableInt64Col(0, traces...),
+		"code":  int64Col(1, codes...),
+	})}}
+
+	op := NewHashJoinOp(left, right, logical.InnerJoin, []string{"trace"}, NewAllocator(0, "", "node1"))
+	op.Init()
+
+	var sizes []int
+
+	for batch := op.Next(); batch.Len != 0; batch = op.Next() {
+		sizes = append(sizes, batch.Len)
+	}
+
+	// every left row matches n right rows.
+	assert.Equal(t, []int{batchSize, batchSize, 20}, sizes)
+
+}
@@ -0,0 +1,161 @@
+// Copyright 2020 The Meerkat Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package physical
+
+import (
+	"fmt"
+	"github.com/RoaringBitmap/roaring"
+	"meerkat/internal/storage"
+	"meerkat/internal/storage/vector"
+)
+
+// timeIndexed is implemented by the timestamp column.
+type timeIndexed interface {
+	Index() storage.TimeIndex
+}
+
+// byteSliceReadable is implemented by the string columns.
+type byteSliceReadable interface {
+	Reader() storage.ByteSliceReader
+}
+
+// intervalRows returns the rows of the segment with a timestamp in the
+// interval using the timestamp index, nil if all the rows of the segment
+// are in it. The string columns don't write an index yet so the filter
+// predicates are always evaluated on the selected rows.
+func intervalRows(segment storage.Segment, interval *storage.Interval) *roaring.Bitmap {
+
+	if interval == nil {
+		return nil
+	}
+
+	info := segment.Info()
+
+	if info.Interval != nil &&
+		!info.Interval.From.Before(interval.From) &&
+		!info.Interval.To.After(interval.To) {
+		return nil
+	}
+
+	indexed, hasIndex := segment.Column(storage.TSColumnName).(timeIndexed)
+
+	if !hasIndex {
+		return nil
+	}
+
+	return indexed.Index().TimeRangeAsBitmap(
+		int(interval.From.UnixNano()),
+		int(interval.To.UnixNano()),
+	)
+
+}
+
+// positionalReader reads the values at the given row ids, which must be
+// in ascending order.
+type positionalReader func(rids []uint32) vector.Vector
+
+func newPositionalReader(col storage.Column) positionalReader {
+
+	switch c := col.(type) {
+	case storage.Int64Column:
+		r := c.Reader()
+		return func(rids []uint32) vector.Vector {
+			v := r.Read(rids)
+			return &v
+		}
+	case storage.Float64Column:
+		r := c.Reader()
+		return func(rids []uint32) vector.Vector {
+			v := r.Read(rids)
+			return &v
+		}
+	case byteSliceReadable:
+		r := c.Reader()
+		return func(rids []uint32) vector.Vector {
+			v := r.Read(rids)
+			return &v
+		}
+	default:
+		panic(fmt.Sprintf("unknown column type : %T", col))
+	}
+
+}
+
+// IndexScanOp reads the rows of a segment selected by a bitmap. Only the
+// selected rows of each column are materialized.
+type IndexScanOp struct {
+	colNames []string
+	colTypes []storage.ColumnType
+	readers  []positionalReader
+	rows     *roaring.Bitmap
+	iter     roaring.ManyIntIterable
+	rids     []uint32
+}
+
+func NewIndexScanOp(
+	segment storage.Segment,
+	columns []string,
+	rows *roaring.Bitmap,
+) *IndexScanOp {
+
+	op := &IndexScanOp{
+		rows: rows,
+		iter: rows.ManyIterator(),
+		rids: make([]uint32, batchSize),
+	}
+
+	for _, columnInfo := range segmentColumns(segment, columns) {
+		op.colNames = append(op.colNames, columnInfo.Name)
+		op.colTypes = append(op.colTypes, columnInfo.ColumnType)
+		op.readers = append(op.readers, newPositionalReader(segment.Column(columnInfo.Name)))
+	}
+
+	return op
+
+}
+
+func (s *IndexScanOp) Init()  {}
+func (s *IndexScanOp) Close() {}
+
+func (s *IndexScanOp) Next() Batch {
+
+	n := s.iter.NextMany(s.rids)
+
+	if n == 0 {
+		return Batch{}
+	}
+
+	batch := NewBatch()
+	batch.Len = n
+
+	for i, name := range s.colNames {
+		batch.Columns[name] = Col{
+			Group:      0,
+			Order:      int64(i),
+			Vec:        s.readers[i](s.rids[:n]),
+			ColumnType: s.colTypes[i],
+			// segments are sorted by timestamp at ingestion.
+			Sorted: name == storage.TSColumnName,
+		}
+	}
+
+	return batch
+
+}
+
+func (s *IndexScanOp) Accept(Visitor) {}
+
+func (s *IndexScanOp) describe() string {
+	return fmt.Sprintf("columns: %v rows: %v", s.colNames, s.rows.GetCardinality())
+}
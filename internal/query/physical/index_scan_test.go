@@ -0,0 +1,217 @@
+// Copyright 2020 The Meerkat Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package physical
+
+import (
+	"github.com/RoaringBitmap/roaring"
+	"github.com/stretchr/testify/assert"
+	"meerkat/internal/query/logical"
+	"meerkat/internal/storage"
+	"meerkat/internal/storage/vector"
+	"testing"
+	"time"
+)
+
+// testSegment is an in memory segment.
+type testSegment struct {
+	info    *storage.SegmentInfo
+	columns map[string]storage.Column
+}
+
+func (s *testSegment) Info() *storage.SegmentInfo        { return s.info }
+func (s *testSegment) Column(name string) storage.Column { return s.columns[name] }
+func (s *testSegment) Close()                            {}
+
+// testStringColumn is an in memory string column, empty strings are
+// stored as nulls.
+type testStringColumn struct {
+	values []string
+	valid  *roaring.Bitmap
+}
+
+func newTestStringColumn(values ...string) *testStringColumn {
+
+	c := &testStringColumn{values: values, valid: roaring.New()}
+
+	for i, s := range values {
+		if s != "" {
+			c.valid.Add(uint32(i))
+		}
+	}
+
+	return c
+
+}
+
+func (c *testStringColumn) Validity() *roaring.Bitmap       { return c.valid }
+func (c *testStringColumn) Reader() storage.ByteSliceReader { return c }
+
+func (c *testStringColumn) Read(pos []uint32) vector.ByteSliceVector {
+
+	data := make([][]byte, len(pos))
+	valid := newValidity(len(pos))
+
+	for i, p := range pos {
+		data[i] = []byte(c.values[p])
+		if c.values[p] != "" {
+			setValid(valid, i)
+		}
+	}
+
+	return vector.NewByteSliceVectorFromByteArray(data, valid)
+
+}
+
+// testInt64Column is an in memory int column without index.
+type testInt64Column struct {
+	values []int64
+}
+
+func (c *testInt64Column) Validity() *roaring.Bitmap         { return nil }
+func (c *testInt64Column) Reader() storage.Int64ColumnReader { return c }
+func (c *testInt64Column) Iterator() storage.Int64Iterator {
+	return &testInt64Iterator{values: c.values}
+}
+func (c *testInt64Column) Index() storage.TimeIndex                { return c }
+func (c *testInt64Column) TimeRange(start int, end int) (int, int) { panic("not implemented") }
+func (c *testInt64Column) TimeRangeAsBitmap(start, end int) *roaring.Bitmap {
+
+	bitmap := roaring.New()
+
+	for i, v := range c.values {
+		if int(v) >= start && int(v) <= end {
+			bitmap.Add(uint32(i))
+		}
+	}
+
+	return bitmap
+
+}
+
+func (c *testInt64Column) Read(pos []uint32) vector.Int64Vector {
+
+	buf := make([]int64, len(pos))
+
+	for i, p := range pos {
+		buf[i] = c.values[p]
+	}
+
+	return vector.NewInt64Vector(buf, nil)
+
+}
+
+type testInt64Iterator struct {
+	values []int64
+}
+
+func (it *testInt64Iterator) HasNext() bool { return len(it.values) > 0 }
+
+func (it *testInt64Iterator) Next() vector.Int64Vector {
+	v := vector.NewInt64Vector(it.values, nil)
+	it.values = nil
+	return v
+}
+
+func newIndexedTestSegment() *testSegment {
+	return &testSegment{
+		info: &storage.SegmentInfo{
+			Len: 6,
+			Columns: []*storage.ColumnInfo{
+				{Name: storage.TSColumnName, ColumnType: storage.ColumnType_TIMESTAMP},
+				{Name: "n", ColumnType: storage.ColumnType_INT64},
+				{Name: "host", ColumnType: storage.ColumnType_STRING},
+			},
+		},
+		columns: map[string]storage.Column{
+			storage.TSColumnName: &testInt64Column{values: []int64{10, 20, 30, 40, 50, 60}},
+			"n":                  &testInt64Column{values: []int64{1, 2, 3, 4, 5, 6}},
+			"host":               newTestStringColumn("web-1", "web-2", "", "db-1", "web-1", "db-2"),
+		},
+	}
+}
+
+func TestIntervalRows(t *testing.T) {
+
+	cases := []struct {
+		name     string
+		interval *storage.Interval
+		segment  *storage.Interval
+		rows     []uint32
+	}{
+		{
+			name: "no interval",
+		},
+		{
+			name:     "partial",
+			interval: &storage.Interval{From: time.Unix(0, 20), To: time.Unix(0, 40)},
+			rows:     []uint32{1, 2, 3},
+		},
+		{
+			name:     "empty",
+			interval: &storage.Interval{From: time.Unix(0, 100), To: time.Unix(0, 200)},
+			rows:     []uint32{},
+		},
+		{
+			name:     "whole segment",
+			interval: &storage.Interval{From: time.Unix(0, 0), To: time.Unix(0, 100)},
+			segment:  &storage.Interval{From: time.Unix(0, 10), To: time.Unix(0, 60)},
+		},
+	}
+
+	for _, c := range cases {
+
+		t.Run(c.name, func(t *testing.T) {
+
+			segment := newIndexedTestSegment()
+			segment.info.Interval = c.segment
+
+			rows := intervalRows(segment, c.interval)
+
+			if c.rows == nil {
+				assert.Nil(t, rows)
+			} else {
+				assert.Equal(t, c.rows, rows.ToArray())
+			}
+
+		})
+
+	}
+
+}
+
+func TestIndexScan(t *testing.T) {
+
+	source := &logical.SourceOp{
+		TableName: "T",
+		Columns:   []string{"n", "host"},
+		Filter:    predicate(t, `(host startswith_cs "web") and n > 1`),
+		Interval: &storage.Interval{
+			From: time.Unix(0, 0).UTC(),
+			To:   time.Unix(0, 40).UTC(),
+		},
+	}
+
+	op := buildScanOp(newIndexedTestSegment(), source, nil, nil)
+
+	_, isFilter := op.(*FilterOp)
+	assert.True(t, isFilter)
+
+	op.Init()
+	result := drain(op)
+	op.Close()
+
+	assert.Equal(t, []interface{}{int64(2)}, result["n"])
+	assert.Equal(t, []interface{}{"web-2"}, result["host"])
+
+}
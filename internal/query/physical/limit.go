@@ -0,0 +1,80 @@
+// Copyright 2020 The Meerkat Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package physical
+
+// LimitOp returns the first n rows of its input. The input is not read
+// anymore once the limit is reached. If an onLimit callback is given it
+// is called at that point, ie. to stop the rest of the query.
+type LimitOp struct {
+	input   BatchOperator
+	n       int
+	count   int
+	onLimit func()
+}
+
+func NewLimitOp(input BatchOperator, n int, onLimit func()) *LimitOp {
+	return &LimitOp{
+		input:   input,
+		n:       n,
+		onLimit: onLimit,
+	}
+}
+
+func (l *LimitOp) Init()  { l.input.Init() }
+func (l *LimitOp) Close() { l.input.Close() }
+
+func (l *LimitOp) Next() Batch {
+
+	if l.count >= l.n {
+		return Batch{}
+	}
+
+	batch := l.input.Next()
+
+	if batch.Len == 0 {
+		return batch
+	}
+
+	remaining := l.n - l.count
+
+	if batch.Len > remaining {
+		batch = sliceBatch(batch, remaining)
+	}
+
+	l.count += batch.Len
+
+	if l.count == l.n && l.onLimit != nil {
+		l.onLimit()
+	}
+
+	return batch
+
+}
+
+func (l *LimitOp) Accept(v Visitor) {
+	l.input = Walk(l.input, v).(BatchOperator)
+}
+
+// sliceBatch returns the first n rows of batch.
+func sliceBatch(batch Batch, n int) Batch {
+
+	sel := make([]int, n)
+
+	for i := range sel {
+		sel[i] = i
+	}
+
+	return selectBatch(batch, sel)
+
+}
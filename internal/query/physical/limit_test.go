@@ -0,0 +1,45 @@
+// Copyright 2020 The Meerkat Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package physical
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestLimitOp(t *testing.T) {
+
+	input := &batchSourceOp{batches: []Batch{
+		testBatch(map[string]Col{"a": stringCol(0, "r1", "r2")}),
+		testBatch(map[string]Col{"a": stringCol(0, "r3", "r4", "r5")}),
+		testBatch(map[string]Col{"a": stringCol(0, "r6")}),
+	}}
+
+	stops := 0
+
+	op := NewLimitOp(input, 4, func() { stops++ })
+
+	assert.Equal(t, []string{"r1", "r2", "r3", "r4"}, drainStrings(op, "a"))
+	assert.Equal(t, 1, stops)
+
+	// the input is not read once the limit is reached.
+	assert.Len(t, input.batches, 1)
+
+	op = NewLimitOp(&batchSourceOp{batches: []Batch{
+		testBatch(map[string]Col{"a": stringCol(0, "r1", "r2")}),
+	}}, 10, nil)
+
+	assert.Equal(t, []string{"r1", "r2"}, drainStrings(op, "a"))
+
+}
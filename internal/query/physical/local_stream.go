@@ -0,0 +1,94 @@
+// Copyright 2020 The Meerkat Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package physical
+
+import (
+	"errors"
+	"meerkat/internal/query/execbase"
+	"meerkat/internal/query/execpb"
+)
+
+const localStreamBufferLen = 16
+
+// canceledError returns the error used to abort the operators blocked on
+// a canceled query.
+func canceledError(execCtx execbase.ExecutionContext) interface{} {
+
+	if err := execCtx.Err(); err != nil {
+		return err
+	}
+
+	return errors.New("query canceled")
+
+}
+
+// localStream connects two operators running on the same node through a
+// channel. Unlike the streams rewritten by the streamRewriteVisitor the
+// sender and the receiver run on different goroutines so it can be used
+// by operators that push batches to several outputs ( ie.
+// HashExchangeOutOp ).
+type localStream struct {
+	ch      chan Batch
+	execCtx execbase.ExecutionContext
+}
+
+func newLocalStream(execCtx execbase.ExecutionContext) *localStream {
+	return &localStream{
+		ch:      make(chan Batch, localStreamBufferLen),
+		execCtx: execCtx,
+	}
+}
+
+func (s *localStream) open() {}
+
+func (s *localStream) send(batch Batch) {
+	select {
+	case s.ch <- batch:
+	case <-s.execCtx.Done():
+		panic(canceledError(s.execCtx))
+	}
+}
+
+// fail doesn't need to propagate the error, the operator failure cancels
+// the query and the receiver is notified through the execution context.
+func (s *localStream) fail(*execpb.ExecError) {}
+
+func (s *localStream) close() { close(s.ch) }
+
+// LocalStreamInOp reads the batches sent through a localStream.
+type LocalStreamInOp struct {
+	stream *localStream
+}
+
+func NewLocalStreamInOp(stream *localStream) *LocalStreamInOp {
+	return &LocalStreamInOp{stream: stream}
+}
+
+func (op *LocalStreamInOp) Init()            {}
+func (op *LocalStreamInOp) Close()           {}
+func (op *LocalStreamInOp) Accept(v Visitor) {}
+
+func (op *LocalStreamInOp) Next() Batch {
+
+	select {
+	case batch, ok := <-op.stream.ch:
+		if !ok {
+			return Batch{}
+		}
+		return batch
+	case <-op.stream.execCtx.Done():
+		panic(canceledError(op.stream.execCtx))
+	}
+
+}
@@ -0,0 +1,177 @@
+// Copyright 2020 The Meerkat Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package physical
+
+import (
+	"container/heap"
+	"meerkat/internal/query/logical"
+	"sync"
+)
+
+// mergeCursor points to the next row of a sorted input.
+type mergeCursor struct {
+	idx     int
+	input   BatchOperator
+	batch   Batch
+	keyCols []Col
+	row     int
+}
+
+// mergeHeap is a min heap of cursors ordered by its current row. Rows with
+// equal keys are taken from the inputs in order so the merge is stable.
+type mergeHeap struct {
+	keys    []sortKey
+	cursors []*mergeCursor
+}
+
+func (h *mergeHeap) Len() int { return len(h.cursors) }
+
+func (h *mergeHeap) Less(i, j int) bool {
+	a, b := h.cursors[i], h.cursors[j]
+	c := compareRows(h.keys, a.keyCols, a.row, b.keyCols, b.row)
+	if c == 0 {
+		return a.idx < b.idx
+	}
+	return c < 0
+}
+
+func (h *mergeHeap) Swap(i, j int) { h.cursors[i], h.cursors[j] = h.cursors[j], h.cursors[i] }
+
+func (h *mergeHeap) Push(x interface{}) { h.cursors = append(h.cursors, x.(*mergeCursor)) }
+
+func (h *mergeHeap) Pop() interface{} {
+	last := h.cursors[len(h.cursors)-1]
+	h.cursors = h.cursors[:len(h.cursors)-1]
+	return last
+}
+
+// MergeSortOp performs a k-way merge of sorted inputs keeping the
+// global order.
+type MergeSortOp struct {
+	input   []BatchOperator
+	heap    *mergeHeap
+	started bool
+}
+
+func NewMergeSortOp(input []BatchOperator, sortExpr []*logical.SortExpr) *MergeSortOp {
+	return &MergeSortOp{
+		input: input,
+		heap:  &mergeHeap{keys: newSortKeys(sortExpr)},
+	}
+}
+
+func (m *MergeSortOp) Init() {
+	for _, operator := range m.input {
+		operator.Init()
+	}
+}
+
+func (m *MergeSortOp) Close() {
+	for _, operator := range m.input {
+		operator.Close()
+	}
+}
+
+// start reads the first batch of every input. The inputs are read
+// concurrently given that an exchange stream not being read can make the
+// remote sender fail.
+func (m *MergeSortOp) start() {
+
+	first := make([]Batch, len(m.input))
+	errs := make([]interface{}, len(m.input))
+
+	var wg sync.WaitGroup
+
+	for i, input := range m.input {
+		wg.Add(1)
+		go func(i int, input BatchOperator) {
+			defer wg.Done()
+			first[i], errs[i] = safeNext(input)
+		}(i, input)
+	}
+
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	for i, input := range m.input {
+		if first[i].Len != 0 {
+			cursor := &mergeCursor{idx: i, input: input}
+			m.setBatch(cursor, first[i])
+			m.heap.cursors = append(m.heap.cursors, cursor)
+		}
+	}
+
+	heap.Init(m.heap)
+
+	m.started = true
+
+}
+
+func (m *MergeSortOp) setBatch(cursor *mergeCursor, batch Batch) {
+	cursor.batch = batch
+	cursor.keyCols = evalSortKeys(m.heap.keys, batch)
+	cursor.row = 0
+}
+
+func (m *MergeSortOp) Next() Batch {
+
+	if !m.started {
+		m.start()
+	}
+
+	builder := newRowBuilder()
+
+	for builder.Len() < batchSize && m.heap.Len() > 0 {
+
+		cursor := m.heap.cursors[0]
+
+		builder.AppendRow(cursor.batch, cursor.row)
+
+		cursor.row++
+
+		if cursor.row == cursor.batch.Len {
+
+			batch := cursor.input.Next()
+
+			if batch.Len == 0 {
+				heap.Pop(m.heap)
+				continue
+			}
+
+			m.setBatch(cursor, batch)
+
+		}
+
+		heap.Fix(m.heap, 0)
+
+	}
+
+	if builder.Len() == 0 {
+		return Batch{}
+	}
+
+	return builder.Build()
+
+}
+
+func (m *MergeSortOp) Accept(v Visitor) {
+	for i, operator := range m.input {
+		m.input[i] = Walk(operator, v).(BatchOperator)
+	}
+}
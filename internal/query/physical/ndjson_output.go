@@ -0,0 +1,144 @@
+// Copyright 2020 The Meerkat Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package physical
+
+import (
+	"encoding/json"
+	"fmt"
+	"meerkat/internal/query/execbase"
+	"meerkat/internal/query/parser"
+	"meerkat/internal/storage"
+	"meerkat/internal/storage/vector"
+)
+
+// NDJSONOutputOp writes the query results as one JSON object per row. All
+// the objects have as fields the columns of the first batch and the other
+// columns of the plan, nulls and the columns missing in a batch are
+// written as null.
+type NDJSONOutputOp struct {
+	input   BatchOperator
+	plan    map[string]parser.Type
+	writer  execbase.QueryOutputWriter
+	columns []outputColumn
+	// names are the encoded column names shared by all the rows.
+	names [][]byte
+	buf   []byte
+}
+
+func NewNDJSONOutputOp(input BatchOperator, plan map[string]parser.Type, writer execbase.QueryOutputWriter) *NDJSONOutputOp {
+	return &NDJSONOutputOp{
+		input:  input,
+		plan:   plan,
+		writer: writer,
+	}
+}
+
+func (o *NDJSONOutputOp) Init()  { o.input.Init() }
+func (o *NDJSONOutputOp) Close() { o.input.Close() }
+
+func (o *NDJSONOutputOp) Run() {
+
+	for {
+
+		batch := o.input.Next()
+
+		if batch.Len == 0 {
+			o.writer.Flush()
+			return
+		}
+
+		o.writeBatch(batch)
+
+	}
+
+}
+
+func (o *NDJSONOutputOp) Accept(v Visitor) {
+	o.input = Walk(o.input, v).(BatchOperator)
+}
+
+func (o *NDJSONOutputOp) writeBatch(batch Batch) {
+
+	if o.columns == nil {
+
+		o.columns = outputColumns(batch, o.plan)
+		o.names = make([][]byte, len(o.columns))
+
+		for i, c := range o.columns {
+			o.names[i] = marshalJSON(c.name)
+		}
+
+	}
+
+	cols := make([]Col, len(o.columns))
+
+	for i, c := range o.columns {
+		cols[i] = c.col(batch)
+	}
+
+	for row := 0; row < batch.Len; row++ {
+
+		o.buf = append(o.buf[:0], '{')
+
+		for i, col := range cols {
+
+			if i > 0 {
+				o.buf = append(o.buf, ',')
+			}
+
+			o.buf = append(o.buf, o.names[i]...)
+			o.buf = append(o.buf, ':')
+			o.buf = appendJSONValue(o.buf, col, row)
+
+		}
+
+		o.buf = append(o.buf, '}', '\n')
+
+		_, err := o.writer.Write(o.buf)
+
+		if err != nil {
+			panic(fmt.Sprintf("cannot write ndjson output: %v", err))
+		}
+
+	}
+
+}
+
+// appendJSONValue appends the i-th value of col encoded as JSON to buf.
+func appendJSONValue(buf []byte, col Col, i int) []byte {
+
+	if isNull(col.Vec, i) {
+		return append(buf, "null"...)
+	}
+
+	// dynamic values are already JSON encoded.
+	if col.ColumnType == storage.ColumnType_DYNAMIC {
+		return append(buf, col.Vec.(*vector.ByteSliceVector).Get(i)...)
+	}
+
+	return append(buf, marshalJSON(jsonValue(col.Vec, i))...)
+
+}
+
+func marshalJSON(v interface{}) []byte {
+
+	b, err := json.Marshal(v)
+
+	if err != nil {
+		panic(fmt.Sprintf("cannot encode json value: %v", err))
+	}
+
+	return b
+
+}
@@ -3,7 +3,10 @@ package physical
 import (
 	"encoding/json"
 	"meerkat/internal/query/execbase"
+	"meerkat/internal/query/parser"
+	"meerkat/internal/storage"
 	"meerkat/internal/storage/vector"
+	"sort"
 )
 
 type JsonOutputOp struct {
@@ -47,8 +50,6 @@ func (o *JsonOutputOp) Accept(v Visitor) {
 
 func (o *JsonOutputOp) writeBatch(batch Batch) {
 
-	// TODO(gvelo): sort columns by group and order.
-
 	m := map[string]interface{}{
 		"type":    "column_batch",
 		"columns": buildJsonColumns(batch.Columns),
@@ -66,8 +67,8 @@ func buildJsonColumns(colMap map[string]Col) []map[string]interface{} {
 
 	var jsonColumns []map[string]interface{}
 
-	for name, column := range colMap {
-		c := buildJsonColumn(name, column)
+	for _, name := range columnOrder(colMap) {
+		c := buildJsonColumn(name, colMap[name])
 		jsonColumns = append(jsonColumns, c)
 	}
 
@@ -80,26 +81,134 @@ func buildJsonColumn(name string, column Col) map[string]interface{} {
 	jsonCol := map[string]interface{}{
 		"name":   name,
 		"type":   column.ColumnType.String(),
-		"values": buildJsonVectorValues(column.Vec),
+		"values": buildJsonVectorValues(column),
 	}
 
 	return jsonCol
 }
 
-func buildJsonVectorValues(vec vector.Vector) interface{} {
+func buildJsonVectorValues(column Col) interface{} {
+
+	vec := column.Vec
+	values := make([]interface{}, vec.Len())
+
+	for i := range values {
+
+		if isNull(vec, i) {
+			continue
+		}
+
+		// dynamic values are already JSON encoded.
+		if column.ColumnType == storage.ColumnType_DYNAMIC {
+			values[i] = json.RawMessage(vec.(*vector.ByteSliceVector).Get(i))
+			continue
+		}
+
+		values[i] = jsonValue(vec, i)
+
+	}
+
+	return values
+
+}
+
+func jsonValue(vec vector.Vector, i int) interface{} {
 
 	switch v := vec.(type) {
 	case *vector.Int64Vector:
-		return v.Values()
+		return v.Get(i)
+	case *vector.Float64Vector:
+		return v.Get(i)
+	case *vector.BoolVector:
+		return v.Get(i)
 	case *vector.ByteSliceVector:
-		s := make([]string, vec.Len())
-		for i := 0; i < v.Len(); i++ {
-			s[i] = string(v.Get(i))
-		}
-		return s
+		return string(v.Get(i))
 	default:
 		panic("cannot convert vector to json")
 
 	}
 
 }
+
+// columnOrder returns the column names sorted by group and order.
+func columnOrder(colMap map[string]Col) []string {
+
+	names := make([]string, 0, len(colMap))
+
+	for name := range colMap {
+		names = append(names, name)
+	}
+
+	sort.Slice(names, func(i, j int) bool {
+		a, b := colMap[names[i]], colMap[names[j]]
+		if a.Group != b.Group {
+			return a.Group < b.Group
+		}
+		if a.Order != b.Order {
+			return a.Order < b.Order
+		}
+		return names[i] < names[j]
+	})
+
+	return names
+
+}
+
+// outputColumn is a column written by the arrow and text outputs.
+type outputColumn struct {
+	name    string
+	colType storage.ColumnType
+}
+
+// outputColumns returns the columns written by the outputs that describe
+// the result before its rows: the columns of the first batch in column
+// order followed by the other columns of the plan sorted by name. A column
+// with an unknown type in the plan, ie. with different types in the tables
+// of a union, is written as text. The columns not returned are not written.
+func outputColumns(first Batch, plan map[string]parser.Type) []outputColumn {
+
+	var columns []outputColumn
+
+	for _, name := range columnOrder(first.Columns) {
+
+		col := first.Columns[name]
+		colType := col.ColumnType
+
+		if t, found := plan[name]; (found && t == parser.UnknownType) || isNullCol(col) {
+			colType = storageType(t)
+		}
+
+		columns = append(columns, outputColumn{name: name, colType: colType})
+
+	}
+
+	var missing []string
+
+	for name := range plan {
+		if _, found := first.Columns[name]; !found {
+			missing = append(missing, name)
+		}
+	}
+
+	sort.Strings(missing)
+
+	for _, name := range missing {
+		columns = append(columns, outputColumn{name: name, colType: storageType(plan[name])})
+	}
+
+	return columns
+
+}
+
+// col returns the column of batch, nulls if the batch doesn't have it.
+func (c outputColumn) col(batch Batch) Col {
+
+	col, found := batch.Columns[c.name]
+
+	if !found {
+		return Col{Vec: newNullVector(c.colType, batch.Len), ColumnType: c.colType}
+	}
+
+	return col
+
+}
@@ -0,0 +1,153 @@
+// Copyright 2020 The Meerkat Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package physical
+
+import (
+	"meerkat/internal/query/logical"
+)
+
+type projection struct {
+	name string
+	expr Evaluator
+}
+
+func newProjections(columns []*logical.ColumnExpr) []projection {
+
+	p := make([]projection, len(columns))
+
+	for i, col := range columns {
+		p[i] = projection{name: col.ColName, expr: NewEvaluator(col.Expr)}
+	}
+
+	return p
+
+}
+
+// ProjectOp evaluates the projected columns over every input batch. The
+// output columns are ordered as they appear in the projection.
+type ProjectOp struct {
+	input   BatchOperator
+	columns []projection
+}
+
+func NewProjectOp(input BatchOperator, columns []*logical.ColumnExpr) *ProjectOp {
+	return &ProjectOp{
+		input:   input,
+		columns: newProjections(columns),
+	}
+}
+
+func (p *ProjectOp) Init()  { p.input.Init() }
+func (p *ProjectOp) Close() { p.input.Close() }
+
+func (p *ProjectOp) Next() Batch {
+
+	batch := p.input.Next()
+
+	if batch.Len == 0 {
+		return batch
+	}
+
+	output := NewBatch()
+	output.Len = batch.Len
+
+	for i, column := range p.columns {
+		col := column.expr.Eval(batch)
+		col.Group = 0
+		col.Order = int64(i)
+		output.Columns[column.name] = col
+	}
+
+	return output
+
+}
+
+func (p *ProjectOp) Accept(v Visitor) {
+	p.input = Walk(p.input, v).(BatchOperator)
+}
+
+// ExtendOp adds the computed columns to every input batch. New columns are
+// placed after the input columns, an existing column with the same name is
+// replaced keeping its position.
+type ExtendOp struct {
+	input   BatchOperator
+	columns []projection
+}
+
+func NewExtendOp(input BatchOperator, columns []*logical.ColumnExpr) *ExtendOp {
+	return &ExtendOp{
+		input:   input,
+		columns: newProjections(columns),
+	}
+}
+
+func (e *ExtendOp) Init()  { e.input.Init() }
+func (e *ExtendOp) Close() { e.input.Close() }
+
+func (e *ExtendOp) Next() Batch {
+
+	batch := e.input.Next()
+
+	if batch.Len == 0 {
+		return batch
+	}
+
+	group, order := lastPosition(batch)
+
+	output := NewBatch()
+	output.Len = batch.Len
+
+	for name, col := range batch.Columns {
+		output.Columns[name] = col
+	}
+
+	// expressions are evaluated over the output batch so a column can
+	// reference the columns computed before it in the same extend.
+	for _, column := range e.columns {
+
+		col := column.expr.Eval(output)
+
+		if old, found := output.Columns[column.name]; found {
+			col.Group, col.Order = old.Group, old.Order
+		} else {
+			order++
+			col.Group, col.Order = group, order
+		}
+
+		output.Columns[column.name] = col
+
+	}
+
+	return output
+
+}
+
+func (e *ExtendOp) Accept(v Visitor) {
+	e.input = Walk(e.input, v).(BatchOperator)
+}
+
+// lastPosition returns the group and order of the last column in batch.
+func lastPosition(batch Batch) (int64, int64) {
+
+	var group, order int64 = 0, -1
+
+	for _, col := range batch.Columns {
+		if col.Group > group || (col.Group == group && col.Order > order) {
+			group, order = col.Group, col.Order
+		}
+	}
+
+	return group, order
+
+}
@@ -0,0 +1,86 @@
+// Copyright 2020 The Meerkat Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package physical
+
+import (
+	"github.com/stretchr/testify/assert"
+	"meerkat/internal/query/logical"
+	"meerkat/internal/query/parser"
+	"testing"
+)
+
+// columnExpr parses the columns of a project or extend operator.
+func columnExpr(t *testing.T, query string) []*logical.ColumnExpr {
+
+	ast, err := parser.Parse("T | " + query)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	switch op := logical.ToLogical(ast)[0].(type) {
+	case *logical.ProjectOp:
+		return op.Columns
+	case *logical.ExtendOp:
+		return op.Columns
+	default:
+		t.Fatalf("unexpected operator %T", op)
+		return nil
+	}
+
+}
+
+func projectInput() BatchOperator {
+	return &batchSourceOp{batches: []Batch{
+		testBatch(map[string]Col{
+			"a":    nullableInt64Col(0, 7, nil, -7),
+			"b":    int64Col(1, 2, 3, 0),
+			"name": stringCol(2, "x", "y", "z"),
+		}),
+	}}
+}
+
+func TestProjectOp(t *testing.T) {
+
+	op := NewProjectOp(projectInput(), columnExpr(t, "project name, q=a/b, r=a%b, f=a/2.0, s=-a+b*2, n=missing+1"))
+
+	batch := op.Next()
+
+	assert.Equal(t, []string{"name", "q", "r", "f", "s", "n"}, columnOrder(batch.Columns))
+	assert.Equal(t, 0, op.Next().Len)
+
+	result := drain(&batchSourceOp{batches: []Batch{batch}})
+
+	assert.Equal(t, []interface{}{int64(3), nil, nil}, result["q"])
+	assert.Equal(t, []interface{}{int64(1), nil, nil}, result["r"])
+	assert.Equal(t, []interface{}{3.5, nil, -3.5}, result["f"])
+	assert.Equal(t, []interface{}{int64(-3), nil, int64(7)}, result["s"])
+	assert.Equal(t, []interface{}{nil, nil, nil}, result["n"])
+
+}
+
+func TestExtendOp(t *testing.T) {
+
+	op := NewExtendOp(projectInput(), columnExpr(t, "extend c=b+1, a=b*10, d=c*2"))
+
+	batch := op.Next()
+
+	assert.Equal(t, []string{"a", "b", "name", "c", "d"}, columnOrder(batch.Columns))
+
+	result := drain(&batchSourceOp{batches: []Batch{batch}})
+
+	assert.Equal(t, []interface{}{int64(20), int64(30), int64(0)}, result["a"])
+	assert.Equal(t, []interface{}{int64(6), int64(8), int64(2)}, result["d"])
+
+}
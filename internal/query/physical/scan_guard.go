@@ -0,0 +1,79 @@
+// Copyright 2020 The Meerkat Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package physical
+
+import (
+	"fmt"
+	"meerkat/internal/query/execbase"
+	"sync/atomic"
+)
+
+// Progress is the amount of data read from the segments by a DAG.
+type Progress struct {
+	RowsScanned  int64 `json:"rowsScanned"`
+	BytesScanned int64 `json:"bytesScanned"`
+}
+
+// scanGuard is shared by the scans of a DAG. It stops them if the query
+// is canceled or if the rows or bytes read exceed the query limits.
+type scanGuard struct {
+	execCtx  execbase.ExecutionContext
+	limits   execbase.QueryLimits
+	nodeName string
+	rows     int64
+	bytes    int64
+}
+
+func newScanGuard(execCtx execbase.ExecutionContext, limits execbase.QueryLimits, nodeName string) *scanGuard {
+	return &scanGuard{
+		execCtx:  execCtx,
+		limits:   limits,
+		nodeName: nodeName,
+	}
+}
+
+// check accounts a batch of rows and bytes read from a segment.
+func (g *scanGuard) check(rows int64, bytes int64) {
+
+	select {
+	case <-g.execCtx.Done():
+		panic(canceledError(g.execCtx))
+	default:
+	}
+
+	totalRows := atomic.AddInt64(&g.rows, rows)
+	totalBytes := atomic.AddInt64(&g.bytes, bytes)
+
+	if g.limits.MaxRowsScanned > 0 && totalRows > g.limits.MaxRowsScanned {
+		panic(execbase.NewExecError(
+			fmt.Sprintf("query scanned rows limit exceeded: %v rows read, limit is %v rows", totalRows, g.limits.MaxRowsScanned),
+			g.nodeName,
+		))
+	}
+
+	if g.limits.MaxBytesScanned > 0 && totalBytes > g.limits.MaxBytesScanned {
+		panic(execbase.NewExecError(
+			fmt.Sprintf("query scanned bytes limit exceeded: %v bytes read, limit is %v bytes", totalBytes, g.limits.MaxBytesScanned),
+			g.nodeName,
+		))
+	}
+
+}
+
+func (g *scanGuard) progress() Progress {
+	return Progress{
+		RowsScanned:  atomic.LoadInt64(&g.rows),
+		BytesScanned: atomic.LoadInt64(&g.bytes),
+	}
+}
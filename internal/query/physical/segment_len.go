@@ -0,0 +1,60 @@
+// Copyright 2020 The Meerkat Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package physical
+
+import (
+	"fmt"
+	"meerkat/internal/storage"
+)
+
+// SegmentLenOp returns batches without columns adding up to the number of
+// rows of a segment. The length is read from the segment info so it's
+// used when only the number of rows is needed, ie. by count.
+type SegmentLenOp struct {
+	rows      int
+	remaining int
+}
+
+func NewSegmentLenOp(segment storage.Segment) *SegmentLenOp {
+	rows := int(segment.Info().Len)
+	return &SegmentLenOp{rows: rows, remaining: rows}
+}
+
+func (s *SegmentLenOp) Init()  {}
+func (s *SegmentLenOp) Close() {}
+
+func (s *SegmentLenOp) Next() Batch {
+
+	if s.remaining == 0 {
+		return Batch{}
+	}
+
+	batch := NewBatch()
+	batch.Len = batchSize
+
+	if s.remaining < batchSize {
+		batch.Len = s.remaining
+	}
+
+	s.remaining -= batch.Len
+
+	return batch
+
+}
+
+func (s *SegmentLenOp) Accept(Visitor) {}
+
+func (s *SegmentLenOp) describe() string {
+	return fmt.Sprintf("rows: %v", s.rows)
+}
@@ -0,0 +1,37 @@
+// Copyright 2020 The Meerkat Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package physical
+
+import (
+	"github.com/stretchr/testify/assert"
+	"meerkat/internal/storage"
+	"testing"
+)
+
+func TestSegmentLenOp(t *testing.T) {
+
+	segment := &testSegment{info: &storage.SegmentInfo{Len: batchSize + 10}}
+
+	op := NewSegmentLenOp(segment)
+
+	var lens []int
+
+	for batch := op.Next(); batch.Len != 0; batch = op.Next() {
+		assert.Empty(t, batch.Columns)
+		lens = append(lens, batch.Len)
+	}
+
+	assert.Equal(t, []int{batchSize, 10}, lens)
+
+}
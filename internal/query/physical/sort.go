@@ -0,0 +1,351 @@
+// Copyright 2020 The Meerkat Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package physical
+
+import (
+	"bytes"
+	"fmt"
+	"meerkat/internal/query/logical"
+	"meerkat/internal/storage/vector"
+	"sort"
+)
+
+type sortKey struct {
+	expr       Evaluator
+	asc        bool
+	nullsFirst bool
+}
+
+func newSortKeys(sortExpr []*logical.SortExpr) []sortKey {
+
+	keys := make([]sortKey, len(sortExpr))
+
+	for i, expr := range sortExpr {
+		keys[i] = sortKey{
+			expr:       NewEvaluator(expr.Expr),
+			asc:        expr.Asc,
+			nullsFirst: expr.NullFirst,
+		}
+	}
+
+	return keys
+
+}
+
+// evalSortKeys evaluates the sort keys over batch.
+func evalSortKeys(keys []sortKey, batch Batch) []Col {
+
+	cols := make([]Col, len(keys))
+
+	for i, key := range keys {
+		cols[i] = key.expr.Eval(batch)
+	}
+
+	return cols
+
+}
+
+// compareRows compares the i-th row of the a key columns with the j-th row
+// of the b key columns. Nulls are placed first or last regardless of the
+// sort direction.
+func compareRows(keys []sortKey, a []Col, i int, b []Col, j int) int {
+
+	for k, key := range keys {
+
+		aNull, bNull := isNull(a[k].Vec, i), isNull(b[k].Vec, j)
+
+		switch {
+		case aNull && bNull:
+			continue
+		case aNull:
+			if key.nullsFirst {
+				return -1
+			}
+			return 1
+		case bNull:
+			if key.nullsFirst {
+				return 1
+			}
+			return -1
+		}
+
+		c := compareValues(a[k], i, b[k], j)
+
+		if c != 0 {
+			if !key.asc {
+				return -c
+			}
+			return c
+		}
+
+	}
+
+	return 0
+
+}
+
+// compareValues compares two non null values. Mixed numeric values are
+// compared as float64.
+func compareValues(a Col, i int, b Col, j int) int {
+
+	switch av := a.Vec.(type) {
+
+	case *vector.Int64Vector:
+		switch bv := b.Vec.(type) {
+		case *vector.Int64Vector:
+			return compareInt(av.Get(i), bv.Get(j))
+		case *vector.Float64Vector:
+			return compareFloat(float64(av.Get(i)), bv.Get(j))
+		}
+
+	case *vector.Float64Vector:
+		switch bv := b.Vec.(type) {
+		case *vector.Float64Vector:
+			return compareFloat(av.Get(i), bv.Get(j))
+		case *vector.Int64Vector:
+			return compareFloat(av.Get(i), float64(bv.Get(j)))
+		}
+
+	case *vector.ByteSliceVector:
+		if bv, ok := b.Vec.(*vector.ByteSliceVector); ok {
+			return bytes.Compare(av.Get(i), bv.Get(j))
+		}
+
+	case *vector.BoolVector:
+		if bv, ok := b.Vec.(*vector.BoolVector); ok {
+			x, y := av.Get(i), bv.Get(j)
+			switch {
+			case x == y:
+				return 0
+			case !x:
+				return -1
+			default:
+				return 1
+			}
+		}
+
+	}
+
+	panic(fmt.Sprintf("cannot compare %v with %v", a.ColumnType, b.ColumnType))
+
+}
+
+func compareInt(x, y int64) int {
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func compareFloat(x, y float64) int {
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	default:
+		return 0
+	}
+}
+
+type rowRef struct {
+	batch int
+	row   int
+}
+
+// rowRefSize is the memory used by a rowRef.
+const rowRefSize = 16
+
+// SortOp sorts the whole input. The input batches are kept in memory and
+// the sorted rows are copied to the output batches. The retained memory
+// is reserved from alloc, when the budget runs out the buffered rows are
+// sorted and spilled to disk and the runs are merged back at the end.
+type SortOp struct {
+	input    BatchOperator
+	sortExpr []*logical.SortExpr
+	keys     []sortKey
+	alloc    Allocator
+	reserved int64
+	batches  []Batch
+	keyCols  [][]Col
+	rows     []rowRef
+	runs     []BatchOperator
+	merge    *MergeSortOp
+	pos      int
+	done     bool
+}
+
+func NewSortOp(input BatchOperator, sortExpr []*logical.SortExpr, alloc Allocator) *SortOp {
+	return &SortOp{
+		input:    input,
+		sortExpr: sortExpr,
+		keys:     newSortKeys(sortExpr),
+		alloc:    alloc,
+	}
+}
+
+func (s *SortOp) Init() { s.input.Init() }
+
+func (s *SortOp) Close() {
+
+	s.release()
+
+	if s.merge != nil {
+		s.merge.Close()
+	}
+
+	s.input.Close()
+
+}
+
+func (s *SortOp) Next() Batch {
+
+	if !s.done {
+		s.sort()
+		s.done = true
+	}
+
+	if s.merge != nil {
+		return s.merge.Next()
+	}
+
+	batch := s.nextSorted()
+
+	if batch.Len == 0 {
+		s.release()
+	}
+
+	return batch
+
+}
+
+// nextSorted returns the next batch of the sorted rows held in memory.
+func (s *SortOp) nextSorted() Batch {
+
+	if s.pos == len(s.rows) {
+		return Batch{}
+	}
+
+	end := s.pos + batchSize
+
+	if end > len(s.rows) {
+		end = len(s.rows)
+	}
+
+	builder := newRowBuilder()
+
+	for _, ref := range s.rows[s.pos:end] {
+		builder.AppendRow(s.batches[ref.batch], ref.row)
+	}
+
+	s.pos = end
+
+	return builder.Build()
+
+}
+
+func (s *SortOp) sort() {
+
+	for batch := s.input.Next(); batch.Len != 0; batch = s.input.Next() {
+
+		idx := len(s.batches)
+
+		keyCols := evalSortKeys(s.keys, batch)
+
+		n := batchBytes(batch) + int64(batch.Len)*rowRefSize
+
+		for _, col := range keyCols {
+			n += vectorBytes(col)
+		}
+
+		if !s.alloc.TryReserve(n) {
+
+			if s.alloc.SpillDir() != "" && len(s.batches) > 0 {
+				s.spill()
+				idx = 0
+			}
+
+			// panics if the batch doesn't fit even after spilling.
+			s.alloc.Reserve(n)
+
+		}
+
+		s.reserved += n
+
+		s.batches = append(s.batches, batch)
+		s.keyCols = append(s.keyCols, keyCols)
+
+		for i := 0; i < batch.Len; i++ {
+			s.rows = append(s.rows, rowRef{batch: idx, row: i})
+		}
+
+	}
+
+	s.sortRows()
+
+	if len(s.runs) == 0 {
+		return
+	}
+
+	if len(s.rows) > 0 {
+		s.spill()
+	}
+
+	s.merge = NewMergeSortOp(s.runs, s.sortExpr)
+	s.merge.Init()
+
+}
+
+func (s *SortOp) sortRows() {
+	sort.SliceStable(s.rows, func(i, j int) bool {
+		a, b := s.rows[i], s.rows[j]
+		return compareRows(s.keys, s.keyCols[a.batch], a.row, s.keyCols[b.batch], b.row) < 0
+	})
+}
+
+// spill writes the buffered rows sorted to a new run and releases the
+// memory used by them.
+func (s *SortOp) spill() {
+
+	s.sortRows()
+
+	writer := newRunWriter(s.alloc.SpillDir())
+
+	for batch := s.nextSorted(); batch.Len != 0; batch = s.nextSorted() {
+		writer.write(batch)
+	}
+
+	s.runs = append(s.runs, writer.close())
+
+	s.batches = nil
+	s.keyCols = nil
+	s.rows = nil
+	s.pos = 0
+
+	s.release()
+
+}
+
+func (s *SortOp) release() {
+	s.alloc.Release(s.reserved)
+	s.reserved = 0
+}
+
+func (s *SortOp) Accept(v Visitor) {
+	s.input = Walk(s.input, v).(BatchOperator)
+}
@@ -0,0 +1,138 @@
+// Copyright 2020 The Meerkat Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package physical
+
+import (
+	"github.com/stretchr/testify/assert"
+	"meerkat/internal/query/logical"
+	"meerkat/internal/query/parser"
+	"meerkat/internal/storage"
+	"meerkat/internal/storage/vector"
+	"testing"
+)
+
+// sortExpr parses the sort expressions of a sort operator.
+func sortExpr(t *testing.T, expr string) []*logical.SortExpr {
+
+	ast, err := parser.Parse("T | sort by " + expr)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return logical.ToLogical(ast)[0].(*logical.SortOp).SortExpr
+
+}
+
+// nullableInt64Col builds an INT64 column where nil values are nulls.
+func nullableInt64Col(order int64, values ...interface{}) Col {
+
+	buf := make([]int64, len(values))
+	valid := newValidity(len(values))
+
+	for i, value := range values {
+		if value != nil {
+			buf[i] = int64(value.(int))
+			setValid(valid, i)
+		}
+	}
+
+	v := vector.NewInt64Vector(buf, valid)
+
+	return Col{Order: order, Vec: &v, ColumnType: storage.ColumnType_INT64}
+
+}
+
+func TestSortOp(t *testing.T) {
+
+	newInput := func() BatchOperator {
+		return &batchSourceOp{batches: []Batch{
+			testBatch(map[string]Col{
+				"a":    nullableInt64Col(0, 2, nil, 1),
+				"b":    stringCol(1, "x", "y", "z"),
+				"name": stringCol(2, "r1", "r2", "r3"),
+			}),
+			testBatch(map[string]Col{
+				"a":    nullableInt64Col(0, 1, 2, nil),
+				"b":    stringCol(1, "w", "v", "u"),
+				"name": stringCol(2, "r4", "r5", "r6"),
+			}),
+		}}
+	}
+
+	cases := []struct {
+		sortExpr string
+		expected []string
+	}{
+		{sortExpr: "a asc", expected: []string{"r3", "r4", "r1", "r5", "r2", "r6"}},
+		{sortExpr: "a asc nulls first", expected: []string{"r2", "r6", "r3", "r4", "r1", "r5"}},
+		{sortExpr: "a", expected: []string{"r1", "r5", "r3", "r4", "r2", "r6"}},
+		{sortExpr: "a desc nulls first, b asc", expected: []string{"r6", "r2", "r5", "r1", "r4", "r3"}},
+		{sortExpr: "a asc, b desc", expected: []string{"r3", "r4", "r1", "r5", "r2", "r6"}},
+		{sortExpr: "b asc", expected: []string{"r6", "r5", "r4", "r1", "r2", "r3"}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.sortExpr, func(t *testing.T) {
+			op := NewSortOp(newInput(), sortExpr(t, c.sortExpr), NewAllocator(0, "", ""))
+			assert.Equal(t, c.expected, drainStrings(op, "name"))
+		})
+	}
+
+}
+
+func TestMergeSortOp(t *testing.T) {
+
+	expr := sortExpr(t, "a asc nulls first")
+
+	node1 := NewSortOp(&batchSourceOp{batches: []Batch{
+		testBatch(map[string]Col{
+			"a":    nullableInt64Col(0, 5, 1, nil, 3),
+			"name": stringCol(1, "n1-5", "n1-1", "n1-null", "n1-3"),
+		}),
+	}}, expr, NewAllocator(0, "", ""))
+
+	node2 := NewSortOp(&batchSourceOp{batches: []Batch{
+		testBatch(map[string]Col{
+			"a":    nullableInt64Col(0, 4, 2),
+			"name": stringCol(1, "n2-4", "n2-2"),
+		}),
+		testBatch(map[string]Col{
+			"a":    nullableInt64Col(0, 3, 6),
+			"name": stringCol(1, "n2-3", "n2-6"),
+		}),
+	}}, expr, NewAllocator(0, "", ""))
+
+	node3 := NewSortOp(&batchSourceOp{}, expr, NewAllocator(0, "", ""))
+
+	op := NewMergeSortOp([]BatchOperator{node1, node2, node3}, expr)
+
+	assert.Equal(t,
+		[]string{"n1-null", "n1-1", "n2-2", "n1-3", "n2-3", "n2-4", "n1-5", "n2-6"},
+		drainStrings(op, "name"),
+	)
+
+}
+
+func drainStrings(op BatchOperator, column string) []string {
+
+	var values []string
+
+	for batch := op.Next(); batch.Len != 0; batch = op.Next() {
+		values = append(values, stringValues(batch.Columns[column])...)
+	}
+
+	return values
+
+}
@@ -0,0 +1,143 @@
+// Copyright 2020 The Meerkat Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package physical
+
+import (
+	"fmt"
+	"github.com/google/uuid"
+	"meerkat/internal/query/execpb"
+	"meerkat/internal/storage/io"
+	"os"
+	"path/filepath"
+)
+
+// runWriter writes a run of batches to a temporary file under the spill
+// directory. The batches are encoded as they are sent to other nodes.
+type runWriter struct {
+	name    string
+	writer  *io.BinaryWriter
+	batches int
+}
+
+func newRunWriter(dir string) *runWriter {
+
+	err := os.MkdirAll(dir, 0755)
+
+	if err != nil {
+		panic(fmt.Sprintf("cannot create spill directory: %v", err))
+	}
+
+	name := filepath.Join(dir, uuid.New().String()+".run")
+
+	writer, err := io.NewBinaryWriter(name)
+
+	if err != nil {
+		panic(fmt.Sprintf("cannot create spill file: %v", err))
+	}
+
+	return &runWriter{
+		name:   name,
+		writer: writer,
+	}
+
+}
+
+func (w *runWriter) write(batch Batch) {
+
+	msg := &execpb.VectorBatch{
+		Len:     int64(batch.Len),
+		Columns: buildColumns(batch),
+	}
+
+	b, err := msg.Marshal()
+
+	if err != nil {
+		panic(fmt.Sprintf("cannot encode spilled batch: %v", err))
+	}
+
+	w.writer.WriteBytes(b)
+	w.batches++
+
+}
+
+// close finishes the run and returns an operator reading it back.
+func (w *runWriter) close() *runReaderOp {
+
+	w.writer.Close()
+
+	return &runReaderOp{
+		name:    w.name,
+		batches: w.batches,
+	}
+
+}
+
+// runReaderOp streams the batches of a run written by a runWriter. The
+// file is removed once the run has been read.
+type runReaderOp struct {
+	name    string
+	batches int
+	file    *io.MMFile
+	reader  *io.BinaryReader
+}
+
+func (r *runReaderOp) Init()            {}
+func (r *runReaderOp) Close()           { r.remove() }
+func (r *runReaderOp) Accept(v Visitor) {}
+
+func (r *runReaderOp) Next() Batch {
+
+	if r.batches == 0 {
+		r.remove()
+		return Batch{}
+	}
+
+	if r.reader == nil {
+
+		file, err := io.MMap(r.name)
+
+		if err != nil {
+			panic(fmt.Sprintf("cannot open spill file: %v", err))
+		}
+
+		r.file = file
+		r.reader = file.NewBinaryReader()
+
+	}
+
+	msg := &execpb.VectorBatch{}
+
+	// the decoded vectors are copied so the file can be unmapped.
+	err := msg.Unmarshal(r.reader.ReadBytes())
+
+	if err != nil {
+		panic(fmt.Sprintf("cannot decode spilled batch: %v", err))
+	}
+
+	r.batches--
+
+	return buildBatch(msg)
+
+}
+
+func (r *runReaderOp) remove() {
+
+	if r.file != nil {
+		_ = r.file.UnMap()
+		r.file = nil
+	}
+
+	_ = os.Remove(r.name)
+
+}
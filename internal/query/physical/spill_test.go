@@ -0,0 +1,133 @@
+// Copyright 2020 The Meerkat Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package physical
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"sort"
+	"testing"
+)
+
+func spillDir(t *testing.T) string {
+
+	dir, err := ioutil.TempDir("", "spill")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return dir
+
+}
+
+func assertEmptyDir(t *testing.T, dir string) {
+
+	files, err := ioutil.ReadDir(dir)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Empty(t, files, "spill files not removed")
+
+}
+
+func TestSortSpill(t *testing.T) {
+
+	dir := spillDir(t)
+	defer os.RemoveAll(dir)
+
+	var batches []Batch
+	var expected []interface{}
+
+	for i := 0; i < 10; i++ {
+
+		values := make([]int64, 100)
+
+		for j := range values {
+			values[j] = int64((i*7919 + j*104729) % 1000)
+			expected = append(expected, values[j])
+		}
+
+		batches = append(batches, testBatch(map[string]Col{"a": int64Col(0, values...)}))
+
+	}
+
+	sort.Slice(expected, func(i, j int) bool { return expected[i].(int64) < expected[j].(int64) })
+
+	// every batch takes 3200 bytes so the rows are spilled every 2 batches.
+	alloc := NewAllocator(7000, dir, "node1")
+
+	op := NewSortOp(&batchSourceOp{batches: batches}, sortExpr(t, "a asc"), alloc)
+
+	assert.Equal(t, expected, drain(op)["a"])
+	assert.Equal(t, int64(0), alloc.Used())
+
+	assertEmptyDir(t, dir)
+
+}
+
+func TestHashAggSpill(t *testing.T) {
+
+	dir := spillDir(t)
+	defer os.RemoveAll(dir)
+
+	op := summarize(t, "count(), sum(latency), avg(latency) by host")
+
+	newInput := func() BatchOperator {
+
+		var batches []Batch
+
+		for i := 0; i < 20; i++ {
+
+			var hosts []string
+			var latency []int64
+
+			for j := 0; j < 10; j++ {
+				hosts = append(hosts, fmt.Sprintf("host-%v", (i*10+j)%50))
+				latency = append(latency, int64(i+j))
+			}
+
+			batches = append(batches, nodeInput(hosts, latency).(*batchSourceOp).batches...)
+
+		}
+
+		return &batchSourceOp{batches: batches}
+
+	}
+
+	aggregate := func(alloc Allocator) map[interface{}]map[string]interface{} {
+		partial := NewHashAggOp(newInput(), op.By, op.Agg, PartialAgg, alloc)
+		final := NewHashAggOp(partial, op.By, op.Agg, FinalAgg, alloc)
+		return rowsByKey(drain(final), "host")
+	}
+
+	expected := aggregate(NewAllocator(0, "", ""))
+
+	// 10 groups take about 1800 bytes, the tables are spilled several times.
+	alloc := NewAllocator(2500, dir, "node1")
+
+	assert.Len(t, expected, 50)
+	assert.Equal(t, expected, aggregate(alloc))
+	assert.Equal(t, int64(0), alloc.Used())
+
+	assertEmptyDir(t, dir)
+
+	// without a spill directory the query fails.
+	assert.Panics(t, func() { aggregate(NewAllocator(2500, "", "node1")) })
+
+}
@@ -0,0 +1,239 @@
+// Copyright 2020 The Meerkat Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package physical
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// OperatorStats are the execution statistics of an operator.
+type OperatorStats struct {
+	Operator string `json:"operator"`
+	// Segment is the id of the segment read by a scan operator.
+	Segment      string `json:"segment,omitempty"`
+	RowsIn       int64  `json:"rowsIn"`
+	RowsOut      int64  `json:"rowsOut"`
+	Batches      int64  `json:"batches"`
+	BytesDecoded int64  `json:"bytesDecoded,omitempty"`
+	// WallTime is the time spent producing the batches including the
+	// time spent on the inputs, SelfTime excludes it.
+	WallTime time.Duration `json:"wallTimeNs"`
+	SelfTime time.Duration `json:"selfTimeNs"`
+	// CPUTime is the CPU time consumed producing the batches including
+	// the CPU time of the inputs, SelfCPUTime excludes it. It's only
+	// measured on linux.
+	CPUTime     time.Duration    `json:"cpuTimeNs"`
+	SelfCPUTime time.Duration    `json:"selfCpuTimeNs"`
+	Inputs      []*OperatorStats `json:"inputs,omitempty"`
+}
+
+// NodeStats are the execution statistics of the DAG executed on a node.
+type NodeStats struct {
+	NodeId          string           `json:"nodeId"`
+	SegmentsScanned int              `json:"segmentsScanned"`
+	SegmentsPruned  int              `json:"segmentsPruned"`
+	Operators       []*OperatorStats `json:"operators"`
+}
+
+func operatorName(op Operator) string {
+
+	// the batches accounting is not an operator of the plan.
+	if b, ok := op.(*batchAllocOp); ok {
+		return operatorName(b.input)
+	}
+
+	return strings.TrimPrefix(fmt.Sprintf("%T", op), "*physical.")
+
+}
+
+// statsOp records the execution statistics of the operator it wraps.
+type statsOp struct {
+	input BatchOperator
+	stats *OperatorStats
+	// scan is true if input reads the batches from a segment.
+	scan bool
+	// guard, if any, limits the rows and bytes read by a scan.
+	guard *scanGuard
+}
+
+func newStatsOp(input BatchOperator) *statsOp {
+	return &statsOp{
+		input: input,
+		stats: &OperatorStats{Operator: operatorName(input)},
+	}
+}
+
+// newScanStatsOp wraps an operator reading the batches from segment.
+func newScanStatsOp(input BatchOperator, segment string, guard *scanGuard) *statsOp {
+	op := newStatsOp(input)
+	op.stats.Segment = segment
+	op.scan = true
+	op.guard = guard
+	return op
+}
+
+func (s *statsOp) Init()  { s.input.Init() }
+func (s *statsOp) Close() { s.input.Close() }
+
+func (s *statsOp) Next() Batch {
+
+	// the goroutine is locked to its thread while the thread CPU time is
+	// measured, the lock is a no-op if the runnable already holds it.
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	start := time.Now()
+	cpuStart := threadCPUTime()
+
+	batch := s.input.Next()
+
+	s.stats.CPUTime += threadCPUTime() - cpuStart
+	s.stats.WallTime += time.Since(start)
+
+	if batch.Len > 0 {
+
+		s.stats.RowsOut += int64(batch.Len)
+		s.stats.Batches++
+
+		if s.scan {
+
+			n := batchBytes(batch)
+
+			s.stats.BytesDecoded += n
+
+			if s.guard != nil {
+				s.guard.check(int64(batch.Len), n)
+			}
+
+		}
+
+	}
+
+	return batch
+
+}
+
+func (s *statsOp) Accept(v Visitor) {
+	s.input = Walk(s.input, v).(BatchOperator)
+}
+
+// batchBytes returns the size of the column values of batch.
+func batchBytes(batch Batch) int64 {
+
+	var n int64
+
+	for _, col := range batch.Columns {
+		n += vectorBytes(col)
+	}
+
+	return n
+
+}
+
+// vectorBytes returns the size of the values of col.
+func vectorBytes(col Col) int64 {
+
+	if v, ok := col.Vec.(interface{ AsBytes() []byte }); ok {
+		return int64(len(v.AsBytes()))
+	}
+
+	return 0
+
+}
+
+// statsTree links the stats recorded by the statsOp found in the tree
+// rooted at root. It must be called before the operators run because
+// some of them drop their exhausted inputs.
+func statsTree(root RunnableOp) *OperatorStats {
+
+	stats := &OperatorStats{Operator: operatorName(root)}
+
+	v := &statsCollector{stack: []*OperatorStats{stats}}
+
+	Walk(root, v)
+
+	return stats
+
+}
+
+// finishRootStats completes the stats of a tree built by statsTree once
+// the root has run for wallTime consuming cpuTime.
+func finishRootStats(stats *OperatorStats, wallTime time.Duration, cpuTime time.Duration) {
+
+	stats.WallTime = wallTime
+	stats.CPUTime = cpuTime
+
+	finishStats(stats)
+
+	// runnable operators consume all their input.
+	stats.RowsOut = stats.RowsIn
+
+}
+
+// finishStats computes the stats derived from the inputs.
+func finishStats(stats *OperatorStats) {
+
+	stats.RowsIn = 0
+	stats.SelfTime = stats.WallTime
+	stats.SelfCPUTime = stats.CPUTime
+
+	for _, input := range stats.Inputs {
+
+		finishStats(input)
+
+		stats.RowsIn += input.RowsOut
+		stats.SelfTime -= input.WallTime
+		stats.SelfCPUTime -= input.CPUTime
+
+	}
+
+	// the inputs can run on their own goroutines.
+	if stats.SelfTime < 0 {
+		stats.SelfTime = 0
+	}
+
+	if stats.SelfCPUTime < 0 {
+		stats.SelfCPUTime = 0
+	}
+
+}
+
+type statsCollector struct {
+	stack []*OperatorStats
+}
+
+func (c *statsCollector) VisitPre(n Operator) Operator {
+
+	if op, ok := n.(*statsOp); ok {
+		parent := c.stack[len(c.stack)-1]
+		parent.Inputs = append(parent.Inputs, op.stats)
+		c.stack = append(c.stack, op.stats)
+	}
+
+	return n
+
+}
+
+func (c *statsCollector) VisitPost(n Operator) Operator {
+
+	if _, ok := n.(*statsOp); ok {
+		c.stack = c.stack[:len(c.stack)-1]
+	}
+
+	return n
+
+}
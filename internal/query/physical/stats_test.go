@@ -0,0 +1,111 @@
+// Copyright 2020 The Meerkat Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package physical
+
+import (
+	"github.com/stretchr/testify/assert"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// drainOp is a RunnableOp consuming all the batches of its input.
+type drainOp struct {
+	input BatchOperator
+}
+
+func (d *drainOp) Init()  { d.input.Init() }
+func (d *drainOp) Close() { d.input.Close() }
+
+func (d *drainOp) Run() {
+	for batch := d.input.Next(); batch.Len != 0; batch = d.input.Next() {
+	}
+}
+
+func (d *drainOp) Accept(v Visitor) {
+	d.input = Walk(d.input, v).(BatchOperator)
+}
+
+func TestStatsTree(t *testing.T) {
+
+	newScan := func(segment string, values ...int64) BatchOperator {
+		return newScanStatsOp(&batchSourceOp{batches: []Batch{
+			testBatch(map[string]Col{"a": int64Col(0, values...)}),
+		}}, segment, nil)
+	}
+
+	filter := newStatsOp(NewFilterOp(
+		newStatsOp(NewMergeOp([]BatchOperator{
+			newScan("s1", 1, 2, 3),
+			newScan("s2", 4, 5),
+		})),
+		NewEvaluator(predicate(t, "a > 2")),
+	))
+
+	root := &drainOp{input: filter}
+	stats := statsTree(root)
+
+	root.Init()
+	root.Run()
+	root.Close()
+
+	finishRootStats(stats, time.Hour, time.Minute)
+
+	assert.Equal(t, "drainOp", stats.Operator)
+	assert.Equal(t, time.Hour, stats.WallTime)
+	assert.Equal(t, time.Minute, stats.CPUTime)
+	assert.Equal(t, int64(3), stats.RowsIn)
+	assert.Equal(t, int64(3), stats.RowsOut)
+
+	filterStats := stats.Inputs[0]
+	assert.Equal(t, "FilterOp", filterStats.Operator)
+	assert.Equal(t, int64(5), filterStats.RowsIn)
+	assert.Equal(t, int64(3), filterStats.RowsOut)
+	assert.True(t, filterStats.SelfTime <= filterStats.WallTime)
+	assert.True(t, filterStats.SelfCPUTime <= filterStats.CPUTime)
+
+	mergeStats := filterStats.Inputs[0]
+	assert.Equal(t, "MergeOp", mergeStats.Operator)
+	assert.Equal(t, int64(5), mergeStats.RowsOut)
+	assert.Len(t, mergeStats.Inputs, 2)
+
+	for _, scan := range mergeStats.Inputs {
+		assert.Equal(t, "batchSourceOp", scan.Operator)
+		assert.Equal(t, int64(1), scan.Batches)
+		assert.Equal(t, 8*scan.RowsOut, scan.BytesDecoded)
+	}
+
+	assert.Equal(t, "s1", mergeStats.Inputs[0].Segment)
+	assert.Equal(t, int64(3), mergeStats.Inputs[0].RowsOut)
+
+}
+
+func TestThreadCPUTime(t *testing.T) {
+
+	if runtime.GOOS != "linux" {
+		t.Skip("the thread CPU time is only measured on linux")
+	}
+
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	start := threadCPUTime()
+
+	// spin until the thread has consumed a millisecond of CPU time.
+	for threadCPUTime()-start < time.Millisecond {
+	}
+
+	assert.True(t, threadCPUTime()-start >= time.Millisecond)
+
+}
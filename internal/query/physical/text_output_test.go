@@ -0,0 +1,118 @@
+// Copyright 2020 The Meerkat Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package physical
+
+import (
+	"github.com/stretchr/testify/assert"
+	"meerkat/internal/query/parser"
+	"meerkat/internal/storage"
+	"testing"
+)
+
+func textOutputInput() BatchOperator {
+
+	ts := int64Col(0, 0, 1500000000)
+	ts.ColumnType = storage.ColumnType_TIMESTAMP
+
+	dynamic := stringCol(3, `{"a":1}`, `[1,2]`)
+	dynamic.ColumnType = storage.ColumnType_DYNAMIC
+
+	return &batchSourceOp{batches: []Batch{
+		testBatch(map[string]Col{
+			"_ts":     ts,
+			"name":    stringCol(1, "a,b", "c"),
+			"latency": nullableInt64Col(2, 10, nil),
+			"payload": dynamic,
+		}),
+	}}
+
+}
+
+func TestCSVOutputOp(t *testing.T) {
+
+	writer := &bufferOutputWriter{}
+
+	NewCSVOutputOp(textOutputInput(), nil, writer).Run()
+
+	expected := "_ts,name,latency,payload\n" +
+		"1970-01-01T00:00:00Z,\"a,b\",10,\"{\"\"a\"\":1}\"\n" +
+		"1970-01-01T00:00:01.5Z,c,,\"[1,2]\"\n"
+
+	assert.Equal(t, expected, writer.String())
+
+}
+
+func TestNDJSONOutputOp(t *testing.T) {
+
+	writer := &bufferOutputWriter{}
+
+	NewNDJSONOutputOp(textOutputInput(), nil, writer).Run()
+
+	expected := `{"_ts":0,"name":"a,b","latency":10,"payload":{"a":1}}` + "\n" +
+		`{"_ts":1500000000,"name":"c","latency":null,"payload":[1,2]}` + "\n"
+
+	assert.Equal(t, expected, writer.String())
+
+}
+
+// mixedColumnsInput returns batches with different columns, b is missing
+// in the first one.
+func mixedColumnsInput(writer *bufferOutputWriter) *writtenSourceOp {
+	return &writtenSourceOp{
+		batchSourceOp: batchSourceOp{batches: []Batch{
+			testBatch(map[string]Col{
+				"a": int64Col(0, 1),
+			}),
+			testBatch(map[string]Col{
+				"a": int64Col(0, 2),
+				"b": stringCol(1, "x"),
+			}),
+		}},
+		writer: writer,
+	}
+}
+
+var mixedColumnsPlan = map[string]parser.Type{
+	"a": parser.IntType,
+	"b": parser.StringType,
+}
+
+func TestCSVOutputOpMixedColumns(t *testing.T) {
+
+	writer := &bufferOutputWriter{}
+	input := mixedColumnsInput(writer)
+
+	NewCSVOutputOp(input, mixedColumnsPlan, writer).Run()
+
+	assert.Equal(t, "a,b\n1,\n2,x\n", writer.String())
+
+	// the header and the first row are written before the second batch.
+	assert.Equal(t, []int{0, len("a,b\n1,\n"), writer.Len()}, input.written)
+
+}
+
+func TestNDJSONOutputOpMixedColumns(t *testing.T) {
+
+	writer := &bufferOutputWriter{}
+	input := mixedColumnsInput(writer)
+
+	NewNDJSONOutputOp(input, mixedColumnsPlan, writer).Run()
+
+	first := `{"a":1,"b":null}` + "\n"
+	expected := first + `{"a":2,"b":"x"}` + "\n"
+
+	assert.Equal(t, expected, writer.String())
+	assert.Equal(t, []int{0, len(first), writer.Len()}, input.written)
+
+}
@@ -0,0 +1,47 @@
+// Copyright 2020 The Meerkat Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build linux
+// +build linux
+
+package physical
+
+import (
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// clockThreadCPUTime is the CLOCK_THREAD_CPUTIME_ID clock of clock_gettime.
+const clockThreadCPUTime = 3
+
+// threadCPUTime returns the CPU time consumed by the calling thread. The
+// goroutine must be locked to its thread to measure the time it runs.
+func threadCPUTime() time.Duration {
+
+	var ts syscall.Timespec
+
+	_, _, errno := syscall.Syscall(
+		syscall.SYS_CLOCK_GETTIME,
+		clockThreadCPUTime,
+		uintptr(unsafe.Pointer(&ts)),
+		0,
+	)
+
+	if errno != 0 {
+		return 0
+	}
+
+	return time.Duration(ts.Nano())
+
+}
@@ -0,0 +1,193 @@
+// Copyright 2020 The Meerkat Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package physical
+
+import (
+	"container/heap"
+	"meerkat/internal/query/logical"
+)
+
+// maxTopBatches is the number of input batches retained by a TopOp before
+// the rows in the heap are compacted into a single batch.
+const maxTopBatches = 64
+
+type topRow struct {
+	batch int
+	row   int
+	// seq is the position of the row in the input, used to break ties.
+	seq int
+}
+
+// topHeap is a max heap holding the best rows found so far, the worst of
+// them is at the top of the heap so it can be replaced by a better one.
+type topHeap struct {
+	keys    []sortKey
+	keyCols [][]Col
+	rows    []topRow
+}
+
+func (h *topHeap) compare(a, b topRow) int {
+	c := compareRows(h.keys, h.keyCols[a.batch], a.row, h.keyCols[b.batch], b.row)
+	if c == 0 {
+		return compareInt(int64(a.seq), int64(b.seq))
+	}
+	return c
+}
+
+func (h *topHeap) Len() int           { return len(h.rows) }
+func (h *topHeap) Less(i, j int) bool { return h.compare(h.rows[i], h.rows[j]) > 0 }
+func (h *topHeap) Swap(i, j int)      { h.rows[i], h.rows[j] = h.rows[j], h.rows[i] }
+func (h *topHeap) Push(x interface{}) { h.rows = append(h.rows, x.(topRow)) }
+
+func (h *topHeap) Pop() interface{} {
+	last := h.rows[len(h.rows)-1]
+	h.rows = h.rows[:len(h.rows)-1]
+	return last
+}
+
+// TopOp returns the first n rows of its input according to the sort keys.
+// Only the best n rows are kept in memory using a bounded heap.
+type TopOp struct {
+	input   BatchOperator
+	n       int
+	heap    *topHeap
+	batches []Batch
+	seq     int
+	output  []topRow
+	pos     int
+	done    bool
+}
+
+func NewTopOp(input BatchOperator, n int, sortExpr []*logical.SortExpr) *TopOp {
+	return &TopOp{
+		input: input,
+		n:     n,
+		heap:  &topHeap{keys: newSortKeys(sortExpr)},
+	}
+}
+
+func (t *TopOp) Init()  { t.input.Init() }
+func (t *TopOp) Close() { t.input.Close() }
+
+func (t *TopOp) Next() Batch {
+
+	if !t.done {
+		t.consume()
+		t.done = true
+	}
+
+	if t.pos == len(t.output) {
+		return Batch{}
+	}
+
+	end := t.pos + batchSize
+
+	if end > len(t.output) {
+		end = len(t.output)
+	}
+
+	builder := newRowBuilder()
+
+	for _, row := range t.output[t.pos:end] {
+		builder.AppendRow(t.batches[row.batch], row.row)
+	}
+
+	t.pos = end
+
+	return builder.Build()
+
+}
+
+func (t *TopOp) consume() {
+
+	if t.n <= 0 {
+		return
+	}
+
+	for batch := t.input.Next(); batch.Len != 0; batch = t.input.Next() {
+
+		t.add(batch)
+
+		if len(t.batches) > maxTopBatches {
+			t.compact()
+		}
+
+	}
+
+	// pop the rows from the worst to the best.
+	t.output = make([]topRow, t.heap.Len())
+
+	for i := len(t.output) - 1; i >= 0; i-- {
+		t.output[i] = heap.Pop(t.heap).(topRow)
+	}
+
+}
+
+func (t *TopOp) add(batch Batch) {
+
+	idx := len(t.batches)
+	used := false
+
+	t.batches = append(t.batches, batch)
+	t.heap.keyCols = append(t.heap.keyCols, evalSortKeys(t.heap.keys, batch))
+
+	for i := 0; i < batch.Len; i++ {
+
+		row := topRow{batch: idx, row: i, seq: t.seq}
+		t.seq++
+
+		if t.heap.Len() < t.n {
+			heap.Push(t.heap, row)
+			used = true
+			continue
+		}
+
+		if t.heap.compare(row, t.heap.rows[0]) < 0 {
+			t.heap.rows[0] = row
+			heap.Fix(t.heap, 0)
+			used = true
+		}
+
+	}
+
+	// release the batch if none of its rows made it to the heap.
+	if !used {
+		t.batches = t.batches[:idx]
+		t.heap.keyCols = t.heap.keyCols[:idx]
+	}
+
+}
+
+// compact copies the rows in the heap to a new batch releasing the input
+// batches.
+func (t *TopOp) compact() {
+
+	builder := newRowBuilder()
+
+	for i, row := range t.heap.rows {
+		builder.AppendRow(t.batches[row.batch], row.row)
+		t.heap.rows[i].batch = 0
+		t.heap.rows[i].row = i
+	}
+
+	batch := builder.Build()
+
+	t.batches = []Batch{batch}
+	t.heap.keyCols = [][]Col{evalSortKeys(t.heap.keys, batch)}
+
+}
+
+func (t *TopOp) Accept(v Visitor) {
+	t.input = Walk(t.input, v).(BatchOperator)
+}
@@ -0,0 +1,82 @@
+// Copyright 2020 The Meerkat Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package physical
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestTopOp(t *testing.T) {
+
+	newInput := func() BatchOperator {
+		return &batchSourceOp{batches: []Batch{
+			testBatch(map[string]Col{
+				"a":    nullableInt64Col(0, 2, nil, 1),
+				"name": stringCol(1, "r1", "r2", "r3"),
+			}),
+			testBatch(map[string]Col{
+				"a":    nullableInt64Col(0, 5, 2, nil),
+				"name": stringCol(1, "r4", "r5", "r6"),
+			}),
+			testBatch(map[string]Col{
+				"a":    nullableInt64Col(0, 0, 0),
+				"name": stringCol(1, "r7", "r8"),
+			}),
+		}}
+	}
+
+	cases := []struct {
+		name     string
+		n        int
+		sortExpr string
+		expected []string
+	}{
+		{name: "desc", n: 3, sortExpr: "a desc", expected: []string{"r4", "r1", "r5"}},
+		{name: "asc", n: 3, sortExpr: "a asc", expected: []string{"r7", "r8", "r3"}},
+		{name: "nulls first", n: 3, sortExpr: "a asc nulls first", expected: []string{"r2", "r6", "r7"}},
+		{name: "all rows", n: 20, sortExpr: "a asc", expected: []string{"r7", "r8", "r3", "r1", "r5", "r4", "r2", "r6"}},
+		{name: "zero rows", n: 0, sortExpr: "a asc"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			op := NewTopOp(newInput(), c.n, sortExpr(t, c.sortExpr))
+			assert.Equal(t, c.expected, drainStrings(op, "name"))
+		})
+	}
+
+}
+
+func TestTopOpCompact(t *testing.T) {
+
+	var batches []Batch
+	var expected []string
+
+	for i := 0; i < maxTopBatches*2; i++ {
+		batches = append(batches, testBatch(map[string]Col{
+			"a":    nullableInt64Col(0, i),
+			"name": stringCol(1, string(rune('a'+i%26))),
+		}))
+	}
+
+	for i := maxTopBatches*2 - 1; i >= maxTopBatches*2-5; i-- {
+		expected = append(expected, string(rune('a'+i%26)))
+	}
+
+	op := NewTopOp(&batchSourceOp{batches: batches}, 5, sortExpr(t, "a desc"))
+
+	assert.Equal(t, expected, drainStrings(op, "name"))
+
+}
@@ -0,0 +1,119 @@
+// Copyright 2020 The Meerkat Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package physical
+
+import (
+	"meerkat/internal/query/parser"
+	"meerkat/internal/storage"
+	"sort"
+)
+
+// UnionColumnsOp adds to the batches of a union branch the columns of the
+// union it is missing, ie. the columns of the other tables, as typed nulls
+// so every branch returns the same columns. The added columns are placed
+// after the input columns in name order.
+type UnionColumnsOp struct {
+	input   BatchOperator
+	names   []string
+	columns map[string]parser.Type
+}
+
+func NewUnionColumnsOp(input BatchOperator, columns map[string]parser.Type) *UnionColumnsOp {
+
+	names := make([]string, 0, len(columns))
+
+	for name := range columns {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return &UnionColumnsOp{
+		input:   input,
+		names:   names,
+		columns: columns,
+	}
+
+}
+
+func (u *UnionColumnsOp) Init()  { u.input.Init() }
+func (u *UnionColumnsOp) Close() { u.input.Close() }
+
+func (u *UnionColumnsOp) Next() Batch {
+
+	batch := u.input.Next()
+
+	if batch.Len == 0 {
+		return batch
+	}
+
+	group, order := lastPosition(batch)
+
+	output := NewBatch()
+	output.Len = batch.Len
+
+	for name, col := range batch.Columns {
+		output.Columns[name] = col
+	}
+
+	for _, name := range u.names {
+
+		if _, found := output.Columns[name]; found {
+			continue
+		}
+
+		order++
+
+		output.Columns[name] = nullCol(u.columns[name], batch.Len, group, order)
+
+	}
+
+	return output
+
+}
+
+func (u *UnionColumnsOp) Accept(v Visitor) {
+	u.input = Walk(u.input, v).(BatchOperator)
+}
+
+// nullCol returns a column of n nulls of type t.
+func nullCol(t parser.Type, n int, group int64, order int64) Col {
+
+	colType := storageType(t)
+
+	return Col{Group: group, Order: order, Vec: newNullVector(colType, n), ColumnType: colType}
+
+}
+
+// storageType returns the column type used for values of type t. The
+// values of an unknown type, ie. a column with different types in the
+// tables of a union, are held as text.
+func storageType(t parser.Type) storage.ColumnType {
+
+	switch t {
+	case parser.BoolType:
+		return storage.ColumnType_BOOL
+	case parser.IntType:
+		return storage.ColumnType_INT64
+	case parser.FloatType:
+		return storage.ColumnType_FLOAT64
+	case parser.DateTimeType:
+		return storage.ColumnType_TIMESTAMP
+	case parser.DynamicType:
+		return storage.ColumnType_DYNAMIC
+	default:
+		return storage.ColumnType_STRING
+	}
+
+}
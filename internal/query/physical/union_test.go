@@ -0,0 +1,68 @@
+// Copyright 2020 The Meerkat Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package physical
+
+import (
+	"github.com/stretchr/testify/assert"
+	"meerkat/internal/query/parser"
+	"meerkat/internal/storage"
+	"meerkat/internal/storage/vector"
+	"testing"
+)
+
+func TestUnionColumnsOp(t *testing.T) {
+
+	columns := map[string]parser.Type{
+		"a":     parser.IntType,
+		"b":     parser.StringType,
+		"c":     parser.DateTimeType,
+		"mixed": parser.UnknownType,
+	}
+
+	op := NewUnionColumnsOp(&batchSourceOp{batches: []Batch{
+		testBatch(map[string]Col{
+			"b": stringCol(0, "x", "y"),
+		}),
+	}}, columns)
+
+	batch := op.Next()
+
+	assert.Equal(t, 2, batch.Len)
+	assert.Equal(t, []string{"b", "a", "c", "mixed"}, columnOrder(batch.Columns))
+
+	a := batch.Columns["a"]
+	assert.Equal(t, storage.ColumnType_INT64, a.ColumnType)
+	assert.IsType(t, &vector.Int64Vector{}, a.Vec)
+
+	c := batch.Columns["c"]
+	assert.Equal(t, storage.ColumnType_TIMESTAMP, c.ColumnType)
+
+	// the type of a column with different types is not known.
+	mixed := batch.Columns["mixed"]
+	assert.Equal(t, storage.ColumnType_STRING, mixed.ColumnType)
+	assert.IsType(t, &vector.ByteSliceVector{}, mixed.Vec)
+
+	for _, name := range []string{"a", "c", "mixed"} {
+		col := batch.Columns[name]
+		assert.Equal(t, 2, col.Vec.Len())
+		assert.True(t, isNull(col.Vec, 0))
+		assert.True(t, isNull(col.Vec, 1))
+	}
+
+	// the branch columns are kept as they are.
+	assert.Equal(t, stringCol(0, "x", "y"), batch.Columns["b"])
+
+	assert.Equal(t, 0, op.Next().Len)
+
+}
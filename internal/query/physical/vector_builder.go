@@ -0,0 +1,277 @@
+// Copyright 2020 The Meerkat Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package physical
+
+import (
+	"fmt"
+	"meerkat/internal/storage"
+	"meerkat/internal/storage/vector"
+)
+
+// vectorBuilder builds a column appending one value at a time. The type of
+// the column is taken from the first non null value, a column built only
+// from nulls is returned as a nullVector.
+type vectorBuilder struct {
+	colType  storage.ColumnType
+	typed    bool
+	n        int
+	hasNulls bool
+	valid    []uint64
+	ints     []int64
+	floats   []float64
+	bools    []bool
+	buf      []byte
+	offsets  []int
+}
+
+// Len returns the number of values appended so far.
+func (b *vectorBuilder) Len() int { return b.n }
+
+func (b *vectorBuilder) setType(colType storage.ColumnType) {
+
+	if b.typed {
+		if vectorKind(b.colType) != vectorKind(colType) {
+			panic(fmt.Sprintf("cannot mix %v and %v values in the same column", b.colType, colType))
+		}
+		return
+	}
+
+	b.typed = true
+	b.colType = colType
+
+	// pad with the nulls appended before the type was known.
+	switch vectorKind(colType) {
+	case storage.ColumnType_INT64:
+		b.ints = make([]int64, b.n)
+	case storage.ColumnType_FLOAT64:
+		b.floats = make([]float64, b.n)
+	case storage.ColumnType_BOOL:
+		b.bools = make([]bool, b.n)
+	case storage.ColumnType_STRING:
+		b.offsets = make([]int, b.n)
+	default:
+		panic(fmt.Sprintf("unsupported column type %v", colType))
+	}
+
+}
+
+func (b *vectorBuilder) grow() {
+	if b.n>>6 >= len(b.valid) {
+		b.valid = append(b.valid, 0)
+	}
+}
+
+func (b *vectorBuilder) appendValid() {
+	b.grow()
+	setValid(b.valid, b.n)
+	b.n++
+}
+
+// AppendNull appends a null value.
+func (b *vectorBuilder) AppendNull() {
+
+	if b.typed {
+		switch vectorKind(b.colType) {
+		case storage.ColumnType_INT64:
+			b.ints = append(b.ints, 0)
+		case storage.ColumnType_FLOAT64:
+			b.floats = append(b.floats, 0)
+		case storage.ColumnType_BOOL:
+			b.bools = append(b.bools, false)
+		case storage.ColumnType_STRING:
+			b.offsets = append(b.offsets, len(b.buf))
+		}
+	}
+
+	b.grow()
+	b.hasNulls = true
+	b.n++
+
+}
+
+// AppendInt64 appends a value to an INT64, TIMESTAMP or DATETIME column.
+func (b *vectorBuilder) AppendInt64(colType storage.ColumnType, value int64) {
+	b.setType(colType)
+	b.ints = append(b.ints, value)
+	b.appendValid()
+}
+
+func (b *vectorBuilder) AppendFloat64(value float64) {
+	b.setType(storage.ColumnType_FLOAT64)
+	b.floats = append(b.floats, value)
+	b.appendValid()
+}
+
+func (b *vectorBuilder) AppendBool(value bool) {
+	b.setType(storage.ColumnType_BOOL)
+	b.bools = append(b.bools, value)
+	b.appendValid()
+}
+
+func (b *vectorBuilder) AppendBytes(value []byte) {
+	b.appendBytes(storage.ColumnType_STRING, value)
+}
+
+// AppendDynamic appends a JSON encoded value to a DYNAMIC column.
+func (b *vectorBuilder) AppendDynamic(value []byte) {
+	b.appendBytes(storage.ColumnType_DYNAMIC, value)
+}
+
+func (b *vectorBuilder) appendBytes(colType storage.ColumnType, value []byte) {
+	b.setType(colType)
+	b.buf = append(b.buf, value...)
+	b.offsets = append(b.offsets, len(b.buf))
+	b.appendValid()
+}
+
+// Append appends the i-th value of col.
+func (b *vectorBuilder) Append(col Col, i int) {
+
+	if isNull(col.Vec, i) {
+		b.AppendNull()
+		return
+	}
+
+	switch v := col.Vec.(type) {
+	case *vector.Int64Vector:
+		b.AppendInt64(col.ColumnType, v.Get(i))
+	case *vector.Float64Vector:
+		b.AppendFloat64(v.Get(i))
+	case *vector.BoolVector:
+		b.AppendBool(v.Get(i))
+	case *vector.ByteSliceVector:
+		b.appendBytes(col.ColumnType, v.Get(i))
+	default:
+		panic(fmt.Sprintf("cannot append values from vector %T", col.Vec))
+	}
+
+}
+
+// Build returns the column built so far.
+func (b *vectorBuilder) Build() Col {
+
+	if !b.typed {
+		return Col{Vec: &nullVector{l: b.n}}
+	}
+
+	var valid []uint64
+
+	if b.hasNulls {
+		valid = b.valid
+	}
+
+	col := Col{ColumnType: b.colType}
+
+	switch vectorKind(b.colType) {
+	case storage.ColumnType_INT64:
+		v := vector.NewInt64Vector(b.ints, valid)
+		col.Vec = &v
+	case storage.ColumnType_FLOAT64:
+		v := vector.NewFloat64Vector(b.floats, valid)
+		col.Vec = &v
+	case storage.ColumnType_BOOL:
+		v := vector.NewBoolVector(b.bools, valid)
+		col.Vec = &v
+	case storage.ColumnType_STRING:
+		v := vector.NewByteSliceVector(b.buf, b.offsets, valid)
+		col.Vec = &v
+	}
+
+	return col
+
+}
+
+// vectorKind maps a column type to the type used to represent its values.
+// INT64, TIMESTAMP and DATETIME columns share the same representation as
+// STRING and DYNAMIC ( JSON encoded ) columns do.
+func vectorKind(colType storage.ColumnType) storage.ColumnType {
+	if isIntegerType(colType) {
+		return storage.ColumnType_INT64
+	}
+	if colType == storage.ColumnType_DYNAMIC {
+		return storage.ColumnType_STRING
+	}
+	return colType
+}
+
+type rowBuilderCol struct {
+	group   int64
+	order   int64
+	builder vectorBuilder
+}
+
+// rowBuilder builds a batch appending rows taken from other batches. The
+// columns missing in some of the source batches are filled with nulls.
+type rowBuilder struct {
+	cols map[string]*rowBuilderCol
+	n    int
+}
+
+func newRowBuilder() *rowBuilder {
+	return &rowBuilder{cols: make(map[string]*rowBuilderCol)}
+}
+
+// Len returns the number of rows appended so far.
+func (b *rowBuilder) Len() int { return b.n }
+
+// AppendRow appends the row at position i of batch.
+func (b *rowBuilder) AppendRow(batch Batch, i int) {
+
+	for name, col := range batch.Columns {
+
+		c, found := b.cols[name]
+
+		if !found {
+			c = &rowBuilderCol{group: col.Group, order: col.Order}
+			for j := 0; j < b.n; j++ {
+				c.builder.AppendNull()
+			}
+			b.cols[name] = c
+		}
+
+		c.builder.Append(col, i)
+
+	}
+
+	b.n++
+
+	if len(b.cols) != len(batch.Columns) {
+		for _, c := range b.cols {
+			if c.builder.Len() < b.n {
+				c.builder.AppendNull()
+			}
+		}
+	}
+
+}
+
+// Build returns the batch built so far and resets the builder.
+func (b *rowBuilder) Build() Batch {
+
+	batch := NewBatch()
+	batch.Len = b.n
+
+	for name, c := range b.cols {
+		col := c.builder.Build()
+		col.Group = c.group
+		col.Order = c.order
+		batch.Columns[name] = col
+	}
+
+	b.cols = make(map[string]*rowBuilderCol)
+	b.n = 0
+
+	return batch
+
+}
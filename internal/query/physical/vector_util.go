@@ -0,0 +1,198 @@
+// Copyright 2020 The Meerkat Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package physical
+
+import (
+	"fmt"
+	"meerkat/internal/storage"
+	"meerkat/internal/storage/vector"
+)
+
+// validity bitmaps use the same layout as the vector package: one bit per
+// value, a set bit means the value is valid ( not null ).
+
+func newValidity(n int) []uint64 {
+	return make([]uint64, (n+63)>>6)
+}
+
+func setValid(valid []uint64, i int) {
+	valid[i>>6] |= 1 << (uint(i) & 63)
+}
+
+func setInvalid(valid []uint64, i int) {
+	valid[i>>6] &^= 1 << (uint(i) & 63)
+}
+
+type validityVector interface {
+	HasNulls() bool
+	IsValid(i int) bool
+}
+
+// isNull returns true if the i-th value of the vector is null.
+func isNull(vec vector.Vector, i int) bool {
+
+	if _, ok := vec.(*nullVector); ok {
+		return true
+	}
+
+	if v, ok := vec.(validityVector); ok && v.HasNulls() {
+		return !v.IsValid(i)
+	}
+
+	return false
+
+}
+
+// mergeValidity builds the validity bitmap of a vector computed row by row
+// from the given vectors. A value is null if any of its inputs is null.
+// Returns nil if none of the vectors contains nulls.
+func mergeValidity(n int, vecs ...vector.Vector) []uint64 {
+
+	var valid []uint64
+
+	for _, vec := range vecs {
+
+		if !vec.HasNulls() {
+			continue
+		}
+
+		if valid == nil {
+			valid = newValidity(n)
+			for i := 0; i < n; i++ {
+				setValid(valid, i)
+			}
+		}
+
+		for i := 0; i < n; i++ {
+			if isNull(vec, i) {
+				setInvalid(valid, i)
+			}
+		}
+
+	}
+
+	return valid
+
+}
+
+// nullVector is an untyped vector where all the values are null. It is used
+// to represent null literals and references to columns that are not present
+// in a batch ( ie. a column missing in some segments ).
+type nullVector struct {
+	l int
+}
+
+func (v *nullVector) Len() int                { return v.l }
+func (v *nullVector) Cap() int                { return v.l }
+func (v *nullVector) HasNulls() bool          { return true }
+func (v *nullVector) AsBytes() []byte         { panic("cannot serialize an untyped null vector") }
+func (v *nullVector) ValidityAsBytes() []byte { panic("cannot serialize an untyped null vector") }
+
+// newNullVector creates a vector of the given type with n null values.
+func newNullVector(colType storage.ColumnType, n int) vector.Vector {
+
+	valid := newValidity(n)
+
+	switch colType {
+	case storage.ColumnType_TIMESTAMP, storage.ColumnType_DATETIME, storage.ColumnType_INT64:
+		v := vector.NewInt64Vector(make([]int64, n), valid)
+		return &v
+	case storage.ColumnType_FLOAT64:
+		v := vector.NewFloat64Vector(make([]float64, n), valid)
+		return &v
+	case storage.ColumnType_BOOL:
+		v := vector.NewBoolVector(make([]bool, n), valid)
+		return &v
+	case storage.ColumnType_STRING, storage.ColumnType_DYNAMIC:
+		v := vector.NewByteSliceVector(nil, make([]int, n), valid)
+		return &v
+	default:
+		panic(fmt.Sprintf("cannot create null vector of type %v", colType))
+	}
+
+}
+
+// selectVector returns a new vector containing the values at the
+// positions in sel.
+func selectVector(vec vector.Vector, sel []int) vector.Vector {
+
+	var valid []uint64
+
+	if vec.HasNulls() {
+		valid = newValidity(len(sel))
+		for i, pos := range sel {
+			if !isNull(vec, pos) {
+				setValid(valid, i)
+			}
+		}
+	}
+
+	switch v := vec.(type) {
+	case *vector.Int64Vector:
+		src := v.Values()
+		buf := make([]int64, len(sel))
+		for i, pos := range sel {
+			buf[i] = src[pos]
+		}
+		r := vector.NewInt64Vector(buf, valid)
+		return &r
+	case *vector.Float64Vector:
+		src := v.Values()
+		buf := make([]float64, len(sel))
+		for i, pos := range sel {
+			buf[i] = src[pos]
+		}
+		r := vector.NewFloat64Vector(buf, valid)
+		return &r
+	case *vector.BoolVector:
+		src := v.Values()
+		buf := make([]bool, len(sel))
+		for i, pos := range sel {
+			buf[i] = src[pos]
+		}
+		r := vector.NewBoolVector(buf, valid)
+		return &r
+	case *vector.ByteSliceVector:
+		var buf []byte
+		offsets := make([]int, len(sel))
+		for i, pos := range sel {
+			buf = append(buf, v.Get(pos)...)
+			offsets[i] = len(buf)
+		}
+		r := vector.NewByteSliceVector(buf, offsets, valid)
+		return &r
+	case *nullVector:
+		return &nullVector{l: len(sel)}
+	default:
+		panic(fmt.Sprintf("cannot select values from vector %T", vec))
+	}
+
+}
+
+// selectBatch returns a new batch containing the rows at the positions
+// in sel. The positions must be in ascending order so sorted columns
+// remain sorted.
+func selectBatch(batch Batch, sel []int) Batch {
+
+	result := NewBatch()
+	result.Len = len(sel)
+
+	for name, col := range batch.Columns {
+		col.Vec = selectVector(col.Vec, sel)
+		result.Columns[name] = col
+	}
+
+	return result
+
+}
@@ -0,0 +1,281 @@
+// Copyright 2020 The Meerkat Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package sketch implements mergeable summaries of large sets of values
+// which can be serialized and shipped between nodes.
+package sketch
+
+import (
+	"encoding/binary"
+	"errors"
+	"github.com/twmb/murmur3"
+	"math"
+	"math/bits"
+	"sort"
+)
+
+const (
+	// HLLPrecision is the number of bits of the hash used to select a
+	// register. 2^14 registers give a standard error of 0.81%.
+	HLLPrecision = 14
+
+	hllRegisters = 1 << HLLPrecision
+	// hllMaxRho is the max value of a register.
+	hllMaxRho = 64 - HLLPrecision + 1
+	// hllSparseMax is the number of registers kept by a sparse sketch
+	// before it's turned into a dense one.
+	hllSparseMax = hllRegisters / 4
+
+	hllDense  = 1
+	hllSparse = 2
+)
+
+// HLL is a HyperLogLog sketch estimating the number of distinct values of
+// a set. Small sketches keep only the registers in use and become dense
+// when they grow, so sketches of small groups are cheap.
+type HLL struct {
+	sparse    map[uint16]uint8
+	registers []uint8
+}
+
+// NewHLL creates an empty sketch.
+func NewHLL() *HLL {
+	return &HLL{sparse: make(map[uint16]uint8)}
+}
+
+// Insert adds a value to the set.
+func (h *HLL) Insert(value []byte) {
+	hash, _ := murmur3.Sum128(value)
+	h.InsertHash(hash)
+}
+
+// InsertHash adds the 64 bits hash of a value to the set.
+func (h *HLL) InsertHash(hash uint64) {
+
+	idx := uint16(hash >> (64 - HLLPrecision))
+	w := hash<<HLLPrecision | 1<<(HLLPrecision-1)
+	rho := uint8(bits.LeadingZeros64(w) + 1)
+
+	h.set(idx, rho)
+
+}
+
+func (h *HLL) set(idx uint16, rho uint8) {
+
+	if h.registers != nil {
+		if rho > h.registers[idx] {
+			h.registers[idx] = rho
+		}
+		return
+	}
+
+	if rho > h.sparse[idx] {
+		h.sparse[idx] = rho
+	}
+
+	if len(h.sparse) > hllSparseMax {
+		h.toDense()
+	}
+
+}
+
+func (h *HLL) toDense() {
+
+	h.registers = make([]uint8, hllRegisters)
+
+	for idx, rho := range h.sparse {
+		h.registers[idx] = rho
+	}
+
+	h.sparse = nil
+
+}
+
+// Merge adds the values of other to the set.
+func (h *HLL) Merge(other *HLL) {
+
+	if other.registers == nil {
+		for idx, rho := range other.sparse {
+			h.set(idx, rho)
+		}
+		return
+	}
+
+	if h.registers == nil {
+		h.toDense()
+	}
+
+	for idx, rho := range other.registers {
+		if rho > h.registers[idx] {
+			h.registers[idx] = rho
+		}
+	}
+
+}
+
+// Estimate returns the estimated number of distinct values. It uses the
+// improved raw estimator described by Otmar Ertl in "New cardinality
+// estimation algorithms for HyperLogLog sketches" which doesn't need
+// empirical bias corrections.
+func (h *HLL) Estimate() uint64 {
+
+	const q = hllMaxRho - 1
+
+	var counts [hllMaxRho + 1]int
+
+	if h.registers != nil {
+		for _, rho := range h.registers {
+			counts[rho]++
+		}
+	} else {
+		counts[0] = hllRegisters - len(h.sparse)
+		for _, rho := range h.sparse {
+			counts[rho]++
+		}
+	}
+
+	m := float64(hllRegisters)
+
+	z := m * hllTau(1-float64(counts[q+1])/m)
+
+	for k := q; k >= 1; k-- {
+		z = 0.5 * (z + float64(counts[k]))
+	}
+
+	z += m * hllSigma(float64(counts[0])/m)
+
+	return uint64(math.Round(m * m / (2 * math.Ln2 * z)))
+
+}
+
+func hllSigma(x float64) float64 {
+
+	if x == 1 {
+		return math.Inf(1)
+	}
+
+	y := 1.0
+	z := x
+
+	for {
+		x *= x
+		prev := z
+		z += x * y
+		y += y
+		if z == prev {
+			return z
+		}
+	}
+
+}
+
+func hllTau(x float64) float64 {
+
+	if x == 0 || x == 1 {
+		return 0
+	}
+
+	y := 1.0
+	z := 1 - x
+
+	for {
+		x = math.Sqrt(x)
+		prev := z
+		y *= 0.5
+		z -= (1 - x) * (1 - x) * y
+		if z == prev {
+			return z / 3
+		}
+	}
+
+}
+
+// MarshalBinary encodes the sketch. Sparse sketches are encoded as a list
+// of ( register, value ) pairs sorted by register.
+func (h *HLL) MarshalBinary() ([]byte, error) {
+
+	if h.registers != nil {
+		return append([]byte{hllDense}, h.registers...), nil
+	}
+
+	idxs := make([]int, 0, len(h.sparse))
+
+	for idx := range h.sparse {
+		idxs = append(idxs, int(idx))
+	}
+
+	sort.Ints(idxs)
+
+	buf := make([]byte, 1, 1+3*len(idxs))
+	buf[0] = hllSparse
+
+	for _, idx := range idxs {
+		buf = append(buf, 0, 0, h.sparse[uint16(idx)])
+		binary.LittleEndian.PutUint16(buf[len(buf)-3:], uint16(idx))
+	}
+
+	return buf, nil
+
+}
+
+var errInvalidHLL = errors.New("invalid hll sketch")
+
+// UnmarshalHLL decodes a sketch encoded with MarshalBinary.
+func UnmarshalHLL(data []byte) (*HLL, error) {
+
+	if len(data) == 0 {
+		return nil, errInvalidHLL
+	}
+
+	switch data[0] {
+
+	case hllDense:
+
+		if len(data) != 1+hllRegisters {
+			return nil, errInvalidHLL
+		}
+
+		registers := make([]uint8, hllRegisters)
+		copy(registers, data[1:])
+
+		for _, rho := range registers {
+			if rho > hllMaxRho {
+				return nil, errInvalidHLL
+			}
+		}
+
+		return &HLL{registers: registers}, nil
+
+	case hllSparse:
+
+		if (len(data)-1)%3 != 0 {
+			return nil, errInvalidHLL
+		}
+
+		h := NewHLL()
+
+		for i := 1; i < len(data); i += 3 {
+			idx := binary.LittleEndian.Uint16(data[i:])
+			if idx >= hllRegisters || data[i+2] > hllMaxRho {
+				return nil, errInvalidHLL
+			}
+			h.set(idx, data[i+2])
+		}
+
+		return h, nil
+
+	default:
+		return nil, errInvalidHLL
+	}
+
+}
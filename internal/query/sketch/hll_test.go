@@ -0,0 +1,77 @@
+// Copyright 2020 The Meerkat Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sketch
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strconv"
+	"testing"
+)
+
+func hllOf(from, to int) *HLL {
+
+	h := NewHLL()
+
+	for i := from; i < to; i++ {
+		h.Insert([]byte(strconv.Itoa(i)))
+	}
+
+	return h
+
+}
+
+func TestHLLEstimate(t *testing.T) {
+
+	assert.Equal(t, uint64(0), NewHLL().Estimate())
+
+	for _, n := range []int{10, 1000, 5000, 50000, 1000000} {
+		h := hllOf(0, n)
+		// the same values again don't change the estimate.
+		h.Merge(hllOf(0, n))
+		assert.InEpsilon(t, n, h.Estimate(), 0.02, "%v values", n)
+	}
+
+}
+
+func TestHLLMerge(t *testing.T) {
+
+	// sparse and dense sketches sharing half of their values.
+	for _, size := range []int{1000, 100000} {
+
+		h := hllOf(0, size)
+		h.Merge(hllOf(size/2, size*2))
+
+		assert.InEpsilon(t, 2*size, h.Estimate(), 0.02)
+
+	}
+
+}
+
+func TestHLLMarshal(t *testing.T) {
+
+	for _, h := range []*HLL{hllOf(0, 100), hllOf(0, 100000)} {
+
+		data, err := h.MarshalBinary()
+		assert.NoError(t, err)
+
+		decoded, err := UnmarshalHLL(data)
+		assert.NoError(t, err)
+		assert.Equal(t, h.Estimate(), decoded.Estimate())
+
+	}
+
+	_, err := UnmarshalHLL([]byte{hllDense, 1, 2})
+	assert.Error(t, err)
+
+}
@@ -0,0 +1,243 @@
+// Copyright 2020 The Meerkat Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sketch
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"sort"
+)
+
+// TDigestCompression bounds the number of centroids of a digest, larger
+// values give more accurate quantiles.
+const TDigestCompression = 100
+
+type centroid struct {
+	mean  float64
+	count float64
+}
+
+// TDigest is a merging t-digest as described by Ted Dunning in "Computing
+// extremely accurate quantiles using t-digests". The values are summarized
+// in centroids that are smaller near the extremes, so the quantiles of the
+// tails are more accurate than the median.
+type TDigest struct {
+	centroids []centroid
+	// buffer holds the values added since the last compression.
+	buffer []centroid
+	count  float64
+	min    float64
+	max    float64
+}
+
+// NewTDigest creates an empty digest.
+func NewTDigest() *TDigest {
+	return &TDigest{min: math.Inf(1), max: math.Inf(-1)}
+}
+
+// Count returns the number of values added to the digest.
+func (t *TDigest) Count() float64 { return t.count }
+
+// Add adds a value to the digest.
+func (t *TDigest) Add(x float64) {
+	t.add(centroid{mean: x, count: 1})
+}
+
+func (t *TDigest) add(c centroid) {
+
+	t.buffer = append(t.buffer, c)
+	t.count += c.count
+	t.min = math.Min(t.min, c.mean)
+	t.max = math.Max(t.max, c.mean)
+
+	if len(t.buffer) >= 5*TDigestCompression {
+		t.compress()
+	}
+
+}
+
+// Merge adds the values of other to the digest.
+func (t *TDigest) Merge(other *TDigest) {
+
+	for _, c := range other.centroids {
+		t.add(c)
+	}
+
+	for _, c := range other.buffer {
+		t.add(c)
+	}
+
+	t.min = math.Min(t.min, other.min)
+	t.max = math.Max(t.max, other.max)
+
+}
+
+// scale is the k1 scale function of the paper, a centroid can't span
+// more than one unit of k.
+func scale(q float64) float64 {
+	return TDigestCompression / (2 * math.Pi) * math.Asin(2*q-1)
+}
+
+func scaleInverse(k float64) float64 {
+	return (math.Sin(k*2*math.Pi/TDigestCompression) + 1) / 2
+}
+
+// compress merges the buffered values into the centroids.
+func (t *TDigest) compress() {
+
+	if len(t.buffer) == 0 {
+		return
+	}
+
+	all := append(t.centroids, t.buffer...)
+
+	sort.Slice(all, func(i, j int) bool { return all[i].mean < all[j].mean })
+
+	merged := make([]centroid, 0, len(t.centroids)+1)
+	current := all[0]
+	weight := 0.0
+	limit := t.count * scaleInverse(scale(0)+1)
+
+	for _, c := range all[1:] {
+
+		if weight+current.count+c.count <= limit {
+			current.count += c.count
+			current.mean += (c.mean - current.mean) * c.count / current.count
+			continue
+		}
+
+		weight += current.count
+		merged = append(merged, current)
+		current = c
+		limit = t.count * scaleInverse(scale(weight/t.count)+1)
+
+	}
+
+	t.centroids = append(merged, current)
+	t.buffer = t.buffer[:0]
+
+}
+
+// Quantile returns the estimated value at quantile q ( 0 <= q <= 1 ). The
+// values are interpolated between the centroids. Returns NaN if the
+// digest is empty.
+func (t *TDigest) Quantile(q float64) float64 {
+
+	t.compress()
+
+	if t.count == 0 {
+		return math.NaN()
+	}
+
+	cs := t.centroids
+	target := q * t.count
+
+	if len(cs) == 1 || target <= 0 {
+		if target <= 0 {
+			return t.min
+		}
+		return cs[0].mean
+	}
+
+	if target >= t.count {
+		return t.max
+	}
+
+	// between the min and the center of the first centroid.
+	if first := cs[0].count / 2; target < first {
+		return t.min + (cs[0].mean-t.min)*target/first
+	}
+
+	weight := 0.0
+
+	for i := 0; i < len(cs)-1; i++ {
+
+		left := weight + cs[i].count/2
+		right := weight + cs[i].count + cs[i+1].count/2
+
+		if target <= right {
+			return cs[i].mean + (cs[i+1].mean-cs[i].mean)*(target-left)/(right-left)
+		}
+
+		weight += cs[i].count
+
+	}
+
+	// between the center of the last centroid and the max.
+	last := cs[len(cs)-1]
+	left := t.count - last.count/2
+
+	return last.mean + (t.max-last.mean)*(target-left)/(last.count/2)
+
+}
+
+// MarshalBinary encodes the digest as its min, max and centroids.
+func (t *TDigest) MarshalBinary() ([]byte, error) {
+
+	t.compress()
+
+	buf := make([]byte, 0, 16*(len(t.centroids)+1))
+	buf = appendFloat64(buf, t.min)
+	buf = appendFloat64(buf, t.max)
+
+	for _, c := range t.centroids {
+		buf = appendFloat64(buf, c.mean)
+		buf = appendFloat64(buf, c.count)
+	}
+
+	return buf, nil
+
+}
+
+var errInvalidTDigest = errors.New("invalid t-digest")
+
+// UnmarshalTDigest decodes a digest encoded with MarshalBinary.
+func UnmarshalTDigest(data []byte) (*TDigest, error) {
+
+	if len(data) < 16 || len(data)%16 != 0 {
+		return nil, errInvalidTDigest
+	}
+
+	t := &TDigest{
+		min: readFloat64(data),
+		max: readFloat64(data[8:]),
+	}
+
+	for i := 16; i < len(data); i += 16 {
+
+		c := centroid{mean: readFloat64(data[i:]), count: readFloat64(data[i+8:])}
+
+		if c.count <= 0 {
+			return nil, errInvalidTDigest
+		}
+
+		t.centroids = append(t.centroids, c)
+		t.count += c.count
+
+	}
+
+	return t, nil
+
+}
+
+func appendFloat64(buf []byte, x float64) []byte {
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], math.Float64bits(x))
+	return append(buf, b[:]...)
+}
+
+func readFloat64(b []byte) float64 {
+	return math.Float64frombits(binary.LittleEndian.Uint64(b))
+}
@@ -0,0 +1,85 @@
+// Copyright 2020 The Meerkat Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sketch
+
+import (
+	"github.com/stretchr/testify/assert"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestTDigestQuantile(t *testing.T) {
+
+	assert.True(t, math.IsNaN(NewTDigest().Quantile(0.5)))
+
+	d := NewTDigest()
+
+	for _, i := range rand.New(rand.NewSource(1)).Perm(100000) {
+		d.Add(float64(i))
+	}
+
+	assert.Equal(t, float64(100000), d.Count())
+	assert.Equal(t, float64(0), d.Quantile(0))
+	assert.Equal(t, float64(99999), d.Quantile(1))
+
+	for _, q := range []float64{0.01, 0.25, 0.5, 0.95, 0.99, 0.999} {
+		assert.InDelta(t, q*100000, d.Quantile(q), 100000*0.002, "quantile %v", q)
+	}
+
+	single := NewTDigest()
+	single.Add(7)
+
+	assert.Equal(t, float64(7), single.Quantile(0.5))
+
+}
+
+func TestTDigestMerge(t *testing.T) {
+
+	d1, d2 := NewTDigest(), NewTDigest()
+
+	for i := 0; i < 50000; i++ {
+		d1.Add(float64(i))
+		d2.Add(float64(i + 50000))
+	}
+
+	d1.Merge(d2)
+
+	assert.Equal(t, float64(100000), d1.Count())
+	assert.InDelta(t, 50000, d1.Quantile(0.5), 100000*0.002)
+	assert.InDelta(t, 99000, d1.Quantile(0.99), 100000*0.002)
+
+}
+
+func TestTDigestMarshal(t *testing.T) {
+
+	d := NewTDigest()
+
+	for i := 0; i < 10000; i++ {
+		d.Add(float64(i))
+	}
+
+	data, err := d.MarshalBinary()
+	assert.NoError(t, err)
+
+	decoded, err := UnmarshalTDigest(data)
+	assert.NoError(t, err)
+
+	assert.Equal(t, d.Count(), decoded.Count())
+	assert.Equal(t, d.Quantile(0.95), decoded.Quantile(0.95))
+
+	_, err = UnmarshalTDigest(data[:20])
+	assert.Error(t, err)
+
+}
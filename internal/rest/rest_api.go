@@ -15,15 +15,21 @@ package rest
 
 import (
 	"context"
+	"fmt"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 	"meerkat/internal/cluster"
 	"meerkat/internal/ingestion"
 	"meerkat/internal/jsoningester"
 	"meerkat/internal/query/exec"
+	"meerkat/internal/query/execbase"
+	"mime"
 	"net/http"
+	"strings"
 	"sync"
+	"time"
 )
 
 type ApiServer struct {
@@ -46,11 +52,75 @@ type ApiError struct {
 
 type QueryBody struct {
 	Query string `json:"query"`
+	// Timeout is the query deadline as a duration string ( ie. "30s" ).
+	Timeout string `json:"timeout,omitempty"`
+	// MaxMemory is the memory budget of the query in bytes on every node.
+	MaxMemory int64 `json:"maxMemory,omitempty"`
+	// MaxRowsScanned limits the rows read from the segments on every node.
+	MaxRowsScanned int64 `json:"maxRowsScanned,omitempty"`
+	// MaxBytesScanned limits the bytes read from the segments on every node.
+	MaxBytesScanned int64 `json:"maxBytesScanned,omitempty"`
+}
+
+// limits returns the query limits requested in the body.
+func (b *QueryBody) limits() (execbase.QueryLimits, error) {
+
+	limits := execbase.QueryLimits{
+		MaxMemory:       b.MaxMemory,
+		MaxRowsScanned:  b.MaxRowsScanned,
+		MaxBytesScanned: b.MaxBytesScanned,
+	}
+
+	if b.Timeout != "" {
+
+		timeout, err := time.ParseDuration(b.Timeout)
+
+		if err != nil {
+			return limits, fmt.Errorf("invalid query timeout: %v", err)
+		}
+
+		limits.Timeout = timeout
+
+	}
+
+	return limits, nil
+
+}
+
+// outputMediaTypes maps the media types accepted by /query to the query
+// output formats.
+var outputMediaTypes = map[string]execbase.OutputFormat{
+	"application/vnd.apache.arrow.stream": execbase.ArrowOutput,
+	"text/csv":                            execbase.CSVOutput,
+	"application/x-ndjson":                execbase.NDJSONOutput,
+}
+
+// outputFormat returns the query output format requested by the Accept
+// header and its media type, the JSON column batches by default.
+func outputFormat(accept string) (execbase.OutputFormat, string) {
+
+	for _, mediaRange := range strings.Split(accept, ",") {
+
+		mediaType, _, err := mime.ParseMediaType(mediaRange)
+
+		if err != nil {
+			continue
+		}
+
+		if format, found := outputMediaTypes[mediaType]; found {
+			return format, mediaType
+		}
+
+	}
+
+	return execbase.JSONOutput, ""
+
 }
 
 const (
 	indexIDParam = "indexID"
 	fieldIDParam = "fieldID"
+	queryIDParam = "queryID"
 )
 
 func NewRestApi(
@@ -90,6 +160,9 @@ func NewRestApi(
 
 	server.router.POST("/ingest/:tableName", server.ingest)
 	server.router.POST("/query", server.query)
+	server.router.POST("/explain", server.explain)
+	server.router.GET("/queries", server.listQueries)
+	server.router.DELETE("/queries/:queryID", server.cancelQuery)
 
 	return server, nil
 
@@ -429,7 +502,20 @@ func (s *ApiServer) query(c *gin.Context) {
 		return
 	}
 
-	err = s.executor.ExecuteQuery(body.Query, c.Writer)
+	limits, err := body.limits()
+
+	if err != nil {
+		bindError("cannot execute query", c, err)
+		return
+	}
+
+	format, mediaType := outputFormat(c.GetHeader("Accept"))
+
+	if mediaType != "" {
+		c.Header("Content-Type", mediaType)
+	}
+
+	err = s.executor.ExecuteQuery(body.Query, limits, format, c.Writer)
 
 	if err != nil {
 		bindError("cannot execute query", c, err)
@@ -438,7 +524,63 @@ func (s *ApiServer) query(c *gin.Context) {
 
 }
 
+func (s *ApiServer) explain(c *gin.Context) {
+
+	body := &QueryBody{}
+
+	err := c.Bind(body)
+
+	if err != nil {
+		bindError("cannot explain query", c, err)
+		return
+	}
+
+	explain, err := s.executor.ExplainQuery(body.Query)
+
+	if err != nil {
+		bindError("cannot explain query", c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, explain)
+
+}
+
+func (s *ApiServer) listQueries(c *gin.Context) {
+	c.JSON(http.StatusOK, s.executor.ListQueries())
+}
+
+func (s *ApiServer) cancelQuery(c *gin.Context) {
+
+	id, err := uuid.Parse(c.Param(queryIDParam))
+
+	if err != nil {
+		bindError("cannot cancel query", c, err)
+		return
+	}
+
+	err = s.executor.CancelQuery(id)
+
+	if err != nil {
+		appError("cannot cancel query", c, err)
+		return
+	}
+
+	c.Status(http.StatusOK)
+
+}
+
 func appError(status string, c *gin.Context, err error) {
+
+	if err == exec.ErrQueryNotFound {
+		sendError(c, &ApiError{
+			Code:      http.StatusNotFound,
+			Status:    status,
+			ErrorText: err.Error(),
+		})
+		return
+	}
+
 	switch err.(type) {
 	//case *schema.ValidationError:
 	//	sendError(c, &ApiError{
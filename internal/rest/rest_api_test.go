@@ -0,0 +1,103 @@
+// Copyright 2020 The Meerkat Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rest
+
+import (
+	"encoding/json"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"meerkat/internal/query/exec"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type testExecutor struct {
+	exec.Executor
+	queries  []*exec.QueryInfo
+	canceled []uuid.UUID
+}
+
+func (e *testExecutor) ListQueries() []*exec.QueryInfo { return e.queries }
+
+func (e *testExecutor) CancelQuery(queryId uuid.UUID) error {
+
+	for _, q := range e.queries {
+		if q.Id == queryId.String() {
+			e.canceled = append(e.canceled, queryId)
+			return nil
+		}
+	}
+
+	return exec.ErrQueryNotFound
+
+}
+
+func serve(t *testing.T, executor exec.Executor, method string, path string) *httptest.ResponseRecorder {
+
+	server, err := NewRestApi(nil, nil, nil, executor)
+
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+
+	return w
+
+}
+
+func TestListQueries(t *testing.T) {
+
+	executor := &testExecutor{
+		queries: []*exec.QueryInfo{
+			{Id: uuid.New().String(), Query: "T | take 1", Nodes: []string{"node1"}},
+		},
+	}
+
+	w := serve(t, executor, http.MethodGet, "/queries")
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var queries []*exec.QueryInfo
+
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &queries))
+	assert.Equal(t, executor.queries, queries)
+
+}
+
+func TestCancelQuery(t *testing.T) {
+
+	id := uuid.New()
+
+	executor := &testExecutor{
+		queries: []*exec.QueryInfo{{Id: id.String()}},
+	}
+
+	w := serve(t, executor, http.MethodDelete, "/queries/"+id.String())
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []uuid.UUID{id}, executor.canceled)
+
+	w = serve(t, executor, http.MethodDelete, "/queries/"+uuid.New().String())
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = serve(t, executor, http.MethodDelete, "/queries/not-a-uuid")
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Len(t, executor.canceled, 1)
+
+}
@@ -30,6 +30,7 @@ import (
 	"meerkat/internal/storage"
 	"net"
 	"os"
+	"path/filepath"
 	"strings"
 	"sync"
 	"time"
@@ -48,6 +49,7 @@ type Meerkat struct {
 	bufReg            ingestion.BufferRegistry
 	segStorage        storage.SegmentStorage
 	segRegistry       storage.SegmentRegistry
+	schemaPublisher   *exec.SchemaPublisher
 }
 
 func (m *Meerkat) Start(ctx context.Context) {
@@ -116,6 +118,12 @@ func (m *Meerkat) Start(ctx context.Context) {
 
 	m.segRegistry.Start()
 
+	// publish the local columns so the queries coordinated by any node can
+	// resolve them.
+	m.schemaPublisher = exec.NewSchemaPublisher(m.catalog, m.segRegistry, m.clusterMgr)
+
+	m.schemaPublisher.Start()
+
 	m.segmentWriterPool = storage.NewSegmentWriterPool(1024,
 		10,
 		m.segStorage,
@@ -139,11 +147,21 @@ func (m *Meerkat) Start(ctx context.Context) {
 
 	streamReg := physical.NewStreamRegistry()
 
-	execServer := exec.NewServer(m.clusterMgr, m.segRegistry, streamReg)
+	// the queries spill the data that doesn't fit in memory under DBPath.
+	spillDir := filepath.Join(m.Conf.DBPath, "spill")
+
+	// remove the runs left behind by a previous process.
+	err = os.RemoveAll(spillDir)
+
+	if err != nil {
+		m.log.Panic().Err(err).Msg("cannot clean the spill directory")
+	}
+
+	execServer := exec.NewServer(m.clusterMgr, m.segRegistry, streamReg, spillDir)
 
 	execpb.RegisterExecutorServer(m.grpcServer, execServer)
 
-	executor := exec.NewExecutor(m.segRegistry, streamReg, m.clusterMgr)
+	executor := exec.NewExecutor(m.catalog, m.segRegistry, streamReg, m.clusterMgr, spillDir)
 
 	m.apiServer, err = rest.NewRestApi(m.clusterMgr, ingRcp, m.bufReg, executor)
 
@@ -197,6 +215,8 @@ func (m *Meerkat) Shutdown(ctx context.Context) {
 
 	m.segmentWriterPool.Stop()
 
+	m.schemaPublisher.Stop()
+
 	err = m.catalog.Shutdown()
 
 	if err != nil {
@@ -240,7 +240,7 @@ func (i *IntColumnIterator) Next() vector.Int64Vector {
 		panic("column EOF")
 	}
 
-	v := i.pool.GetInt64Vector()
+	v := i.pool.GetNotNullableInt64Vector()
 	l := 0
 
 	for i.rid < i.colLen && v.RemainingLen() > 0 {
@@ -386,7 +386,7 @@ func NewIntColumnReader(br encoding.BlockReader,
 
 func (r *intColumnReader) Read(rids []uint32) vector.Int64Vector {
 
-	v := r.pool.GetInt64Vector()
+	v := r.pool.GetNotNullableInt64Vector()
 	vBuf := v.Buf()
 
 	for i, rid := range rids {
@@ -759,7 +759,7 @@ func (i *FloatColumnIterator) Next() vector.Float64Vector {
 		panic("column EOF")
 	}
 
-	v := i.pool.GetFloat64Vector()
+	v := i.pool.GetNotNullableFloat64Vector()
 	l := 0
 
 	for i.rid < i.colLen && v.RemainingLen() > 0 {
@@ -905,7 +905,7 @@ func NewFloatColumnReader(br encoding.BlockReader,
 
 func (r *floatColumnReader) Read(rids []uint32) vector.Float64Vector {
 
-	v := r.pool.GetFloat64Vector()
+	v := r.pool.GetNotNullableFloat64Vector()
 	vBuf := v.Buf()
 
 	for i, rid := range rids {
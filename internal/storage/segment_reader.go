@@ -122,7 +122,7 @@ func (s *segment) readColumns(cd []colData) {
 
 		switch cData.colType {
 		case ColumnType_TIMESTAMP:
-			col = NewInt64Column(s.f.Bytes, cData.bounds, s.numOfRows)
+			col = newTimeColumn(NewInt64Column(s.f.Bytes, cData.bounds, s.numOfRows))
 		case ColumnType_INT64:
 			col = NewInt64Column(s.f.Bytes, cData.bounds, s.numOfRows)
 		case ColumnType_FLOAT64:
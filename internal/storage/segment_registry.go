@@ -20,7 +20,12 @@ const (
 
 type SegmentRegistry interface {
 	SegmentInfos() []*SegmentInfo
-	Segments(partitions []uint64, dbName string, tableName string) []Segment
+	// Segments opens the segments matching the given partitions, database,
+	// table and time interval and returns them. The database is matched
+	// exactly, "" is the default database. Nil partitions, an empty table
+	// and a nil interval match every segment. The returned segments must be
+	// released.
+	Segments(partitions []uint64, dbName string, tableName string, interval *Interval) []Segment
 	AddSegment(segmentInfo *SegmentInfo)
 	RemoveSegment(segmentId uuid.UUID)
 	MergeSegment(segmentInfo *SegmentInfo, mergedSegments []uuid.UUID)
@@ -130,11 +135,11 @@ func (s *segmentRegistry) SegmentInfos() []*SegmentInfo {
 
 }
 
-func (s *segmentRegistry) Segments(partitions []uint64, dbName string, tableName string) []Segment {
+func (s *segmentRegistry) Segments(partitions []uint64, dbName string, tableName string, interval *Interval) []Segment {
 
 	s.log.Debug().Msg("getSegments")
 
-	entries := s.filterSegments(partitions, dbName, tableName)
+	entries := s.filterSegments(partitions, dbName, tableName, interval)
 
 	s.openSegments(entries)
 	segments := make([]Segment, len(entries))
@@ -193,7 +198,12 @@ func (s *segmentRegistry) openSegment(entry *segmentEntry) {
 
 }
 
-func (s *segmentRegistry) filterSegments(partitions []uint64, dbName string, tableName string) []*segmentEntry {
+func (s *segmentRegistry) filterSegments(
+	partitions []uint64,
+	dbName string,
+	tableName string,
+	interval *Interval,
+) []*segmentEntry {
 
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -202,8 +212,6 @@ func (s *segmentRegistry) filterSegments(partitions []uint64, dbName string, tab
 		panic(errors.New("segment registry not running"))
 	}
 
-	// TODO(gvelo): implement filtering.
-
 	var entries []*segmentEntry
 
 	for _, entry := range s.cache {
@@ -212,6 +220,10 @@ func (s *segmentRegistry) filterSegments(partitions []uint64, dbName string, tab
 			continue
 		}
 
+		if !MatchSegment(entry.segmentInfo, partitions, dbName, tableName, interval) {
+			continue
+		}
+
 		entry.refCount++
 
 		entries = append(entries, entry)
@@ -221,6 +233,51 @@ func (s *segmentRegistry) filterSegments(partitions []uint64, dbName string, tab
 	return entries
 }
 
+// MatchSegment returns true if the segment is one of the segments returned
+// by SegmentRegistry.Segments for the given filters.
+func MatchSegment(
+	info *SegmentInfo,
+	partitions []uint64,
+	dbName string,
+	tableName string,
+	interval *Interval,
+) bool {
+
+	if info.DatabaseName != dbName {
+		return false
+	}
+
+	if tableName != "" && info.TableName != tableName {
+		return false
+	}
+
+	if len(partitions) > 0 && !containsPartition(partitions, info.PartitionId) {
+		return false
+	}
+
+	if interval != nil && info.Interval != nil && !overlaps(info.Interval, interval) {
+		return false
+	}
+
+	return true
+
+}
+
+func containsPartition(partitions []uint64, id uint64) bool {
+	for _, p := range partitions {
+		if p == id {
+			return true
+		}
+	}
+	return false
+}
+
+// overlaps returns true if the intervals have a common point. Both ends of
+// an interval are inclusive.
+func overlaps(a *Interval, b *Interval) bool {
+	return !a.From.After(b.To) && !b.From.After(a.To)
+}
+
 func (s *segmentRegistry) AddSegment(segmentInfo *SegmentInfo) {
 
 	s.mu.Lock()
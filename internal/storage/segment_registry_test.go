@@ -49,6 +49,7 @@ func (s *segmentMock) Close() {
 var tests = map[string]func(t *testing.T){
 	"test add segment":         testAddSegment,
 	"test read write registry": testReadWrite,
+	"test filter segments":     testFilterSegments,
 }
 
 func TestSegmentRegistry(t *testing.T) {
@@ -76,7 +77,7 @@ func testAddSegment(t *testing.T) {
 
 	reg.AddSegment(segmentInfo)
 
-	result := reg.Segments(nil, "", "")
+	result := reg.Segments(nil, "test-db", "", nil)
 	assert.Len(t, result, 1)
 	assert.Equal(t, segMock, result[0])
 	reg.Stop()
@@ -109,6 +110,78 @@ func testReadWrite(t *testing.T) {
 
 }
 
+func testFilterSegments(t *testing.T) {
+
+	day := func(d int) time.Time { return time.Date(2020, 5, d, 0, 0, 0, 0, time.UTC) }
+
+	newInfo := func(table string, partition uint64, from int, to int) *SegmentInfo {
+		info := createTestSegmentInfo()
+		info.TableName = table
+		info.PartitionId = partition
+		info.Interval = &Interval{From: day(from), To: day(to)}
+		return info
+	}
+
+	// a table with the same name in another database.
+	otherDB := newInfo("logs", 0, 1, 6)
+	otherDB.DatabaseName = "other-db"
+
+	infos := []*SegmentInfo{
+		newInfo("logs", 0, 1, 2),
+		newInfo("logs", 1, 3, 4),
+		newInfo("logs", 0, 5, 6),
+		newInfo("metrics", 0, 1, 6),
+		otherDB,
+	}
+
+	storageMock := &storageMock{}
+	segments := make(map[*SegmentInfo]Segment)
+
+	for _, info := range infos {
+		segMock := &segmentMock{}
+		segMock.On("Close").Return()
+		storageMock.On("OpenSegment", info).Return(segMock)
+		segments[info] = segMock
+	}
+
+	reg := NewSegmentRegistry(os.TempDir(), storageMock)
+	reg.Start()
+	defer reg.Stop()
+
+	for _, info := range infos {
+		reg.AddSegment(info)
+	}
+
+	tests := []struct {
+		partitions []uint64
+		table      string
+		interval   *Interval
+		expected   []*SegmentInfo
+	}{
+		{nil, "", nil, infos[:4]},
+		{nil, "logs", nil, infos[:3]},
+		{[]uint64{0}, "logs", nil, []*SegmentInfo{infos[0], infos[2]}},
+		{nil, "logs", &Interval{From: day(2), To: day(3)}, infos[:2]},
+		{nil, "logs", &Interval{From: day(7), To: day(8)}, nil},
+		{[]uint64{0}, "", &Interval{From: day(6), To: day(6)}, infos[2:4]},
+	}
+
+	for _, test := range tests {
+
+		var expected []Segment
+
+		for _, info := range test.expected {
+			expected = append(expected, segments[info])
+		}
+
+		result := reg.Segments(test.partitions, "test-db", test.table, test.interval)
+
+		assert.ElementsMatch(t, expected, result)
+
+	}
+
+}
+
 func createTestSegmentInfo() *SegmentInfo {
 
 	id := uuid.New()
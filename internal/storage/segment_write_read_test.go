@@ -326,7 +326,7 @@ func (t *TestSegmentSource) ColumnSource(colName string, blockSize int) ColumnSo
 		case ColumnType_FLOAT64:
 			return NewFloat64TestColumnSrc(t.columns[colInfo.Name], blockSize)
 		case ColumnType_TIMESTAMP:
-			panic("not implemented yet")
+			return NewInt64TestColumnSrc(t.columns[colInfo.Name], blockSize)
 		case ColumnType_STRING:
 			return NewByteSliceTestColumnSrc(t.columns[colInfo.Name], blockSize)
 		default:
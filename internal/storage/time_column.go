@@ -0,0 +1,74 @@
+// Copyright 2020 The Meerkat Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"github.com/RoaringBitmap/roaring"
+	"sort"
+)
+
+// timeColumn is the timestamp column of a segment. The rows of a segment
+// are sorted by timestamp so the column can be used as a range index.
+type timeColumn struct {
+	*int64Column
+}
+
+func newTimeColumn(col *int64Column) *timeColumn {
+	return &timeColumn{int64Column: col}
+}
+
+func (cr *timeColumn) Index() TimeIndex {
+	return &timeIndex{reader: cr.Reader, numOfRows: cr.numOfRows}
+}
+
+type timeIndex struct {
+	reader    func() Int64ColumnReader
+	numOfRows int
+}
+
+// TimeRange returns the positions [startPos, endPos) of the rows with a
+// timestamp in the closed interval [start, end]. The rows are sorted by
+// timestamp so the bounds are binary searched, decoding only the blocks
+// holding the probed rows.
+func (idx *timeIndex) TimeRange(start int, end int) (startPos, endPos int) {
+
+	startPos = sort.Search(idx.numOfRows, func(pos int) bool {
+		return idx.timestamp(pos) >= start
+	})
+
+	endPos = startPos + sort.Search(idx.numOfRows-startPos, func(i int) bool {
+		return idx.timestamp(startPos+i) > end
+	})
+
+	return startPos, endPos
+
+}
+
+// timestamp returns the timestamp of the row at pos. The readers only
+// move forward so a new one is used for every probe.
+func (idx *timeIndex) timestamp(pos int) int {
+	v := idx.reader().Read([]uint32{uint32(pos)})
+	return int(v.Values()[0])
+}
+
+func (idx *timeIndex) TimeRangeAsBitmap(start int, end int) *roaring.Bitmap {
+
+	startPos, endPos := idx.TimeRange(start, end)
+
+	bitmap := roaring.New()
+	bitmap.AddRange(uint64(startPos), uint64(endPos))
+
+	return bitmap
+
+}
@@ -0,0 +1,147 @@
+// Copyright 2020 The Meerkat Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"meerkat/internal/storage/encoding"
+	"meerkat/internal/storage/vector"
+	"os"
+	"path"
+	"testing"
+)
+
+type sliceInt64Reader struct {
+	values []int64
+	reads  *int
+}
+
+func (r *sliceInt64Reader) Read(rids []uint32) vector.Int64Vector {
+
+	values := make([]int64, len(rids))
+
+	for i, rid := range rids {
+		values[i] = r.values[rid]
+	}
+
+	*r.reads++
+
+	return vector.NewInt64Vector(values, nil)
+
+}
+
+func TestTimeIndex(t *testing.T) {
+
+	values := []int64{10, 20, 20, 30, 40, 50}
+	reads := 0
+
+	idx := &timeIndex{
+		reader: func() Int64ColumnReader {
+			return &sliceInt64Reader{values: values, reads: &reads}
+		},
+		numOfRows: len(values),
+	}
+
+	tests := []struct {
+		start, end       int
+		startPos, endPos int
+	}{
+		{start: 20, end: 40, startPos: 1, endPos: 5},
+		{start: 0, end: 100, startPos: 0, endPos: 6},
+		{start: 21, end: 29, startPos: 3, endPos: 3},
+		{start: 60, end: 70, startPos: 6, endPos: 6},
+		{start: 0, end: 5, startPos: 0, endPos: 0},
+		{start: 45, end: 50, startPos: 5, endPos: 6},
+	}
+
+	for _, test := range tests {
+		startPos, endPos := idx.TimeRange(test.start, test.end)
+		assert.Equal(t, test.startPos, startPos, "start of [%v,%v]", test.start, test.end)
+		assert.Equal(t, test.endPos, endPos, "end of [%v,%v]", test.start, test.end)
+	}
+
+	assert.Equal(t, []uint32{1, 2, 3, 4}, idx.TimeRangeAsBitmap(20, 40).ToArray())
+
+	// the bounds are binary searched instead of reading every row
+	values = make([]int64, 1<<16)
+
+	for i := range values {
+		values[i] = int64(i)
+	}
+
+	idx.numOfRows = len(values)
+	reads = 0
+
+	startPos, endPos := idx.TimeRange(1000, 2000)
+	assert.Equal(t, 1000, startPos)
+	assert.Equal(t, 2001, endPos)
+	assert.True(t, reads <= 2*17, "%v reads", reads)
+
+}
+
+func TestTimeIndexSegment(t *testing.T) {
+
+	const segmentLen = 100000
+
+	id := [16]byte(uuid.New())
+
+	info := SegmentSourceInfo{
+		Id:           id,
+		DatabaseName: "test-db",
+		TableName:    "test-table",
+		Len:          segmentLen,
+		Columns: []ColumnSourceInfo{
+			{
+				Name:       TSColumnName,
+				ColumnType: ColumnType_TIMESTAMP,
+				IndexType:  IndexType_NONE,
+				Encoding:   encoding.Plain,
+				Len:        segmentLen,
+			},
+		},
+	}
+
+	// every timestamp is repeated 3 times
+	ts := make([]interface{}, segmentLen)
+
+	for i := range ts {
+		ts[i] = int64(i / 3)
+	}
+
+	src := NewTestSegmentSource(info)
+	src.columns[TSColumnName] = ts
+
+	segmentFile := path.Join(os.TempDir(), "test-time-segment")
+
+	WriteSegment(segmentFile, src)
+
+	segment := ReadSegment(segmentFile)
+	defer segment.Close()
+
+	idx := segment.Column(TSColumnName).(TimeColumn).Index()
+
+	startPos, endPos := idx.TimeRange(1000, 2000)
+	assert.Equal(t, 3000, startPos)
+	assert.Equal(t, 6003, endPos)
+
+	startPos, endPos = idx.TimeRange(-10, -1)
+	assert.Equal(t, 0, startPos)
+	assert.Equal(t, 0, endPos)
+
+	startPos, endPos = idx.TimeRange(0, segmentLen)
+	assert.Equal(t, 0, startPos)
+	assert.Equal(t, segmentLen, endPos)
+
+}
@@ -105,7 +105,10 @@ func (v *Int64Vector) AsBytes() []byte {
 }
 
 func (v *Int64Vector) ValidityAsBytes() []byte {
-	return sliceutil.U642B(v.valid[:v.l/8])
+	if v.valid == nil {
+		return nil
+	}
+	return sliceutil.U642B(v.valid[:validityLen(v.l)])
 }
 
 type Int32Vector struct {
@@ -189,7 +192,10 @@ func (v *Int32Vector) AsBytes() []byte {
 }
 
 func (v *Int32Vector) ValidityAsBytes() []byte {
-	return sliceutil.U642B(v.valid[:v.l/8])
+	if v.valid == nil {
+		return nil
+	}
+	return sliceutil.U642B(v.valid[:validityLen(v.l)])
 }
 
 type Float64Vector struct {
@@ -273,7 +279,10 @@ func (v *Float64Vector) AsBytes() []byte {
 }
 
 func (v *Float64Vector) ValidityAsBytes() []byte {
-	return sliceutil.U642B(v.valid[:v.l/8])
+	if v.valid == nil {
+		return nil
+	}
+	return sliceutil.U642B(v.valid[:validityLen(v.l)])
 }
 
 type BoolVector struct {
@@ -357,5 +366,8 @@ func (v *BoolVector) AsBytes() []byte {
 }
 
 func (v *BoolVector) ValidityAsBytes() []byte {
-	return sliceutil.U642B(v.valid[:v.l/8])
+	if v.valid == nil {
+		return nil
+	}
+	return sliceutil.U642B(v.valid[:validityLen(v.l)])
 }
@@ -22,6 +22,12 @@ const (
 	wordSize     = uint(64)
 )
 
+// validityLen returns the number of words needed to hold the validity
+// bitmap of a vector with l values.
+func validityLen(l int) int {
+	return (l + int(wordSize) - 1) >> log2WordSize
+}
+
 type Vector interface {
 	Len() int
 	Cap() int
@@ -79,7 +85,10 @@ func (v *ByteSliceVector) Offsets() []int {
 }
 
 func (v *ByteSliceVector) AsBytes() []byte {
-	return v.buf[:v.l]
+	if v.l == 0 {
+		return nil
+	}
+	return v.buf[:v.offsets[v.l-1]]
 }
 
 func (v *ByteSliceVector) OffsetsAsBytes() []byte {
@@ -87,7 +96,10 @@ func (v *ByteSliceVector) OffsetsAsBytes() []byte {
 }
 
 func (v *ByteSliceVector) ValidityAsBytes() []byte {
-	return sliceutil.U642B(v.valid[:v.l/8])
+	if v.valid == nil {
+		return nil
+	}
+	return sliceutil.U642B(v.valid[:validityLen(v.l)])
 }
 
 func (v *ByteSliceVector) IsValid(i int) bool {
@@ -154,6 +166,8 @@ func NewByteSliceVector(data []byte, offsets []int, valid []uint64) ByteSliceVec
 		offsets: offsets,
 		buf:     data,
 		valid:   valid,
+		l:       len(offsets),
+		c:       len(offsets),
 	}
 }
 
@@ -109,6 +109,16 @@ func B2U64(b []byte) []uint64 {
 	return res
 }
 
+func B2Bool(b []byte) []bool {
+	h := (*reflect.SliceHeader)(unsafe.Pointer(&b))
+	var res []bool
+	s := (*reflect.SliceHeader)(unsafe.Pointer(&res))
+	s.Data = h.Data
+	s.Len = h.Len
+	s.Cap = h.Cap
+	return res
+}
+
 func B2S(bs []byte) string {
 	return *(*string)(unsafe.Pointer(&bs))
 }